@@ -0,0 +1,29 @@
+// Command profiletometrics-pipeline prints a collector configuration wiring an OTLP profiles
+// receiver, the profiletometrics connector and the exporter of a backend:
+//
+//	profiletometrics-pipeline -backend prometheus > config.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	profiletometrics "github.com/henrikrexed/profiletoMetrics"
+)
+
+func main() {
+	backend := flag.String("backend", profiletometrics.BackendPrometheus, "metrics backend: prometheus, otlp or dynatrace")
+	endpoint := flag.String("endpoint", "", "exporter endpoint (default: the backend's usual endpoint)")
+	flag.Parse()
+
+	config, err := profiletometrics.ExamplePipeline(profiletometrics.ExamplePipelineOptions{
+		Backend:  *backend,
+		Endpoint: *endpoint,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fmt.Print(config)
+}
@@ -1,6 +1,10 @@
 package profiletometrics
 
 import (
+	"time"
+
+	"go.opentelemetry.io/collector/pipeline"
+
 	"github.com/henrikrexed/profiletoMetrics/pkg/profiletometrics"
 )
 
@@ -8,4 +12,31 @@ import (
 type Config struct {
 	// ConverterConfig embeds the converter configuration
 	ConverterConfig profiletometrics.ConverterConfig `mapstructure:",squash"`
+
+	// PprofExportDir, when set, writes every received batch as pprof files (one per process) for debugging
+	PprofExportDir string `mapstructure:"pprof_export_dir"`
+	// PprofExportCompression selects "gzip" (default) or "none" for the exported pprof files
+	PprofExportCompression string `mapstructure:"pprof_export_compression"`
+	// PprofExportMaxFileBytes skips exported pprof files larger than this many bytes; 0 disables the limit
+	PprofExportMaxFileBytes int64 `mapstructure:"pprof_export_max_file_bytes"`
+
+	// DiagnosticsEndpoint, when set, serves the diagnostics of the last conversions as JSON on
+	// http://<endpoint>/debug/profiletometrics; requires diagnostics_history
+	DiagnosticsEndpoint string `mapstructure:"diagnostics_endpoint"`
+
+	// Routing sends function-level metrics and the remaining metrics to different output pipelines
+	Routing RoutingConfig `mapstructure:"routing"`
+
+	// FlushInterval, when set, accumulates the metrics of every conversion and sends one consolidated
+	// batch per interval, summing identical series, instead of one batch per received payload
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// RoutingConfig routes metric families to pipelines the connector exports to
+// Routing is disabled when no function pipelines are set
+type RoutingConfig struct {
+	// FunctionPipelines receive function-level data points (function.name or the group_by attribute)
+	FunctionPipelines []pipeline.ID `mapstructure:"function_pipelines"`
+	// DefaultPipelines receive all other data points; defaults to every pipeline not listed above
+	DefaultPipelines []pipeline.ID `mapstructure:"default_pipelines"`
 }
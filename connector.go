@@ -2,10 +2,21 @@ package profiletometrics
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 
 	"github.com/henrikrexed/profiletoMetrics/pkg/profiletometrics"
@@ -17,22 +28,163 @@ type profileToMetricsConnector struct {
 	nextConsumer consumer.Metrics
 	logger       *zap.Logger
 	converter    *profiletometrics.Converter
+
+	// functionConsumer receives function-level metrics when routing is configured; nextConsumer
+	// then only receives the remaining metrics
+	functionConsumer consumer.Metrics
+
+	// diagnosticsServer serves the conversion diagnostics when diagnostics_endpoint is set
+	diagnosticsServer *http.Server
+
+	// telemetryRegistration publishes the accumulator state when aggregation_temporality is set
+	telemetryRegistration metric.Registration
+
+	// aggregator consolidates the converted metrics until the next flush when flush_interval is set
+	aggregator *profiletometrics.IntervalAggregator
+	// stopFlush stops the flush loop; flushDone is closed once it has returned
+	stopFlush chan struct{}
+	flushDone chan struct{}
+}
+
+// diagnosticsPath is the HTTP path of the conversion diagnostics
+const diagnosticsPath = "/debug/profiletometrics"
+
+// setupRouting resolves the consumers of the routed pipelines
+func (c *profileToMetricsConnector) setupRouting(nextConsumer consumer.Metrics, routing RoutingConfig) error {
+	router, ok := nextConsumer.(connector.MetricsRouterAndConsumer)
+	if !ok {
+		return fmt.Errorf("routing requires the connector to export to multiple metrics pipelines")
+	}
+
+	functionPipelines := make(map[pipeline.ID]bool, len(routing.FunctionPipelines))
+	for _, id := range routing.FunctionPipelines {
+		functionPipelines[id] = true
+	}
+	defaultPipelines := routing.DefaultPipelines
+	if len(defaultPipelines) == 0 {
+		for _, id := range router.PipelineIDs() {
+			if !functionPipelines[id] {
+				defaultPipelines = append(defaultPipelines, id)
+			}
+		}
+	}
+	if len(defaultPipelines) == 0 {
+		return fmt.Errorf("routing.default_pipelines is empty: every pipeline receives function metrics")
+	}
+
+	functionConsumer, err := router.Consumer(routing.FunctionPipelines...)
+	if err != nil {
+		return fmt.Errorf("invalid routing.function_pipelines: %w", err)
+	}
+	defaultConsumer, err := router.Consumer(defaultPipelines...)
+	if err != nil {
+		return fmt.Errorf("invalid routing.default_pipelines: %w", err)
+	}
+	c.functionConsumer = functionConsumer
+	c.nextConsumer = defaultConsumer
+	return nil
 }
 
 // Start implements component.Component.
 func (c *profileToMetricsConnector) Start(_ context.Context, host component.Host) error {
 	c.logger.Info("Starting ProfileToMetrics connector")
+	if c.config != nil && c.config.DiagnosticsEndpoint != "" {
+		if err := c.startDiagnosticsServer(c.config.DiagnosticsEndpoint); err != nil {
+			return err
+		}
+	}
+	if c.aggregator != nil {
+		c.startFlushLoop(c.config.FlushInterval)
+	}
 	c.logger.Debug("ProfileToMetrics connector started successfully")
 	return nil
 }
 
 // Shutdown implements component.Component.
-func (c *profileToMetricsConnector) Shutdown(_ context.Context) error {
+func (c *profileToMetricsConnector) Shutdown(ctx context.Context) error {
 	c.logger.Info("Shutting down ProfileToMetrics connector")
+	if c.stopFlush != nil {
+		close(c.stopFlush)
+		<-c.flushDone
+		c.stopFlush = nil
+	}
+	if c.aggregator != nil {
+		// Send the metrics of the last, partial interval
+		if err := c.flush(ctx); err != nil {
+			return fmt.Errorf("failed to flush metrics: %w", err)
+		}
+	}
+	if c.telemetryRegistration != nil {
+		if err := c.telemetryRegistration.Unregister(); err != nil {
+			return fmt.Errorf("failed to unregister accumulator telemetry: %w", err)
+		}
+	}
+	if c.diagnosticsServer != nil {
+		if err := c.diagnosticsServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to stop diagnostics server: %w", err)
+		}
+	}
 	c.logger.Debug("ProfileToMetrics connector shutdown completed")
 	return nil
 }
 
+// startFlushLoop sends the consolidated metrics every interval until Shutdown
+func (c *profileToMetricsConnector) startFlushLoop(interval time.Duration) {
+	c.stopFlush = make(chan struct{})
+	c.flushDone = make(chan struct{})
+	go func() {
+		defer close(c.flushDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.flush(context.Background()); err != nil {
+					c.logger.Error("Failed to flush metrics", zap.Error(err))
+				}
+			case <-c.stopFlush:
+				return
+			}
+		}
+	}()
+}
+
+// flush sends the metrics consolidated since the previous flush, if any
+func (c *profileToMetricsConnector) flush(ctx context.Context) error {
+	metrics := c.aggregator.Flush()
+	if metrics.ResourceMetrics().Len() == 0 {
+		return nil
+	}
+	return c.consumeMetrics(ctx, metrics)
+}
+
+// emitMetrics sends converted metrics on, or keeps them for the next flush when flush_interval is set
+func (c *profileToMetricsConnector) emitMetrics(ctx context.Context, metrics pmetric.Metrics) error {
+	if c.aggregator != nil {
+		c.aggregator.Add(metrics)
+		return nil
+	}
+	return c.consumeMetrics(ctx, metrics)
+}
+
+// startDiagnosticsServer serves the converter diagnostics on the given endpoint
+func (c *profileToMetricsConnector) startDiagnosticsServer(endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to listen on diagnostics_endpoint %q: %w", endpoint, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(diagnosticsPath, c.converter.DiagnosticsHandler())
+	c.diagnosticsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := c.diagnosticsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.logger.Error("Diagnostics server failed", zap.Error(err))
+		}
+	}()
+	c.logger.Info("Serving conversion diagnostics", zap.String("url", "http://"+listener.Addr().String()+diagnosticsPath))
+	return nil
+}
+
 // Capabilities implements connector interfaces.
 func (c *profileToMetricsConnector) Capabilities() consumer.Capabilities {
 	return consumer.Capabilities{MutatesData: true}
@@ -49,6 +201,20 @@ func (c *profileToMetricsConnector) ConsumeProfiles(ctx context.Context, profile
 		zap.Int("total_samples", totalSamples),
 	)
 
+	// Dump the batch as pprof files so the converter's attribution can be cross-checked
+	if c.config != nil && c.config.PprofExportDir != "" {
+		paths, err := profiletometrics.WritePprofFilesWithOptions(profiles, c.config.PprofExportDir,
+			profiletometrics.PprofWriteOptions{
+				Compression:  c.config.PprofExportCompression,
+				MaxFileBytes: c.config.PprofExportMaxFileBytes,
+			})
+		if err != nil {
+			c.logger.Warn("Failed to export profiles as pprof", zap.Error(err), zap.Strings("files", paths))
+		} else {
+			c.logger.Debug("Exported profiles as pprof", zap.Strings("files", paths))
+		}
+	}
+
 	// Convert profiles to metrics using the converter
 	metrics, err := c.converter.ConvertProfilesToMetrics(ctx, profiles)
 	if err != nil {
@@ -59,6 +225,59 @@ func (c *profileToMetricsConnector) ConsumeProfiles(ctx context.Context, profile
 		return err
 	}
 
+	if err := c.emitMetrics(ctx, metrics); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Profiles successfully processed and metrics sent to next consumer")
+	return nil
+}
+
+// ConsumeLogs implements connector.Logs: the profile payloads embedded in log records are converted
+// like received profiles.
+func (c *profileToMetricsConnector) ConsumeLogs(ctx context.Context, logs plog.Logs) error {
+	c.logger.Debug("Processing logs", zap.Int("log_records_count", logs.LogRecordCount()))
+
+	metrics, err := c.converter.ConvertLogsToMetrics(ctx, logs)
+	if err != nil {
+		c.logger.Error("Failed to convert log profile payloads to metrics", zap.Error(err))
+		return err
+	}
+	if metrics.ResourceMetrics().Len() == 0 {
+		return nil
+	}
+	if err := c.emitMetrics(ctx, metrics); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Logs successfully processed and metrics sent to next consumer")
+	return nil
+}
+
+// ConsumeTraces implements connector.Traces: the profile payloads attached to spans are converted
+// like received profiles.
+func (c *profileToMetricsConnector) ConsumeTraces(ctx context.Context, traces ptrace.Traces) error {
+	c.logger.Debug("Processing traces", zap.Int("span_count", traces.SpanCount()))
+
+	metrics, err := c.converter.ConvertTracesToMetrics(ctx, traces)
+	if err != nil {
+		c.logger.Error("Failed to convert span profile payloads to metrics", zap.Error(err))
+		return err
+	}
+	if metrics.ResourceMetrics().Len() == 0 {
+		return nil
+	}
+	if err := c.emitMetrics(ctx, metrics); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Traces successfully processed and metrics sent to next consumer")
+	return nil
+}
+
+// consumeMetrics routes function-level metrics to their pipelines, when configured, and sends the
+// remaining metrics to the next consumer
+func (c *profileToMetricsConnector) consumeMetrics(ctx context.Context, metrics pmetric.Metrics) error {
 	// Log output statistics
 	resourceMetricsCount := metrics.ResourceMetrics().Len()
 	totalMetrics := 0
@@ -70,11 +289,21 @@ func (c *profileToMetricsConnector) ConsumeProfiles(ctx context.Context, profile
 	}
 
 	c.logger.Debug("Profiles converted to metrics",
-		zap.Int("input_samples", totalSamples),
 		zap.Int("output_resource_metrics", resourceMetricsCount),
 		zap.Int("output_metrics", totalMetrics),
 	)
 
+	// Route function-level metrics to their own pipelines
+	if c.functionConsumer != nil {
+		functionMetrics := c.converter.SplitFunctionMetrics(metrics)
+		if functionMetrics.ResourceMetrics().Len() > 0 {
+			if err := c.functionConsumer.ConsumeMetrics(ctx, functionMetrics); err != nil {
+				c.logger.Error("Failed to send function metrics to routed pipelines", zap.Error(err))
+				return err
+			}
+		}
+	}
+
 	// Send metrics to the next consumer
 	if err := c.nextConsumer.ConsumeMetrics(ctx, metrics); err != nil {
 		c.logger.Error("Failed to send metrics to next consumer",
@@ -84,6 +313,5 @@ func (c *profileToMetricsConnector) ConsumeProfiles(ctx context.Context, profile
 		return err
 	}
 
-	c.logger.Debug("Profiles successfully processed and metrics sent to next consumer")
 	return nil
 }
@@ -1,15 +1,30 @@
 package profiletometrics
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/google/pprof/profile"
 	"github.com/henrikrexed/profiletoMetrics/pkg/profiletometrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func TestProfileToMetricsConnector_Start(t *testing.T) {
@@ -75,3 +90,325 @@ func TestProfileToMetricsConnector_ConsumeProfiles(t *testing.T) {
 	err = connector.ConsumeProfiles(context.Background(), profiles)
 	assert.NoError(t, err)
 }
+
+func TestProfileToMetricsConnector_RoutesFunctionMetrics(t *testing.T) {
+	functionSink := new(consumertest.MetricsSink)
+	defaultSink := new(consumertest.MetricsSink)
+	functionPipeline := pipeline.NewIDWithName(pipeline.SignalMetrics, "functions")
+	defaultPipeline := pipeline.NewIDWithName(pipeline.SignalMetrics, "totals")
+	router := connector.NewMetricsRouter(map[pipeline.ID]consumer.Metrics{
+		functionPipeline: functionSink,
+		defaultPipeline:  defaultSink,
+	})
+
+	config := createDefaultConfig().(*Config)
+	config.Routing.FunctionPipelines = []pipeline.ID{functionPipeline}
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	profilesConnector, err := createProfilesToMetricsConnector(context.Background(), settings, config, router)
+	require.NoError(t, err)
+
+	profiles := pprofile.NewProfiles()
+	dictionary := profiles.Dictionary()
+	dictionary.StringTable().Append("", "main", "process.executable.name")
+	dictionary.FunctionTable().AppendEmpty().SetNameStrindex(1)
+	dictionary.LocationTable().AppendEmpty().Line().AppendEmpty().SetFunctionIndex(0)
+	dictionary.StackTable().AppendEmpty().LocationIndices().Append(0)
+	attribute := dictionary.AttributeTable().AppendEmpty()
+	attribute.SetKeyStrindex(2)
+	attribute.Value().SetStr("app")
+	sample := profiles.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty().Profiles().AppendEmpty().Sample().AppendEmpty()
+	sample.SetStackIndex(0)
+	sample.AttributeIndices().Append(0)
+	sample.Values().Append(1000000000, 1024)
+
+	require.NoError(t, profilesConnector.ConsumeProfiles(context.Background(), profiles))
+
+	countDataPoints := func(sink *consumertest.MetricsSink, key string) (withKey, total int) {
+		for _, metrics := range sink.AllMetrics() {
+			for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+				scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+				for j := 0; j < scopeMetrics.Len(); j++ {
+					for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+						dataPoints := scopeMetrics.At(j).Metrics().At(k).Gauge().DataPoints()
+						for l := 0; l < dataPoints.Len(); l++ {
+							if _, ok := dataPoints.At(l).Attributes().Get(key); ok {
+								withKey++
+							}
+							total++
+						}
+					}
+				}
+			}
+		}
+		return withKey, total
+	}
+
+	functionPoints, functionTotal := countDataPoints(functionSink, "function.name")
+	assert.Equal(t, 2, functionPoints)
+	assert.Equal(t, functionPoints, functionTotal)
+	defaultFunctionPoints, defaultTotal := countDataPoints(defaultSink, "function.name")
+	assert.Equal(t, 0, defaultFunctionPoints)
+	assert.Positive(t, defaultTotal)
+}
+
+func TestProfileToMetricsConnector_ConsumeLogs(t *testing.T) {
+	sink := new(consumertest.MetricsSink)
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	logsConnector, err := createLogsToMetricsConnector(context.Background(), settings, createDefaultConfig(), sink)
+	require.NoError(t, err)
+
+	function := &profile.Function{ID: 1, Name: "main.main"}
+	location := &profile.Location{ID: 1, Line: []profile.Line{{Function: function}}}
+	var payload bytes.Buffer
+	require.NoError(t, (&profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Sample:     []*profile.Sample{{Location: []*profile.Location{location}, Value: []int64{1000000000}}},
+		Location:   []*profile.Location{location},
+		Function:   []*profile.Function{function},
+	}).Write(&payload))
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr(base64.StdEncoding.EncodeToString(payload.Bytes()))
+	records.AppendEmpty().Body().SetStr("plain log line")
+
+	require.NoError(t, logsConnector.ConsumeLogs(context.Background(), logs))
+	require.Len(t, sink.AllMetrics(), 1)
+	assert.Positive(t, sink.AllMetrics()[0].DataPointCount())
+}
+
+func TestProfileToMetricsConnector_ConsumeTraces(t *testing.T) {
+	sink := new(consumertest.MetricsSink)
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	tracesConnector, err := createTracesToMetricsConnector(context.Background(), settings, createDefaultConfig(), sink)
+	require.NoError(t, err)
+
+	function := &profile.Function{ID: 1, Name: "main.main"}
+	location := &profile.Location{ID: 1, Line: []profile.Line{{Function: function}}}
+	var payload bytes.Buffer
+	require.NoError(t, (&profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Sample:     []*profile.Sample{{Location: []*profile.Location{location}, Value: []int64{1000000000}}},
+		Location:   []*profile.Location{location},
+		Function:   []*profile.Function{function},
+	}).Write(&payload))
+
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	event := span.Events().AppendEmpty()
+	event.SetName("profile")
+	event.Attributes().PutEmptyBytes("profile.data").FromRaw(payload.Bytes())
+
+	require.NoError(t, tracesConnector.ConsumeTraces(context.Background(), traces))
+	require.Len(t, sink.AllMetrics(), 1)
+	assert.Positive(t, sink.AllMetrics()[0].DataPointCount())
+
+	// Traces without profiles produce no metrics
+	require.NoError(t, tracesConnector.ConsumeTraces(context.Background(), ptrace.NewTraces()))
+	assert.Len(t, sink.AllMetrics(), 1)
+}
+
+func TestCreateProfilesToMetricsConnector_RoutingRequiresRouter(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	config.Routing.FunctionPipelines = []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalMetrics, "functions")}
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+
+	_, err := createProfilesToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	assert.Error(t, err)
+}
+
+func TestProfileToMetricsConnector_ServesDiagnostics(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	config := createDefaultConfig().(*Config)
+	config.DiagnosticsEndpoint = endpoint
+	config.ConverterConfig.DiagnosticsHistory = 5
+	require.NoError(t, config.Validate())
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	profilesConnector, err := createProfilesToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, profilesConnector.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { require.NoError(t, profilesConnector.Shutdown(context.Background())) }()
+
+	require.NoError(t, profilesConnector.ConsumeProfiles(context.Background(), pprofile.NewProfiles()))
+
+	response, err := http.Get("http://" + endpoint + diagnosticsPath)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var conversions []profiletometrics.ConversionDiagnostics
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&conversions))
+	assert.Len(t, conversions, 1)
+}
+
+func TestProfileToMetricsConnector_SharedAcrossSignals(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	config := createDefaultConfig().(*Config)
+	config.DiagnosticsEndpoint = endpoint
+	config.ConverterConfig.DiagnosticsHistory = 5
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	profilesConnector, err := createProfilesToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	require.NoError(t, err)
+	logsConnector, err := createLogsToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.Same(t, profilesConnector, logsConnector)
+
+	// The second pipeline does not listen on the diagnostics endpoint again
+	require.NoError(t, profilesConnector.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, logsConnector.Start(context.Background(), componenttest.NewNopHost()))
+
+	require.NoError(t, profilesConnector.Shutdown(context.Background()))
+	response, err := http.Get("http://" + endpoint + diagnosticsPath)
+	require.NoError(t, err, "the connector keeps running until its last pipeline shuts down")
+	response.Body.Close()
+
+	require.NoError(t, logsConnector.Shutdown(context.Background()))
+	_, err = http.Get("http://" + endpoint + diagnosticsPath)
+	assert.Error(t, err)
+
+	// A new pipeline after the shutdown gets a new connector
+	tracesConnector, err := createTracesToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.NotSame(t, profilesConnector, tracesConnector)
+	require.NoError(t, tracesConnector.Shutdown(context.Background()))
+}
+
+func TestProfileToMetricsConnector_AccumulatorTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	settings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	config := createDefaultConfig().(*Config)
+	config.ConverterConfig.AggregationTemporality = "cumulative"
+	profilesConnector, err := createProfilesToMetricsConnector(context.Background(), settings, config, consumertest.NewNop())
+	require.NoError(t, err)
+
+	profiles := pprofile.NewProfiles()
+	dictionary := profiles.Dictionary()
+	dictionary.StringTable().Append("", "main")
+	dictionary.FunctionTable().AppendEmpty().SetNameStrindex(1)
+	dictionary.LocationTable().AppendEmpty().Line().AppendEmpty().SetFunctionIndex(0)
+	dictionary.StackTable().AppendEmpty().LocationIndices().Append(0)
+	sample := profiles.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty().Profiles().AppendEmpty().Sample().AppendEmpty()
+	sample.SetStackIndex(0)
+	sample.Values().Append(1000000000)
+	require.NoError(t, profilesConnector.ConsumeProfiles(context.Background(), profiles))
+
+	var resourceMetrics metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &resourceMetrics))
+	values := make(map[string]int64)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, m := range scopeMetrics.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			case metricdata.Sum[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			}
+		}
+	}
+	assert.Positive(t, values["profiletometrics.accumulator.active_series"])
+	assert.Zero(t, values["profiletometrics.accumulator.evictions"])
+	assert.Positive(t, values["profiletometrics.accumulator.memory_usage"])
+	assert.NoError(t, profilesConnector.Shutdown(context.Background()))
+}
+
+func TestConfig_DiagnosticsEndpointRequiresHistory(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	config.DiagnosticsEndpoint = "localhost:0"
+	assert.Error(t, config.Validate())
+}
+
+func TestConfig_ValidatesConverterConfig(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	require.NoError(t, config.Validate())
+
+	config.ConverterConfig.ProcessFilter.Enabled = true
+	config.ConverterConfig.ProcessFilter.Patterns = []string{"("}
+	assert.ErrorContains(t, config.Validate(), "process_filter.patterns")
+}
+
+func TestProfileToMetricsConnector_FlushInterval(t *testing.T) {
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+	config := createDefaultConfig().(*Config)
+	config.FlushInterval = time.Hour
+	require.NoError(t, config.Validate())
+	sink := &consumertest.MetricsSink{}
+	profilesConnector, err := createProfilesToMetricsConnector(context.Background(), settings, config, sink)
+	require.NoError(t, err)
+	require.NoError(t, profilesConnector.Start(context.Background(), componenttest.NewNopHost()))
+
+	newProfiles := func() pprofile.Profiles {
+		profiles := pprofile.NewProfiles()
+		dictionary := profiles.Dictionary()
+		dictionary.StringTable().Append("", "main")
+		dictionary.FunctionTable().AppendEmpty().SetNameStrindex(1)
+		dictionary.LocationTable().AppendEmpty().Line().AppendEmpty().SetFunctionIndex(0)
+		dictionary.StackTable().AppendEmpty().LocationIndices().Append(0)
+		resourceProfiles := profiles.ResourceProfiles().AppendEmpty()
+		resourceProfiles.Resource().Attributes().PutStr("service.name", "checkout")
+		sample := resourceProfiles.ScopeProfiles().AppendEmpty().Profiles().AppendEmpty().Sample().AppendEmpty()
+		sample.SetStackIndex(0)
+		sample.Values().Append(1000000000)
+		return profiles
+	}
+	require.NoError(t, profilesConnector.ConsumeProfiles(context.Background(), newProfiles()))
+	require.NoError(t, profilesConnector.ConsumeProfiles(context.Background(), newProfiles()))
+	assert.Empty(t, sink.AllMetrics(), "metrics are held until the interval ends")
+
+	require.NoError(t, profilesConnector.Shutdown(context.Background()))
+	require.Len(t, sink.AllMetrics(), 1, "shutdown flushes the partial interval as one batch")
+	metrics := sink.AllMetrics()[0]
+	require.Equal(t, 1, metrics.ResourceMetrics().Len())
+	cpuTimes := 0
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics()
+	for i := 0; i < scopeMetrics.Len(); i++ {
+		for j := 0; j < scopeMetrics.At(i).Metrics().Len(); j++ {
+			metric := scopeMetrics.At(i).Metrics().At(j)
+			if metric.Name() == "cpu_time" {
+				cpuTimes++
+				require.Equal(t, 1, metric.Gauge().DataPoints().Len())
+				assert.InDelta(t, 2.0, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9)
+			}
+		}
+	}
+	assert.Equal(t, 1, cpuTimes)
+}
+
+func TestConfig_NegativeFlushInterval(t *testing.T) {
+	config := createDefaultConfig().(*Config)
+	config.FlushInterval = -time.Second
+	assert.Error(t, config.Validate())
+}
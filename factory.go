@@ -3,11 +3,13 @@ package profiletometrics
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/connector"
 	"go.opentelemetry.io/collector/connector/xconnector"
 	"go.opentelemetry.io/collector/consumer"
+	"go.uber.org/zap"
 
 	"github.com/henrikrexed/profiletoMetrics/pkg/profiletometrics"
 )
@@ -21,8 +23,13 @@ func NewFactory() connector.Factory {
 	return xconnector.NewFactory(
 		component.MustNewType(typeStr),
 		createDefaultConfig,
-		// This is a pure profiles-to-metrics connector
 		xconnector.WithProfilesToMetrics(createProfilesToMetricsConnector, component.StabilityLevelAlpha),
+		// Logs pipelines carry profiles embedded in log records (see logs.payload_field)
+		xconnector.WithLogsToMetrics(createLogsToMetricsConnector, component.StabilityLevelAlpha),
+		// Traces pipelines carry profiles attached to spans (see traces.payload_attribute)
+		xconnector.WithTracesToMetrics(createTracesToMetricsConnector, component.StabilityLevelAlpha),
+		// Traces pipelines can also receive profiles as span trees (see span_output.enabled)
+		xconnector.WithProfilesToTraces(createProfilesToTracesConnector, component.StabilityLevelAlpha),
 	)
 }
 
@@ -32,7 +39,46 @@ func createProfilesToMetricsConnector(
 	cfg component.Config,
 	nextConsumer consumer.Metrics,
 ) (xconnector.Profiles, error) {
-	config := cfg.(*Config)
+	c, err := sharedConnectors.getOrCreate(set, cfg.(*Config), nextConsumer)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func createLogsToMetricsConnector(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (connector.Logs, error) {
+	c, err := sharedConnectors.getOrCreate(set, cfg.(*Config), nextConsumer)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func createTracesToMetricsConnector(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (connector.Traces, error) {
+	c, err := sharedConnectors.getOrCreate(set, cfg.(*Config), nextConsumer)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newProfileToMetricsConnector creates the connector shared by the profiles, logs and traces
+// pipelines of a component (see sharedConnectors)
+func newProfileToMetricsConnector(
+	set connector.Settings,
+	config *Config,
+	nextConsumer consumer.Metrics,
+) (*profileToMetricsConnector, error) {
 	converter, err := profiletometrics.NewConverter(&config.ConverterConfig)
 	if err != nil {
 		return nil, err
@@ -40,13 +86,31 @@ func createProfilesToMetricsConnector(
 
 	// Set the logger on the converter
 	converter.SetLogger(set.Logger)
+	for _, collision := range converter.MetricNameCollisions() {
+		set.Logger.Warn("Metric name shared by generators with different attributes; set metric_name_suffix to separate them",
+			zap.String("collision", collision))
+	}
 
-	return &profileToMetricsConnector{
+	c := &profileToMetricsConnector{
 		config:       config,
 		nextConsumer: nextConsumer,
 		logger:       set.Logger,
 		converter:    converter,
-	}, nil
+	}
+	if config.FlushInterval > 0 {
+		c.aggregator = profiletometrics.NewIntervalAggregator(converter)
+	}
+	if len(config.Routing.FunctionPipelines) > 0 {
+		if err := c.setupRouting(nextConsumer, config.Routing); err != nil {
+			return nil, err
+		}
+	}
+	if config.ConverterConfig.AggregationTemporality != "" {
+		if err := c.registerAccumulatorTelemetry(set.MeterProvider); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
 }
 
 func createDefaultConfig() component.Config {
@@ -56,16 +120,112 @@ func createDefaultConfig() component.Config {
 				CPU: profiletometrics.CPUMetricConfig{
 					Enabled:    true,
 					MetricName: "cpu_time",
-					Unit:       "ns",
 				},
 				Memory: profiletometrics.MemoryMetricConfig{
 					Enabled:    true,
 					MetricName: "memory_allocation",
-					Unit:       "bytes",
 				},
 				Function: profiletometrics.FunctionMetricConfig{
+					Enabled:            true,
+					Attribution:        "self",
+					GroupBy:            "function",
+					GroupByThread:      false,
+					CodeFilePath:       false,
+					IncludeLineNumbers: false,
+					HeatBuckets: profiletometrics.HeatBucketConfig{
+						Enabled:              false,
+						HotThresholdPercent:  10,
+						WarmThresholdPercent: 1,
+					},
+					Slope: profiletometrics.FunctionSlopeConfig{
+						Enabled:    false,
+						MetricName: "cpu_time.slope",
+						Window:     5 * time.Minute,
+					},
+					Diff: profiletometrics.FunctionDiffConfig{
+						Enabled:          false,
+						DeltaMetricName:  "cpu_time.delta",
+						ChangeMetricName: "cpu_time.change",
+						BaselineTTL:      10 * time.Minute,
+					},
+					Anomaly: profiletometrics.FunctionAnomalyConfig{
+						Enabled:         false,
+						MetricName:      "profile.function.anomaly_score",
+						Alpha:           0.3,
+						Threshold:       3,
+						MinObservations: 5,
+					},
+				},
+				Process: profiletometrics.ProcessMetricConfig{
 					Enabled: true,
 				},
+				Thread: profiletometrics.ThreadMetricConfig{
+					Enabled: false,
+				},
+				CPUUtilization: profiletometrics.CPUUtilizationMetricConfig{
+					Enabled:    false,
+					MetricName: "cpu.utilization",
+					Cores:      1,
+				},
+				HottestStack: profiletometrics.HottestStackMetricConfig{
+					Enabled:    false,
+					MetricName: "hottest_stack_share",
+				},
+				HotPath: profiletometrics.HotPathMetricConfig{
+					Enabled:       false,
+					MetricName:    "hot_path_cpu_share",
+					MaxPathLength: 1024,
+				},
+				Stack: profiletometrics.StackMetricConfig{
+					Enabled:                false,
+					AverageDepthMetricName: "stack_depth_average",
+					MaxDepthMetricName:     "stack_depth_max",
+					TruncatedMetricName:    "truncated_stacks",
+				},
+				Symbolization: profiletometrics.SymbolizationMetricConfig{
+					Enabled:    false,
+					MetricName: "profiles.symbolization.coverage",
+				},
+				CodeOrigin: profiletometrics.CodeOriginMetricConfig{
+					Enabled:    false,
+					MetricName: "cpu_share_by_code_origin",
+				},
+				FrameType: profiletometrics.FrameTypeMetricConfig{
+					Enabled:    false,
+					MetricName: "cpu_share_by_frame_type",
+				},
+				KernelUser: profiletometrics.KernelUserMetricConfig{
+					Enabled:          false,
+					KernelMetricName: "cpu.time.kernel",
+					UserMetricName:   "cpu.time.user",
+				},
+				CallGraph: profiletometrics.CallGraphMetricConfig{
+					Enabled:    false,
+					MetricName: "cpu_time_by_call_edge",
+				},
+				TraceCorrelation: profiletometrics.TraceCorrelationMetricConfig{
+					Enabled:    false,
+					MetricName: "cpu_time_by_trace",
+				},
+				Runtime: profiletometrics.RuntimeMetricConfig{
+					Enabled:              false,
+					HeapLiveMetricName:   "runtime.heap.live",
+					GCCPUShareMetricName: "runtime.gc.cpu_share",
+				},
+				Ingestion: profiletometrics.IngestionMetricConfig{
+					Enabled:      false,
+					MetricPrefix: "profiletometrics.ingestion",
+				},
+				Lock: profiletometrics.LockMetricConfig{
+					Enabled:                   false,
+					ContentionTimeMetricName:  "lock_contention_time",
+					ContentionCountMetricName: "lock_contention_count",
+				},
+				Exceptions: profiletometrics.ExceptionMetricConfig{
+					Enabled:       false,
+					MetricName:    "exception_count",
+					TypeAttribute: "exception.type",
+				},
 			},
 			Attributes: []profiletometrics.AttributeConfig{
 				{
@@ -85,7 +245,8 @@ func createDefaultConfig() component.Config {
 				},
 			},
 			ProcessFilter: profiletometrics.ProcessFilterConfig{
-				Enabled: false,
+				Enabled:           false,
+				KeepGlobalMetrics: false,
 			},
 			PatternFilter: profiletometrics.PatternFilterConfig{
 				Enabled: false,
@@ -93,15 +254,103 @@ func createDefaultConfig() component.Config {
 			ThreadFilter: profiletometrics.ThreadFilterConfig{
 				Enabled: false,
 			},
+			FunctionFilter: profiletometrics.FunctionFilterConfig{
+				Enabled: false,
+			},
+			Origin: profiletometrics.OriginConfig{
+				Enabled:      false,
+				AttributeKey: "origin",
+			},
+			FrameSelection: profiletometrics.FrameSelectionConfig{
+				Mode: "leaf",
+			},
+			FoldedStack: profiletometrics.FoldedStackConfig{
+				Enabled:   false,
+				Separator: ";",
+				MaxLength: 0,
+			},
+			SpanOutput: profiletometrics.TraceSpanConfig{
+				Enabled:            false,
+				MaxSpansPerProfile: 0,
+				SpanNameTemplate:   "{function}",
+				ExemplarStacks:     false,
+				DeterministicIDs:   false,
+				MaxEventsPerSpan:   0,
+			},
+			TraceDedup: profiletometrics.TraceDedupConfig{
+				Enabled: false,
+				Window:  time.Minute,
+			},
+			MaxAttributeValueLength: 0,
+			Logs: profiletometrics.LogsConfig{
+				PayloadField:  "body",
+				PayloadFormat: "auto",
+			},
+			Traces: profiletometrics.TracesConfig{
+				EventName:        "profile",
+				PayloadAttribute: "profile.data",
+				PayloadFormat:    "auto",
+			},
+			TruncatedStacks: profiletometrics.TruncatedStackConfig{
+				Enabled:  false,
+				MaxDepth: 0,
+			},
+			UseProfileTimestamps: false,
+			TimestampSource:      "receive_time",
+			OutputLayout:         "merged",
+			ProfileComments:      false,
+			SemconvAttributes:    false,
+			AggregationWindow:    0,
+			ArrayAttributes:      "join",
+			Auto:                 false,
+			Estimation: profiletometrics.EstimationConfig{
+				Enabled:         false,
+				SampleDuration:  0,
+				AllocationBytes: 2048,
+				SeparateMetrics: false,
+			},
+			Provenance: false,
+			Degradation: profiletometrics.DegradationConfig{
+				Enabled:             false,
+				LatencyThreshold:    0,
+				MemoryLimitMiB:      0,
+				RecoveryConversions: 3,
+				DownsampleRatio:     10,
+				MetricPrefix:        "profiletometrics.degradation",
+			},
+			Sampling: profiletometrics.ProfileSamplingConfig{
+				Ratio:                1,
+				MaxProfilesPerSecond: 0,
+			},
+			Staleness: profiletometrics.StalenessConfig{
+				Enabled: false,
+				TTL:     5 * time.Minute,
+			},
+			DiagnosticsHistory: 0,
 		},
 	}
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	// Validate that at least one metric is enabled
-	if !c.ConverterConfig.Metrics.CPU.Enabled && !c.ConverterConfig.Metrics.Memory.Enabled {
+	// Validate that at least one metric is enabled; auto mode enables them from the profiles
+	if !c.ConverterConfig.Auto && !c.ConverterConfig.Metrics.CPU.Enabled && !c.ConverterConfig.Metrics.Memory.Enabled {
 		return fmt.Errorf("at least one metric must be enabled")
 	}
+	if err := c.ConverterConfig.Validate(); err != nil {
+		return err
+	}
+	if err := profiletometrics.ValidatePprofCompression(c.PprofExportCompression); err != nil {
+		return err
+	}
+	if c.DiagnosticsEndpoint != "" && c.ConverterConfig.DiagnosticsHistory <= 0 {
+		return fmt.Errorf("diagnostics_endpoint requires diagnostics_history to be positive")
+	}
+	if c.PprofExportMaxFileBytes < 0 {
+		return fmt.Errorf("pprof_export_max_file_bytes must not be negative")
+	}
+	if c.FlushInterval < 0 {
+		return fmt.Errorf("flush_interval must not be negative")
+	}
 	return nil
 }
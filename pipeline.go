@@ -0,0 +1,143 @@
+package profiletometrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backends of the example pipelines generated by ExamplePipeline
+const (
+	BackendPrometheus = "prometheus"
+	BackendOTLP       = "otlp"
+	BackendDynatrace  = "dynatrace"
+)
+
+// ExamplePipelineOptions selects the backend of a generated pipeline
+type ExamplePipelineOptions struct {
+	// Backend is "prometheus", "otlp" or "dynatrace"
+	Backend string
+	// Endpoint overrides the exporter endpoint; each backend has a default
+	Endpoint string
+}
+
+// examplePipelineBackend holds the backend-specific parts of an example pipeline
+type examplePipelineBackend struct {
+	exporter        string
+	defaultEndpoint string
+	// maxAttributeValueLength is the backend's attribute value limit; 0 sets no cap
+	maxAttributeValueLength int
+}
+
+var examplePipelineBackends = map[string]examplePipelineBackend{
+	BackendPrometheus: {exporter: "prometheus", defaultEndpoint: "0.0.0.0:8889"},
+	BackendOTLP:       {exporter: "otlp", defaultEndpoint: "otel-backend:4317"},
+	// Dynatrace ingests gauges over OTLP/HTTP and caps dimension values at 250 characters. The
+	// connector's delta temporality subtracts successive profiles, which are already per window, so
+	// the default gauges are kept.
+	BackendDynatrace: {
+		exporter:                "otlphttp",
+		defaultEndpoint:         "${env:DT_ENDPOINT}/api/v2/otlp",
+		maxAttributeValueLength: 250,
+	},
+}
+
+var examplePipelineTemplate = template.Must(template.New("pipeline").Funcs(template.FuncMap{
+	"yamlString": yamlString,
+}).Parse(`# Collector configuration generated for the {{.Backend}} backend.
+# Run the collector with --feature-gates=+service.profilesSupport.
+
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+      http:
+        endpoint: 0.0.0.0:4318
+
+processors:
+  batch:
+
+connectors:
+  profiletometrics:
+    metrics:
+      cpu:
+        enabled: true
+        metric_name: "cpu_time"
+      memory:
+        enabled: true
+        metric_name: "memory_allocation"
+      process:
+        enabled: true
+        metric_name_suffix: ".by_process"
+      function:
+        enabled: true
+        metric_name_suffix: ".by_function"
+        top_n:
+          cpu: 20
+          memory: 10
+{{- if .MaxAttributeValueLength}}
+    max_attribute_value_length: {{.MaxAttributeValueLength}}
+{{- end}}
+
+exporters:
+  {{.Exporter}}:
+    endpoint: {{yamlString .Endpoint}}
+{{- if eq .Backend "otlp"}}
+    tls:
+      insecure: true
+{{- end}}
+{{- if eq .Backend "dynatrace"}}
+    headers:
+      Authorization: "Api-Token ${env:DT_API_TOKEN}"
+{{- end}}
+
+service:
+  pipelines:
+    profiles:
+      receivers: [otlp]
+      exporters: [profiletometrics]
+    metrics:
+      receivers: [profiletometrics]
+      processors: [batch]
+      exporters: [{{.Exporter}}]
+`))
+
+// ExamplePipeline returns a complete collector configuration wiring an OTLP profiles receiver, the
+// connector with recommended options and the exporter of the chosen backend
+func ExamplePipeline(opts ExamplePipelineOptions) (string, error) {
+	backend, ok := examplePipelineBackends[opts.Backend]
+	if !ok {
+		return "", fmt.Errorf("unknown backend %q: must be %q, %q or %q",
+			opts.Backend, BackendPrometheus, BackendOTLP, BackendDynatrace)
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = backend.defaultEndpoint
+	}
+
+	var out bytes.Buffer
+	err := examplePipelineTemplate.Execute(&out, map[string]any{
+		"Backend":                 opts.Backend,
+		"Exporter":                backend.exporter,
+		"Endpoint":                endpoint,
+		"MaxAttributeValueLength": backend.maxAttributeValueLength,
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// yamlString renders a value as a double-quoted YAML scalar, escaping quotes, colons, comment
+// markers and control characters
+func yamlString(value string) (string, error) {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: value})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
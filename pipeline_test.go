@@ -0,0 +1,95 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExamplePipeline(t *testing.T) {
+	tests := []struct {
+		backend  string
+		exporter string
+		endpoint string
+	}{
+		{backend: BackendPrometheus, exporter: "prometheus", endpoint: "0.0.0.0:8889"},
+		{backend: BackendOTLP, exporter: "otlp", endpoint: "otel-backend:4317"},
+		{backend: BackendDynatrace, exporter: "otlphttp", endpoint: "${env:DT_ENDPOINT}/api/v2/otlp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			out, err := ExamplePipeline(ExamplePipelineOptions{Backend: tt.backend})
+			require.NoError(t, err)
+
+			var config struct {
+				Connectors map[string]map[string]any `yaml:"connectors"`
+				Exporters  map[string]map[string]any `yaml:"exporters"`
+				Service    struct {
+					Pipelines map[string]map[string][]string `yaml:"pipelines"`
+				} `yaml:"service"`
+			}
+			require.NoError(t, yaml.Unmarshal([]byte(out), &config))
+
+			assert.Equal(t, tt.endpoint, config.Exporters[tt.exporter]["endpoint"])
+			connector := config.Connectors[typeStr]
+			require.NotNil(t, connector)
+			// Profiles are already per window: delta temporality would subtract successive windows
+			assert.NotContains(t, connector, "aggregation_temporality")
+			assertValidConnectorConfig(t, connector)
+			assert.Equal(t, []string{typeStr}, config.Service.Pipelines["profiles"]["exporters"])
+			assert.Equal(t, []string{typeStr}, config.Service.Pipelines["metrics"]["receivers"])
+			assert.Equal(t, []string{tt.exporter}, config.Service.Pipelines["metrics"]["exporters"])
+		})
+	}
+}
+
+// assertValidConnectorConfig decodes a connector block over the default configuration the way the
+// collector does and checks that the result validates
+func assertValidConnectorConfig(t *testing.T, raw map[string]any) {
+	t.Helper()
+	cfg := createDefaultConfig().(*Config)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      cfg,
+		ErrorUnused: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.TextUnmarshallerHookFunc(),
+		),
+	})
+	require.NoError(t, err)
+	require.NoError(t, decoder.Decode(raw))
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestExamplePipeline_Endpoint(t *testing.T) {
+	out, err := ExamplePipeline(ExamplePipelineOptions{Backend: BackendOTLP, Endpoint: "collector.example.com:4317"})
+	require.NoError(t, err)
+	assert.Contains(t, out, `endpoint: "collector.example.com:4317"`)
+}
+
+func TestExamplePipeline_EndpointEscaping(t *testing.T) {
+	for _, endpoint := range []string{
+		`https://example.com:443/otlp # not a comment`,
+		`say "hi"`,
+		`it's: here`,
+		"line\nbreak\ttab",
+	} {
+		out, err := ExamplePipeline(ExamplePipelineOptions{Backend: BackendOTLP, Endpoint: endpoint})
+		require.NoError(t, err)
+
+		var config struct {
+			Exporters map[string]map[string]any `yaml:"exporters"`
+		}
+		require.NoError(t, yaml.Unmarshal([]byte(out), &config), endpoint)
+		assert.Equal(t, endpoint, config.Exporters["otlp"]["endpoint"])
+	}
+}
+
+func TestExamplePipeline_UnknownBackend(t *testing.T) {
+	_, err := ExamplePipeline(ExamplePipelineOptions{Backend: "graphite"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Aggregations of the per-sample values of a series; an empty value means sum
+	aggregationSum   = "sum"
+	aggregationAvg   = "avg"
+	aggregationMin   = "min"
+	aggregationMax   = "max"
+	aggregationCount = "count"
+	aggregationP95   = "p95"
+
+	// digestCompression bounds the centroids kept by a quantile digest to about twice its value
+	digestCompression = 100
+)
+
+// validateAggregation checks an aggregation option
+func validateAggregation(option, aggregation string) error {
+	switch aggregation {
+	case "", aggregationSum, aggregationAvg, aggregationMin, aggregationMax, aggregationCount, aggregationP95:
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: must be %q, %q, %q, %q, %q or %q", option, aggregation,
+			aggregationSum, aggregationAvg, aggregationMin, aggregationMax, aggregationCount, aggregationP95)
+	}
+}
+
+// effectiveAggregation returns the aggregation applied for an option value
+func effectiveAggregation(aggregation string) string {
+	if aggregation == "" {
+		return aggregationSum
+	}
+	return aggregation
+}
+
+// summedAggregation reports whether an aggregation adds the sample values up
+func summedAggregation(aggregation string) bool {
+	return effectiveAggregation(aggregation) == aggregationSum
+}
+
+// additiveAggregation reports whether an aggregation adds up across conversions, as sums and counts do
+func additiveAggregation(aggregation string) bool {
+	switch effectiveAggregation(aggregation) {
+	case aggregationSum, aggregationCount:
+		return true
+	default:
+		return false
+	}
+}
+
+// generateAggregatedGaugeMetric emits a gauge valued with aggregated per-sample values. Only counts
+// are marked additive: averages, minima, maxima and percentiles are neither accumulated by
+// aggregation_temporality nor added up by flush_interval. With estimation.separate_metrics, the
+// samples without values are left out of the aggregated values and their estimate is summed in
+// <name>.estimated, as for sums.
+func (c *Converter) generateAggregatedGaugeMetric(
+	name, description string,
+	values *valueAggregator,
+	estimated float64,
+	attributes map[string]string,
+	profile pprofile.Profile,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	metric := c.generateGaugeMetric(name, fmt.Sprintf("%s (%s of the sample values)", description, values.aggregation),
+		values.value(), attributes, profile, scopeMetrics)
+	if additiveAggregation(values.aggregation) {
+		c.markAdditive(metric)
+	}
+	if c.config.Estimation.SeparateMetrics && estimated > 0 {
+		c.markAdditive(c.generateGaugeMetric(name+estimatedMetricSuffix, description+", estimated for samples without values",
+			estimated, attributes, profile, scopeMetrics))
+	}
+}
+
+// valueAggregator reduces the per-sample values of a series with an aggregation
+type valueAggregator struct {
+	aggregation string
+	sum         float64
+	min         float64
+	max         float64
+	count       int
+	digest      *quantileDigest
+}
+
+// newValueAggregator returns an aggregator, keeping a digest only for percentiles
+func newValueAggregator(aggregation string) *valueAggregator {
+	aggregator := &valueAggregator{aggregation: effectiveAggregation(aggregation)}
+	if aggregator.aggregation == aggregationP95 {
+		aggregator.digest = &quantileDigest{}
+	}
+	return aggregator
+}
+
+// add records the value of a sample
+func (a *valueAggregator) add(value float64) {
+	if a.count == 0 {
+		a.min, a.max = value, value
+	}
+	a.sum += value
+	a.min = min(a.min, value)
+	a.max = max(a.max, value)
+	a.count++
+	if a.digest != nil {
+		a.digest.add(value)
+	}
+}
+
+// value returns the aggregated value; series without samples are 0
+func (a *valueAggregator) value() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	switch a.aggregation {
+	case aggregationAvg:
+		return a.sum / float64(a.count)
+	case aggregationMin:
+		return a.min
+	case aggregationMax:
+		return a.max
+	case aggregationCount:
+		return float64(a.count)
+	case aggregationP95:
+		return a.digest.quantile(0.95)
+	default:
+		return a.sum
+	}
+}
+
+// centroid is a cluster of digested values
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// quantileDigest estimates quantiles in bounded memory by merging neighboring values into
+// centroids of at most count/digestCompression values. Quantiles are exact until the first merge.
+type quantileDigest struct {
+	centroids []centroid
+	count     float64
+	sorted    bool
+}
+
+// add records a value, compressing the digest once it holds too many centroids
+func (d *quantileDigest) add(value float64) {
+	d.centroids = append(d.centroids, centroid{mean: value, weight: 1})
+	d.count++
+	d.sorted = false
+	if len(d.centroids) > 10*digestCompression {
+		d.compress()
+	}
+}
+
+// sort orders the centroids by mean
+func (d *quantileDigest) sort() {
+	if !d.sorted {
+		sort.Slice(d.centroids, func(i, j int) bool { return d.centroids[i].mean < d.centroids[j].mean })
+		d.sorted = true
+	}
+}
+
+// compress merges neighboring centroids while they stay under the maximum weight
+func (d *quantileDigest) compress() {
+	d.sort()
+	maxWeight := d.count / digestCompression
+	merged := d.centroids[:1]
+	for _, next := range d.centroids[1:] {
+		last := &merged[len(merged)-1]
+		if last.weight+next.weight <= maxWeight {
+			total := last.weight + next.weight
+			last.mean += (next.mean - last.mean) * next.weight / total
+			last.weight = total
+			continue
+		}
+		merged = append(merged, next)
+	}
+	d.centroids = merged
+}
+
+// quantile returns the q quantile, interpolating linearly between the ranks of the centroid centers
+func (d *quantileDigest) quantile(q float64) float64 {
+	if len(d.centroids) == 0 {
+		return 0
+	}
+	d.sort()
+	rank := q * (d.count - 1)
+	var cumulative float64
+	previousCenter, previousMean := 0.0, d.centroids[0].mean
+	for i, c := range d.centroids {
+		center := cumulative + (c.weight-1)/2
+		if rank <= center {
+			if i == 0 || center == previousCenter {
+				return c.mean
+			}
+			return previousMean + (c.mean-previousMean)*(rank-previousCenter)/(center-previousCenter)
+		}
+		previousCenter, previousMean = center, c.mean
+		cumulative += c.weight
+	}
+	return d.centroids[len(d.centroids)-1].mean
+}
@@ -0,0 +1,181 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestValueAggregator(t *testing.T) {
+	tests := []struct {
+		aggregation string
+		expected    float64
+	}{
+		{aggregation: "", expected: 100},
+		{aggregation: "sum", expected: 100},
+		{aggregation: "avg", expected: 25},
+		{aggregation: "min", expected: 10},
+		{aggregation: "max", expected: 40},
+		{aggregation: "count", expected: 4},
+		{aggregation: "p95", expected: 38.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			aggregator := newValueAggregator(tt.aggregation)
+			assert.Equal(t, 0.0, aggregator.value())
+			for _, value := range []float64{30, 10, 40, 20} {
+				aggregator.add(value)
+			}
+			assert.InDelta(t, tt.expected, aggregator.value(), 1e-9)
+		})
+	}
+}
+
+func TestQuantileDigest(t *testing.T) {
+	digest := &quantileDigest{}
+	for i := 10000; i >= 1; i-- {
+		digest.add(float64(i))
+	}
+	// The digest stays bounded and close to the exact 9500.05
+	assert.LessOrEqual(t, len(digest.centroids), 10*digestCompression)
+	assert.InEpsilon(t, 9500.05, digest.quantile(0.95), 0.01)
+	assert.InEpsilon(t, 5000.5, digest.quantile(0.5), 0.01)
+}
+
+func TestValidateAggregation(t *testing.T) {
+	assert.NoError(t, validateAggregation("metrics.cpu.aggregation", ""))
+	assert.NoError(t, validateAggregation("metrics.cpu.aggregation", "p95"))
+	assert.ErrorContains(t, validateAggregation("metrics.memory.aggregation", "median"),
+		`invalid metrics.memory.aggregation "median"`)
+}
+
+func TestConverter_MemoryAggregation(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:  MemoryMetricConfig{Enabled: true, MetricName: "memory_live", Aggregation: "max"},
+			Process: ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	app := map[string]string{"process.executable.name": "app"}
+	db := map[string]string{"process.executable.name": "db"}
+	b.sample(b.stack("main.main"), app, 1000000000, 4096)
+	b.sample(b.stack("main.main"), app, 1000000000, 1024)
+	b.sample(b.stack("main.main"), db, 1000000000, 2048)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+		dataPoints := metric.Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			processName, _ := dataPoints.At(j).Attributes().Get("process.name")
+			values[metric.Name()+"/"+processName.Str()] = dataPoints.At(j).DoubleValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"cpu_time/":                  3,
+		"memory_live/":               4096,
+		"cpu_time.by_process/app":    2,
+		"cpu_time.by_process/db":     1,
+		"memory_live.by_process/app": 4096,
+		"memory_live.by_process/db":  2048,
+	}, values)
+}
+
+func TestConverter_AggregationAcrossConversions(t *testing.T) {
+	// convert converts profiles with the given per-sample allocations and returns the metric types
+	// and values by name, of the last conversion or of the interval when an aggregator is given
+	convert := func(t *testing.T, converter *Converter, aggregator *IntervalAggregator) map[string]any {
+		var metrics pmetric.Metrics
+		for _, allocations := range [][]int64{{4096, 1024}, {2048}} {
+			b := newTestProfileBuilder()
+			for _, allocation := range allocations {
+				b.sample(b.stack("main.main"), nil, 1000000000, allocation)
+			}
+			converted, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+			metrics = converted
+			if aggregator != nil {
+				aggregator.Add(converted)
+			}
+		}
+		if aggregator != nil {
+			metrics = aggregator.Flush()
+		}
+		values := make(map[string]any)
+		metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		for i := 0; i < metricSlice.Len(); i++ {
+			metric := metricSlice.At(i)
+			if metric.Type() == pmetric.MetricTypeGauge {
+				values[metric.Name()] = metric.Gauge().DataPoints().At(0).DoubleValue()
+			} else {
+				values[metric.Name()] = metric.Type()
+			}
+		}
+		return values
+	}
+	cfg := ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "samples", Aggregation: "count"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_live", Aggregation: "max"},
+		},
+	}
+
+	t.Run("aggregation_temporality", func(t *testing.T) {
+		temporalityCfg := cfg
+		temporalityCfg.AggregationTemporality = aggregationTemporalityCumulative
+		converter, err := NewConverter(&temporalityCfg)
+		require.NoError(t, err)
+
+		// Counts are accumulated, maxima stay gauges
+		assert.Equal(t, map[string]any{"samples": pmetric.MetricTypeSum, "memory_live": 2048.0}, convert(t, converter, nil))
+	})
+
+	t.Run("flush_interval", func(t *testing.T) {
+		converter, err := NewConverter(&cfg)
+		require.NoError(t, err)
+
+		// Counts add up, maxima keep the latest value
+		assert.Equal(t, map[string]any{"samples": 3.0, "memory_live": 2048.0}, convert(t, converter, NewIntervalAggregator(converter)))
+	})
+}
+
+func TestConverter_AggregationSeparateEstimates(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time", Aggregation: "avg"},
+		},
+		Estimation: EstimationConfig{Enabled: true, SampleDuration: 10 * time.Millisecond, SeparateMetrics: true},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main"), nil, 1000000000, 512)
+	b.sample(b.stack("main"), nil, 3000000000, 512)
+	b.sample(b.stack("main"), nil)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		values[metricSlice.At(i).Name()] = metricSlice.At(i).Gauge().DataPoints().At(0).DoubleValue()
+	}
+	// The average only covers the measured samples; the estimate is summed separately
+	assert.InDelta(t, 2.0, values["cpu_time"], 1e-9)
+	assert.InDelta(t, 0.01, values["cpu_time.estimated"], 1e-9)
+}
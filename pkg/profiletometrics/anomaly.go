@@ -0,0 +1,177 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	defaultFunctionAnomalyMetricName      = "profile.function.anomaly_score"
+	defaultFunctionAnomalyAlpha           = 0.3
+	defaultFunctionAnomalyThreshold       = 3
+	defaultFunctionAnomalyMinObservations = 5
+
+	// anomalyIdleTTL is how long the statistics of a function not seen are kept
+	anomalyIdleTTL = time.Hour
+	// anomalyMinDeviationShare floors the standard deviation at a share of the mean, so that a
+	// function with a perfectly steady history still scores a jump instead of dividing by zero
+	anomalyMinDeviationShare = 0.01
+
+	// anomalyScoreUnit is the unit of a z-score, in standard deviations
+	anomalyScoreUnit = "{stddev}"
+)
+
+// validateFunctionAnomaly checks the detector settings
+func validateFunctionAnomaly(cfg FunctionAnomalyConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Alpha < 0 || cfg.Alpha > 1 {
+		return fmt.Errorf("metrics.function.anomaly.alpha must be between 0 and 1, got %v", cfg.Alpha)
+	}
+	if cfg.Threshold < 0 || cfg.MinObservations < 0 {
+		return fmt.Errorf("metrics.function.anomaly threshold and min_observations must not be negative")
+	}
+	return nil
+}
+
+// anomalySeries holds the exponentially weighted mean and variance of the CPU time of a function
+type anomalySeries struct {
+	mean         float64
+	variance     float64
+	observations int
+	lastSeen     time.Time
+}
+
+// anomalyDetector scores each observation of a series against the exponentially weighted moving
+// average and variance of its previous observations, then folds it into them
+type anomalyDetector struct {
+	mu              sync.Mutex
+	alpha           float64
+	minObservations int
+	series          map[string]*anomalySeries
+	now             func() time.Time
+}
+
+// newAnomalyDetector creates a detector with the configured smoothing and warm-up
+func newAnomalyDetector(cfg FunctionAnomalyConfig) *anomalyDetector {
+	alpha := cfg.Alpha
+	if alpha == 0 {
+		alpha = defaultFunctionAnomalyAlpha
+	}
+	minObservations := cfg.MinObservations
+	if minObservations == 0 {
+		minObservations = defaultFunctionAnomalyMinObservations
+	}
+	return &anomalyDetector{
+		alpha:           alpha,
+		minObservations: minObservations,
+		series:          make(map[string]*anomalySeries),
+		now:             time.Now,
+	}
+}
+
+// observe returns the z-score of every value against the history of its series, for series with
+// at least min_observations previous values, and updates the histories. Series not observed for
+// an hour are forgotten.
+func (d *anomalyDetector) observe(values map[string]float64) map[string]float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	scores := make(map[string]float64, len(values))
+	for key, value := range values {
+		series, exists := d.series[key]
+		if !exists {
+			d.series[key] = &anomalySeries{mean: value, observations: 1, lastSeen: now}
+			continue
+		}
+		if series.observations >= d.minObservations {
+			deviation := max(math.Sqrt(series.variance), anomalyMinDeviationShare*math.Abs(series.mean))
+			if deviation > 0 {
+				scores[key] = (value - series.mean) / deviation
+			}
+		}
+		// Incremental EWMA of the mean and variance (Finch, 2009)
+		diff := value - series.mean
+		increment := d.alpha * diff
+		series.mean += increment
+		series.variance = (1 - d.alpha) * (series.variance + diff*increment)
+		series.observations++
+		series.lastSeen = now
+	}
+	for key, series := range d.series {
+		if now.Sub(series.lastSeen) > anomalyIdleTTL {
+			delete(d.series, key)
+		}
+	}
+	return scores
+}
+
+// generateFunctionAnomalyMetrics scores the CPU time of the profile's (process, function) data
+// points against their history and emits the score of every function deviating by at least the
+// threshold, in standard deviations; positive scores are spikes, negative ones drops. Only CPU
+// profiles are tracked, without their thread and truncated stack data points.
+func (c *Converter) generateFunctionAnomalyMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	points []functionDataPoint,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	if sampleType, _ := getProfileSampleTypeCommon(profiles, profile); !isCPUSampleType(sampleType) {
+		return
+	}
+
+	values := make(map[string]float64)
+	tracked := make(map[string]functionDataPoint)
+	var keys []string
+	for _, point := range points {
+		if point.threadName != "" || point.truncated {
+			continue
+		}
+		key := functionSlopeKey(attributes, point)
+		if _, exists := tracked[key]; !exists {
+			keys = append(keys, key)
+			tracked[key] = point
+		}
+		values[key] += point.cpuTime
+	}
+	scores := c.functionAnomalies.observe(values)
+
+	anomaly := c.config.Metrics.Function.Anomaly
+	threshold := anomaly.Threshold
+	if threshold == 0 {
+		threshold = defaultFunctionAnomalyThreshold
+	}
+	var gauge pmetric.Gauge
+	created := false
+	for _, key := range keys {
+		score, ok := scores[key]
+		if !ok || math.Abs(score) < threshold {
+			continue
+		}
+		if !created {
+			created = true
+			metric := scopeMetrics.Metrics().AppendEmpty()
+			metric.SetName(c.functionAnomalyMetricName())
+			metric.SetDescription("Deviation of the CPU time of a function from its moving average, in standard deviations")
+			metric.SetUnit(anomalyScoreUnit)
+			gauge = metric.SetEmptyGauge()
+		}
+		c.appendFunctionDataPoint(gauge, profile, attributes, tracked[key], score)
+	}
+}
+
+// functionAnomalyMetricName returns the anomaly score metric name, defaulting an empty name
+func (c *Converter) functionAnomalyMetricName() string {
+	if name := c.config.Metrics.Function.Anomaly.MetricName; name != "" {
+		return name
+	}
+	return defaultFunctionAnomalyMetricName
+}
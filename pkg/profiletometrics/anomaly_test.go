@@ -0,0 +1,87 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalyDetector_Observe(t *testing.T) {
+	detector := newAnomalyDetector(FunctionAnomalyConfig{Alpha: 0.5, MinObservations: 3})
+
+	for _, value := range []float64{1, 1.2, 0.8} {
+		assert.Empty(t, detector.observe(map[string]float64{"hot": value}), "no score during warm-up")
+	}
+	scores := detector.observe(map[string]float64{"hot": 1})
+	assert.InDelta(t, 0, scores["hot"], 1, "values within the usual noise score low")
+	scores = detector.observe(map[string]float64{"hot": 10})
+	assert.Greater(t, scores["hot"], 3.0, "a spike scores several standard deviations")
+}
+
+func TestAnomalyDetector_SteadyHistory(t *testing.T) {
+	detector := newAnomalyDetector(FunctionAnomalyConfig{MinObservations: 2})
+	detector.observe(map[string]float64{"hot": 2})
+	detector.observe(map[string]float64{"hot": 2})
+	scores := detector.observe(map[string]float64{"hot": 2.2})
+	// The deviation of a steady history is floored at 1% of the mean
+	assert.InDelta(t, 10, scores["hot"], 1e-9)
+}
+
+func TestAnomalyDetector_ForgetsIdleSeries(t *testing.T) {
+	detector := newAnomalyDetector(FunctionAnomalyConfig{})
+	now := time.Unix(1700000000, 0)
+	detector.now = func() time.Time { return now }
+	detector.observe(map[string]float64{"hot": 1})
+	now = now.Add(2 * time.Hour)
+	detector.observe(map[string]float64{"cold": 1})
+	assert.NotContains(t, detector.series, "hot")
+	assert.Contains(t, detector.series, "cold")
+}
+
+func TestConverter_FunctionAnomaly(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{
+				Enabled: true,
+				Anomaly: FunctionAnomalyConfig{Enabled: true, Threshold: 3, MinObservations: 3},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var scores []float64
+	for _, cpuMillis := range []int64{1000, 1100, 900, 1000, 5000} {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, cpuMillis*int64(time.Millisecond))
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+
+		metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		for i := 0; i < metricSlice.Len(); i++ {
+			metric := metricSlice.At(i)
+			if metric.Name() != "profile.function.anomaly_score" {
+				continue
+			}
+			assert.Equal(t, anomalyScoreUnit, metric.Unit())
+			dataPoint := metric.Gauge().DataPoints().At(0)
+			functionName, _ := dataPoint.Attributes().Get("function.name")
+			assert.Equal(t, "hot", functionName.Str())
+			scores = append(scores, dataPoint.DoubleValue())
+		}
+	}
+	require.Len(t, scores, 1, "only the spike crosses the threshold")
+	assert.Greater(t, scores[0], 3.0)
+}
+
+func TestNewConverter_InvalidFunctionAnomaly(t *testing.T) {
+	function := FunctionMetricConfig{Enabled: true, Anomaly: FunctionAnomalyConfig{Enabled: true, Alpha: 1.5}}
+	_, err := NewConverter(&ConverterConfig{Metrics: MetricsConfig{Function: function}})
+	assert.Error(t, err)
+	_, err = NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Function: FunctionMetricConfig{Anomaly: FunctionAnomalyConfig{Enabled: true}}},
+	})
+	assert.Error(t, err, "anomaly requires function metrics")
+}
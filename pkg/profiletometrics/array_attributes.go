@@ -0,0 +1,96 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Policies for array-valued sample attributes; an empty value means join
+	arrayAttributesJoin    = "join"
+	arrayAttributesFirst   = "first"
+	arrayAttributesExplode = "explode"
+)
+
+// validateArrayAttributes checks the configured array attribute policy
+func validateArrayAttributes(policy string) error {
+	switch policy {
+	case "", arrayAttributesJoin, arrayAttributesFirst, arrayAttributesExplode:
+		return nil
+	default:
+		return fmt.Errorf("invalid array_attributes %q: must be %q, %q or %q",
+			policy, arrayAttributesJoin, arrayAttributesFirst, arrayAttributesExplode)
+	}
+}
+
+// attributeValueStrings returns the elements of an array value as strings, or the value itself
+func attributeValueStrings(value pcommon.Value) []string {
+	if value.Type() != pcommon.ValueTypeSlice {
+		return []string{value.AsString()}
+	}
+	values := make([]string, 0, value.Slice().Len())
+	for i := 0; i < value.Slice().Len(); i++ {
+		values = append(values, value.Slice().At(i).AsString())
+	}
+	return values
+}
+
+// getSampleAttributeValuesCommon returns the values of a sample attribute following the
+// array_attributes policy: the array joined with commas, its first element, or every element
+func getSampleAttributeValuesCommon(cfg *ConverterConfig, profiles dictionaryProvider, sample pprofile.Sample, key string) []string {
+	return appendSampleAttributeValuesCommon(nil, cfg, profiles, sample, key)
+}
+
+// appendSampleAttributeValuesCommon appends the values of a sample attribute to dst following the
+// array_attributes policy. Loops over samples pass the previous result truncated to zero length so
+// that scalar values, the common case, are looked up without allocating.
+func appendSampleAttributeValuesCommon(
+	dst []string,
+	cfg *ConverterConfig,
+	profiles dictionaryProvider,
+	sample pprofile.Sample,
+	key string,
+) []string {
+	value, ok := lookupAttributeCommon(profiles, sample.AttributeIndices(), key)
+	if !ok {
+		return dst
+	}
+	if value.Type() != pcommon.ValueTypeSlice {
+		return append(dst, value.AsString())
+	}
+	elements := value.Slice()
+	if elements.Len() == 0 {
+		return dst
+	}
+	switch cfg.ArrayAttributes {
+	case arrayAttributesFirst:
+		return append(dst, elements.At(0).AsString())
+	case arrayAttributesExplode:
+		for i := 0; i < elements.Len(); i++ {
+			dst = append(dst, elements.At(i).AsString())
+		}
+		return dst
+	default:
+		return append(dst, strings.Join(attributeValueStrings(value), ","))
+	}
+}
+
+// sampleHasAttributeValueCommon reports whether one of the values of a sample attribute equals value
+func sampleHasAttributeValueCommon(
+	cfg *ConverterConfig,
+	profiles dictionaryProvider,
+	sample pprofile.Sample,
+	key, value string,
+) bool {
+	var buffer [4]string
+	for _, v := range appendSampleAttributeValuesCommon(buffer[:0], cfg, profiles, sample, key) {
+		if v == value {
+			return true
+		}
+	}
+	// Samples without the attribute match the empty value
+	return value == "" && !hasAttributeCommon(profiles, sample.AttributeIndices(), key)
+}
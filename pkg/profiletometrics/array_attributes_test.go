@@ -0,0 +1,102 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_ArrayAttributes(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected map[string]float64
+	}{
+		{
+			policy:   "",
+			expected: map[string]float64{"worker-1,worker-2": 1, "worker-1": 2},
+		},
+		{
+			policy:   "first",
+			expected: map[string]float64{"worker-1": 3},
+		},
+		{
+			policy:   "explode",
+			expected: map[string]float64{"worker-1": 3, "worker-2": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Thread: ThreadMetricConfig{Enabled: true},
+				},
+				ArrayAttributes: tt.policy,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			stack := b.stack("main")
+			attributeTable := b.profiles.Dictionary().AttributeTable()
+			threads := attributeTable.AppendEmpty()
+			threads.SetKeyStrindex(b.str("thread.name"))
+			threads.Value().SetEmptySlice().FromRaw([]any{"worker-1", "worker-2"})
+			b.sample(stack, nil, 1000000000).AttributeIndices().Append(int32(attributeTable.Len() - 1))
+			b.sample(stack, map[string]string{"thread.name": "worker-1"}, 2000000000)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			cpuTimes := make(map[string]float64)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				if metricSlice.At(i).Name() != "cpu_time" {
+					continue
+				}
+				dataPoints := metricSlice.At(i).Gauge().DataPoints()
+				for j := 0; j < dataPoints.Len(); j++ {
+					if threadName, ok := dataPoints.At(j).Attributes().Get("thread.name"); ok {
+						cpuTimes[threadName.Str()] += dataPoints.At(j).DoubleValue()
+					}
+				}
+			}
+			assert.Equal(t, tt.expected, cpuTimes)
+		})
+	}
+}
+
+func TestNewConverter_InvalidArrayAttributes(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{ArrayAttributes: "split"})
+	assert.Error(t, err)
+}
+
+func TestAppendSampleAttributeValues(t *testing.T) {
+	b := newTestProfileBuilder()
+	stack := b.stack("main")
+	attributeTable := b.profiles.Dictionary().AttributeTable()
+	threads := attributeTable.AppendEmpty()
+	threads.SetKeyStrindex(b.str("thread.name"))
+	threads.Value().SetEmptySlice().FromRaw([]any{"worker-1", "worker-2"})
+	arraySample := b.sample(stack, nil, 1)
+	arraySample.AttributeIndices().Append(int32(attributeTable.Len() - 1))
+	scalarSample := b.sample(stack, map[string]string{"thread.name": "worker-1"}, 1)
+
+	for _, policy := range []string{"", "first", "explode"} {
+		cfg := &ConverterConfig{ArrayAttributes: policy}
+		buffer := []string{"stale"}
+		buffer = appendSampleAttributeValuesCommon(buffer[:0], cfg, b.profiles, arraySample, "thread.name")
+		assert.Equal(t, getSampleAttributeValuesCommon(cfg, b.profiles, arraySample, "thread.name"), buffer, "policy %q", policy)
+		assert.Empty(t, appendSampleAttributeValuesCommon(buffer[:0], cfg, b.profiles, arraySample, "process.executable.name"))
+	}
+
+	cache := newDictionaryCache(b.profiles, functionNameRules{})
+	buffer := make([]string, 0, 4)
+	allocs := testing.AllocsPerRun(100, func() {
+		buffer = appendSampleAttributeValuesCommon(buffer[:0], &ConverterConfig{}, cache, scalarSample, "thread.name")
+	})
+	assert.Zero(t, allocs, "scalar values are appended without allocating")
+	assert.Equal(t, []string{"worker-1"}, buffer)
+}
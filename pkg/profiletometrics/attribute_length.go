@@ -0,0 +1,95 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// attributeValueTruncationMarker ends the attribute values cut to max_attribute_value_length
+const attributeValueTruncationMarker = "…"
+
+// validateMaxAttributeValueLength checks that the attribute value cap is not negative
+func validateMaxAttributeValueLength(maxLength int) error {
+	if maxLength < 0 {
+		return fmt.Errorf("max_attribute_value_length must not be negative")
+	}
+	return nil
+}
+
+// truncateAttributeValueCommon cuts a value longer than maxLength bytes on a character boundary
+// and ends it with the truncation marker, the marker counting towards the cap; 0 disables the cap
+func truncateAttributeValueCommon(value string, maxLength int) string {
+	if maxLength <= 0 || len(value) <= maxLength {
+		return value
+	}
+	marker := attributeValueTruncationMarker
+	if maxLength < len(marker) {
+		marker = ""
+	}
+	cut := maxLength - len(marker)
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + marker
+}
+
+// truncateAttributeValuesCommon truncates the string values of an attribute map in place
+func truncateAttributeValuesCommon(attributes pcommon.Map, maxLength int) {
+	attributes.Range(func(_ string, value pcommon.Value) bool {
+		if value.Type() == pcommon.ValueTypeStr {
+			if truncated := truncateAttributeValueCommon(value.Str(), maxLength); truncated != value.Str() {
+				value.SetStr(truncated)
+			}
+		}
+		return true
+	})
+}
+
+// truncateMetricAttributes caps the string attribute values of every resource and data point, such
+// as function names, file paths and folded stacks
+func truncateMetricAttributes(metrics pmetric.Metrics, maxLength int) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		truncateAttributeValuesCommon(metrics.ResourceMetrics().At(i).Resource().Attributes(), maxLength)
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				var dataPoints pmetric.NumberDataPointSlice
+				switch metric := metricSlice.At(k); metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					truncateAttributeValuesCommon(dataPoints.At(l).Attributes(), maxLength)
+				}
+			}
+		}
+	}
+}
+
+// truncateTraceAttributes caps the span names and the string attribute values of spans and span
+// events, such as function names, file paths and folded stacks
+func truncateTraceAttributes(traces ptrace.Traces, maxLength int) {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		scopeSpans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				span.SetName(truncateAttributeValueCommon(span.Name(), maxLength))
+				truncateAttributeValuesCommon(span.Attributes(), maxLength)
+				for l := 0; l < span.Events().Len(); l++ {
+					truncateAttributeValuesCommon(span.Events().At(l).Attributes(), maxLength)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package profiletometrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestTruncateAttributeValueCommon(t *testing.T) {
+	assert.Equal(t, "main.handler", truncateAttributeValueCommon("main.handler", 0))
+	assert.Equal(t, "main.handler", truncateAttributeValueCommon("main.handler", 12))
+	assert.Equal(t, "main.ha…", truncateAttributeValueCommon("main.handler", 10))
+	// Multi-byte characters are never split
+	assert.Equal(t, "hé…", truncateAttributeValueCommon("hé€llo", 7))
+	assert.Equal(t, "ma", truncateAttributeValueCommon("main.handler", 2))
+}
+
+func TestConverter_MaxAttributeValueLength(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+		},
+		MaxAttributeValueLength: 16,
+	})
+	require.NoError(t, err)
+
+	longName := "github.com/example/service/internal/handlers.(*Server).ServeHTTP"
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", longName), map[string]string{"process.executable.name": "app"}, 100)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	found := false
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() != "cpu_time.by_function" {
+			continue
+		}
+		dataPoints := metricSlice.At(i).Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			name, ok := dataPoints.At(j).Attributes().Get("function.name")
+			require.True(t, ok)
+			assert.LessOrEqual(t, len(name.Str()), 16)
+			if strings.HasPrefix(longName, strings.TrimSuffix(name.Str(), attributeValueTruncationMarker)) &&
+				strings.HasSuffix(name.Str(), attributeValueTruncationMarker) {
+				found = true
+			}
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestTraceConverter_MaxAttributeValueLength(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		FoldedStack:             FoldedStackConfig{Enabled: true},
+		MaxAttributeValueLength: 8,
+	})
+	require.NoError(t, err)
+
+	spans := convertTestTraces(t, converter)
+	require.Greater(t, spans.Len(), 0)
+	for i := 0; i < spans.Len(); i++ {
+		span := spans.At(i)
+		assert.LessOrEqual(t, len(span.Name()), 8)
+		for j := 0; j < span.Events().Len(); j++ {
+			span.Events().At(j).Attributes().Range(func(key string, value pcommon.Value) bool {
+				assert.LessOrEqual(t, len(value.AsString()), 8, key)
+				return true
+			})
+		}
+	}
+}
+
+func TestNewConverter_NegativeMaxAttributeValueLength(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{MaxAttributeValueLength: -1})
+	assert.Error(t, err)
+	_, err = NewTraceConverter(&ConverterConfig{MaxAttributeValueLength: -1})
+	assert.Error(t, err)
+}
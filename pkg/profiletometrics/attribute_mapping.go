@@ -0,0 +1,102 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// sampleAttributeOutputKeys maps the sample attributes that are emitted under another key to that
+// key, so that mapping rules may name either
+var sampleAttributeOutputKeys = map[string]string{
+	"process.executable.name": "process.name",
+}
+
+// compileAttributeMapping validates the attribute_mapping rules and returns them as a map from the
+// emitted key to its new name, nil when no rule is configured
+func compileAttributeMapping(rules []AttributeMappingRule) (map[string]string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	mapping := make(map[string]string, len(rules))
+	for i, rule := range rules {
+		if rule.From == "" || rule.To == "" {
+			return nil, fmt.Errorf("attribute_mapping[%d]: from and to must be set", i)
+		}
+		from := rule.From
+		if outputKey, exists := sampleAttributeOutputKeys[from]; exists {
+			from = outputKey
+		}
+		if _, exists := mapping[from]; exists {
+			return nil, fmt.Errorf("attribute_mapping[%d]: %q is mapped more than once", i, rule.From)
+		}
+		mapping[from] = rule.To
+	}
+	return mapping, nil
+}
+
+// renameAttributesCommon renames the keys of an attribute map in place. Rules apply simultaneously,
+// so swapping two keys works, and a renamed attribute replaces an existing one with the new name.
+func renameAttributesCommon(attributes pcommon.Map, mapping map[string]string) {
+	renamed := make(map[string]pcommon.Value)
+	for from, to := range mapping {
+		if value, exists := attributes.Get(from); exists {
+			copied := pcommon.NewValueEmpty()
+			value.CopyTo(copied)
+			renamed[to] = copied
+		}
+	}
+	if len(renamed) == 0 {
+		return
+	}
+	for from := range mapping {
+		attributes.Remove(from)
+	}
+	for to, value := range renamed {
+		value.CopyTo(attributes.PutEmpty(to))
+	}
+}
+
+// renameMetricAttributes applies the attribute mapping to every data point and resource
+func renameMetricAttributes(metrics pmetric.Metrics, mapping map[string]string) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		renameAttributesCommon(metrics.ResourceMetrics().At(i).Resource().Attributes(), mapping)
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				var dataPoints pmetric.NumberDataPointSlice
+				switch metric := metricSlice.At(k); metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					renameAttributesCommon(dataPoints.At(l).Attributes(), mapping)
+				}
+			}
+		}
+	}
+}
+
+// renameTraceAttributes applies the attribute mapping to every span and span event
+func renameTraceAttributes(traces ptrace.Traces, mapping map[string]string) {
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		scopeSpans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			spans := scopeSpans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				renameAttributesCommon(span.Attributes(), mapping)
+				for l := 0; l < span.Events().Len(); l++ {
+					renameAttributesCommon(span.Events().At(l).Attributes(), mapping)
+				}
+			}
+		}
+	}
+}
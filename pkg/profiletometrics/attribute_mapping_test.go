@@ -0,0 +1,102 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestCompileAttributeMapping(t *testing.T) {
+	mapping, err := compileAttributeMapping(nil)
+	require.NoError(t, err)
+	assert.Nil(t, mapping)
+
+	mapping, err = compileAttributeMapping([]AttributeMappingRule{
+		{From: "process.executable.name", To: "service.instance.id"},
+		{From: "function.name", To: "code.function"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"process.name": "service.instance.id", "function.name": "code.function"}, mapping)
+
+	_, err = compileAttributeMapping([]AttributeMappingRule{{From: "function.name"}})
+	assert.Error(t, err)
+	_, err = compileAttributeMapping([]AttributeMappingRule{
+		{From: "process.name", To: "a"},
+		{From: "process.executable.name", To: "b"},
+	})
+	assert.Error(t, err)
+
+	_, err = NewConverter(&ConverterConfig{AttributeMapping: []AttributeMappingRule{{To: "a"}}})
+	assert.Error(t, err)
+	_, err = NewTraceConverter(&ConverterConfig{AttributeMapping: []AttributeMappingRule{{To: "a"}}})
+	assert.Error(t, err)
+}
+
+func TestRenameAttributesCommon(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("a", "1")
+	attributes.PutStr("b", "2")
+	attributes.PutStr("c", "3")
+	attributes.PutInt("d", 4)
+
+	renameAttributesCommon(attributes, map[string]string{"a": "b", "b": "a", "c": "d", "missing": "e"})
+
+	assert.Equal(t, map[string]any{"a": "2", "b": "1", "d": "3"}, attributes.AsRaw())
+}
+
+func TestAttributeMapping_MetricsAndTraces(t *testing.T) {
+	cfg := &ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Process: ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+		},
+		AttributeMapping: []AttributeMappingRule{
+			{From: "process.executable.name", To: "service.instance.id"},
+			{From: "service.name", To: "app"},
+		},
+	}
+	converter, err := NewConverter(cfg)
+	require.NoError(t, err)
+	traceConverter, err := NewTraceConverter(cfg)
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.resource.Resource().Attributes().PutStr("service.name", "checkout")
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "checkout-server"}, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	dataPoint, found := attributeMappingDataPoint(metrics, "cpu_time.by_process")
+	require.True(t, found)
+	assert.Equal(t, map[string]any{"app": "checkout", "service.instance.id": "checkout-server"}, dataPoint.Attributes().AsRaw())
+
+	traces, err := traceConverter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Positive(t, spans.Len())
+	app, ok := spans.At(0).Attributes().Get("app")
+	require.True(t, ok)
+	assert.Equal(t, "checkout", app.Str())
+	_, ok = spans.At(0).Attributes().Get("service.name")
+	assert.False(t, ok)
+}
+
+// attributeMappingDataPoint returns the first data point of the named gauge
+func attributeMappingDataPoint(metrics pmetric.Metrics, name string) (pmetric.NumberDataPoint, bool) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() == name && metric.Gauge().DataPoints().Len() > 0 {
+					return metric.Gauge().DataPoints().At(0), true
+				}
+			}
+		}
+	}
+	return pmetric.NumberDataPoint{}, false
+}
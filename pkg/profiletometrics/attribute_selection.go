@@ -0,0 +1,37 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"path"
+)
+
+// validateAttributeSelection checks that the attribute_selection globs are well formed
+func validateAttributeSelection(cfg AttributeSelectionConfig) error {
+	for _, patterns := range [][]string{cfg.Include, cfg.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid attribute_selection pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// attributeSelected reports whether a resource attribute is copied onto data points and spans: it
+// must match an include glob, when any is set, and no exclude glob
+func attributeSelected(cfg AttributeSelectionConfig, key string) bool {
+	if len(cfg.Include) > 0 && !matchesAnyGlob(cfg.Include, key) {
+		return false
+	}
+	return !matchesAnyGlob(cfg.Exclude, key)
+}
+
+// matchesAnyGlob reports whether a key matches one of the globs
+func matchesAnyGlob(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeSelected(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      AttributeSelectionConfig
+		key      string
+		expected bool
+	}{
+		{name: "Empty selection keeps everything", key: "host.name", expected: true},
+		{name: "Include glob", cfg: AttributeSelectionConfig{Include: []string{"k8s.*"}}, key: "k8s.pod.name", expected: true},
+		{name: "Not included", cfg: AttributeSelectionConfig{Include: []string{"k8s.*"}}, key: "host.name", expected: false},
+		{name: "Exclude glob", cfg: AttributeSelectionConfig{Exclude: []string{"*.id"}}, key: "container.id", expected: false},
+		{
+			name:     "Exclude wins over include",
+			cfg:      AttributeSelectionConfig{Include: []string{"k8s.*"}, Exclude: []string{"k8s.pod.uid"}},
+			key:      "k8s.pod.uid",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, attributeSelected(tt.cfg, tt.key))
+		})
+	}
+}
+
+func TestValidateAttributeSelection(t *testing.T) {
+	assert.NoError(t, validateAttributeSelection(AttributeSelectionConfig{Include: []string{"k8s.*"}}))
+	assert.Error(t, validateAttributeSelection(AttributeSelectionConfig{Exclude: []string{"k8s.[pod"}}))
+
+	_, err := NewConverter(&ConverterConfig{AttributeSelection: AttributeSelectionConfig{Include: []string{"["}}})
+	assert.Error(t, err)
+	_, err = NewTraceConverter(&ConverterConfig{AttributeSelection: AttributeSelectionConfig{Include: []string{"["}}})
+	assert.Error(t, err)
+}
+
+func TestAttributeSelection_MetricsAndTraces(t *testing.T) {
+	cfg := &ConverterConfig{
+		Metrics:    MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+		Attributes: []AttributeConfig{{Key: "env", Value: "prod", Type: attrTypeLiteral}},
+		AttributeSelection: AttributeSelectionConfig{
+			Include: []string{"service.*", "k8s.*"},
+			Exclude: []string{"k8s.pod.uid"},
+		},
+	}
+	converter, err := NewConverter(cfg)
+	require.NoError(t, err)
+	traceConverter, err := NewTraceConverter(cfg)
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	resourceAttributes := b.resource.Resource().Attributes()
+	resourceAttributes.PutStr("service.name", "checkout")
+	resourceAttributes.PutStr("k8s.pod.name", "checkout-1")
+	resourceAttributes.PutStr("k8s.pod.uid", "0c9f")
+	resourceAttributes.PutStr("host.name", "node-1")
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "checkout"}, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+
+	traces, err := traceConverter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Positive(t, spans.Len())
+	span := spans.At(0)
+
+	for _, key := range []string{"service.name", "k8s.pod.name", "env"} {
+		_, ok := dataPoint.Attributes().Get(key)
+		assert.True(t, ok, "metric attribute %s", key)
+		_, ok = span.Attributes().Get(key)
+		assert.True(t, ok, "span attribute %s", key)
+	}
+	for _, key := range []string{"k8s.pod.uid", "host.name"} {
+		_, ok := dataPoint.Attributes().Get(key)
+		assert.False(t, ok, "metric attribute %s", key)
+		_, ok = span.Attributes().Get(key)
+		assert.False(t, ok, "span attribute %s", key)
+	}
+}
@@ -0,0 +1,222 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// This file resolves the attributes shared by every data point or span of a profile. Both the
+// metrics and the traces converter use it, so the attribute configuration behaves identically
+// for both outputs.
+
+// extractResourceAttributesCommon extracts attributes from the resource
+func extractResourceAttributesCommon(resource pcommon.Resource) map[string]string {
+	attributes := make(map[string]string)
+
+	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
+		attributes[key] = value.AsString()
+		return true
+	})
+
+	return attributes
+}
+
+// extractProfileAttributesCommon returns the resource attributes of a profile extended with the
+// configured attribute rules, the profile origin and the profile comments
+func extractProfileAttributesCommon(
+	cfg *ConverterConfig,
+	profiles dictionaryProvider,
+	scope pcommon.InstrumentationScope,
+	profile pprofile.Profile,
+	resourceAttributes map[string]string,
+) map[string]string {
+	attributes := make(map[string]string)
+
+	// Copy the selected resource attributes
+	for k, v := range resourceAttributes {
+		if attributeSelected(cfg.AttributeSelection, k) {
+			attributes[k] = v
+		}
+	}
+
+	// Extract attributes based on configuration rules
+	for _, attr := range cfg.Attributes {
+		value := extractAttributeValueCommon(profiles, profile, attr)
+		if value != "" {
+			attributes[attr.Key] = value
+		}
+	}
+
+	if cfg.Origin.Enabled {
+		addOriginAttributeCommon(cfg.Origin, attributes, scope)
+	}
+	if cfg.ProfileComments {
+		if comments := getProfileCommentsCommon(profiles, profile); len(comments) > 0 {
+			attributes[profileCommentAttributeKey] = strings.Join(comments, "; ")
+		}
+	}
+
+	return attributes
+}
+
+// addOriginAttributeCommon adds the profile origin (receiver name and agent scope name) to the attributes
+func addOriginAttributeCommon(cfg OriginConfig, attributes map[string]string, scope pcommon.InstrumentationScope) {
+	key := cfg.AttributeKey
+	if key == "" {
+		key = defaultOriginAttributeKey
+	}
+
+	parts := make([]string, 0, 2)
+	if cfg.ReceiverName != "" {
+		parts = append(parts, cfg.ReceiverName)
+	}
+	if scope.Name() != "" {
+		parts = append(parts, scope.Name())
+	}
+	if len(parts) == 0 {
+		return
+	}
+	attributes[key] = strings.Join(parts, "/")
+}
+
+// extractAttributeValueCommon extracts a single attribute value based on the rule
+func extractAttributeValueCommon(profiles dictionaryProvider, profile pprofile.Profile, attr AttributeConfig) string {
+	switch attr.Type {
+	case attrTypeLiteral:
+		return attr.Value
+	case attrTypeRegex:
+		// Extract from string table using regex pattern
+		return extractFromStringTableCommon(profiles, attr.Value)
+	case attrTypeStringTable:
+		// Direct string table index access
+		return extractFromStringTableByIndexCommon(profiles, attr.Value)
+	case attrTypeSampleAttribute:
+		return extractFromSampleAttributeCommon(profiles, profile, attr)
+	default:
+		return attr.Value
+	}
+}
+
+// extractFromStringTableCommon extracts values from profile string table using regex pattern
+func extractFromStringTableCommon(profiles dictionaryProvider, _ string) string {
+	// Access the string table from the profiles dictionary
+	stringTable := profiles.Dictionary().StringTable()
+
+	// For now, return the first string as a placeholder
+	// In a real implementation, you would:
+	// 1. Compile the regex pattern
+	// 2. Match against all strings in the table
+	// 3. Return the first match
+	if stringTable.Len() > 0 {
+		return stringTable.At(0)
+	}
+	return ""
+}
+
+// extractFromStringTableByIndexCommon returns the string at the configured string table index;
+// negative indexes count from the end of the table (-1 is the last string). Out-of-range indexes
+// resolve to "", so the attribute is not set.
+func extractFromStringTableByIndexCommon(profiles dictionaryProvider, indexStr string) string {
+	index, err := strconv.Atoi(strings.TrimSpace(indexStr))
+	if err != nil {
+		return ""
+	}
+	stringTable := profiles.Dictionary().StringTable()
+	if index < 0 {
+		index += stringTable.Len()
+	}
+	if index < 0 || index >= stringTable.Len() {
+		return ""
+	}
+	return stringTable.At(index)
+}
+
+// sampleAttributePatterns caches the compiled sample_attribute patterns by expression
+var sampleAttributePatterns sync.Map
+
+// sampleAttributePattern returns the compiled sample_attribute pattern
+func sampleAttributePattern(pattern string) (*regexp.Regexp, error) {
+	if re, exists := sampleAttributePatterns.Load(pattern); exists {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sampleAttributePatterns.Store(pattern, re)
+	return re, nil
+}
+
+// extractFromSampleAttributeCommon returns the value of a sample attribute carried by the most
+// samples of the profile (the smallest value on ties), after the optional regex transform
+func extractFromSampleAttributeCommon(profiles dictionaryProvider, profile pprofile.Profile, attr AttributeConfig) string {
+	var re *regexp.Regexp
+	if attr.Pattern != "" {
+		var err error
+		if re, err = sampleAttributePattern(attr.Pattern); err != nil {
+			return ""
+		}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		value := getSampleAttributeValueCommon(profiles, profile.Sample().At(i), attr.Value)
+		if value == "" {
+			continue
+		}
+		if re != nil {
+			match := re.FindStringSubmatch(value)
+			if match == nil {
+				continue
+			}
+			value = match[0]
+			if len(match) > 1 {
+				value = match[1]
+			}
+		}
+		counts[value]++
+	}
+
+	best, bestCount := "", 0
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
+
+// validateAttributes checks that the string_table attribute rules hold integer indexes and that
+// the sample_attribute rules name a key and hold a valid pattern
+func validateAttributes(attributes []AttributeConfig) error {
+	for i, attr := range attributes {
+		switch attr.Type {
+		case attrTypeStringTable:
+			if _, err := strconv.Atoi(strings.TrimSpace(attr.Value)); err != nil {
+				return fmt.Errorf("attributes[%d]: string_table index %q is not an integer", i, attr.Value)
+			}
+		case attrTypeSampleAttribute:
+			if attr.Value == "" {
+				return fmt.Errorf("attributes[%d]: sample_attribute requires the attribute key as value", i)
+			}
+			if attr.Pattern == "" {
+				continue
+			}
+			if _, err := sampleAttributePattern(attr.Pattern); err != nil {
+				return fmt.Errorf("attributes[%d]: invalid pattern %q: %w", i, attr.Pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// scopeOfProfile returns the instrumentation scope of the profile at the given indices
+func scopeOfProfile(profiles pprofile.Profiles, resourceIndex, scopeIndex int) pcommon.InstrumentationScope {
+	return profiles.ResourceProfiles().At(resourceIndex).ScopeProfiles().At(scopeIndex).Scope()
+}
@@ -0,0 +1,146 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileAttributes_SharedByMetricsAndTraces(t *testing.T) {
+	cfg := &ConverterConfig{
+		Metrics:         MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+		Attributes:      []AttributeConfig{{Key: "env", Value: "prod", Type: attrTypeLiteral}},
+		Origin:          OriginConfig{Enabled: true, ReceiverName: "otlp"},
+		ProfileComments: true,
+	}
+	converter, err := NewConverter(cfg)
+	require.NoError(t, err)
+	traceConverter, err := NewTraceConverter(cfg)
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.resource.Resource().Attributes().PutStr("service.name", "checkout")
+	b.scope.Scope().SetName("go.opentelemetry.io/ebpf-profiler")
+	b.profile.CommentStrindices().Append(b.str("load test"))
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "checkout"}, 1000000000)
+
+	expected := map[string]string{
+		"service.name":             "checkout",
+		"env":                      "prod",
+		defaultOriginAttributeKey:  "otlp/go.opentelemetry.io/ebpf-profiler",
+		profileCommentAttributeKey: "load test",
+	}
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+
+	traces, err := traceConverter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Positive(t, spans.Len())
+	span := spans.At(0)
+
+	for key, value := range expected {
+		metricValue, ok := dataPoint.Attributes().Get(key)
+		require.True(t, ok, "metric attribute %s", key)
+		assert.Equal(t, value, metricValue.Str())
+		spanValue, ok := span.Attributes().Get(key)
+		require.True(t, ok, "span attribute %s", key)
+		assert.Equal(t, value, spanValue.Str())
+	}
+}
+
+func TestExtractFromStringTableByIndexCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	b.str("checkout")
+	b.str("prod")
+
+	tests := []struct {
+		index    string
+		expected string
+	}{
+		{index: "1", expected: "checkout"},
+		{index: " 2 ", expected: "prod"},
+		{index: "-1", expected: "prod"},
+		{index: "-3", expected: ""},
+		{index: "3", expected: ""},
+		{index: "-4", expected: ""},
+		{index: "two", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.index, func(t *testing.T) {
+			assert.Equal(t, tt.expected, extractFromStringTableByIndexCommon(b.profiles, tt.index))
+		})
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	assert.NoError(t, validateAttributes([]AttributeConfig{
+		{Key: "env", Value: "prod", Type: attrTypeLiteral},
+		{Key: "service", Value: "-1", Type: attrTypeStringTable},
+	}))
+	assert.Error(t, validateAttributes([]AttributeConfig{{Key: "service", Value: "last", Type: attrTypeStringTable}}))
+
+	cfg := &ConverterConfig{Attributes: []AttributeConfig{{Key: "service", Value: "1.5", Type: attrTypeStringTable}}}
+	_, err := NewConverter(cfg)
+	assert.Error(t, err)
+	_, err = NewTraceConverter(cfg)
+	assert.Error(t, err)
+}
+
+func TestExtractFromSampleAttributeCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	stack := b.stack("main")
+	b.sample(stack, map[string]string{"thread.id": "tid-12", "container.image": "registry/app:1.4.2"}, 1)
+	b.sample(stack, map[string]string{"thread.id": "tid-12", "container.image": "registry/app:1.4.2"}, 1)
+	b.sample(stack, map[string]string{"thread.id": "tid-7"}, 1)
+	b.sample(stack, nil, 1)
+
+	tests := []struct {
+		name     string
+		attr     AttributeConfig
+		expected string
+	}{
+		{name: "Most frequent value", attr: AttributeConfig{Value: "thread.id"}, expected: "tid-12"},
+		{name: "Capture group", attr: AttributeConfig{Value: "container.image", Pattern: `:([0-9.]+)$`}, expected: "1.4.2"},
+		{name: "Whole match", attr: AttributeConfig{Value: "thread.id", Pattern: `[0-9]+`}, expected: "12"},
+		{name: "No match", attr: AttributeConfig{Value: "thread.id", Pattern: `^pid`}, expected: ""},
+		{name: "Missing attribute", attr: AttributeConfig{Value: "span.id"}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.attr.Key = "out"
+			tt.attr.Type = attrTypeSampleAttribute
+			assert.Equal(t, tt.expected, extractAttributeValueCommon(b.profiles, b.profile, tt.attr))
+		})
+	}
+}
+
+func TestSampleAttribute_DataPoints(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+		Attributes: []AttributeConfig{
+			{Key: "runtime.version", Value: "runtime", Type: attrTypeSampleAttribute, Pattern: `^go([0-9.]+)`},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main"), map[string]string{"runtime": "go1.24.0"}, 1000000000)
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	version, ok := dataPoint.Attributes().Get("runtime.version")
+	require.True(t, ok)
+	assert.Equal(t, "1.24.0", version.Str())
+}
+
+func TestValidateAttributes_SampleAttribute(t *testing.T) {
+	assert.NoError(t, validateAttributes([]AttributeConfig{{Key: "tid", Value: "thread.id", Type: attrTypeSampleAttribute}}))
+	assert.Error(t, validateAttributes([]AttributeConfig{{Key: "tid", Type: attrTypeSampleAttribute}}))
+	assert.Error(t, validateAttributes([]AttributeConfig{{Key: "tid", Value: "thread.id", Type: attrTypeSampleAttribute, Pattern: "("}}))
+}
@@ -0,0 +1,144 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Function attribution modes; an empty value means self
+	functionAttributionSelf  = "self"
+	functionAttributionTotal = "total"
+	functionAttributionBoth  = "both"
+
+	// functionAttributionAttributeKey distinguishes self and total data points
+	functionAttributionAttributeKey = "function.attribution"
+)
+
+// validateFunctionAttribution checks the configured function attribution mode
+func validateFunctionAttribution(attribution string) error {
+	switch attribution {
+	case "", functionAttributionSelf, functionAttributionTotal, functionAttributionBoth:
+		return nil
+	default:
+		return fmt.Errorf("invalid metrics.function.attribution %q: must be %q, %q or %q",
+			attribution, functionAttributionSelf, functionAttributionTotal, functionAttributionBoth)
+	}
+}
+
+// functionAttributionModes returns the attributions to emit and whether data points are labeled with them
+func (c *Converter) functionAttributionModes() ([]string, bool) {
+	switch c.config.Metrics.Function.Attribution {
+	case functionAttributionTotal:
+		return []string{functionAttributionTotal}, true
+	case functionAttributionBoth:
+		return []string{functionAttributionSelf, functionAttributionTotal}, true
+	default:
+		return []string{functionAttributionSelf}, false
+	}
+}
+
+// calculateFunctionTotals credits every sample to each distinct function on its stack, matching
+// flamegraph "total" semantics; recursive frames are only counted once per sample
+func (c *Converter) calculateFunctionTotals(profiles dictionaryProvider, profile pprofile.Profile) []functionDataPoint {
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+
+	type functionLineKey struct {
+		processFunctionKey
+		lineNumber int64
+	}
+	includeLineNumbers := c.config.Metrics.Function.IncludeLineNumbers
+
+	totals := make(map[functionLineKey]*functionDataPoint)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok {
+			continue
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		memory := c.sampleMemoryAllocation(sample, summary)
+
+		seen := make(map[functionLineKey]bool)
+		for j := 0; j < locationIndices.Len(); j++ {
+			location, ok := locationAtCommon(profiles, locationIndices.At(j))
+			if !ok {
+				continue
+			}
+			for _, frame := range locationFramesCommon(profiles, location) {
+				if frame.functionName == "" {
+					summary.unresolvedFunctions.Add(1)
+					continue
+				}
+				key := functionLineKey{processFunctionKey: processFunctionKey{processName: processName, functionName: frame.functionName}}
+				if includeLineNumbers {
+					key.lineNumber = frame.lineNumber
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				point, exists := totals[key]
+				if !exists {
+					point = &functionDataPoint{
+						processName:  processName,
+						functionName: frame.functionName,
+						fileName:     frame.fileName,
+						lineNumber:   key.lineNumber,
+						attribution:  functionAttributionTotal,
+					}
+					if c.config.Metrics.Function.CodeFilePath {
+						point.codeFilePath = point.fileName
+						if point.codeFilePath == "" {
+							point.codeFilePath = c.getLocationCodeFilePath(profiles, location)
+						}
+					}
+					totals[key] = point
+				}
+				point.cpuTime += cpuTime
+				point.memory += memory
+			}
+		}
+	}
+
+	points := make([]functionDataPoint, 0, len(totals))
+	for _, point := range totals {
+		points = append(points, *point)
+	}
+	sortFunctionLineDataPoints(points)
+	return points
+}
+
+// stringTableValue resolves a string table index, returning "" when out of range
+func stringTableValue(stringTable pcommon.StringSlice, index int32) string {
+	if index < 0 || int(index) >= stringTable.Len() {
+		return ""
+	}
+	return stringTable.At(int(index))
+}
+
+// sampleCPUTime returns the CPU time of a sample in seconds, estimated for samples without values
+// when estimation is enabled and estimates are not reported separately
+func (c *Converter) sampleCPUTime(sample pprofile.Sample, weight cpuWeight, summary *conversionSummary) float64 {
+	measured, estimated := c.sampleCPUTimeTotals(sample, weight, summary)
+	if c.config.Estimation.SeparateMetrics {
+		return measured
+	}
+	return measured + estimated
+}
+
+// sampleMemoryAllocation returns the memory allocation of a sample in bytes, estimated for samples
+// without values when estimation is enabled and estimates are not reported separately
+func (c *Converter) sampleMemoryAllocation(sample pprofile.Sample, summary *conversionSummary) float64 {
+	measured, estimated := c.sampleMemoryTotals(sample, summary)
+	if c.config.Estimation.SeparateMetrics {
+		return measured
+	}
+	return measured + estimated
+}
@@ -0,0 +1,155 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// autoTopN bounds function series in auto mode when no top_n is configured
+const autoTopN = 20
+
+// memorySampleTypes lists sample type names reporting allocations
+var memorySampleTypes = map[string]bool{
+	"alloc_space":   true,
+	"alloc_objects": true,
+	"inuse_space":   true,
+	"inuse_objects": true,
+	"alloc":         true,
+	"alloc_size":    true,
+	"memory":        true,
+}
+
+// memorySampleUnits maps the sample type units of allocation profiles to metric units
+var memorySampleUnits = map[string]string{
+	"bytes":   "By",
+	"count":   "{object}",
+	"objects": "{object}",
+}
+
+// autoProfileTraits are the properties of a batch auto mode derives its configuration from
+type autoProfileTraits struct {
+	// sampleTypes maps the sample type names of the batch to their units
+	sampleTypes        map[string]string
+	hasProcess         bool
+	hasThread          bool
+	hasExceptionType   bool
+	hasInterpretedCode bool
+}
+
+// applyAutoConfig derives the configuration from the first batch when auto mode is enabled; it
+// fills in the converter's own copy of the configuration, conversions waiting until it is done
+func (c *Converter) applyAutoConfig(profiles pprofile.Profiles) {
+	c.autoOnce.Do(func() {
+		c.deriveAutoConfig(c.inspectProfiles(profiles))
+		metrics := c.config.Metrics
+		c.logInfo("Auto mode derived configuration",
+			zap.Bool("cpu", metrics.CPU.Enabled),
+			zap.String("cpu_unit", metrics.CPU.Unit),
+			zap.Bool("memory", metrics.Memory.Enabled),
+			zap.String("memory_unit", metrics.Memory.Unit),
+			zap.Bool("process", metrics.Process.Enabled),
+			zap.Bool("thread", metrics.Thread.Enabled),
+			zap.Bool("function", metrics.Function.Enabled),
+			zap.Int("function_top_n_cpu", metrics.Function.TopN.CPU),
+			zap.Int("function_top_n_memory", metrics.Function.TopN.Memory),
+			zap.Bool("function_code_filepath", metrics.Function.CodeFilePath),
+			zap.Bool("lock", metrics.Lock.Enabled),
+			zap.Bool("exceptions", metrics.Exceptions.Enabled),
+			zap.Bool("runtime", metrics.Runtime.Enabled))
+	})
+}
+
+// inspectProfiles collects the sample types, attributes and frame types of a batch
+func (c *Converter) inspectProfiles(profiles pprofile.Profiles) autoProfileTraits {
+	traits := autoProfileTraits{sampleTypes: make(map[string]string)}
+	exceptionTypeAttribute := c.config.Metrics.Exceptions.TypeAttribute
+	if exceptionTypeAttribute == "" {
+		exceptionTypeAttribute = defaultExceptionTypeAttribute
+	}
+
+	iterateProfilesCommon(profiles, c.extractResourceAttributes, func(dictionary dictionaryProvider, _, _, _ int, profile pprofile.Profile, _ map[string]string) {
+		if sampleType, unit := getProfileSampleTypeCommon(dictionary, profile); sampleType != "" {
+			traits.sampleTypes[sampleType] = unit
+		}
+		for i := 0; i < profile.Sample().Len(); i++ {
+			sample := profile.Sample().At(i)
+			traits.hasProcess = traits.hasProcess || c.getSampleAttributeValue(dictionary, sample, "process.executable.name") != ""
+			traits.hasThread = traits.hasThread || c.getSampleAttributeValue(dictionary, sample, "thread.name") != ""
+			traits.hasExceptionType = traits.hasExceptionType || c.getSampleAttributeValue(dictionary, sample, exceptionTypeAttribute) != ""
+		}
+	})
+
+	for i := 0; i < profiles.ResourceProfiles().Len() && !traits.hasInterpretedCode; i++ {
+		dictionary := resourceDictionaryCommon(profiles, i)
+		locationTable := dictionary.Dictionary().LocationTable()
+		for j := 0; j < locationTable.Len() && !traits.hasInterpretedCode; j++ {
+			frameType := getAttributeValueCommon(dictionary, locationTable.At(j).AttributeIndices(), frameTypeAttributeKey)
+			traits.hasInterpretedCode = frameType != "" && !nativeFrameTypes[frameType]
+		}
+	}
+	return traits
+}
+
+// deriveAutoConfig fills in the metric families, breakdowns, units and names suited to the
+// inspected batch; settings the user made are kept, so families are only ever turned on
+func (c *Converter) deriveAutoConfig(traits autoProfileTraits) {
+	metrics := &c.config.Metrics
+
+	// Anything that is not an allocation, lock or exception profile is treated as CPU samples
+	hasCPU := len(traits.sampleTypes) == 0
+	var hasMemory, hasLock, hasException, hasRuntime bool
+	memoryUnits := make(map[string]bool)
+	for sampleType, unit := range traits.sampleTypes {
+		switch {
+		case memorySampleTypes[sampleType]:
+			hasMemory = true
+			memoryUnits[memorySampleUnits[unit]] = true
+		case lockDelaySampleTypes[sampleType], lockCountSampleTypes[sampleType]:
+			hasLock = true
+		case exceptionSampleTypes[sampleType]:
+			hasException = true
+		case liveHeapSampleTypes[sampleType], gcCPUSampleTypes[sampleType]:
+			hasRuntime = true
+		default:
+			hasCPU = true
+		}
+	}
+
+	metrics.CPU.Enabled = metrics.CPU.Enabled || hasCPU
+	metrics.Memory.Enabled = metrics.Memory.Enabled || hasMemory
+	metrics.Process.Enabled = metrics.Process.Enabled || traits.hasProcess
+	metrics.Thread.Enabled = metrics.Thread.Enabled || traits.hasThread
+	metrics.Function.CodeFilePath = metrics.Function.CodeFilePath || traits.hasInterpretedCode
+	if metrics.Function.TopN.CPU == 0 && metrics.Function.TopN.Memory == 0 {
+		metrics.Function.TopN = FunctionTopNConfig{CPU: autoTopN, Memory: autoTopN}
+	}
+	metrics.Lock.Enabled = metrics.Lock.Enabled || hasLock
+	metrics.Exceptions.Enabled = metrics.Exceptions.Enabled || hasException || traits.hasExceptionType
+	metrics.Runtime.Enabled = metrics.Runtime.Enabled || hasRuntime
+
+	// CPU values are always converted to seconds; memory values keep the unit of their sample
+	// type, which is left unset when the allocation profiles of the batch disagree
+	if hasCPU {
+		setDefaultName(&metrics.CPU.Unit, "s")
+	}
+	if len(memoryUnits) == 1 {
+		for unit := range memoryUnits {
+			setDefaultName(&metrics.Memory.Unit, unit)
+		}
+	}
+
+	setDefaultName(&metrics.CPU.MetricName, "cpu_time")
+	setDefaultName(&metrics.Memory.MetricName, "memory_allocation")
+	setDefaultName(&metrics.Lock.ContentionTimeMetricName, "lock_contention_time")
+	setDefaultName(&metrics.Lock.ContentionCountMetricName, "lock_contention_count")
+	setDefaultName(&metrics.Exceptions.MetricName, "exception_count")
+	setDefaultName(&metrics.Runtime.HeapLiveMetricName, "runtime.heap.live")
+	setDefaultName(&metrics.Runtime.GCCPUShareMetricName, "runtime.gc.cpu_share")
+}
+
+// setDefaultName sets an empty metric name or unit to its default
+func setDefaultName(name *string, defaultName string) {
+	if *name == "" {
+		*name = defaultName
+	}
+}
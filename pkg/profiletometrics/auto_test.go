@@ -0,0 +1,129 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_AutoConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleType string
+		unit       string
+		attributes map[string]string
+		check      func(t *testing.T, metrics MetricsConfig)
+	}{
+		{
+			name:       "cpu profile",
+			sampleType: "cpu",
+			unit:       "nanoseconds",
+			attributes: map[string]string{"process.executable.name": "app"},
+			check: func(t *testing.T, metrics MetricsConfig) {
+				assert.True(t, metrics.CPU.Enabled)
+				assert.False(t, metrics.Memory.Enabled)
+				assert.True(t, metrics.Process.Enabled)
+				assert.False(t, metrics.Thread.Enabled)
+				assert.Equal(t, "cpu_time", metrics.CPU.MetricName)
+				assert.Equal(t, "s", metrics.CPU.Unit)
+				assert.Empty(t, metrics.Memory.Unit)
+			},
+		},
+		{
+			name:       "allocation profile with threads",
+			sampleType: "alloc_space",
+			unit:       "bytes",
+			attributes: map[string]string{"thread.name": "worker"},
+			check: func(t *testing.T, metrics MetricsConfig) {
+				assert.False(t, metrics.CPU.Enabled)
+				assert.True(t, metrics.Memory.Enabled)
+				assert.True(t, metrics.Thread.Enabled)
+				assert.Equal(t, "memory_allocation", metrics.Memory.MetricName)
+				assert.Equal(t, "By", metrics.Memory.Unit)
+				assert.Empty(t, metrics.CPU.Unit)
+			},
+		},
+		{
+			name:       "object allocation profile",
+			sampleType: "alloc_objects",
+			unit:       "count",
+			check: func(t *testing.T, metrics MetricsConfig) {
+				assert.True(t, metrics.Memory.Enabled)
+				assert.Equal(t, "{object}", metrics.Memory.Unit)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ConverterConfig{Auto: true, Metrics: MetricsConfig{Function: FunctionMetricConfig{Enabled: true}}}
+			converter, err := NewConverter(config)
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType(tt.sampleType, tt.unit)
+			b.sample(b.stack("main.main", "main.work"), tt.attributes, 100)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+			assert.Positive(t, metrics.MetricCount())
+
+			derived := converter.config.Metrics
+			tt.check(t, derived)
+			assert.True(t, derived.Function.Enabled)
+			assert.Equal(t, FunctionTopNConfig{CPU: autoTopN, Memory: autoTopN}, derived.Function.TopN)
+			// The caller's configuration is left as it was
+			assert.Equal(t, MetricsConfig{Function: FunctionMetricConfig{Enabled: true}}, config.Metrics)
+		})
+	}
+}
+
+func TestConverter_AutoConfigKeepsExplicitSettings(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Auto: true,
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "custom_cpu"},
+			Function: FunctionMetricConfig{TopN: FunctionTopNConfig{CPU: 5}},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("alloc_space", "bytes")
+	b.sample(b.stack("main.main"), nil, 100)
+	_, err = converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	derived := converter.config.Metrics
+	assert.True(t, derived.CPU.Enabled)
+	assert.Equal(t, "custom_cpu", derived.CPU.MetricName)
+	assert.True(t, derived.Memory.Enabled)
+	assert.Equal(t, FunctionTopNConfig{CPU: 5}, derived.Function.TopN)
+	assert.False(t, derived.Function.Enabled)
+}
+
+func TestConverter_AutoConfigUnits(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Auto:    true,
+		Metrics: MetricsConfig{Memory: MemoryMetricConfig{Unit: "bytes"}},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main.main"), nil, 1000000000)
+	b.profile = b.scope.Profiles().AppendEmpty()
+	b.withSampleType("alloc_space", "bytes")
+	b.sample(b.stack("main.main"), nil, 1024)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	units := make(map[string]string)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		units[metricSlice.At(i).Name()] = metricSlice.At(i).Unit()
+	}
+	assert.Equal(t, "s", units["cpu_time"])
+	// A unit the user configured is kept
+	assert.Equal(t, "bytes", units["memory_allocation"])
+}
@@ -0,0 +1,169 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// newBenchmarkProfiles builds a CPU profile of 100k samples spread over 50 processes, 20 threads and
+// 200 leaf functions
+func newBenchmarkProfiles() pprofile.Profiles {
+	const (
+		samples   = 100000
+		processes = 50
+		threads   = 20
+		functions = 200
+	)
+	b := newTestProfileBuilder()
+	b.withSampleType("cpu", "nanoseconds")
+	stacks := make([]int32, functions)
+	for i := range stacks {
+		stacks[i] = b.stack("main", fmt.Sprintf("handler%d", i%10), fmt.Sprintf("function%d", i))
+	}
+	// Agents intern attribute table entries: samples share them
+	processAttributes := make([]int32, processes)
+	for i := range processAttributes {
+		processAttributes[i] = b.attribute("process.executable.name", fmt.Sprintf("process%d", i))
+	}
+	threadAttributes := make([]int32, threads)
+	for i := range threadAttributes {
+		threadAttributes[i] = b.attribute("thread.name", fmt.Sprintf("thread%d", i))
+	}
+	for i := 0; i < samples; i++ {
+		sample := b.sample(stacks[i%functions], nil, 10000000)
+		sample.AttributeIndices().Append(processAttributes[i%processes], threadAttributes[i%threads])
+	}
+	return b.profiles
+}
+
+func BenchmarkConvertProfilesToMetrics(b *testing.B) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Process:  ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+			Thread:   ThreadMetricConfig{Enabled: true, MetricNameSuffix: ".by_thread"},
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	profiles := newBenchmarkProfiles()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := converter.ConvertProfilesToMetrics(context.Background(), profiles); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProcessTotals compares one filtered pass over the samples per process with the single
+// aggregation pass used by process metrics
+func BenchmarkProcessTotals(b *testing.B) {
+	converter, err := NewConverter(&ConverterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	profiles := newBenchmarkProfiles()
+	profile := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+	processNames := converter.getUniqueProcessNames(profiles, profile)
+
+	b.Run("filtered passes", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, processName := range processNames {
+				filter := map[string]string{"process.executable.name": processName}
+				converter.calculateCPUTimeTotalsForFilter(profiles, profile, filter)
+				converter.calculateMemoryAllocationTotalsForFilter(profiles, profile, filter)
+			}
+		}
+	})
+	b.Run("single pass", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			converter.aggregateSamplesBy(profiles, profile, "process.executable.name")
+		}
+	})
+}
+
+func BenchmarkProcessFunctions(b *testing.B) {
+	converter, err := NewConverter(&ConverterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	profiles := newBenchmarkProfiles()
+	profile := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		converter.aggregateProcessFunctions(profiles, profile)
+	}
+}
+
+// BenchmarkSampleFunctionName compares resolving the function of every sample through the
+// dictionary with the per-conversion cache
+func BenchmarkSampleFunctionName(b *testing.B) {
+	converter, err := NewConverter(&ConverterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	profiles := newBenchmarkProfiles()
+	samples := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Sample()
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < samples.Len(); j++ {
+				converter.getSampleFunctionName(profiles, samples.At(j))
+				getSampleAttributeValueCommon(profiles, samples.At(j), "thread.name")
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cache := newDictionaryCache(profiles, functionNameRules{})
+			for j := 0; j < samples.Len(); j++ {
+				converter.getSampleFunctionName(cache, samples.At(j))
+				getSampleAttributeValueCommon(cache, samples.At(j), "thread.name")
+			}
+		}
+	})
+}
+
+// BenchmarkSampleAttributeValues compares returning the thread names of every sample in a new slice
+// with appending them to a reused buffer
+func BenchmarkSampleAttributeValues(b *testing.B) {
+	cfg := &ConverterConfig{}
+	profiles := newBenchmarkProfiles()
+	samples := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Sample()
+	cache := newDictionaryCache(profiles, functionNameRules{})
+
+	b.Run("allocating", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < samples.Len(); j++ {
+				getSampleAttributeValuesCommon(cfg, cache, samples.At(j), "thread.name")
+			}
+		}
+	})
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		var buffer []string
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < samples.Len(); j++ {
+				buffer = appendSampleAttributeValuesCommon(buffer[:0], cfg, cache, samples.At(j), "thread.name")
+			}
+		}
+	})
+}
+
+func BenchmarkSanitizeMetricName(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sanitizeMetricName("process.cpu.time-by/function")
+	}
+}
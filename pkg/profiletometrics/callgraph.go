@@ -0,0 +1,108 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Data point attributes of a call graph edge
+	callerFunctionAttributeKey = "caller.function"
+	calleeFunctionAttributeKey = "callee.function"
+)
+
+// callEdge is a call from one function to another within a process
+type callEdge struct {
+	processName string
+	caller      string
+	callee      string
+}
+
+// stackCallEdges returns the distinct caller→callee pairs of adjacent frames of a stack, so that a
+// recursive stack counts each edge once
+func stackCallEdges(profiles dictionaryProvider, stackIndex int32) [][2]string {
+	frames := stackFunctionNamesCommon(profiles, stackIndex)
+	edges := make([][2]string, 0, len(frames))
+	seen := make(map[[2]string]bool, len(frames))
+	for i := 1; i < len(frames); i++ {
+		edge := [2]string{frames[i-1], frames[i]}
+		if !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// generateCallGraphMetrics emits the CPU time flowing through each caller→callee edge of the
+// profile's stacks, per process, with caller.function and callee.function attributes. A sample
+// counts towards every edge between adjacent frames of its stack, inlined frames included.
+func (c *Converter) generateCallGraphMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	stackEdges := make(map[int32][][2]string)
+	totals := make(map[callEdge]float64)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		if sample.StackIndex() < 0 {
+			continue
+		}
+		edges, exists := stackEdges[sample.StackIndex()]
+		if !exists {
+			edges = stackCallEdges(profiles, sample.StackIndex())
+			stackEdges[sample.StackIndex()] = edges
+		}
+		if len(edges) == 0 {
+			continue
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		for _, edge := range edges {
+			totals[callEdge{processName: processName, caller: edge[0], callee: edge[1]}] += cpuTime
+		}
+	}
+	if len(totals) == 0 {
+		return
+	}
+
+	keys := make([]callEdge, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		if keys[i].caller != keys[j].caller {
+			return keys[i].caller < keys[j].caller
+		}
+		return keys[i].callee < keys[j].callee
+	})
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.CallGraph.MetricName)
+	metric.SetDescription("CPU time spent in calls from a caller function to a callee function")
+	metric.SetUnit("s")
+	c.markAdditive(metric)
+	gauge := metric.SetEmptyGauge()
+	for _, key := range keys {
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(totals[key])
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if key.processName != "" {
+			dataPoint.Attributes().PutStr("process.name", key.processName)
+		}
+		dataPoint.Attributes().PutStr(callerFunctionAttributeKey, key.caller)
+		dataPoint.Attributes().PutStr(calleeFunctionAttributeKey, key.callee)
+	}
+}
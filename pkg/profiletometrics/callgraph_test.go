@@ -0,0 +1,56 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_CallGraphMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CallGraph: CallGraphMetricConfig{Enabled: true, MetricName: "cpu_time_by_call_edge"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main.main", "api.Handle", "json.Marshal"), app, 2000000000)
+	b.sample(b.stack("main.main", "api.Handle"), app, 1000000000)
+	// The recursive walk.Visit edge counts once for the sample
+	b.sample(b.stack("main.main", "walk.Visit", "walk.Visit", "walk.Visit"), app, 1000000000)
+	b.sample(b.stack("main.main", "api.Handle"), map[string]string{"process.executable.name": "db"}, 3000000000)
+	// A single frame has no edge
+	b.sample(b.stack("main.main"), app, 5000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, "cpu_time_by_call_edge", metric.Name())
+	edges := make(map[string]float64)
+	for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+		dp := metric.Gauge().DataPoints().At(i)
+		process, _ := dp.Attributes().Get("process.name")
+		caller, _ := dp.Attributes().Get(callerFunctionAttributeKey)
+		callee, _ := dp.Attributes().Get(calleeFunctionAttributeKey)
+		edges[process.Str()+": "+caller.Str()+" -> "+callee.Str()] = dp.DoubleValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"app: main.main -> api.Handle":    3,
+		"app: api.Handle -> json.Marshal": 2,
+		"app: main.main -> walk.Visit":    1,
+		"app: walk.Visit -> walk.Visit":   1,
+		"db: main.main -> api.Handle":     3,
+	}, edges)
+}
+
+func TestConverter_CallGraphMetricName(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CallGraph: CallGraphMetricConfig{Enabled: true, MetricName: "1edges"}},
+	})
+	assert.Error(t, err)
+}
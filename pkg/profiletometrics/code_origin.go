@@ -0,0 +1,123 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// codeOriginAttributeKey is the data point attribute holding the class of the code burning CPU
+	codeOriginAttributeKey = "code.origin"
+
+	// Code origin classes
+	codeOriginFirstParty = "first_party"
+	codeOriginDependency = "dependency"
+)
+
+// validateCodeOrigin checks that enabled code origin metrics have rules identifying first-party code
+func validateCodeOrigin(cfg CodeOriginMetricConfig) error {
+	if cfg.Enabled && len(cfg.FunctionPrefixes) == 0 && len(cfg.MappingPrefixes) == 0 {
+		return fmt.Errorf("metrics.code_origin requires function_prefixes or mapping_prefixes when enabled")
+	}
+	return nil
+}
+
+// classifyCodeOrigin classifies a frame as first-party when its function name, or the path of its
+// binary mapping, starts with a configured prefix, and as a dependency otherwise
+func (c *Converter) classifyCodeOrigin(profiles dictionaryProvider, location pprofile.Location) string {
+	cfg := c.config.Metrics.CodeOrigin
+	if functionName := c.getLocationFunctionName(profiles, location); functionName != "" {
+		for _, prefix := range cfg.FunctionPrefixes {
+			if strings.HasPrefix(functionName, prefix) {
+				return codeOriginFirstParty
+			}
+		}
+	}
+	if mappingFile := c.getLocationMappingFileName(profiles, location); mappingFile != "" {
+		for _, prefix := range cfg.MappingPrefixes {
+			if strings.HasPrefix(mappingFile, prefix) {
+				return codeOriginFirstParty
+			}
+		}
+	}
+	return codeOriginDependency
+}
+
+// generateCodeOriginMetrics emits, for the profile and for each process, the percent share of CPU
+// time whose leaf frame is first-party code and third-party dependencies. Samples without a
+// resolvable leaf frame are left out, so the shares of a process add up to 100.
+func (c *Converter) generateCodeOriginMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	type originTotals map[string]float64
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	profileTotals := make(originTotals)
+	processTotals := make(map[string]originTotals)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		location, ok := c.getSampleTopLocation(profiles, sample)
+		if !ok {
+			continue
+		}
+		origin := c.classifyCodeOrigin(profiles, location)
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		profileTotals[origin] += cpuTime
+
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		if processName == "" {
+			continue
+		}
+		if processTotals[processName] == nil {
+			processTotals[processName] = make(originTotals)
+		}
+		processTotals[processName][origin] += cpuTime
+	}
+	if len(profileTotals) == 0 {
+		return
+	}
+
+	processNames := make([]string, 0, len(processTotals))
+	for processName := range processTotals {
+		processNames = append(processNames, processName)
+	}
+	sort.Strings(processNames)
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.CodeOrigin.MetricName)
+	metric.SetDescription("Share of CPU time spent in first-party code and in third-party dependencies")
+	metric.SetUnit(percentUnit)
+	gauge := metric.SetEmptyGauge()
+
+	appendShares := func(totals originTotals, processName string) {
+		total := totals[codeOriginFirstParty] + totals[codeOriginDependency]
+		for _, origin := range []string{codeOriginFirstParty, codeOriginDependency} {
+			share := 0.0
+			if total > 0 {
+				share = totals[origin] / total * 100
+			}
+			dataPoint := gauge.DataPoints().AppendEmpty()
+			c.setDataPointTimestamps(dataPoint, profile)
+			dataPoint.SetDoubleValue(share)
+			for k, v := range attributes {
+				dataPoint.Attributes().PutStr(k, v)
+			}
+			if processName != "" {
+				dataPoint.Attributes().PutStr("process.name", processName)
+			}
+			dataPoint.Attributes().PutStr(codeOriginAttributeKey, origin)
+		}
+	}
+	appendShares(profileTotals, "")
+	for _, processName := range processNames {
+		appendShares(processTotals[processName], processName)
+	}
+}
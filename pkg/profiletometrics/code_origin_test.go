@@ -0,0 +1,66 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_CodeOriginMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CodeOrigin: CodeOriginMetricConfig{
+				Enabled:          true,
+				MetricName:       "cpu_share_by_code_origin",
+				FunctionPrefixes: []string{"github.com/mycompany/"},
+				MappingPrefixes:  []string{"/app/"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	// Mapping index 0 is the zero mapping, used by locations without a binary
+	b.mapping("")
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main.main", "github.com/mycompany/api.Handle"), app, 1000000000)
+	b.sample(b.stack("main.main", "encoding/json.Marshal"), app, 2000000000)
+	b.sample(b.stack("main.main", "processRequest"), app, 1000000000)
+	b.location("processRequest", "").SetMappingIndex(b.mapping("/app/server"))
+	b.sample(b.stack("main.main", "encoding/json.Marshal"), map[string]string{"process.executable.name": "db"}, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, "cpu_share_by_code_origin", metric.Name())
+	assert.Equal(t, percentUnit, metric.Unit())
+
+	shares := make(map[string]float64)
+	for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+		dp := metric.Gauge().DataPoints().At(i)
+		processName := "profile"
+		if process, ok := dp.Attributes().Get("process.name"); ok {
+			processName = process.Str()
+		}
+		origin, _ := dp.Attributes().Get(codeOriginAttributeKey)
+		shares[processName+"/"+origin.Str()] = dp.DoubleValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"profile/first_party": 40,
+		"profile/dependency":  60,
+		"app/first_party":     50,
+		"app/dependency":      50,
+		"db/first_party":      0,
+		"db/dependency":       100,
+	}, shares)
+}
+
+func TestNewConverter_CodeOriginRequiresPrefixes(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CodeOrigin: CodeOriginMetricConfig{Enabled: true}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package profiletometrics
+
+import (
+	"path"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// codeFilePathAttributeKey is the data point attribute holding the code artifact of a function
+	codeFilePathAttributeKey = "code.filepath"
+
+	// frameTypeAttributeKey is the location attribute set by the eBPF profiler with the frame's language
+	frameTypeAttributeKey = "profile.frame.type"
+)
+
+// nativeFrameTypes are frame types whose mapping is the code artifact itself
+var nativeFrameTypes = map[string]bool{
+	"native": true,
+	"kernel": true,
+	"go":     true,
+}
+
+// interpreterBinaryPattern matches the basename of interpreter and VM binaries (and their shared
+// libraries); their mapping never identifies the user's code
+var interpreterBinaryPattern = regexp.MustCompile(
+	`^(lib)?(python|ruby|node|php|perl|lua|luajit|java|jvm|dotnet|coreclr|bash|erl|beam)[0-9.]*(-fpm|-cgi|-cli|\.smp)?(\.so[0-9.]*)?$`)
+
+// isInterpretedLocation reports whether a location belongs to an interpreted or JIT-compiled frame,
+// based on the frame type attribute or, when absent, on the mapping being an interpreter binary
+func (c *Converter) isInterpretedLocation(profiles dictionaryProvider, location pprofile.Location) bool {
+	if frameType := getAttributeValueCommon(profiles, location.AttributeIndices(), frameTypeAttributeKey); frameType != "" {
+		return !nativeFrameTypes[frameType]
+	}
+	mappingFile := c.getLocationMappingFileName(profiles, location)
+	return mappingFile != "" && interpreterBinaryPattern.MatchString(path.Base(mappingFile))
+}
+
+// getLocationMappingFileName returns the filename of the binary mapping of a location
+func (c *Converter) getLocationMappingFileName(profiles dictionaryProvider, location pprofile.Location) string {
+	mappingTable := profiles.Dictionary().MappingTable()
+	mappingIndex := location.MappingIndex()
+	if mappingIndex < 0 || int(mappingIndex) >= mappingTable.Len() {
+		return ""
+	}
+	return stringTableValue(profiles.Dictionary().StringTable(), mappingTable.At(int(mappingIndex)).FilenameStrindex())
+}
+
+// getLocationCodeFilePath returns the code.filepath of a location: the function's source or script
+// path when known, otherwise the mapped binary for native frames. Interpreted frames never fall back
+// to the mapping, which is the interpreter (e.g. python3.11) rather than the user's script.
+func (c *Converter) getLocationCodeFilePath(profiles dictionaryProvider, location pprofile.Location) string {
+	if fileName := c.getLocationFileName(profiles, location); fileName != "" {
+		return fileName
+	}
+	if c.isInterpretedLocation(profiles, location) {
+		return ""
+	}
+	return c.getLocationMappingFileName(profiles, location)
+}
+
+// getSampleCodeFilePath returns the code.filepath of the frame identifying a sample's function
+func (c *Converter) getSampleCodeFilePath(profiles dictionaryProvider, sample pprofile.Sample) string {
+	location, ok := c.getSampleTopLocation(profiles, sample)
+	if !ok {
+		return ""
+	}
+	return c.getLocationCodeFilePath(profiles, location)
+}
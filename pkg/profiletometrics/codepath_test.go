@@ -0,0 +1,79 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_GetLocationCodeFilePath(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	python := b.mapping("/usr/bin/python3.11")
+	binary := b.mapping("/app/bin/server")
+
+	// Python frame with a script path: the script wins over the interpreter
+	script := b.location("handle_request", "/srv/app/views.py")
+	script.SetMappingIndex(python)
+
+	// Python frame without a script path: never reported as the interpreter binary
+	unknownScript := b.location("<module>", "")
+	unknownScript.SetMappingIndex(python)
+
+	// Native frame without symbols: the mapped binary is the code artifact
+	native := b.location("0x4a2f10", "")
+	native.SetMappingIndex(binary)
+
+	// Frame type attribute takes precedence over the mapping name
+	jit := b.location("lambda$0", "")
+	jit.SetMappingIndex(binary)
+	jit.AttributeIndices().Append(b.attribute("profile.frame.type", "hotspot"))
+
+	assert.Equal(t, "/srv/app/views.py", converter.getLocationCodeFilePath(b.profiles, script))
+	assert.Equal(t, "", converter.getLocationCodeFilePath(b.profiles, unknownScript))
+	assert.Equal(t, "/app/bin/server", converter.getLocationCodeFilePath(b.profiles, native))
+	assert.Equal(t, "", converter.getLocationCodeFilePath(b.profiles, jit))
+}
+
+func TestInterpreterBinaryPattern(t *testing.T) {
+	for _, name := range []string{"python3.11", "python3", "libpython3.11.so.1.0", "ruby", "node", "php-fpm", "perl5.36", "java", "libjvm.so", "beam.smp"} {
+		assert.True(t, interpreterBinaryPattern.MatchString(name), name)
+	}
+	for _, name := range []string{"server", "libc.so.6", "nginx", "pythonapp.bin"} {
+		assert.False(t, interpreterBinaryPattern.MatchString(name), name)
+	}
+}
+
+func TestConverter_FunctionMetricsCodeFilePath(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true, CodeFilePath: true},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.location("handle_request", "").SetMappingIndex(b.mapping("/usr/bin/python3.11"))
+	b.location("compress", "").SetMappingIndex(b.mapping("/usr/lib/libz.so.1"))
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("handle_request"), process, 1000000000, 0)
+	b.sample(b.stack("compress"), process, 1000000000, 0)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+	codeFilePaths := make(map[string]string)
+	dataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+	for i := 0; i < dataPoints.Len(); i++ {
+		functionName, _ := dataPoints.At(i).Attributes().Get("function.name")
+		codeFilePath, _ := dataPoints.At(i).Attributes().Get("code.filepath")
+		codeFilePaths[functionName.Str()] = codeFilePath.Str()
+	}
+	assert.Equal(t, map[string]string{"handle_request": "", "compress": "/usr/lib/libz.so.1"}, codeFilePaths)
+}
@@ -0,0 +1,148 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// compactionStats describes the emitted payload after compaction
+type compactionStats struct {
+	removedMetrics int
+	mergedMetrics  int
+	metrics        int
+	series         int
+	dataPoints     int
+}
+
+// fields returns the stats as zap fields
+func (s compactionStats) fields() []zap.Field {
+	return []zap.Field{
+		zap.Int("removed_metrics", s.removedMetrics),
+		zap.Int("merged_metrics", s.mergedMetrics),
+		zap.Int("metrics", s.metrics),
+		zap.Int("series", s.series),
+		zap.Int("data_points", s.dataPoints),
+	}
+}
+
+// compactMetrics removes metrics without data points, merges scopes and metrics sharing the same
+// descriptor, and drops empty scopes and resources, keeping the payload small for exporters
+func compactMetrics(metrics pmetric.Metrics) compactionStats {
+	var stats compactionStats
+
+	resourceMetricsSlice := metrics.ResourceMetrics()
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		scopeMetricsSlice := resourceMetricsSlice.At(i).ScopeMetrics()
+
+		// Merge scopes with the same name and version into the first occurrence
+		scopes := make(map[string]pmetric.ScopeMetrics)
+		scopeMetricsSlice.RemoveIf(func(scopeMetrics pmetric.ScopeMetrics) bool {
+			key := scopeMetrics.Scope().Name() + "\x00" + scopeMetrics.Scope().Version()
+			target, exists := scopes[key]
+			if !exists {
+				scopes[key] = scopeMetrics
+				return false
+			}
+			scopeMetrics.Metrics().MoveAndAppendTo(target.Metrics())
+			return true
+		})
+
+		for j := 0; j < scopeMetricsSlice.Len(); j++ {
+			descriptors := make(map[string]pmetric.Metric)
+			scopeMetricsSlice.At(j).Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+				if metricDataPoints(metric) == 0 {
+					stats.removedMetrics++
+					return true
+				}
+				key := metricDescriptorKey(metric)
+				target, exists := descriptors[key]
+				if !exists {
+					descriptors[key] = metric
+					return false
+				}
+				moveMetricDataPoints(metric, target)
+				stats.mergedMetrics++
+				return true
+			})
+		}
+
+		scopeMetricsSlice.RemoveIf(func(scopeMetrics pmetric.ScopeMetrics) bool {
+			return scopeMetrics.Metrics().Len() == 0
+		})
+	}
+	resourceMetricsSlice.RemoveIf(func(resourceMetrics pmetric.ResourceMetrics) bool {
+		return resourceMetrics.ScopeMetrics().Len() == 0
+	})
+
+	// Count the final payload
+	for i := 0; i < resourceMetricsSlice.Len(); i++ {
+		resourceMetrics := resourceMetricsSlice.At(i)
+		resourceKey := attributesKey(resourceMetrics.Resource().Attributes())
+		series := make(map[string]bool)
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			metricSlice := resourceMetrics.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				stats.metrics++
+				stats.dataPoints += metricDataPoints(metric)
+				for _, key := range numberDataPointKeys(metric) {
+					series[metric.Name()+"\x00"+resourceKey+"\x00"+key] = true
+				}
+			}
+		}
+		stats.series += len(series)
+	}
+	return stats
+}
+
+// metricDescriptorKey identifies metrics that can share a single descriptor
+func metricDescriptorKey(metric pmetric.Metric) string {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", metric.Name(), metric.Description(), metric.Unit(), metric.Type())
+	if metric.Type() == pmetric.MetricTypeSum {
+		key += fmt.Sprintf("\x00%d\x00%t", metric.Sum().AggregationTemporality(), metric.Sum().IsMonotonic())
+	}
+	return key
+}
+
+// metricDataPoints returns the number of data points of a gauge or sum
+func metricDataPoints(metric pmetric.Metric) int {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		return metric.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return metric.Sum().DataPoints().Len()
+	default:
+		// Other types are never emitted empty by the converter; keep them as-is
+		return 1
+	}
+}
+
+// moveMetricDataPoints moves the data points of src into dst; both share the same descriptor
+func moveMetricDataPoints(src, dst pmetric.Metric) {
+	switch src.Type() {
+	case pmetric.MetricTypeGauge:
+		src.Gauge().DataPoints().MoveAndAppendTo(dst.Gauge().DataPoints())
+	case pmetric.MetricTypeSum:
+		src.Sum().DataPoints().MoveAndAppendTo(dst.Sum().DataPoints())
+	}
+}
+
+// numberDataPointKeys returns the attribute keys of the data points of a gauge or sum
+func numberDataPointKeys(metric pmetric.Metric) []string {
+	var dataPoints pmetric.NumberDataPointSlice
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dataPoints = metric.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		dataPoints = metric.Sum().DataPoints()
+	default:
+		return nil
+	}
+	keys := make([]string, 0, dataPoints.Len())
+	for i := 0; i < dataPoints.Len(); i++ {
+		keys = append(keys, attributesKey(dataPoints.At(i).Attributes()))
+	}
+	return keys
+}
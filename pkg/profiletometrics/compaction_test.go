@@ -0,0 +1,57 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestCompactMetrics(t *testing.T) {
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+
+	// Two scopes from two profiles with the same descriptor, plus an empty gauge
+	for _, process := range []string{"app", "db"} {
+		scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+		scopeMetrics.Scope().SetName("profiletometrics")
+		metric := scopeMetrics.Metrics().AppendEmpty()
+		metric.SetName("cpu_time")
+		metric.SetUnit("s")
+		dataPoint := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dataPoint.SetDoubleValue(1)
+		dataPoint.Attributes().PutStr("process.name", process)
+
+		empty := scopeMetrics.Metrics().AppendEmpty()
+		empty.SetName("memory_allocation")
+		empty.SetEmptyGauge()
+	}
+	// A resource left without data points is dropped entirely
+	metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+
+	stats := compactMetrics(metrics)
+
+	assert.Equal(t, compactionStats{removedMetrics: 2, mergedMetrics: 1, metrics: 1, series: 2, dataPoints: 2}, stats)
+	require.Equal(t, 1, metrics.ResourceMetrics().Len())
+	require.Equal(t, 1, metrics.ResourceMetrics().At(0).ScopeMetrics().Len())
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 1, metricSlice.Len())
+	assert.Equal(t, 2, metricSlice.At(0).Gauge().DataPoints().Len())
+}
+
+func TestCompactMetrics_KeepsDistinctDescriptors(t *testing.T) {
+	metrics := pmetric.NewMetrics()
+	metricSlice := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, unit := range []string{"s", "ns"} {
+		metric := metricSlice.AppendEmpty()
+		metric.SetName("cpu_time")
+		metric.SetUnit(unit)
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+	}
+
+	stats := compactMetrics(metrics)
+
+	assert.Equal(t, 0, stats.mergedMetrics)
+	assert.Equal(t, 2, metricSlice.Len())
+}
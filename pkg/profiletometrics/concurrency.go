@@ -0,0 +1,59 @@
+package profiletometrics
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// fork returns the state of a worker of the conversion: the shared summary and degradation step,
+// with dictionary caches of its own
+func (cv *conversion) fork() *conversion {
+	return &conversion{summary: cv.summary, degradationStep: cv.degradationStep, caches: make(dictionaryCaches)}
+}
+
+// convertResourcesConcurrently converts the resource profiles of a batch across a pool of up to
+// concurrency workers. Each resource profile is generated into its own payload, with dictionary
+// caches owned by its worker, and the payloads are merged in resource order once every worker is
+// done, so the output matches a sequential conversion. Counters are shared through the atomic
+// conversion summary; rolling top-k and slope state is updated under their own locks.
+func (c *Converter) convertResourcesConcurrently(
+	conversion *conversion,
+	profiles pprofile.Profiles,
+	metrics pmetric.Metrics,
+	resourceMetrics pmetric.ResourceMetrics,
+) {
+	count := profiles.ResourceProfiles().Len()
+	parts := make([]pmetric.Metrics, count)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(c.config.Concurrency, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := conversion.fork()
+			for i := range jobs {
+				part := pmetric.NewMetrics()
+				c.convertResource(worker, profiles, i, part.ResourceMetrics().AppendEmpty(), newResourceLayout(c.config, part))
+				parts[i] = part
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, part := range parts {
+		// The first ResourceMetrics of a part holds the merged layout output
+		partResourceMetrics := part.ResourceMetrics()
+		partResourceMetrics.At(0).ScopeMetrics().MoveAndAppendTo(resourceMetrics.ScopeMetrics())
+		partResourceMetrics.RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+			return rm.ScopeMetrics().Len() == 0
+		})
+		partResourceMetrics.MoveAndAppendTo(metrics.ResourceMetrics())
+	}
+}
@@ -0,0 +1,86 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestConverter_Concurrency(t *testing.T) {
+	newProfiles := func() pprofile.Profiles {
+		b := newTestProfileBuilder()
+		b.profile.SetTime(pcommon.Timestamp(1700000000000000000))
+		b.profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+		b.sample(b.stack("main", "handler"), map[string]string{"process.executable.name": "api"}, 1000000000)
+		b.sample(b.stack("main", "worker"), map[string]string{"process.executable.name": "batch"}, 500000000)
+		for i := 1; i < 8; i++ {
+			other := b.profiles.ResourceProfiles().AppendEmpty()
+			b.resource.CopyTo(other)
+			other.Resource().Attributes().PutStr("k8s.pod.name", fmt.Sprintf("pod-%d", i))
+		}
+		return b.profiles
+	}
+
+	for _, layout := range []string{"merged", "per_resource"} {
+		t.Run(layout, func(t *testing.T) {
+			newConverter := func(concurrency int) *Converter {
+				converter, err := NewConverter(&ConverterConfig{
+					Metrics: MetricsConfig{
+						CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+						Process:  ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+						Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+					},
+					TimestampSource: "profile_end",
+					OutputLayout:    layout,
+					Concurrency:     concurrency,
+				})
+				require.NoError(t, err)
+				return converter
+			}
+
+			sequential, err := newConverter(0).ConvertProfilesToMetrics(context.Background(), newProfiles())
+			require.NoError(t, err)
+			concurrent, err := newConverter(4).ConvertProfilesToMetrics(context.Background(), newProfiles())
+			require.NoError(t, err)
+
+			assert.Equal(t, concurrencyDataPoints(sequential), concurrencyDataPoints(concurrent),
+				"workers produce the output of a sequential conversion, in resource order")
+		})
+	}
+
+	_, err := NewConverter(&ConverterConfig{Concurrency: -1})
+	assert.Error(t, err)
+}
+
+// concurrencyDataPoints lists the resource, metric, attributes, value and timestamp of the data
+// points of each resource metrics in turn, sorted within a resource metrics since the points of a
+// metric are not emitted in a fixed order
+func concurrencyDataPoints(metrics pmetric.Metrics) [][]string {
+	var resources [][]string
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		resourceMetrics := metrics.ResourceMetrics().At(i)
+		var dataPoints []string
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			metricSlice := resourceMetrics.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				points := metric.Gauge().DataPoints()
+				for l := 0; l < points.Len(); l++ {
+					dataPoints = append(dataPoints, fmt.Sprintf("%v %s %v %v %d", resourceMetrics.Resource().Attributes().AsRaw(),
+						metric.Name(), points.At(l).Attributes().AsRaw(), points.At(l).DoubleValue(), points.At(l).Timestamp()))
+				}
+			}
+		}
+		sort.Strings(dataPoints)
+		resources = append(resources, dataPoints)
+	}
+	return resources
+}
@@ -1,36 +1,361 @@
 package profiletometrics
 
+import "time"
+
 // MetricsConfig defines the metrics configuration
 type MetricsConfig struct {
 	CPU      CPUMetricConfig      `mapstructure:"cpu"`
 	Memory   MemoryMetricConfig   `mapstructure:"memory"`
 	Function FunctionMetricConfig `mapstructure:"function"`
+	Process  ProcessMetricConfig  `mapstructure:"process"`
+	Thread   ThreadMetricConfig   `mapstructure:"thread"`
+	// GroupBy adds a grouping level to the CPU and memory metrics: "container" aggregates them per
+	// container.id sample attribute
+	GroupBy string `mapstructure:"group_by"`
+	// Container names the per-container metrics of group_by: container
+	Container ContainerMetricConfig `mapstructure:"container"`
+	// CPUUtilization emits the sampled CPU time as a ratio of the profile duration and cores
+	CPUUtilization CPUUtilizationMetricConfig `mapstructure:"cpu_utilization"`
+	// HottestStack emits the hottest stack of each process with its percent share of CPU time
+	HottestStack HottestStackMetricConfig `mapstructure:"hottest_stack"`
+	// HotPath emits the most expensive root-to-leaf path of each process with its percent share of CPU time
+	HotPath HotPathMetricConfig `mapstructure:"hot_path"`
+	// Stack reports stack depths and truncated stacks
+	Stack StackMetricConfig `mapstructure:"stack"`
+	// Symbolization reports how much of the sampled frames is symbolized
+	Symbolization SymbolizationMetricConfig `mapstructure:"symbolization"`
+	// CodeOrigin splits CPU time between first-party code and third-party dependencies
+	CodeOrigin CodeOriginMetricConfig `mapstructure:"code_origin"`
+	// FrameType splits the CPU time of each process by the profile.frame.type of the leaf frames
+	FrameType FrameTypeMetricConfig `mapstructure:"frame_type"`
+	// KernelUser splits the CPU time between kernel and user code
+	KernelUser KernelUserMetricConfig `mapstructure:"kernel_user"`
+	// CallGraph emits the CPU time of each caller→callee edge of the stacks
+	CallGraph CallGraphMetricConfig `mapstructure:"callgraph"`
+	// TraceCorrelation reports the CPU time of sampled traces, keyed by the samples' trace_id
+	TraceCorrelation TraceCorrelationMetricConfig `mapstructure:"trace_correlation"`
+	// Runtime derives heap and garbage collection metrics from runtime-specific sample types
+	Runtime RuntimeMetricConfig `mapstructure:"runtime"`
+	// Ingestion reports the health of the converted profiles (processed, dropped and malformed samples)
+	Ingestion  IngestionMetricConfig `mapstructure:"ingestion"`
+	Lock       LockMetricConfig      `mapstructure:"lock"`
+	Exceptions ExceptionMetricConfig `mapstructure:"exceptions"`
+	// Custom maps arbitrary sample types to metrics
+	Custom []CustomMetricConfig `mapstructure:"custom"`
+}
+
+// MetricOverrides sets a user-defined description and constant attributes (e.g. team, env) on
+// the metrics of a metric configuration; static attributes replace generated ones with the same key
+type MetricOverrides struct {
+	Description      string            `mapstructure:"description"`
+	StaticAttributes map[string]string `mapstructure:"static_attributes"`
 }
 
 // CPUMetricConfig defines CPU metric configuration
+// Its overrides apply to the CPU time metrics of profiles, processes, threads, containers and
+// functions; its aggregation to those of profiles, processes, threads and containers
 type CPUMetricConfig struct {
-	Enabled    bool   `mapstructure:"enabled"`
-	MetricName string `mapstructure:"metric_name"`
-	Unit       string `mapstructure:"unit"`
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	Unit            string `mapstructure:"unit"`
+	Aggregation     string `mapstructure:"aggregation"`
+	MetricOverrides `mapstructure:",squash"`
 }
 
 // MemoryMetricConfig defines memory metric configuration
+// Its overrides apply to the memory allocation metrics of profiles, processes, threads,
+// containers and functions; its aggregation to those of profiles, processes, threads and containers
 type MemoryMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	Unit            string `mapstructure:"unit"`
+	Aggregation     string `mapstructure:"aggregation"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// CPUUtilizationMetricConfig defines the CPU utilization of each profile, and of each of its
+// processes, as a 0–1 ratio: sampled CPU time / (profile duration × cores)
+type CPUUtilizationMetricConfig struct {
 	Enabled    bool   `mapstructure:"enabled"`
 	MetricName string `mapstructure:"metric_name"`
-	Unit       string `mapstructure:"unit"`
+	// Cores is the number of CPU cores of the profiled hosts; 0 counts a single core
+	Cores           int `mapstructure:"cores"`
+	MetricOverrides `mapstructure:",squash"`
 }
 
 // FunctionMetricConfig defines function-level metric configuration
 type FunctionMetricConfig struct {
+	Enabled     bool               `mapstructure:"enabled"`
+	RollingTopK RollingTopKConfig  `mapstructure:"rolling_top_k"`
+	TopN        FunctionTopNConfig `mapstructure:"top_n"`
+	// Attribution selects how samples are credited to functions: "self" (leaf frame only, default),
+	// "total" (every function on the stack) or "both"
+	Attribution string `mapstructure:"attribution"`
+	// GroupBy aggregates function data points per "function" (default), "file", "package" or "module"
+	GroupBy string `mapstructure:"group_by"`
+	// GroupByThread adds (thread.name, function.name) data points next to the process breakdown
+	GroupByThread bool `mapstructure:"group_by_thread"`
+	// CodeFilePath adds a code.filepath attribute: the source/script path of the function, or the
+	// mapped binary for native frames without source information
+	CodeFilePath bool `mapstructure:"code_filepath"`
+	// IncludeLineNumbers aggregates data points per (function, line), adding a code.lineno attribute
+	IncludeLineNumbers bool             `mapstructure:"include_line_numbers"`
+	HeatBuckets        HeatBucketConfig `mapstructure:"heat_buckets"`
+	// Slope emits the CPU growth rate of each (process, function) over a sliding window
+	Slope FunctionSlopeConfig `mapstructure:"slope"`
+	// Diff emits the CPU change of each (process, function) since the previous profile of a resource
+	Diff FunctionDiffConfig `mapstructure:"diff"`
+	// Anomaly emits a z-score for each (process, function) whose CPU time deviates from its history
+	Anomaly FunctionAnomalyConfig `mapstructure:"anomaly"`
+	// MetricNameSuffix is appended to the function CPU and memory metric names (e.g. ".by_function")
+	MetricNameSuffix string `mapstructure:"metric_name_suffix"`
+}
+
+// HeatBucketConfig classifies function data points as hot, warm or cold by their percentage of the
+// process total, exposed as the function.heat attribute
+type HeatBucketConfig struct {
+	Enabled              bool    `mapstructure:"enabled"`
+	HotThresholdPercent  float64 `mapstructure:"hot_threshold_percent"`  // default: 10
+	WarmThresholdPercent float64 `mapstructure:"warm_threshold_percent"` // default: 1
+}
+
+// ProcessMetricConfig defines the per-process CPU and memory breakdown (process.name attribute)
+// Empty metric names reuse the CPU and memory metric names
+type ProcessMetricConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	CPUMetricName    string `mapstructure:"cpu_metric_name"`
+	MemoryMetricName string `mapstructure:"memory_metric_name"`
+	// MetricNameSuffix is appended to the process CPU and memory metric names (e.g. ".by_process")
+	MetricNameSuffix string `mapstructure:"metric_name_suffix"`
+	// HierarchyAttributes splits the process series by process.pid, process.parent_pid and
+	// process.command_line and attaches them, telling apart processes of the same executable
+	HierarchyAttributes bool `mapstructure:"hierarchy_attributes"`
+}
+
+// ContainerMetricConfig defines the per-container CPU time and memory metrics
+type ContainerMetricConfig struct {
+	// MetricNameSuffix is appended to the container CPU and memory metric names (e.g. ".by_container")
+	MetricNameSuffix string `mapstructure:"metric_name_suffix"`
+}
+
+// StackMetricConfig defines stack depth and truncation metrics, emitted per profile and per process
+// Truncated stacks are detected as configured in truncated_stacks
+type StackMetricConfig struct {
+	Enabled                bool   `mapstructure:"enabled"`
+	AverageDepthMetricName string `mapstructure:"average_depth_metric_name"`
+	MaxDepthMetricName     string `mapstructure:"max_depth_metric_name"`
+	TruncatedMetricName    string `mapstructure:"truncated_metric_name"`
+}
+
+// SymbolizationMetricConfig defines the symbolization coverage metric
+// Data points report, per process and binary mapping (with its build ID), the percentage of sampled
+// frames resolved to a function name and to a source file name
+type SymbolizationMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// CodeOriginMetricConfig defines the CPU share of first-party code and third-party dependencies,
+// emitted per profile and per process with a code.origin attribute. Samples are classified by their
+// leaf frame (honoring frame_selection); frames matching no prefix are dependencies.
+type CodeOriginMetricConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	MetricName string `mapstructure:"metric_name"`
+	// FunctionPrefixes identify first-party functions by name (e.g. "github.com/mycompany/")
+	FunctionPrefixes []string `mapstructure:"function_prefixes"`
+	// MappingPrefixes identify first-party binaries by path (e.g. "/app/")
+	MappingPrefixes []string `mapstructure:"mapping_prefixes"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// FrameTypeMetricConfig defines the per-process CPU share of each frame type (e.g. kernel, native,
+// jvm, cpython), emitted with the profile.frame.type and frame.category (kernel, user or
+// interpreted) attributes
+type FrameTypeMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// KernelUserMetricConfig defines the CPU time of CPU profiles spent in kernel and user code, emitted
+// per profile and per process like the CPU time metrics. Samples are classified by their leaf frame:
+// profile.frame.type kernel or, without frame type, a vmlinux or [kernel.*] mapping.
+type KernelUserMetricConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	KernelMetricName string `mapstructure:"kernel_metric_name"`
+	UserMetricName   string `mapstructure:"user_metric_name"`
+	MetricOverrides  `mapstructure:",squash"`
+}
+
+// CallGraphMetricConfig defines the CPU time of the caller→callee edges between adjacent frames of
+// the stacks, emitted per process with caller.function and callee.function attributes
+type CallGraphMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// TraceCorrelationMetricConfig defines the CPU time of sampled traces, emitted per process with a
+// trace_id attribute. Trace context is read from the trace_id and span_id sample attributes, or
+// from the link referenced by the sample.
+type TraceCorrelationMetricConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	MetricName string `mapstructure:"metric_name"`
+	// IncludeSpanID breaks the CPU time of a trace down per span with a span_id attribute
+	IncludeSpanID   bool `mapstructure:"include_span_id"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// RuntimeMetricConfig defines the heap and garbage collection metrics derived, per resource, from
+// live heap (e.g. "live_heap", "inuse_space") and GC CPU ("gc_cpu") sample types
+type RuntimeMetricConfig struct {
+	Enabled              bool   `mapstructure:"enabled"`
+	HeapLiveMetricName   string `mapstructure:"heap_live_metric_name"`
+	GCCPUShareMetricName string `mapstructure:"gc_cpu_share_metric_name"`
+}
+
+// IngestionMetricConfig defines the ingestion health metrics, emitted once per conversion as
+// <metric_prefix>.profiles, .samples, .samples.dropped, .samples.missing_values and
+// .dictionary.lookup_failures
+type IngestionMetricConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	MetricPrefix string `mapstructure:"metric_prefix"`
+}
+
+// HotPathMetricConfig defines the per-process hot path metric
+// Data points carry the folded root-to-leaf path owning the most CPU time, capped at MaxPathLength
+// bytes (default 1024) by dropping root-side frames, and with StackHash its stack.hash
+type HotPathMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	MaxPathLength   int    `mapstructure:"max_path_length"`
+	StackHash       bool   `mapstructure:"stack_hash"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// HottestStackMetricConfig defines the per-process hottest stack metric
+// Data points carry the leaf function and file of the stack owning the most CPU time, and with
+// StackHash the stack.hash identifying the whole stack
+type HottestStackMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	StackHash       bool   `mapstructure:"stack_hash"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// ThreadMetricConfig defines per-thread metric configuration
+// Threads are identified by the thread.name sample attribute and restricted by the thread filter
+type ThreadMetricConfig struct {
 	Enabled bool `mapstructure:"enabled"`
+	// MetricNameSuffix is appended to the thread CPU and memory metric names (e.g. ".by_thread")
+	MetricNameSuffix string `mapstructure:"metric_name_suffix"`
+}
+
+// FunctionTopNConfig limits function data points to the N hottest functions per process,
+// ranked separately by CPU time and by memory; the remainder is folded into an "other" bucket
+// A value of 0 disables the limit
+type FunctionTopNConfig struct {
+	CPU    int `mapstructure:"cpu"`
+	Memory int `mapstructure:"memory"`
+}
+
+// RollingTopKConfig limits function metrics to the K hottest (process, function) pairs tracked
+// across batches with exponentially decaying CPU time, so the selection stays stable over minutes
+type RollingTopKConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	K        int           `mapstructure:"k"`
+	Capacity int           `mapstructure:"capacity"`  // tracked candidates (default: 10 × k)
+	HalfLife time.Duration `mapstructure:"half_life"` // decay half-life (default: 5m)
+}
+
+// FunctionSlopeConfig tracks the CPU time of each (process, function) across conversions and emits
+// its least-squares slope over a sliding window, in CPU seconds per profile gained per second, so
+// functions heating up stand out before their raw CPU time does
+type FunctionSlopeConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	MetricName      string        `mapstructure:"metric_name"`
+	Window          time.Duration `mapstructure:"window"` // observations kept per function (default: 5m)
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// FunctionDiffConfig retains the previous profile of each resource and emits the CPU delta and the
+// percentage change of each (process, function) against it, flagged with change.direction
+type FunctionDiffConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	DeltaMetricName  string        `mapstructure:"delta_metric_name"`
+	ChangeMetricName string        `mapstructure:"change_metric_name"`
+	BaselineTTL      time.Duration `mapstructure:"baseline_ttl"` // age after which a baseline is dropped (default: 10m)
+	MetricOverrides  `mapstructure:",squash"`
+}
+
+// FunctionAnomalyConfig tracks an exponentially weighted moving average and variance of the CPU
+// time of each (process, function) and emits its z-score when it deviates beyond Threshold
+type FunctionAnomalyConfig struct {
+	Enabled         bool    `mapstructure:"enabled"`
+	MetricName      string  `mapstructure:"metric_name"`
+	Alpha           float64 `mapstructure:"alpha"`            // weight of the latest observation (default: 0.3)
+	Threshold       float64 `mapstructure:"threshold"`        // z-score emitted from (default: 3)
+	MinObservations int     `mapstructure:"min_observations"` // observations before scoring (default: 5)
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// LockMetricConfig defines lock contention metric configuration
+// It applies to mutex/block profiles whose sample type reports contention delay or contention count
+type LockMetricConfig struct {
+	Enabled                   bool   `mapstructure:"enabled"`
+	ContentionTimeMetricName  string `mapstructure:"contention_time_metric_name"`
+	ContentionCountMetricName string `mapstructure:"contention_count_metric_name"`
+}
+
+// CustomMetricConfig maps the samples of profiles whose sample type name matches the SampleType
+// regex to the gauge MetricName, aggregating their first value with sum (default), avg, min, max,
+// count or p95 per combination of Dimensions: sample attribute keys, or function.name for the leaf
+// function
+type CustomMetricConfig struct {
+	SampleType      string   `mapstructure:"sample_type"`
+	MetricName      string   `mapstructure:"metric_name"`
+	Unit            string   `mapstructure:"unit"`
+	Aggregation     string   `mapstructure:"aggregation"`
+	Dimensions      []string `mapstructure:"dimensions"`
+	MetricOverrides `mapstructure:",squash"`
+}
+
+// ExceptionMetricConfig defines exception count metric configuration
+// Samples carrying the type attribute are counted per exception type and process
+type ExceptionMetricConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	MetricName      string `mapstructure:"metric_name"`
+	TypeAttribute   string `mapstructure:"type_attribute"`
+	MetricOverrides `mapstructure:",squash"`
 }
 
 // AttributeConfig defines attribute extraction configuration
 type AttributeConfig struct {
 	Key   string `mapstructure:"key"`
 	Value string `mapstructure:"value"`
-	Type  string `mapstructure:"type"` // "literal" or "regex"
+	// Type is "literal", "regex", "string_table" (Value is the index) or "sample_attribute" (Value
+	// is the sample attribute key)
+	Type string `mapstructure:"type"`
+	// Pattern transforms sample_attribute values with a regex: the first capture group, or the
+	// whole match without groups, becomes the value; values that do not match are dropped
+	Pattern string `mapstructure:"pattern"`
+}
+
+// OriginConfig defines the profile origin attribute configuration
+// The origin value combines the configured receiver name with the instrumentation scope name of the
+// profiling agent, e.g. "otlp/go.opentelemetry.io/ebpf-profiler"
+type OriginConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	AttributeKey string `mapstructure:"attribute_key"`
+	ReceiverName string `mapstructure:"receiver_name"`
+}
+
+// OwnershipRule assigns functions whose name, source file or code path starts with Prefix
+// (e.g. an import path or package prefix) to Team
+type OwnershipRule struct {
+	Prefix string `mapstructure:"prefix"`
+	Team   string `mapstructure:"team"`
 }
 
 // ProcessFilterConfig defines process filtering configuration
@@ -38,16 +363,188 @@ type ProcessFilterConfig struct {
 	Enabled  bool     `mapstructure:"enabled"`
 	Pattern  string   `mapstructure:"pattern"`  // backward-compat: single pattern
 	Patterns []string `mapstructure:"patterns"` // preferred: list of patterns
+	// ExcludePatterns drop the processes they match, even when an include pattern matches
+	ExcludePatterns []string `mapstructure:"exclude_patterns"`
+	// KeepGlobalMetrics also emits the unfiltered global metrics alongside the filtered per-process ones
+	KeepGlobalMetrics bool `mapstructure:"keep_global_metrics"`
 }
 
 // PatternFilterConfig defines pattern filtering configuration
+// Rules are evaluated against resource, profile and sample attributes before metric generation
 type PatternFilterConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Pattern string `mapstructure:"pattern"`
+	Enabled bool                `mapstructure:"enabled"`
+	Pattern string              `mapstructure:"pattern"` // Deprecated: include rule matching any attribute value
+	Rules   []PatternFilterRule `mapstructure:"rules"`
+}
+
+// PatternFilterRule includes or excludes samples whose attribute matches a regex
+type PatternFilterRule struct {
+	AttributeKey string `mapstructure:"attribute_key"` // empty matches any attribute value
+	Regex        string `mapstructure:"regex"`
+	Action       string `mapstructure:"action"` // include (default) or exclude
 }
 
 // ThreadFilterConfig defines thread filtering configuration
+// When enabled, only samples whose thread.name matches Pattern or any of Patterns are aggregated
 type ThreadFilterConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Pattern string `mapstructure:"pattern"`
+	Enabled  bool     `mapstructure:"enabled"`
+	Pattern  string   `mapstructure:"pattern"`  // Deprecated: use Patterns
+	Patterns []string `mapstructure:"patterns"` // Multiple patterns
+}
+
+// FrameSelectionConfig selects which frame of a sample's stack identifies the "owning" function
+// Mode is one of "leaf" (default), "root", "first_user_frame" (leaf-most frame not matching the
+// built-in Go/Java/Python runtime patterns or Patterns) and "skip_runtime_frames" (leaf-most frame
+// not matching Patterns)
+type FrameSelectionConfig struct {
+	Mode     string   `mapstructure:"mode"`
+	Patterns []string `mapstructure:"patterns"`
+}
+
+// NormalizationConfig rewrites function names so that the same logical function maps to a single
+// series: Java lambda classes and generic arguments, Go pointer receivers and type arguments, and
+// Python source locations are stripped. The rules follow the runtime.name of the resource or
+// profile, else DefaultRuntime ("go", "java" or "python"); other runtimes are left untouched.
+type NormalizationConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	DefaultRuntime string `mapstructure:"default_runtime"`
+}
+
+// SymbolConfig controls the rendering of native symbols. Demangle is "none" (default), "short"
+// (C++ and Rust names without parameters and template arguments, e.g. foo::bar) or "full" (e.g.
+// foo::bar(int)); names that are not mangled are kept.
+type SymbolConfig struct {
+	Demangle string `mapstructure:"demangle"`
+}
+
+// FoldedStackConfig adds the collapsed stack of each sample ("main;foo;bar", root first) to the
+// span events of the trace converter, so that downstream tools can rebuild flamegraphs
+type FoldedStackConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Separator separates frames; default ";"
+	Separator string `mapstructure:"separator"`
+	// MaxLength caps the attribute length, dropping root-side frames first; 0 disables the cap
+	MaxLength int `mapstructure:"max_length"`
+}
+
+// TraceSpanConfig shapes the spans generated by the trace converter. By default a span carries one
+// "sample" event per sample; with ExemplarStacks it carries one "stack" event per distinct full
+// stack, hottest first, with the folded stack and the summed sample values.
+type TraceSpanConfig struct {
+	// Enabled lets the connector export profiles to traces pipelines
+	Enabled bool `mapstructure:"enabled"`
+	// MaxSpansPerProfile caps the spans generated from a profile, hottest stacks first; 0 disables the cap
+	MaxSpansPerProfile int `mapstructure:"max_spans_per_profile"`
+	// SpanNameTemplate names spans from the {function}, {file} and {process} placeholders
+	// (default "{function}")
+	SpanNameTemplate string `mapstructure:"span_name_template"`
+	ExemplarStacks   bool   `mapstructure:"exemplar_stacks"`
+	// DeterministicIDs derives trace and span IDs from the profile content instead of drawing them
+	// at random, so converting the same profile again yields the same IDs
+	DeterministicIDs bool `mapstructure:"deterministic_ids"`
+	// MaxEventsPerSpan caps the events of a span, counting the others as dropped; 0 disables the cap
+	MaxEventsPerSpan int `mapstructure:"max_events_per_span"`
+}
+
+// TraceDedupConfig makes the trace converter extend the trace of a stack seen within the window
+// instead of emitting a new span tree for every profile. The trace ID is derived from the process,
+// profile attributes and stack; each repeat adds one span under the leaf span of the existing trace.
+type TraceDedupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is how long a trace keeps being extended after the stack was last seen
+	Window time.Duration `mapstructure:"window"`
+}
+
+// LogsConfig locates the profile payloads embedded in log records converted by ConvertLogsToMetrics.
+// Payloads are base64-encoded strings or raw bytes.
+type LogsConfig struct {
+	// PayloadField is "body" (default) or "attributes.<key>"
+	PayloadField string `mapstructure:"payload_field"`
+	// PayloadFormat is "auto" (default), "pprof" or "otlp" (an OTLP ExportProfilesServiceRequest)
+	PayloadFormat string `mapstructure:"payload_format"`
+}
+
+// TracesConfig locates the profile payloads attached to spans converted by ConvertTracesToMetrics:
+// a span attribute or an attribute of the span's profile events. Payloads are base64-encoded strings
+// or raw bytes.
+type TracesConfig struct {
+	// EventName is the name of span events carrying a payload (default "profile")
+	EventName string `mapstructure:"event_name"`
+	// PayloadAttribute is the span or event attribute holding the payload (default "profile.data")
+	PayloadAttribute string `mapstructure:"payload_attribute"`
+	// PayloadFormat is "auto" (default), "pprof" or "otlp" (an OTLP ExportProfilesServiceRequest)
+	PayloadFormat string `mapstructure:"payload_format"`
+}
+
+// TruncatedStackConfig detects samples whose stack was truncated by the profiler, via a truncation
+// flag attribute, a truncation marker frame or a stack depth reaching MaxDepth (0 disables the
+// depth heuristic). Their leaf attribution is unreliable, so they are reported separately.
+type TruncatedStackConfig struct {
+	Enabled  bool `mapstructure:"enabled"`
+	MaxDepth int  `mapstructure:"max_depth"`
+}
+
+// DegradationConfig sheds load step by step when conversions overload: function metrics are
+// dropped first, then thread metrics, then samples are downsampled, and finally whole profiles are
+// dropped. A step is taken after every conversion slower than LatencyThreshold or ending with a
+// heap above MemoryLimitMiB, and undone after RecoveryConversions healthy conversions.
+type DegradationConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	LatencyThreshold    time.Duration `mapstructure:"latency_threshold"`    // 0 ignores latency
+	MemoryLimitMiB      int           `mapstructure:"memory_limit_mib"`     // 0 ignores memory
+	RecoveryConversions int           `mapstructure:"recovery_conversions"` // default: 3
+	DownsampleRatio     int           `mapstructure:"downsample_ratio"`     // keep one sample in N (default: 10)
+	MetricPrefix        string        `mapstructure:"metric_prefix"`        // default: profiletometrics.degradation
+}
+
+// ProfileSamplingConfig converts only part of the incoming profiles to protect metric backends
+// during profile storms. Whole profiles are kept or skipped, so the metrics of a converted profile
+// are complete; the profiles skipped are counted by the ingestion metrics.
+type ProfileSamplingConfig struct {
+	Ratio                float64 `mapstructure:"ratio"`                   // share of profiles converted; 0 or 1 converts all
+	MaxProfilesPerSecond float64 `mapstructure:"max_profiles_per_second"` // 0 disables the rate limit
+}
+
+// StalenessConfig ends series that stop appearing in profiles, such as exited processes, instead of
+// leaving dashboards with their last value. A series unseen for TTL gets a final data point flagged
+// NoRecordedValue, which Prometheus exporters write as a staleness marker.
+type StalenessConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"` // default: 5m
+}
+
+// AttributeMappingRule renames the attribute From to To on data points and spans (e.g.
+// process.executable.name to service.instance.id)
+type AttributeMappingRule struct {
+	From string `mapstructure:"from"`
+	To   string `mapstructure:"to"`
+}
+
+// AttributeSelectionConfig selects the resource attributes copied onto data points and spans with
+// globs on attribute keys (e.g. "k8s.*"); an empty include list keeps every attribute
+type AttributeSelectionConfig struct {
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// FunctionFilterConfig restricts function metrics with include/exclude regexes on function names
+// Samples whose function (the leaf frame, or the frame chosen by frame_selection) matches an
+// exclude pattern are dropped entirely
+type FunctionFilterConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// EstimationConfig defines the values attributed to samples without values (e.g. stack-only eBPF
+// profiles); without it such samples count as 0
+type EstimationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SampleDuration is the CPU time of a sample; 0 shares one second among the profile's samples
+	SampleDuration time.Duration `mapstructure:"sample_duration"`
+	// AllocationBytes is the memory allocation of a sample (default: 2048)
+	AllocationBytes int64 `mapstructure:"allocation_bytes"`
+	// SeparateMetrics reports estimates in <metric>.estimated CPU and memory totals only, keeping
+	// every other series, including the avg, min, max, count and p95 aggregations, measured
+	SeparateMetrics bool `mapstructure:"separate_metrics"`
 }
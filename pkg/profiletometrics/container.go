@@ -0,0 +1,77 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Metric grouping levels; an empty value adds none
+	metricsGroupByContainer = "container"
+
+	containerIDAttributeKey   = "container.id"
+	containerNameAttributeKey = "k8s.container.name"
+)
+
+// validateMetricsGroupBy checks the configured grouping level of the CPU and memory metrics
+func validateMetricsGroupBy(groupBy string) error {
+	switch groupBy {
+	case "", metricsGroupByContainer:
+		return nil
+	default:
+		return fmt.Errorf("invalid metrics.group_by %q: must be %q", groupBy, metricsGroupByContainer)
+	}
+}
+
+// containerMetricNames returns the CPU and memory metric names of container metrics
+func (c *Converter) containerMetricNames() (string, string) {
+	metrics := c.config.Metrics
+	return metrics.CPU.MetricName + metrics.Container.MetricNameSuffix, metrics.Memory.MetricName + metrics.Container.MetricNameSuffix
+}
+
+// generateContainerMetrics generates CPU time and memory metrics per container.id sample
+// attribute; the k8s.container.name of the container's samples is attached when present
+func (c *Converter) generateContainerMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	cpuMetricName, memoryMetricName := c.containerMetricNames()
+	totals := c.aggregateSamplesBy(profiles, profile, containerIDAttributeKey)
+	if len(totals) == 0 {
+		return
+	}
+
+	containerNames := make(map[string]string)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		containerID := c.getSampleAttributeValue(profiles, sample, containerIDAttributeKey)
+		if containerID == "" || containerNames[containerID] != "" {
+			continue
+		}
+		containerNames[containerID] = c.getSampleAttributeValue(profiles, sample, containerNameAttributeKey)
+	}
+
+	containerIDs := make([]string, 0, len(totals))
+	for containerID := range totals {
+		containerIDs = append(containerIDs, containerID)
+	}
+	sort.Strings(containerIDs)
+
+	for _, containerID := range containerIDs {
+		attrs := attributes
+		if name := containerNames[containerID]; name != "" {
+			attrs = make(map[string]string, len(attributes)+1)
+			for k, v := range attributes {
+				attrs[k] = v
+			}
+			attrs[containerNameAttributeKey] = name
+		}
+		c.generateEntityMetrics(profile, attrs, scopeMetrics, containerIDAttributeKey, containerID, totals[containerID],
+			cpuMetricName, memoryMetricName)
+	}
+}
@@ -0,0 +1,85 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_ContainerMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:    MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			GroupBy:   "container",
+			Container: ContainerMetricConfig{MetricNameSuffix: ".by_container"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "app", "container.id": "c2"}, 1000000000, 1024)
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "sidecar", "container.id": "c2"}, 500000000, 512)
+	b.sample(b.stack("main"), map[string]string{
+		"process.executable.name": "db", "container.id": "c1", "k8s.container.name": "postgres",
+	}, 2000000000, 2048)
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "host"}, 4000000000, 4096)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	found := false
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		if metric.Name() != "cpu_time.by_container" {
+			continue
+		}
+		found = true
+		dataPoints := metric.Gauge().DataPoints()
+		require.Equal(t, 2, dataPoints.Len())
+
+		containerID, _ := dataPoints.At(0).Attributes().Get("container.id")
+		assert.Equal(t, "c1", containerID.Str())
+		containerName, exists := dataPoints.At(0).Attributes().Get("k8s.container.name")
+		require.True(t, exists)
+		assert.Equal(t, "postgres", containerName.Str())
+		assert.InDelta(t, 2.0, dataPoints.At(0).DoubleValue(), 1e-9)
+
+		containerID, _ = dataPoints.At(1).Attributes().Get("container.id")
+		assert.Equal(t, "c2", containerID.Str())
+		_, exists = dataPoints.At(1).Attributes().Get("k8s.container.name")
+		assert.False(t, exists)
+		assert.InDelta(t, 1.5, dataPoints.At(1).DoubleValue(), 1e-9)
+	}
+	assert.True(t, found)
+}
+
+func TestConverter_ContainerMetricsDisabled(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Container: ContainerMetricConfig{MetricNameSuffix: ".by_container"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main"), map[string]string{"container.id": "c1"}, 1000000000, 1024)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		assert.NotEqual(t, "cpu_time.by_container", metricSlice.At(i).Name())
+	}
+}
+
+func TestValidateMetricsGroupBy(t *testing.T) {
+	assert.NoError(t, validateMetricsGroupBy(""))
+	assert.NoError(t, validateMetricsGroupBy("container"))
+	assert.EqualError(t, validateMetricsGroupBy("pod"), `invalid metrics.group_by "pod": must be "container"`)
+}
@@ -2,7 +2,9 @@ package profiletometrics
 
 import (
 	"context"
-	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"regexp"
@@ -17,32 +19,218 @@ const (
 	nanosecondsPerSecond = 1e9
 
 	// Attribute extraction types
-	attrTypeLiteral     = "literal"
-	attrTypeRegex       = "regex"
-	attrTypeStringTable = "string_table"
+	attrTypeLiteral         = "literal"
+	attrTypeRegex           = "regex"
+	attrTypeStringTable     = "string_table"
+	attrTypeSampleAttribute = "sample_attribute"
+
+	defaultOriginAttributeKey = "origin"
+
+	// profileCommentAttributeKey carries operator-supplied profile comments (e.g. "load test run 42")
+	profileCommentAttributeKey = "profile.comment"
+
+	// otherFunctionName is the function.name of the bucket aggregating functions outside the top N
+	otherFunctionName = "other"
 )
 
 // ConverterConfig defines the configuration for the converter
 type ConverterConfig struct {
-	Metrics       MetricsConfig       `mapstructure:"metrics"`
-	Attributes    []AttributeConfig   `mapstructure:"attributes"`
-	ProcessFilter ProcessFilterConfig `mapstructure:"process_filter"`
-	PatternFilter PatternFilterConfig `mapstructure:"pattern_filter"`
-	ThreadFilter  ThreadFilterConfig  `mapstructure:"thread_filter"`
+	// Auto derives metric families, breakdowns and names from the first batch of profiles
+	Auto       bool              `mapstructure:"auto"`
+	Metrics    MetricsConfig     `mapstructure:"metrics"`
+	Attributes []AttributeConfig `mapstructure:"attributes"`
+	// AttributeSelection restricts the resource attributes copied onto data points and spans
+	AttributeSelection AttributeSelectionConfig `mapstructure:"attribute_selection"`
+	// AttributeMapping renames data point attributes to match an organization's naming scheme
+	AttributeMapping []AttributeMappingRule `mapstructure:"attribute_mapping"`
+	ProcessFilter    ProcessFilterConfig    `mapstructure:"process_filter"`
+	PatternFilter    PatternFilterConfig    `mapstructure:"pattern_filter"`
+	ThreadFilter     ThreadFilterConfig     `mapstructure:"thread_filter"`
+	// FunctionFilter restricts function metrics by function name
+	FunctionFilter FunctionFilterConfig `mapstructure:"function_filter"`
+	Origin         OriginConfig         `mapstructure:"origin"`
+	// Ownership tags function data points with the owner.team of the longest matching prefix
+	Ownership []OwnershipRule `mapstructure:"ownership"`
+	// FrameSelection selects the frame used to resolve a sample's function
+	FrameSelection FrameSelectionConfig `mapstructure:"frame_selection"`
+	// Normalization rewrites function names with per-language rules selected from runtime.name
+	Normalization NormalizationConfig `mapstructure:"normalization"`
+	// Symbol controls how native symbols are rendered as function names
+	Symbol SymbolConfig `mapstructure:"symbol"`
+	// FoldedStack adds the collapsed stack of each sample to span events
+	FoldedStack FoldedStackConfig `mapstructure:"folded_stack"`
+	// SpanOutput shapes the spans and span events of the trace converter; it is unrelated to
+	// Traces, which reads profiles attached to incoming spans
+	SpanOutput TraceSpanConfig `mapstructure:"span_output"`
+	// TraceDedup extends the traces of stacks repeated across profiles instead of emitting new ones
+	TraceDedup TraceDedupConfig `mapstructure:"trace_dedup"`
+	// TruncatedStacks tags function data points from truncated stacks with stack.truncated
+	TruncatedStacks TruncatedStackConfig `mapstructure:"truncated_stacks"`
+	// ProfileComments adds the profile comments as the profile.comment attribute
+	ProfileComments bool `mapstructure:"profile_comments"`
+	// SemconvAttributes emits code.function.name, code.file.path and code.line.number instead of
+	// function.name, file.name, code.filepath and code.lineno
+	SemconvAttributes bool `mapstructure:"semconv_attributes"`
+	// ArrayAttributes selects how array-valued sample attributes are read: "join" (comma-separated,
+	// default), "first" (first element) or "explode" (a sample counts for each element in process and
+	// thread breakdowns and filters)
+	ArrayAttributes string `mapstructure:"array_attributes"`
+	// UseProfileTimestamps stamps data points with the profile time window instead of the conversion time
+	UseProfileTimestamps bool `mapstructure:"use_profile_timestamps"`
+	// TimestampSource selects the data point timestamp: "receive_time" (conversion time, default),
+	// "profile_end", "profile_start" or "sample" (latest sample timestamp)
+	TimestampSource string `mapstructure:"timestamp_source"`
+	// AggregationTemporality emits monotonic sums ("delta" or "cumulative") instead of gauges,
+	// tracking last-seen totals per series across conversions
+	AggregationTemporality string `mapstructure:"aggregation_temporality"`
+	// AggregationMaxSeries caps the series tracked for aggregation_temporality, evicting the least
+	// recently updated ones; 0 tracks every series
+	AggregationMaxSeries int `mapstructure:"aggregation_max_series"`
+	// AggregationWindow stamps data points with the UTC start of their window (e.g. 1h or 24h)
+	AggregationWindow time.Duration `mapstructure:"aggregation_window"`
+	// Estimation attributes CPU time and memory to samples without values; disabled by default
+	Estimation EstimationConfig `mapstructure:"estimation"`
+	// Provenance adds converter.version, attribution.mode and value.source (measured or estimated)
+	// to the data points generated from profiles
+	Provenance bool `mapstructure:"provenance"`
+	// GroupByResourceAttributes rolls CPU and memory data points up per value of these resource
+	// attributes (e.g. k8s.pod.name, container.id), summing the processes and resources of a group
+	GroupByResourceAttributes []string `mapstructure:"group_by_resource_attributes"`
+	// OutputLayout arranges the emitted metrics: "merged" (default) emits a single ResourceMetrics
+	// and scope with one definition per metric, and resource attributes on the data points;
+	// "per_resource" mirrors the input resources one-to-one with their attributes on the Resource
+	OutputLayout string `mapstructure:"output_layout"`
+	// ResourceAttributePlacement places resource attributes on the output "resource", each
+	// "datapoint" or "both"; resource and both require the per_resource layout. Empty follows
+	// output_layout: datapoint when merged, resource when per_resource
+	ResourceAttributePlacement string `mapstructure:"resource_attribute_placement"`
+	// StreamFlushDataPoints makes ConvertStream flush once this many data points are pending
+	// instead of after every resource profile; 0 flushes every resource profile
+	StreamFlushDataPoints int `mapstructure:"stream_flush_data_points"`
+	// Concurrency converts the resource profiles of a batch across up to this many goroutines;
+	// 0 or 1 converts them sequentially
+	Concurrency int `mapstructure:"concurrency"`
+	// MaxAttributeValueLength caps string attribute values (function names, file paths, folded
+	// stacks) in bytes, ending truncated values with "…"; 0 disables the cap
+	MaxAttributeValueLength int `mapstructure:"max_attribute_value_length"`
+	// Logs locates the profile payloads embedded in log records for ConvertLogsToMetrics
+	Logs LogsConfig `mapstructure:"logs"`
+	// Traces locates the profile payloads attached to spans for ConvertTracesToMetrics
+	Traces TracesConfig `mapstructure:"traces"`
+	// Degradation sheds load step by step when conversions are slow or memory runs high
+	Degradation DegradationConfig `mapstructure:"degradation"`
+	// Sampling converts only a share of the incoming profiles, at most at a given rate
+	Sampling ProfileSamplingConfig `mapstructure:"sampling"`
+	// Staleness marks series that stopped appearing in profiles as stale
+	Staleness StalenessConfig `mapstructure:"staleness"`
+	// DiagnosticsHistory keeps the diagnostics of the last N conversions (see DiagnosticsHandler); 0 disables them
+	DiagnosticsHistory int `mapstructure:"diagnostics_history"`
 }
 
 // Converter converts profiling data to metrics
 type Converter struct {
-	config *ConverterConfig
-	logger *zap.Logger
+	config      *ConverterConfig
+	logger      *zap.Logger
+	accumulator *temporalityAccumulator
+	// functionTopK tracks the hottest functions across conversions when rolling_top_k is enabled
+	functionTopK *decayingTopK
+	// functionSlopes tracks the CPU time of functions across conversions when slope is enabled
+	functionSlopes *slopeTracker
+	// functionDiffs retains the previous profile of each resource when diff is enabled
+	functionDiffs *diffTracker
+	// functionAnomalies scores the CPU time of functions against their history when anomaly is enabled
+	functionAnomalies *anomalyDetector
+	// skipFramePatterns are the compiled frame_selection patterns of frames to skip
+	skipFramePatterns []*regexp.Regexp
+	// filters holds the process, pattern, thread and function filters shared with the trace converter
+	filters *filterEngine
+	// ownershipRules are the ownership rules ordered longest prefix first
+	ownershipRules []OwnershipRule
+	// attributeMapping maps emitted attribute keys to their new names, nil without attribute_mapping
+	attributeMapping map[string]string
+	// customMetrics holds the compiled metrics.custom definitions
+	customMetrics []customMetric
+	// autoOnce derives the configuration from the first batch in auto mode
+	autoOnce sync.Once
+	// additiveGauges holds the names of the gauges whose values add up across conversions
+	additiveGauges sync.Map
+	// diagnostics holds the last conversions when diagnostics_history is set
+	diagnostics *diagnosticsHistory
+	// degradation tracks the degradation step, nil when degradation is disabled
+	degradation *degradationLadder
+	// sampler selects the profiles converted, nil when sampling keeps every profile
+	sampler *profileSampler
+	// staleness tracks the emitted series when staleness is enabled
+	staleness *stalenessTracker
 }
 
 // NewConverter creates a new profile to metrics converter
 func NewConverter(cfg *ConverterConfig) (*Converter, error) {
-	return &Converter{
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Auto {
+		// Auto mode fills in the configuration, which must not change the caller's one
+		owned := *cfg
+		cfg = &owned
+	}
+
+	converter := &Converter{
 		config: cfg,
 		logger: nil, // Will be set by the connector
-	}, nil
+	}
+	skipFramePatterns, err := compileFrameSelection(cfg.FrameSelection)
+	if err != nil {
+		return nil, err
+	}
+	converter.skipFramePatterns = skipFramePatterns
+	filters, err := newFilterEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	converter.filters = filters
+	ownershipRules, err := compileOwnershipRules(cfg.Ownership)
+	if err != nil {
+		return nil, err
+	}
+	converter.ownershipRules = ownershipRules
+	attributeMapping, err := compileAttributeMapping(cfg.AttributeMapping)
+	if err != nil {
+		return nil, err
+	}
+	converter.attributeMapping = attributeMapping
+	customMetrics, err := compileCustomMetrics(cfg.Metrics.Custom)
+	if err != nil {
+		return nil, err
+	}
+	converter.customMetrics = customMetrics
+	if cfg.AggregationTemporality != "" {
+		converter.accumulator = newTemporalityAccumulator(cfg.AggregationMaxSeries)
+	}
+	if cfg.DiagnosticsHistory > 0 {
+		converter.diagnostics = &diagnosticsHistory{size: cfg.DiagnosticsHistory}
+	}
+	if cfg.Metrics.Function.RollingTopK.Enabled {
+		converter.functionTopK = newDecayingTopK(cfg.Metrics.Function.RollingTopK)
+	}
+	if slope := cfg.Metrics.Function.Slope; slope.Enabled {
+		converter.functionSlopes = newSlopeTracker(slope.Window)
+	}
+	if diff := cfg.Metrics.Function.Diff; diff.Enabled {
+		converter.functionDiffs = newDiffTracker(diff.BaselineTTL)
+	}
+	if anomaly := cfg.Metrics.Function.Anomaly; anomaly.Enabled {
+		converter.functionAnomalies = newAnomalyDetector(anomaly)
+	}
+	if cfg.Degradation.Enabled {
+		converter.degradation = newDegradationLadder(cfg.Degradation)
+	}
+	converter.sampler = newProfileSampler(cfg.Sampling)
+	if cfg.Staleness.Enabled {
+		converter.staleness = newStalenessTracker(cfg.Staleness)
+	}
+	return converter, nil
 }
 
 // SetLogger sets the logger for the converter
@@ -72,159 +260,244 @@ func (c *Converter) logWarn(msg string, fields ...zap.Field) {
 }
 
 // matchesSampleFilter checks if a sample matches the given filter criteria
-func (c *Converter) matchesSampleFilter(profiles pprofile.Profiles, sample pprofile.Sample, filter map[string]string) bool {
+func (c *Converter) matchesSampleFilter(profiles dictionaryProvider, sample pprofile.Sample, filter map[string]string) bool {
 	if len(filter) == 0 {
 		return true // No filter means match all
 	}
 
 	// Check if the sample matches all filter criteria
 	for key, expectedValue := range filter {
-		actualValue := c.getSampleAttributeValue(profiles, sample, key)
-		if actualValue != expectedValue {
-			c.logDebug("Sample does not match filter",
-				zap.String("key", key),
-				zap.String("expected_value", expectedValue),
-				zap.String("actual_value", actualValue))
+		if !sampleHasAttributeValueCommon(c.config, profiles, sample, key, expectedValue) {
 			return false
 		}
 	}
-
-	c.logDebug("Sample matches filter", zap.Any("filter", filter))
 	return true
 }
 
 // getSampleAttributeValue extracts a specific attribute value from a sample
 // In the pprofile schema, samples have AttributeIndices that point to AttributeTable entries
 // Each AttributeTable entry has KeyStrindex, Value, and UnitStrindex
-func (c *Converter) getSampleAttributeValue(profiles pprofile.Profiles, sample pprofile.Sample, key string) string {
-	return getSampleAttributeValueCommon(profiles, sample, key)
+// Array values follow the array_attributes policy; with explode, the first element is returned
+func (c *Converter) getSampleAttributeValue(profiles dictionaryProvider, sample pprofile.Sample, key string) string {
+	var buffer [4]string
+	if values := appendSampleAttributeValuesCommon(buffer[:0], c.config, profiles, sample, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
 }
 
 // ConvertProfilesToMetrics converts profiling data to metrics
 func (c *Converter) ConvertProfilesToMetrics(ctx context.Context, profiles pprofile.Profiles) (pmetric.Metrics, error) {
+	start := time.Now()
 	c.logInfo("Starting profile to metrics conversion",
 		zap.Int("resource_profiles_count", profiles.ResourceProfiles().Len()))
 
+	conversion := c.beginConversion(profiles)
 	metrics := pmetric.NewMetrics()
 	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
 
-	iterateProfilesCommon(
-		profiles,
-		c.extractResourceAttributes,
-		func(resourceIndex, scopeIndex, profileIndex int, profile pprofile.Profile, resourceAttributes map[string]string) {
-			c.logDebug("Processing profile",
-				zap.Int("resource_index", resourceIndex),
-				zap.Int("scope_index", scopeIndex),
-				zap.Int("profile_index", profileIndex),
-				zap.Int("samples_count", profile.Sample().Len()))
+	if c.config.Concurrency > 1 {
+		c.convertResourcesConcurrently(conversion, profiles, metrics, resourceMetrics)
+	} else {
+		layout := newResourceLayout(c.config, metrics)
+		for i := 0; i < profiles.ResourceProfiles().Len(); i++ {
+			c.convertResource(conversion, profiles, i, resourceMetrics, layout)
+		}
+	}
+	c.generateConversionMetrics(start, profiles, conversion.summary, resourceMetrics)
+
+	if len(c.config.GroupByResourceAttributes) > 0 {
+		c.rollUpResourceGroups(profiles, metrics)
+	}
+	c.finishMetrics(metrics)
 
-			profileAttributes := c.extractProfileAttributes(profiles, profile, resourceAttributes)
-			c.logDebug("Extracted profile attributes", zap.Any("attributes", profileAttributes))
+	compaction := compactMetrics(metrics)
+	c.logDebug("Compacted metrics output", compaction.fields()...)
 
-			c.generateMetricsFromProfile(profiles, profile, profileAttributes, resourceMetrics)
-		},
-	)
+	if c.diagnostics != nil {
+		output := &conversionOutput{}
+		c.tallyOutput(output, metrics)
+		c.recordDiagnostics(start, profiles, output, conversion.summary)
+	}
 
+	c.logDebug("Profile conversion summary", conversion.summary.fields()...)
 	c.logInfo("Profile to metrics conversion completed")
 	return metrics, nil
 }
 
-// extractResourceAttributes extracts attributes from the resource
-func (c *Converter) extractResourceAttributes(resource pcommon.Resource) map[string]string {
-	attributes := make(map[string]string)
+// conversion is the state shared by the profiles of one conversion
+type conversion struct {
+	summary *conversionSummary
+	// degradationStep is the degradation step in effect for the whole conversion
+	degradationStep int
+	// caches memoize the dictionary lookups of the conversion
+	caches dictionaryCaches
+}
 
-	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
-		attributes[key] = value.AsString()
-		return true
-	})
+// beginConversion applies the auto configuration and starts the conversion summary
+func (c *Converter) beginConversion(profiles pprofile.Profiles) *conversion {
+	if c.config.Auto {
+		c.applyAutoConfig(profiles)
+	}
+	return &conversion{summary: &conversionSummary{}, degradationStep: c.degradationStep(), caches: make(dictionaryCaches)}
+}
 
-	return attributes
+// convertResource converts the profiles of one resource profile
+func (c *Converter) convertResource(
+	conversion *conversion,
+	profiles pprofile.Profiles,
+	resourceIndex int,
+	resourceMetrics pmetric.ResourceMetrics,
+	layout *resourceLayout,
+) {
+	resourceProfile := profiles.ResourceProfiles().At(resourceIndex)
+	dictionary := resourceDictionaryCommon(profiles, resourceIndex)
+	resourceAttributes := c.extractResourceAttributes(resourceProfile.Resource())
+	for j := 0; j < resourceProfile.ScopeProfiles().Len(); j++ {
+		scopeProfile := resourceProfile.ScopeProfiles().At(j)
+		for k := 0; k < scopeProfile.Profiles().Len(); k++ {
+			c.convertProfile(conversion, profiles, dictionary, resourceIndex, j, k, scopeProfile.Profiles().At(k),
+				resourceAttributes, resourceMetrics, layout)
+		}
+	}
 }
 
-// extractProfileAttributes extracts attributes from the profile data
-func (c *Converter) extractProfileAttributes(
+// convertProfile filters a profile and generates its metrics into resourceMetrics, or into the
+// ResourceMetrics of its resource with the per_resource layout
+func (c *Converter) convertProfile(
+	conversion *conversion,
 	profiles pprofile.Profiles,
+	dictionary dictionaryProvider,
+	resourceIndex, scopeIndex, profileIndex int,
 	profile pprofile.Profile,
 	resourceAttributes map[string]string,
-) map[string]string {
-	attributes := make(map[string]string)
-
-	// Copy resource attributes
-	for k, v := range resourceAttributes {
-		attributes[k] = v
+	resourceMetrics pmetric.ResourceMetrics,
+	layout *resourceLayout,
+) {
+	summary := conversion.summary
+	summary.profiles.Add(1)
+	cache := conversion.caches.get(dictionary, functionNameRulesFor(c.config, dictionary, profile, resourceAttributes))
+	cache.summary = summary
+	dictionary = cache
+	if conversion.degradationStep >= degradationDropProfiles {
+		summary.droppedSamples.Add(int64(profile.Sample().Len()))
+		return
+	}
+	if c.sampler != nil && !c.sampler.keep() {
+		summary.sampledOutProfiles.Add(1)
+		return
 	}
+	if c.config.Metrics.Ingestion.Enabled {
+		c.inspectSampleHealth(dictionary, profile, summary)
+	}
+	c.logDebug("Processing profile",
+		zap.Int("resource_index", resourceIndex),
+		zap.Int("scope_index", scopeIndex),
+		zap.Int("profile_index", profileIndex),
+		zap.Int("samples_count", profile.Sample().Len()))
 
-	// Extract attributes based on configuration rules
-	for _, attr := range c.config.Attributes {
-		value := c.extractAttributeValue(profiles, profile, attr)
-		if value != "" {
-			attributes[attr.Key] = value
+	scope := scopeOfProfile(profiles, resourceIndex, scopeIndex)
+	profileAttributes := c.extractProfileAttributes(dictionary, scope, profile, resourceAttributes)
+	if c.debugEnabled() {
+		if comments := getProfileCommentsCommon(dictionary, profile); len(comments) > 0 {
+			c.logDebug("Profile comments", zap.Strings("comments", comments))
 		}
 	}
+	c.logDebug("Extracted profile attributes", zap.Any("attributes", profileAttributes))
 
-	return attributes
+	profile = c.applySampleFilters(dictionary, profile, profileAttributes)
+	if conversion.degradationStep >= degradationDownsampleSamples {
+		profile = c.downsampleProfile(profile, summary)
+	}
+	if layout != nil {
+		target := layout.resourceMetrics(resourceIndex, profiles.ResourceProfiles().At(resourceIndex).Resource())
+		c.generateMetricsFromProfile(dictionary, profile, layout.dataPointAttributes(profileAttributes, resourceAttributes), target)
+		return
+	}
+	c.generateMetricsFromProfile(dictionary, profile, profileAttributes, resourceMetrics)
 }
 
-// extractAttributeValue extracts a single attribute value based on the rule
-func (c *Converter) extractAttributeValue(profiles pprofile.Profiles, _ pprofile.Profile, attr AttributeConfig) string {
-	switch attr.Type {
-	case attrTypeLiteral:
-		return attr.Value
-	case attrTypeRegex:
-		// Extract from string table using regex pattern
-		return c.extractFromStringTable(profiles, attr.Value)
-	case attrTypeStringTable:
-		// Direct string table index access
-		return c.extractFromStringTableByIndex(profiles, attr.Value)
-	default:
-		return attr.Value
+// generateConversionMetrics emits the converter self-metrics once the profiles of a conversion are
+// converted: runtime, ingestion health and degradation
+func (c *Converter) generateConversionMetrics(
+	start time.Time,
+	profiles pprofile.Profiles,
+	summary *conversionSummary,
+	resourceMetrics pmetric.ResourceMetrics,
+) {
+	if c.config.Metrics.Runtime.Enabled {
+		c.generateRuntimeMetrics(profiles, resourceMetrics)
+	}
+	if c.config.Metrics.Ingestion.Enabled {
+		c.generateIngestionMetrics(summary, resourceMetrics)
+	}
+	if c.degradation != nil {
+		c.recordDegradation(start, resourceMetrics)
+	}
+}
+
+// finishMetrics applies the output passes to generated metrics: attribute mapping, metric
+// overrides and units, value truncation, aggregation windows and temporality
+func (c *Converter) finishMetrics(metrics pmetric.Metrics) {
+	// Attributes are renamed once the converter no longer looks them up by name
+	if c.attributeMapping != nil {
+		renameMetricAttributes(metrics, c.attributeMapping)
+	}
+	if overrides := c.metricOverrides(); overrides != nil {
+		applyMetricOverrides(metrics, overrides)
+	}
+	if units := c.metricUnits(); units != nil {
+		applyMetricUnits(metrics, units)
+	}
+	// Values are truncated before accumulation so that series keys match across conversions
+	if c.config.MaxAttributeValueLength > 0 {
+		truncateMetricAttributes(metrics, c.config.MaxAttributeValueLength)
 	}
+	// Windows are stamped first so that accumulated series restart with every window
+	if c.config.AggregationWindow > 0 {
+		c.stampAggregationWindows(metrics)
+	}
+	if c.accumulator != nil {
+		c.accumulator.apply(metrics, c.config.AggregationTemporality, c.additiveMetric)
+	}
+	// Series are tracked as emitted, so markers carry the final names, attributes and types
+	if c.staleness != nil {
+		c.staleness.track(metrics)
+	}
+}
+
+// extractResourceAttributes extracts attributes from the resource
+func (c *Converter) extractResourceAttributes(resource pcommon.Resource) map[string]string {
+	return extractResourceAttributesCommon(resource)
+}
+
+// extractProfileAttributes resolves the attributes shared by every data point of a profile
+func (c *Converter) extractProfileAttributes(
+	dictionary dictionaryProvider,
+	scope pcommon.InstrumentationScope,
+	profile pprofile.Profile,
+	resourceAttributes map[string]string,
+) map[string]string {
+	return extractProfileAttributesCommon(c.config, dictionary, scope, profile, resourceAttributes)
 }
 
 // generateMetricsFromProfile generates metrics from profile data
 func (c *Converter) generateMetricsFromProfile(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	resourceMetrics pmetric.ResourceMetrics,
 ) {
-	// pattern_filter deprecated: no-op
-
-	// Apply process filtering against profile samples (process.executable.name), supporting multiple patterns
-	// Also, when enabled, restrict metrics generation to matched processes only.
+	// Apply process filtering against profile samples (process.executable.name); when enabled,
+	// restrict metrics generation to the matched processes only, keeping the global metrics when
+	// keep_global_metrics is set
 	var matchedProcessNames []string
-	if c.config.ProcessFilter.Enabled {
-		if !c.profileMatchesProcessFilter(profiles, profile) {
-			return
-		}
-		// Build regexes and filter the discovered processes
-		allProcessNames := c.getUniqueProcessNames(profiles, profile)
-		var patterns []string
-		if len(c.config.ProcessFilter.Patterns) > 0 {
-			patterns = c.config.ProcessFilter.Patterns
-		} else if c.config.ProcessFilter.Pattern != "" {
-			patterns = []string{c.config.ProcessFilter.Pattern}
-		}
-		regexes := make([]*regexp.Regexp, 0, len(patterns))
-		for _, p := range patterns {
-			re, err := regexp.Compile(p)
-			if err != nil {
-				c.logWarn("Invalid process filter pattern - ignoring", zap.String("pattern", p), zap.Error(err))
-				continue
-			}
-			regexes = append(regexes, re)
-		}
-		for _, name := range allProcessNames {
-			for _, re := range regexes {
-				if re.MatchString(name) {
-					matchedProcessNames = append(matchedProcessNames, name)
-					break
-				}
-			}
-		}
+	if c.filters.filtersProcesses() {
+		matchedProcessNames = c.filters.allowedProcessNames(c.getUniqueProcessNames(profiles, profile))
 		c.logDebug("Process filter matched processes", zap.Strings("process_names", matchedProcessNames))
-		if len(matchedProcessNames) == 0 {
+		if len(matchedProcessNames) == 0 && !c.config.ProcessFilter.KeepGlobalMetrics {
 			// No processes matched; nothing to emit
+			summaryOf(profiles).droppedSamples.Add(int64(profile.Sample().Len()))
 			return
 		}
 	}
@@ -232,10 +505,10 @@ func (c *Converter) generateMetricsFromProfile(
 	// Create a single scope metrics for all metrics from this profile
 	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
 	scopeMetrics.Scope().SetName("profiletometrics")
-	scopeMetrics.Scope().SetVersion("1.0.0")
+	scopeMetrics.Scope().SetVersion(converterVersion)
 
-	// If process filter is enabled, skip unfiltered/global metrics; emit only per-process metrics
-	if !c.config.ProcessFilter.Enabled {
+	// If process filter is enabled, skip unfiltered/global metrics unless keep_global_metrics is set
+	if c.emitsGlobalMetrics() {
 		// Generate CPU time metrics if enabled
 		if c.config.Metrics.CPU.Enabled {
 			c.generateCPUTimeMetrics(profiles, profile, attributes, scopeMetrics)
@@ -248,102 +521,124 @@ func (c *Converter) generateMetricsFromProfile(
 		c.logDebug("Process filter enabled - skipping global metrics in favor of per-process metrics")
 	}
 
-	// Generate metrics for specific processes
-	processNames := matchedProcessNames
-	if !c.config.ProcessFilter.Enabled {
-		processNames = c.getUniqueProcessNames(profiles, profile)
+	// Generate metrics for specific processes (if enabled)
+	if c.config.Metrics.Process.Enabled {
+		processNames := matchedProcessNames
+		if !c.config.ProcessFilter.Enabled {
+			processNames = c.getUniqueProcessNames(profiles, profile)
+		}
+		c.generateProcessMetrics(profiles, profile, attributes, scopeMetrics, processNames)
 	}
-	for _, processName := range processNames {
-		c.logDebug("Generating metrics for process", zap.String("process_name", processName))
-		c.generateProcessMetrics(profiles, profile, attributes, scopeMetrics, processName)
+
+	// Generate metrics per container (if grouped by container); like the profile totals, they
+	// include every process of a container
+	if c.config.Metrics.GroupBy == metricsGroupByContainer && c.emitsGlobalMetrics() {
+		c.generateContainerMetrics(profiles, profile, attributes, scopeMetrics)
+	}
+
+	// Generate metrics for specific threads (if enabled)
+	if c.config.Metrics.Thread.Enabled && c.degradationStep() < degradationDropThreadMetrics {
+		c.generateThreadMetrics(profiles, profile, attributes, scopeMetrics, c.getUniqueThreadNames(profiles, profile))
+	}
+
+	// Generate CPU utilization ratios (if enabled)
+	if c.config.Metrics.CPUUtilization.Enabled {
+		c.generateCPUUtilizationMetrics(profiles, profile, attributes, scopeMetrics, matchedProcessNames)
 	}
 
 	// Generate function-level metrics (if enabled)
-	if c.config.Metrics.Function.Enabled {
+	if c.config.Metrics.Function.Enabled && c.degradationStep() < degradationDropFunctionMetrics {
 		c.generateFunctionMetrics(profiles, profile, attributes, scopeMetrics)
 	}
-}
 
-// matchesPatternFilter checks if attributes match the pattern filter
-func (c *Converter) matchesPatternFilter(attributes map[string]string) bool {
-	if !c.config.PatternFilter.Enabled {
-		return true
-	}
-	// Check if any attribute value matches the pattern
-	for _, value := range attributes {
-		if c.config.PatternFilter.Pattern != "" &&
-			value != "" {
-			// Simple substring matching for now
-			return true
-		}
+	// Generate the hottest stack of each process (if enabled)
+	if c.config.Metrics.HottestStack.Enabled {
+		c.generateHottestStackMetrics(profiles, profile, attributes, scopeMetrics)
+	}
+
+	// Generate the hottest root-to-leaf path of each process (if enabled)
+	if c.config.Metrics.HotPath.Enabled {
+		c.generateHotPathMetrics(profiles, profile, attributes, scopeMetrics)
 	}
-	return false
-}
 
-// matchesProcessFilter checks if the profile matches the process filter
-func (c *Converter) matchesProcessFilter(attributes map[string]string) bool {
-	// Backward-compat for existing unit tests: if enabled and no process_name attribute, return false
-	if !c.config.ProcessFilter.Enabled {
-		return true
+	// Generate stack depth and truncation metrics (if enabled)
+	if c.config.Metrics.Stack.Enabled {
+		c.generateStackMetrics(profiles, profile, attributes, scopeMetrics)
 	}
-	if _, exists := attributes["process_name"]; !exists {
-		return false
+
+	// Generate symbolization coverage metrics (if enabled)
+	if c.config.Metrics.Symbolization.Enabled {
+		c.generateSymbolizationMetrics(profiles, profile, attributes, scopeMetrics)
 	}
-	return true
-}
 
-// profileMatchesProcessFilter checks if the profile contains any process that matches configured patterns
-func (c *Converter) profileMatchesProcessFilter(profiles pprofile.Profiles, profile pprofile.Profile) bool {
-	if !c.config.ProcessFilter.Enabled {
-		return true
+	// Generate the first-party vs dependency CPU shares (if enabled)
+	if c.config.Metrics.CodeOrigin.Enabled {
+		c.generateCodeOriginMetrics(profiles, profile, attributes, scopeMetrics)
 	}
 
-	// Build pattern list (prefer list; fallback to single)
-	var patterns []string
-	if len(c.config.ProcessFilter.Patterns) > 0 {
-		patterns = c.config.ProcessFilter.Patterns
-	} else if c.config.ProcessFilter.Pattern != "" {
-		patterns = []string{c.config.ProcessFilter.Pattern}
-	} else {
-		return true // enabled but no patterns => allow all
+	// Generate the CPU shares of the frame types (if enabled)
+	if c.config.Metrics.FrameType.Enabled {
+		c.generateFrameTypeMetrics(profiles, profile, attributes, scopeMetrics)
 	}
 
-	// Precompile regexes
-	regexes := make([]*regexp.Regexp, 0, len(patterns))
-	for _, p := range patterns {
-		re, err := regexp.Compile(p)
-		if err != nil {
-			c.logWarn("Invalid process filter pattern - ignoring", zap.String("pattern", p), zap.Error(err))
-			continue
-		}
-		regexes = append(regexes, re)
+	// Generate the kernel vs user CPU time split (if enabled)
+	if c.config.Metrics.KernelUser.Enabled {
+		c.generateKernelUserMetrics(profiles, profile, attributes, scopeMetrics, matchedProcessNames)
 	}
-	if len(regexes) == 0 {
-		return true // no valid patterns
+
+	// Generate the CPU time of caller→callee edges (if enabled)
+	if c.config.Metrics.CallGraph.Enabled {
+		c.generateCallGraphMetrics(profiles, profile, attributes, scopeMetrics)
 	}
 
-	// Check unique process names from samples
-	processNames := c.getUniqueProcessNames(profiles, profile)
-	for _, name := range processNames {
-		for _, re := range regexes {
-			if re.MatchString(name) {
-				c.logDebug("Process filter matched", zap.String("process", name), zap.Strings("patterns", patterns))
-				return true
-			}
-		}
+	// Generate the CPU time of sampled traces (if enabled)
+	if c.config.Metrics.TraceCorrelation.Enabled {
+		c.generateTraceCorrelationMetrics(profiles, profile, attributes, scopeMetrics)
+	}
+
+	// Generate lock contention metrics for mutex/block profiles (if enabled)
+	if c.config.Metrics.Lock.Enabled {
+		c.generateLockMetrics(profiles, profile, attributes, scopeMetrics)
+	}
+
+	// Generate the custom sample type metrics matching the profile
+	if len(c.customMetrics) > 0 {
+		c.generateCustomMetrics(profiles, profile, attributes, scopeMetrics)
+	}
+
+	// Generate exception count metrics (if enabled)
+	if c.config.Metrics.Exceptions.Enabled {
+		c.generateExceptionMetrics(profiles, profile, attributes, scopeMetrics)
 	}
 
-	c.logDebug("Process filter did not match any process", zap.Strings("processes", processNames), zap.Strings("patterns", patterns))
-	return false
+	// Tag data points with how they were produced (if enabled)
+	if c.config.Provenance {
+		c.stampProvenance(profile, scopeMetrics)
+	}
 }
 
-// generateGaugeMetric generates a gauge metric with the given configuration
+// matchesPatternFilter checks if attributes match the pattern filter
+func (c *Converter) matchesPatternFilter(attributes map[string]string) bool {
+	return c.filters.matchesAttributes(attributes)
+}
+
+// setDataPointTimestamps sets the timestamps of a data point generated from a profile
+func (c *Converter) setDataPointTimestamps(dataPoint pmetric.NumberDataPoint, profile pprofile.Profile) {
+	start, end := c.dataPointTimestamps(profile)
+	if start != 0 {
+		dataPoint.SetStartTimestamp(start)
+	}
+	dataPoint.SetTimestamp(end)
+}
+
+// generateGaugeMetric generates a gauge metric with the given configuration and returns it
 func (c *Converter) generateGaugeMetric(
 	name, description string,
 	value float64,
 	attributes map[string]string,
+	profile pprofile.Profile,
 	scopeMetrics pmetric.ScopeMetrics,
-) {
+) pmetric.Metric {
 	metric := scopeMetrics.Metrics().AppendEmpty()
 	metric.SetName(name)
 	metric.SetDescription(description)
@@ -352,70 +647,104 @@ func (c *Converter) generateGaugeMetric(
 	gauge := metric.SetEmptyGauge()
 
 	dataPoint := gauge.DataPoints().AppendEmpty()
-	dataPoint.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	c.setDataPointTimestamps(dataPoint, profile)
 	dataPoint.SetDoubleValue(value)
 
 	// Add attributes to the data point
 	for key, val := range attributes {
 		dataPoint.Attributes().PutStr(key, val)
 	}
+	return metric
 }
 
 // generateCPUTimeMetrics generates CPU time metrics from profile data
 func (c *Converter) generateCPUTimeMetrics(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
 ) {
-	cpuTime := c.calculateCPUTime(profiles, profile)
-	c.generateGaugeMetric(c.config.Metrics.CPU.MetricName, "CPU time in seconds", cpuTime, attributes, scopeMetrics)
+	if cfg := c.config.Metrics.CPU; !summedAggregation(cfg.Aggregation) {
+		totals := c.aggregateSamples(profiles, profile)
+		c.generateAggregatedGaugeMetric(cfg.MetricName, "CPU time in seconds", totals.cpuValues, totals.estimatedCPUTime,
+			attributes, profile, scopeMetrics)
+		return
+	}
+	measured, estimated := c.calculateCPUTimeTotalsForFilter(profiles, profile, nil)
+	c.generateEstimatedGaugeMetrics(c.config.Metrics.CPU.MetricName, "CPU time in seconds", measured, estimated,
+		attributes, profile, scopeMetrics)
 }
 
 // generateMemoryAllocationMetrics generates memory allocation metrics from profile data
 func (c *Converter) generateMemoryAllocationMetrics(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
 ) {
-	memoryAllocation := c.calculateMemoryAllocation(profiles, profile)
-	c.generateGaugeMetric(c.config.Metrics.Memory.MetricName, "Memory allocation in bytes", memoryAllocation, attributes, scopeMetrics)
+	if cfg := c.config.Metrics.Memory; !summedAggregation(cfg.Aggregation) {
+		totals := c.aggregateSamples(profiles, profile)
+		c.generateAggregatedGaugeMetric(cfg.MetricName, "Memory allocation in bytes", totals.memoryValues, totals.estimatedMemory,
+			attributes, profile, scopeMetrics)
+		return
+	}
+	measured, estimated := c.calculateMemoryAllocationTotalsForFilter(profiles, profile, nil)
+	c.generateEstimatedGaugeMetrics(c.config.Metrics.Memory.MetricName, "Memory allocation in bytes", measured, estimated,
+		attributes, profile, scopeMetrics)
 }
 
 // generateThreadMetrics generates CPU time and memory metrics for threads with thread.name as attribute
 func (c *Converter) generateThreadMetrics(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
-	threadName string,
+	threadNames []string,
 ) {
-	c.generateEntityMetrics(profiles, profile, attributes, scopeMetrics, "thread.name", "thread.name", threadName)
+	cpuMetricName, memoryMetricName := c.threadMetricNames()
+	totals := c.aggregateSamplesBy(profiles, profile, "thread.name")
+	for _, threadName := range threadNames {
+		c.generateEntityMetrics(profile, attributes, scopeMetrics, "thread.name", threadName, totals[threadName],
+			cpuMetricName, memoryMetricName)
+	}
 }
 
 // generateProcessMetrics generates CPU time and memory metrics for processes with process.name as attribute
 func (c *Converter) generateProcessMetrics(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
-	processName string,
+	processNames []string,
 ) {
-	c.generateEntityMetrics(profiles, profile, attributes, scopeMetrics, "process.executable.name", "process.name", processName)
+	cpuMetricName, memoryMetricName := c.processMetricNames()
+	if c.config.Metrics.Process.HierarchyAttributes {
+		c.generateProcessHierarchyMetrics(profiles, profile, attributes, scopeMetrics, processNames, cpuMetricName, memoryMetricName)
+		return
+	}
+	totals := c.aggregateSamplesBy(profiles, profile, "process.executable.name")
+	for _, processName := range processNames {
+		c.logDebug("Generating metrics for process", zap.String("process_name", processName))
+		c.generateEntityMetrics(profile, attributes, scopeMetrics, "process.name", processName, totals[processName],
+			cpuMetricName, memoryMetricName)
+	}
 }
 
-// generateEntityMetrics is a generic helper used by thread and process metrics generators
+// generateEntityMetrics is a generic helper used by thread and process metrics generators, emitting
+// the totals aggregated for one entity; nil totals emit zero values
 func (c *Converter) generateEntityMetrics(
-	profiles pprofile.Profiles,
 	profile pprofile.Profile,
 	baseAttributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
-	filterKey string,
 	attributeName string,
 	attributeValue string,
+	totals *sampleTotals,
+	cpuMetricName string,
+	memoryMetricName string,
 ) {
-	filter := map[string]string{filterKey: attributeValue}
+	if totals == nil {
+		totals = c.newSampleTotals()
+	}
 
 	attrs := make(map[string]string)
 	for k, v := range baseAttributes {
@@ -423,311 +752,413 @@ func (c *Converter) generateEntityMetrics(
 	}
 	attrs[attributeName] = attributeValue
 
-	cpuTime := c.calculateCPUTimeForFilter(profiles, profile, filter)
-	c.generateGaugeMetric(c.config.Metrics.CPU.MetricName, "CPU time in seconds", cpuTime, attrs, scopeMetrics)
+	if totals.cpuValues != nil {
+		c.generateAggregatedGaugeMetric(cpuMetricName, "CPU time in seconds", totals.cpuValues, totals.estimatedCPUTime,
+			attrs, profile, scopeMetrics)
+	} else {
+		c.generateEstimatedGaugeMetrics(cpuMetricName, "CPU time in seconds", totals.measuredCPUTime, totals.estimatedCPUTime,
+			attrs, profile, scopeMetrics)
+	}
+	if totals.memoryValues != nil {
+		c.generateAggregatedGaugeMetric(memoryMetricName, "Memory allocation in bytes", totals.memoryValues, totals.estimatedMemory,
+			attrs, profile, scopeMetrics)
+	} else {
+		c.generateEstimatedGaugeMetrics(memoryMetricName, "Memory allocation in bytes", totals.measuredMemory, totals.estimatedMemory,
+			attrs, profile, scopeMetrics)
+	}
+}
 
-	memoryAllocation := c.calculateMemoryAllocationForFilter(profiles, profile, filter)
-	c.generateGaugeMetric(c.config.Metrics.Memory.MetricName, "Memory allocation in bytes", memoryAllocation, attrs, scopeMetrics)
+// functionDataPoint holds the aggregated values of a (process, function) or (thread, function) pair
+type functionDataPoint struct {
+	processName  string
+	threadName   string
+	functionName string
+	fileName     string
+	codeFilePath string
+	lineNumber   int64
+	cpuTime      float64
+	memory       float64
+	// attribution is "self" or "total"; only emitted when function attribution is not the default
+	attribution string
+	// heat is the hot/warm/cold bucket of the emitted measure when heat buckets are enabled
+	heat string
+	// truncated marks data points aggregated from samples with truncated stacks
+	truncated bool
 }
 
 // generateFunctionMetrics generates CPU time and memory metrics for specific functions
 func (c *Converter) generateFunctionMetrics(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeMetrics pmetric.ScopeMetrics,
 ) {
 	c.logDebug("generateFunctionMetrics called - starting function metric generation")
 
-	// Get all function names
-	functionNames := c.getUniqueFunctionNames(profiles, profile)
+	// Samples with truncated stacks are attributed to separate, tagged data points
+	complete, truncated := profile, pprofile.NewProfile()
+	if c.config.TruncatedStacks.Enabled {
+		complete, truncated = c.splitTruncatedSamples(profiles, profile)
+	}
 
-	if len(functionNames) == 0 {
+	points := c.calculateFunctionDataPoints(profiles, complete)
+	for _, point := range c.calculateFunctionDataPoints(profiles, truncated) {
+		point.truncated = true
+		points = append(points, point)
+	}
+	if len(points) == 0 {
 		c.logDebug("No functions found in profile")
 		return
 	}
 
-	c.logDebug("Generating function-level metrics",
-		zap.Int("function_count", len(functionNames)),
-		zap.Strings("function_names", functionNames))
-
-	// Precompute function -> filename mapping
-	functionToFilename := c.getFunctionFilenameMap(profiles, profile)
+	points = c.selectFunctionDataPoints(c.groupFunctionDataPoints(c.filterFunctionDataPoints(points)))
+	if c.config.Metrics.Function.GroupByThread {
+		threadPoints := c.filterFunctionDataPoints(c.calculateThreadFunctionDataPoints(profiles, complete))
+		for _, point := range c.filterFunctionDataPoints(c.calculateThreadFunctionDataPoints(profiles, truncated)) {
+			point.truncated = true
+			threadPoints = append(threadPoints, point)
+		}
+		points = append(points, c.groupFunctionDataPoints(threadPoints)...)
+	}
 
 	// Create a metric for CPU time with function attributes
-	cpuMetricName := c.config.Metrics.CPU.MetricName
-	description := "CPU time in seconds"
-
+	cpuMetricName, memoryMetricName := c.functionMetricNames()
 	cpuMetric := scopeMetrics.Metrics().AppendEmpty()
 	cpuMetric.SetName(cpuMetricName)
-	cpuMetric.SetDescription(description)
+	cpuMetric.SetDescription("CPU time in seconds")
+	c.markAdditive(cpuMetric)
 	cpuGauge := cpuMetric.SetEmptyGauge()
 
 	// Create a metric for memory allocation with function attributes
-	memoryMetricName := c.config.Metrics.Memory.MetricName
-	memDescription := "Memory allocation in bytes"
-
 	memoryMetric := scopeMetrics.Metrics().AppendEmpty()
 	memoryMetric.SetName(memoryMetricName)
-	memoryMetric.SetDescription(memDescription)
+	memoryMetric.SetDescription("Memory allocation in bytes")
+	c.markAdditive(memoryMetric)
 	memoryGauge := memoryMetric.SetEmptyGauge()
 
-	// Get all unique process names to combine with function names
-	processNames := c.getUniqueProcessNames(profiles, profile)
-
-	// Create data points for each (process, function) combination
-	for _, processName := range processNames {
-		for _, functionName := range functionNames {
-			c.logDebug("Adding data point for process and function",
-				zap.String("process_name", processName),
-				zap.String("function_name", functionName))
-
-			// Calculate values for this process and function combination
-			cpuTime := c.calculateFunctionCPUTimeForProcess(profiles, profile, processName, functionName)
-			memoryAllocation := c.calculateFunctionMemoryAllocationForProcess(profiles, profile, processName, functionName)
-
-			// Create CPU data point with both process and function attributes
-			cpuDataPoint := cpuGauge.DataPoints().AppendEmpty()
-			cpuDataPoint.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
-			cpuDataPoint.SetDoubleValue(cpuTime)
-
-			// Add base attributes
-			for key, val := range attributes {
-				cpuDataPoint.Attributes().PutStr(key, val)
-			}
-			// Add process and function names as attributes
-			cpuDataPoint.Attributes().PutStr("process.name", processName)
-			cpuDataPoint.Attributes().PutStr("function.name", functionName)
-			if filename, ok := functionToFilename[functionName]; ok && filename != "" {
-				cpuDataPoint.Attributes().PutStr("file.name", filename)
-				c.logDebug("Attached file.name to CPU datapoint",
-					zap.String("process_name", processName),
-					zap.String("function_name", functionName),
-					zap.String("file_name", filename))
-			}
+	topN := c.config.Metrics.Function.TopN
+	cpuValue := func(p functionDataPoint) float64 { return p.cpuTime }
+	memoryValue := func(p functionDataPoint) float64 { return p.memory }
+	c.appendFunctionDataPoints(cpuGauge, profile, attributes, limitFunctionDataPoints(points, topN.CPU, cpuValue), cpuValue)
+	c.appendFunctionDataPoints(memoryGauge, profile, attributes, limitFunctionDataPoints(points, topN.Memory, memoryValue), memoryValue)
 
-			// Create memory data point with both process and function attributes
-			memoryDataPoint := memoryGauge.DataPoints().AppendEmpty()
-			memoryDataPoint.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
-			memoryDataPoint.SetDoubleValue(memoryAllocation)
-
-			// Add base attributes
-			for key, val := range attributes {
-				memoryDataPoint.Attributes().PutStr(key, val)
-			}
-			// Add process and function names as attributes
-			memoryDataPoint.Attributes().PutStr("process.name", processName)
-			memoryDataPoint.Attributes().PutStr("function.name", functionName)
-			if filename, ok := functionToFilename[functionName]; ok && filename != "" {
-				memoryDataPoint.Attributes().PutStr("file.name", filename)
-				c.logDebug("Attached file.name to Memory datapoint",
-					zap.String("process_name", processName),
-					zap.String("function_name", functionName),
-					zap.String("file_name", filename))
-			}
-		}
+	if c.functionSlopes != nil {
+		c.generateFunctionSlopeMetrics(profiles, profile, attributes, points, scopeMetrics)
+	}
+	if c.functionDiffs != nil {
+		c.generateFunctionDiffMetrics(profiles, profile, attributes, points, scopeMetrics)
+	}
+	if c.functionAnomalies != nil {
+		c.generateFunctionAnomalyMetrics(profiles, profile, attributes, points, scopeMetrics)
 	}
 }
 
-// getUniqueFunctionNames extracts all unique function names from a profile
-func (c *Converter) getUniqueFunctionNames(profiles pprofile.Profiles, profile pprofile.Profile) []string {
-	c.logDebug("Starting to extract unique function names",
-		zap.Int("samples_count", profile.Sample().Len()))
-
-	functionNames := make(map[string]bool)
-
-	for i := 0; i < profile.Sample().Len(); i++ {
-		sample := profile.Sample().At(i)
-		c.logDebug("Processing sample for function name",
-			zap.Int("sample_index", i))
+// calculateFunctionDataPoints calculates the (process, function) data points of a profile
+// for the configured attribution modes
+func (c *Converter) calculateFunctionDataPoints(profiles dictionaryProvider, profile pprofile.Profile) []functionDataPoint {
+	modes, labelAttribution := c.functionAttributionModes()
 
-		functionName := c.getSampleFunctionName(profiles, sample)
-		if functionName != "" {
-			c.logDebug("Found function name",
-				zap.Int("sample_index", i),
-				zap.String("function_name", functionName))
-			functionNames[functionName] = true
-		} else {
-			c.logDebug("Skipping sample with empty function name",
-				zap.Int("sample_index", i))
+	var points []functionDataPoint
+	for _, mode := range modes {
+		if mode == functionAttributionTotal {
+			points = append(points, c.calculateFunctionTotals(profiles, profile)...)
+			continue
 		}
+		if c.config.Metrics.Function.IncludeLineNumbers {
+			points = append(points, c.calculateFunctionLineDataPoints(profiles, profile)...)
+			continue
+		}
+		points = append(points, c.aggregateProcessFunctions(profiles, profile)...)
 	}
-
-	var result []string
-	for functionName := range functionNames {
-		result = append(result, functionName)
+	if !labelAttribution {
+		for i := range points {
+			points[i].attribution = ""
+		}
 	}
-
-	c.logDebug("Extracted unique function names",
-		zap.Int("count", len(result)),
-		zap.Strings("function_names", result))
-
-	return result
+	return points
 }
 
-// getFunctionFilenameMap builds a map from function name to source filename using the top location of samples
-func (c *Converter) getFunctionFilenameMap(profiles pprofile.Profiles, profile pprofile.Profile) map[string]string {
-	result := make(map[string]string)
+// appendFunctionDataPoints appends the data points of one measure, classifying them into heat buckets if enabled
+func (c *Converter) appendFunctionDataPoints(
+	gauge pmetric.Gauge,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	points []functionDataPoint,
+	value func(functionDataPoint) float64,
+) {
+	var heatLevels []string
+	if c.config.Metrics.Function.HeatBuckets.Enabled {
+		heatLevels = c.functionHeatLevels(points, value)
+	}
+	for i, point := range points {
+		if heatLevels != nil {
+			point.heat = heatLevels[i]
+		}
+		c.appendFunctionDataPoint(gauge, profile, attributes, point, value(point))
+	}
+}
 
-	for i := 0; i < profile.Sample().Len(); i++ {
+// calculateThreadFunctionDataPoints aggregates self values per (thread, function) pair, for samples
+// carrying a thread.name attribute
+func (c *Converter) calculateThreadFunctionDataPoints(profiles dictionaryProvider, profile pprofile.Profile) []functionDataPoint {
+	type threadFunctionKey struct {
+		threadName   string
+		functionName string
+		lineNumber   int64
+	}
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	byKey := make(map[threadFunctionKey]*functionDataPoint)
+	var threadNames []string
+	for i := 0; i < sampleCount; i++ {
 		sample := profile.Sample().At(i)
 		functionName := c.getSampleFunctionName(profiles, sample)
 		if functionName == "" {
 			continue
 		}
-
-		// Resolve filename from the same top location
-		filename := c.getSampleFileName(profiles, sample)
-		c.logDebug("Resolved filename for function from sample",
-			zap.Int("sample_index", i),
-			zap.String("function_name", functionName),
-			zap.String("file_name", filename))
-		if filename == "" {
-			continue
+		var lineNumber int64
+		if c.config.Metrics.Function.IncludeLineNumbers {
+			if location, ok := c.getSampleTopLocation(profiles, sample); ok {
+				lineNumber = getLocationLineNumber(location)
+			}
 		}
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		memory := c.sampleMemoryAllocation(sample, summary)
 
-		if _, exists := result[functionName]; !exists {
-			result[functionName] = filename
+		threadNames = appendSampleAttributeValuesCommon(threadNames[:0], c.config, profiles, sample, "thread.name")
+		for _, threadName := range threadNames {
+			if threadName == "" {
+				continue
+			}
+			key := threadFunctionKey{threadName: threadName, functionName: functionName, lineNumber: lineNumber}
+			point, exists := byKey[key]
+			if !exists {
+				point = &functionDataPoint{
+					threadName:   threadName,
+					functionName: functionName,
+					fileName:     c.getSampleFileName(profiles, sample),
+					lineNumber:   key.lineNumber,
+				}
+				if c.config.Metrics.Function.CodeFilePath {
+					point.codeFilePath = c.getSampleCodeFilePath(profiles, sample)
+				}
+				if c.config.Metrics.Function.Attribution == functionAttributionTotal ||
+					c.config.Metrics.Function.Attribution == functionAttributionBoth {
+					point.attribution = functionAttributionSelf
+				}
+				byKey[key] = point
+			}
+			point.cpuTime += cpuTime
+			point.memory += memory
 		}
 	}
 
-	return result
+	points := make([]functionDataPoint, 0, len(byKey))
+	for _, point := range byKey {
+		points = append(points, *point)
+	}
+	sortFunctionLineDataPoints(points)
+	return points
 }
 
-// calculateFunctionCPUTime calculates CPU time for a specific function
-func (c *Converter) calculateFunctionCPUTime(profiles pprofile.Profiles, profile pprofile.Profile, functionName string) float64 {
-	var totalCPUTime float64
-	defaultProfileDuration := 1.0
-	sampleCount := profile.Sample().Len()
+// limitFunctionDataPoints keeps the n highest-valued functions of each process (or thread) and folds
+// the remaining functions of that process into a single "other" data point
+func limitFunctionDataPoints(points []functionDataPoint, n int, value func(functionDataPoint) float64) []functionDataPoint {
+	if n <= 0 {
+		return points
+	}
 
-	for i := 0; i < sampleCount; i++ {
-		sample := profile.Sample().At(i)
-		sampleFunctionName := c.getSampleFunctionName(profiles, sample)
+	// Self and total data points (and those from truncated stacks) are ranked separately
+	type groupKey struct {
+		processName string
+		threadName  string
+		attribution string
+		truncated   bool
+	}
+	var groupOrder []groupKey
+	byGroup := make(map[groupKey][]functionDataPoint)
+	for _, point := range points {
+		key := groupKey{
+			processName: point.processName,
+			threadName:  point.threadName,
+			attribution: point.attribution,
+			truncated:   point.truncated,
+		}
+		if _, exists := byGroup[key]; !exists {
+			groupOrder = append(groupOrder, key)
+		}
+		byGroup[key] = append(byGroup[key], point)
+	}
 
-		// Skip samples with empty function names
-		if sampleFunctionName == "" {
+	result := make([]functionDataPoint, 0, len(groupOrder)*(n+1))
+	for _, key := range groupOrder {
+		group := byGroup[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			if value(group[i]) != value(group[j]) {
+				return value(group[i]) > value(group[j])
+			}
+			return group[i].functionName < group[j].functionName
+		})
+		if len(group) <= n {
+			result = append(result, group...)
 			continue
 		}
 
-		if sampleFunctionName == functionName {
-			values := sample.Values()
-			if values.Len() > 0 {
-				cpuTimeNs := float64(values.At(0))
-				totalCPUTime += cpuTimeNs / nanosecondsPerSecond
-			} else if sampleCount > 0 && defaultProfileDuration > 0 {
-				totalCPUTime += defaultProfileDuration / float64(sampleCount)
-			}
+		result = append(result, group[:n]...)
+		other := functionDataPoint{
+			processName:  key.processName,
+			threadName:   key.threadName,
+			functionName: otherFunctionName,
+			attribution:  key.attribution,
+			truncated:    key.truncated,
+		}
+		for _, point := range group[n:] {
+			other.cpuTime += point.cpuTime
+			other.memory += point.memory
 		}
+		result = append(result, other)
 	}
+	return result
+}
 
-	return totalCPUTime
+// appendFunctionDataPoint appends a data point with process and function attributes to a gauge
+func (c *Converter) appendFunctionDataPoint(
+	gauge pmetric.Gauge,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	point functionDataPoint,
+	value float64,
+) {
+	dataPoint := gauge.DataPoints().AppendEmpty()
+	c.setDataPointTimestamps(dataPoint, profile)
+	dataPoint.SetDoubleValue(value)
+
+	// Add base attributes
+	for key, val := range attributes {
+		dataPoint.Attributes().PutStr(key, val)
+	}
+	// Add process (or thread) and function names as attributes
+	if point.threadName != "" {
+		dataPoint.Attributes().PutStr("thread.name", point.threadName)
+	} else {
+		dataPoint.Attributes().PutStr("process.name", point.processName)
+	}
+	keys := c.codeAttributeKeys()
+	dataPoint.Attributes().PutStr(c.functionGroupAttributeKey(), point.functionName)
+	if point.fileName != "" {
+		dataPoint.Attributes().PutStr(keys.fileName, point.fileName)
+	}
+	if point.codeFilePath != "" {
+		dataPoint.Attributes().PutStr(keys.codeFilePath, point.codeFilePath)
+	}
+	if point.lineNumber > 0 {
+		dataPoint.Attributes().PutInt(keys.lineNumber, point.lineNumber)
+	}
+	if point.attribution != "" {
+		dataPoint.Attributes().PutStr(functionAttributionAttributeKey, point.attribution)
+	}
+	if point.heat != "" {
+		dataPoint.Attributes().PutStr(functionHeatAttributeKey, point.heat)
+	}
+	if point.truncated {
+		dataPoint.Attributes().PutBool(stackTruncatedAttributeKey, true)
+	}
+	if team := c.functionOwnerTeam(point); team != "" {
+		dataPoint.Attributes().PutStr(ownerTeamAttributeKey, team)
+	}
 }
 
-// calculateFunctionMemoryAllocation calculates memory allocation for a specific function
-func (c *Converter) calculateFunctionMemoryAllocation(profiles pprofile.Profiles, profile pprofile.Profile, functionName string) float64 {
-	var totalMemoryAllocation float64
-	sampleCount := profile.Sample().Len()
+// selectFunctionDataPoints restricts function data points to the configured selection
+func (c *Converter) selectFunctionDataPoints(points []functionDataPoint) []functionDataPoint {
+	if c.functionTopK == nil {
+		return points
+	}
 
-	for i := 0; i < sampleCount; i++ {
-		sample := profile.Sample().At(i)
-		sampleFunctionName := c.getSampleFunctionName(profiles, sample)
+	weights := make(map[string]float64, len(points))
+	for _, point := range points {
+		// Self and total points of the same function share a key, weighted by the larger value
+		key := point.processName + "\x00" + point.functionName
+		if point.cpuTime > weights[key] {
+			weights[key] = point.cpuTime
+		}
+	}
+	c.functionTopK.observe(weights)
+	top := c.functionTopK.top(c.config.Metrics.Function.RollingTopK.K)
 
-		// Skip samples with empty function names
-		if sampleFunctionName == "" {
-			continue
+	selected := points[:0]
+	for _, point := range points {
+		if top[point.processName+"\x00"+point.functionName] {
+			selected = append(selected, point)
 		}
+	}
+	c.logDebug("Applied rolling top-k function selection",
+		zap.Int("functions_in", len(points)),
+		zap.Int("functions_selected", len(selected)))
+	return selected
+}
 
-		if sampleFunctionName == functionName {
-			values := sample.Values()
-			if values.Len() > 1 {
-				totalMemoryAllocation += float64(values.At(1))
-			} else if values.Len() == 1 {
-				totalMemoryAllocation += float64(values.At(0))
-			} else {
-				totalMemoryAllocation += 2048.0 // Default 2KB for stack trace profiles
-			}
+// getUniqueFunctionNames extracts all unique function names from a profile
+func (c *Converter) getUniqueFunctionNames(profiles dictionaryProvider, profile pprofile.Profile) []string {
+	functionNames := make(map[string]bool)
+
+	for i := 0; i < profile.Sample().Len(); i++ {
+		functionName := c.getSampleFunctionName(profiles, profile.Sample().At(i))
+		if functionName != "" {
+			functionNames[functionName] = true
 		}
 	}
 
-	return totalMemoryAllocation
+	var result []string
+	for functionName := range functionNames {
+		result = append(result, functionName)
+	}
+
+	c.logDebug("Extracted unique function names", zap.Int("count", len(result)))
+	return result
 }
 
-// calculateFunctionCPUTimeForProcess calculates CPU time for a specific function within a specific process
-func (c *Converter) calculateFunctionCPUTimeForProcess(
-	profiles pprofile.Profiles,
-	profile pprofile.Profile,
-	processName, functionName string,
-) float64 {
+// calculateFunctionCPUTime calculates CPU time for a specific function
+func (c *Converter) calculateFunctionCPUTime(profiles dictionaryProvider, profile pprofile.Profile, functionName string) float64 {
 	var totalCPUTime float64
-	defaultProfileDuration := 1.0
 	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
 
 	for i := 0; i < sampleCount; i++ {
 		sample := profile.Sample().At(i)
-
-		// Check if sample belongs to this process
-		sampleProcessName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
-		if sampleProcessName != processName {
-			continue
-		}
-
-		// Check if sample belongs to this function
 		sampleFunctionName := c.getSampleFunctionName(profiles, sample)
+
+		// Skip samples with empty function names
 		if sampleFunctionName == "" {
-			continue // Skip samples with empty function names
-		}
-		if sampleFunctionName != functionName {
 			continue
 		}
 
-		// Add the value
-		values := sample.Values()
-		if values.Len() > 0 {
-			cpuTimeNs := float64(values.At(0))
-			totalCPUTime += cpuTimeNs / nanosecondsPerSecond
-		} else if sampleCount > 0 && defaultProfileDuration > 0 {
-			totalCPUTime += defaultProfileDuration / float64(sampleCount)
+		if sampleFunctionName == functionName {
+			totalCPUTime += c.sampleCPUTime(sample, weight, summary)
 		}
 	}
 
 	return totalCPUTime
 }
 
-// calculateFunctionMemoryAllocationForProcess calculates memory allocation for a specific function within a specific process
-func (c *Converter) calculateFunctionMemoryAllocationForProcess(
-	profiles pprofile.Profiles,
-	profile pprofile.Profile,
-	processName, functionName string,
-) float64 {
+// calculateFunctionMemoryAllocation calculates memory allocation for a specific function
+func (c *Converter) calculateFunctionMemoryAllocation(profiles dictionaryProvider, profile pprofile.Profile, functionName string) float64 {
 	var totalMemoryAllocation float64
 	sampleCount := profile.Sample().Len()
+	summary := summaryOf(profiles)
 
 	for i := 0; i < sampleCount; i++ {
 		sample := profile.Sample().At(i)
-
-		// Check if sample belongs to this process
-		sampleProcessName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
-		if sampleProcessName != processName {
-			continue
-		}
-
-		// Check if sample belongs to this function
 		sampleFunctionName := c.getSampleFunctionName(profiles, sample)
+
+		// Skip samples with empty function names
 		if sampleFunctionName == "" {
-			continue // Skip samples with empty function names
-		}
-		if sampleFunctionName != functionName {
 			continue
 		}
 
-		// Add the value
-		values := sample.Values()
-		if values.Len() > 1 {
-			totalMemoryAllocation += float64(values.At(1))
-		} else if values.Len() == 1 {
-			totalMemoryAllocation += float64(values.At(0))
-		} else {
-			totalMemoryAllocation += 2048.0 // Default 2KB for stack trace profiles
+		if sampleFunctionName == functionName {
+			totalMemoryAllocation += c.sampleMemoryAllocation(sample, summary)
 		}
 	}
 
@@ -737,439 +1168,176 @@ func (c *Converter) calculateFunctionMemoryAllocationForProcess(
 // sanitizeMetricName sanitizes a string to be used as a metric name
 func sanitizeMetricName(name string) string {
 	// Replace invalid characters with underscores
-	result := ""
+	var result strings.Builder
+	result.Grow(len(name))
 	for _, char := range name {
 		if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '_' {
-			result += string(char)
+			result.WriteRune(char)
 		} else {
-			result += "_"
+			result.WriteByte('_')
 		}
 	}
-	return result
+	return result.String()
 }
 
 // getFunctionName extracts the function name from a function index using the profiles dictionary
-func (c *Converter) getFunctionName(profiles pprofile.Profiles, functionIndex int32) string {
-	if functionIndex < 0 {
-		c.logDebug("Function index is negative", zap.Int32("function_index", functionIndex))
-		return ""
+func (c *Converter) getFunctionName(profiles dictionaryProvider, functionIndex int32) string {
+	name, ok := functionNameCommon(profiles, functionIndex)
+	if !ok {
+		summaryOf(profiles).unresolvedFunctions.Add(1)
 	}
-
-	dictionary := profiles.Dictionary()
-	functionTable := dictionary.FunctionTable()
-
-	if int(functionIndex) >= functionTable.Len() {
-		c.logDebug("Function index out of range",
-			zap.Int32("function_index", functionIndex),
-			zap.Int("function_table_len", functionTable.Len()))
-		return ""
-	}
-
-	function := functionTable.At(int(functionIndex))
-	nameIndex := function.NameStrindex()
-
-	stringTable := dictionary.StringTable()
-	if nameIndex < 0 || int(nameIndex) >= stringTable.Len() {
-		c.logDebug("Function name index out of range",
-			zap.Int32("name_index", nameIndex),
-			zap.Int32("function_index", functionIndex),
-			zap.Int("string_table_len", stringTable.Len()))
-		return ""
-	}
-
-	functionName := stringTable.At(int(nameIndex))
-	if functionName == "" {
-		c.logDebug("Function name is empty string",
-			zap.Int32("function_index", functionIndex),
-			zap.Int32("name_index", nameIndex))
-		return ""
-	}
-
-	c.logDebug("Resolved function name",
-		zap.Int32("function_index", functionIndex),
-		zap.String("function_name", functionName))
-
-	return functionName
+	return name
 }
 
 // getLocationFunctionName gets the function name from a location using the profiles dictionary
-func (c *Converter) getLocationFunctionName(profiles pprofile.Profiles, location pprofile.Location) string {
-	// Locations have Lines, and Lines have FunctionIndex
-	lines := location.Line()
-	c.logDebug("Getting function name from location",
-		zap.Int("lines_count", lines.Len()))
-
-	if lines.Len() == 0 {
-		c.logDebug("Location has no lines")
-		return ""
-	}
-
+func (c *Converter) getLocationFunctionName(profiles dictionaryProvider, location pprofile.Location) string {
 	// Get the first line's function (most specific in the call stack)
-	line := lines.At(0)
-	functionIndex := line.FunctionIndex()
-
-	c.logDebug("Location line info",
-		zap.Int32("function_index", functionIndex))
-
-	functionName := c.getFunctionName(profiles, functionIndex)
-	if functionName == "" {
-		c.logDebug("Function name is empty - skipping")
-		return ""
-	}
-
-	return functionName
+	return c.getFunctionName(profiles, locationFunctionIndexCommon(location))
 }
 
 // getLocationFileName gets the source filename from a location using the profiles dictionary
-func (c *Converter) getLocationFileName(profiles pprofile.Profiles, location pprofile.Location) string {
-	filename := getLocationFileNameCommon(profiles, location)
-	if filename == "" {
-		c.logDebug("Location has no lines for filename resolution")
-	} else {
-		c.logDebug("Resolved file.name from location", zap.String("file_name", filename))
-	}
-	return filename
+func (c *Converter) getLocationFileName(profiles dictionaryProvider, location pprofile.Location) string {
+	return getLocationFileNameCommon(profiles, location)
 }
 
-// getSampleFileName gets the top frame's source filename from a sample's stack
-func (c *Converter) getSampleFileName(profiles pprofile.Profiles, sample pprofile.Sample) string {
-	stackIndex := sample.StackIndex()
-	if stackIndex < 0 {
-		return ""
-	}
-
-	dictionary := profiles.Dictionary()
-	stackTable := dictionary.StackTable()
-	if int(stackIndex) >= stackTable.Len() {
-		return ""
+// getSampleTopLocation returns the location identifying a sample's function: the top location
+// (last entry) of the stack unless frame_selection picks another frame
+func (c *Converter) getSampleTopLocation(profiles dictionaryProvider, sample pprofile.Sample) (pprofile.Location, bool) {
+	locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+	if !ok || locationIndices.Len() == 0 {
+		return pprofile.Location{}, false
 	}
 
-	stack := stackTable.At(int(stackIndex))
-	locationIndices := stack.LocationIndices()
-	if locationIndices.Len() == 0 {
-		return ""
-	}
-
-	locationIndex := locationIndices.At(locationIndices.Len() - 1)
-	locationTable := dictionary.LocationTable()
-	if locationIndex < 0 || int(locationIndex) >= locationTable.Len() {
-		return ""
-	}
-
-	location := locationTable.At(int(locationIndex))
-	filename := c.getLocationFileName(profiles, location)
-	c.logDebug("Resolved file.name from sample top frame",
-		zap.Int32("stack_index", stackIndex),
-		zap.Int32("location_index", locationIndex),
-		zap.String("file_name", filename))
-	return filename
-}
-
-// getSampleFunctionName gets the top function name from a sample's stack
-func (c *Converter) getSampleFunctionName(profiles pprofile.Profiles, sample pprofile.Sample) string {
-	stackIndex := sample.StackIndex()
-	c.logDebug("Getting function name from sample",
-		zap.Int32("stack_index", stackIndex))
-
-	if stackIndex < 0 {
-		c.logDebug("Sample has no stack index")
-		return ""
-	}
-
-	dictionary := profiles.Dictionary()
-	stackTable := dictionary.StackTable()
-
-	c.logDebug("Stack table info",
-		zap.Int32("stack_index", stackIndex),
-		zap.Int("stack_table_len", stackTable.Len()))
-
-	if int(stackIndex) >= stackTable.Len() {
-		c.logDebug("Stack index out of range",
-			zap.Int32("stack_index", stackIndex),
-			zap.Int("stack_table_len", stackTable.Len()))
-		return ""
-	}
-
-	stack := stackTable.At(int(stackIndex))
-	locationIndices := stack.LocationIndices()
-
-	c.logDebug("Stack location indices",
-		zap.Int32("stack_index", stackIndex),
-		zap.Int("location_indices_count", locationIndices.Len()))
-
-	if locationIndices.Len() == 0 {
-		c.logDebug("Stack has no locations")
-		return ""
-	}
-
-	// Get the LAST location (top of the call stack)
+	// By default, get the LAST location (top of the call stack)
 	// The stack grows downward, so the most recent function is at the end
-	locationIndex := locationIndices.At(locationIndices.Len() - 1)
-	locationTable := dictionary.LocationTable()
-
-	c.logDebug("Location table info",
-		zap.Int32("location_index", locationIndex),
-		zap.Int("location_table_len", locationTable.Len()))
+	return locationAtCommon(profiles, c.selectFrame(profiles, locationIndices))
+}
 
-	if locationIndex < 0 || int(locationIndex) >= locationTable.Len() {
-		c.logDebug("Location index out of range",
-			zap.Int32("location_index", locationIndex),
-			zap.Int("location_table_len", locationTable.Len()))
+// getSampleFileName gets the top frame's source filename from a sample's stack
+func (c *Converter) getSampleFileName(profiles dictionaryProvider, sample pprofile.Sample) string {
+	location, ok := c.getSampleTopLocation(profiles, sample)
+	if !ok {
 		return ""
 	}
+	return c.getLocationFileName(profiles, location)
+}
 
-	location := locationTable.At(int(locationIndex))
-	functionName := c.getLocationFunctionName(profiles, location)
-
-	c.logDebug("Extracted function name from sample",
-		zap.Int32("stack_index", stackIndex),
-		zap.Int32("location_index", locationIndex),
-		zap.String("function_name", functionName))
-
-	return functionName
+// getSampleFunctionName gets the top function name from a sample's stack, once per stack and conversion
+func (c *Converter) getSampleFunctionName(profiles dictionaryProvider, sample pprofile.Sample) string {
+	return cachedStackFunctionCommon(profiles, sample.StackIndex(), func() string {
+		location, ok := c.getSampleTopLocation(profiles, sample)
+		if !ok {
+			return ""
+		}
+		return c.getLocationFunctionName(profiles, location)
+	})
 }
 
 // getUniqueThreadNames extracts all unique thread names from a profile
 // In the pprofile schema, thread information is stored as resource attributes
-func (c *Converter) getUniqueThreadNames(profiles pprofile.Profiles, profile pprofile.Profile) []string {
-	result := getUniqueAttributeValuesCommon(profiles, profile, "thread.name")
-	c.logDebug("Extracted unique thread names", zap.Int("count", len(result)), zap.Strings("thread_names", result))
+func (c *Converter) getUniqueThreadNames(profiles dictionaryProvider, profile pprofile.Profile) []string {
+	result := getUniqueAttributeValuesCommon(c.config, profiles, profile, "thread.name")
+	c.logDebug("Extracted unique thread names", zap.Int("count", len(result)))
 	return result
 }
 
 // getUniqueProcessNames extracts all unique process names from a profile
 // In the pprofile schema, process information is stored as resource attributes
-func (c *Converter) getUniqueProcessNames(profiles pprofile.Profiles, profile pprofile.Profile) []string {
-	result := getUniqueAttributeValuesCommon(profiles, profile, "process.executable.name")
-	c.logDebug("Extracted unique process names", zap.Int("count", len(result)), zap.Strings("process_names", result))
+func (c *Converter) getUniqueProcessNames(profiles dictionaryProvider, profile pprofile.Profile) []string {
+	result := getUniqueAttributeValuesCommon(c.config, profiles, profile, "process.executable.name")
+	c.logDebug("Extracted unique process names", zap.Int("count", len(result)))
 	return result
 }
 
 // calculateCPUTime calculates CPU time from profile samples
-func (c *Converter) calculateCPUTime(profiles pprofile.Profiles, profile pprofile.Profile) float64 {
+func (c *Converter) calculateCPUTime(profiles dictionaryProvider, profile pprofile.Profile) float64 {
 	return c.calculateCPUTimeForFilter(profiles, profile, nil)
 }
 
 // calculateCPUTimeForFilter calculates CPU time from profile samples with optional filtering
-func (c *Converter) calculateCPUTimeForFilter(profiles pprofile.Profiles, profile pprofile.Profile, filter map[string]string) float64 {
-	var totalCPUTime float64
-	sampleCount := profile.Sample().Len()
-
-	c.logDebug("Calculating CPU time",
-		zap.Int("samples_count", sampleCount),
-		zap.Any("filter", filter))
+func (c *Converter) calculateCPUTimeForFilter(profiles dictionaryProvider, profile pprofile.Profile, filter map[string]string) float64 {
+	measured, estimated := c.calculateCPUTimeTotalsForFilter(profiles, profile, filter)
+	return measured + estimated
+}
 
-	// For stack trace profiles, we'll use a default duration since we can't get timing from the profile
-	// This is a reasonable assumption for profiling data
-	defaultProfileDuration := 1.0 // 1 second default
-	c.logDebug("Profile timing",
-		zap.Float64("default_duration_seconds", defaultProfileDuration))
+// calculateCPUTimeTotalsForFilter returns the CPU time measured from sample values and the CPU time
+// estimated for samples without values, with optional filtering
+func (c *Converter) calculateCPUTimeTotalsForFilter(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	filter map[string]string,
+) (float64, float64) {
+	var totalCPUTime, estimatedCPUTime float64
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
 
 	// Sum up CPU time from all samples
 	for i := 0; i < sampleCount; i++ {
 		sample := profile.Sample().At(i)
-		values := sample.Values()
 
 		// Apply filtering if specified
 		if filter != nil && !c.matchesSampleFilter(profiles, sample, filter) {
-			c.logDebug("Sample filtered out",
-				zap.Int("sample_index", i),
-				zap.Any("filter", filter))
+			summary.filteredSamples.Add(1)
 			continue
 		}
-
-		c.logDebug("Processing sample",
-			zap.Int("sample_index", i),
-			zap.Int("values_count", values.Len()))
-
-		// Log all values in the sample for debugging
-		if values.Len() > 0 {
-			valueStrings := make([]string, values.Len())
-			for v := 0; v < values.Len(); v++ {
-				valueStrings[v] = fmt.Sprintf("values[%d]=%d", v, values.At(v))
-			}
-			c.logDebug("Sample values",
-				zap.Int("sample_index", i),
-				zap.Strings("values", valueStrings))
-		} else {
-			c.logWarn("Sample has no values", zap.Int("sample_index", i))
-
-			// Let's also check if there are other ways to access sample data
-			c.logDebug("Sample structure analysis",
-				zap.Int("sample_index", i),
-				zap.String("sample_type", fmt.Sprintf("%T", sample)))
-		}
-
-		// Look for CPU time in sample values
-		// For CPU time, we typically want the first value (index 0)
-		// or we need to check the value type if available
-		if values.Len() > 0 {
-			// Take the first value as CPU time (in nanoseconds)
-			cpuTimeNs := float64(values.At(0))
-			// Convert nanoseconds to seconds for better readability
-			cpuTimeSeconds := cpuTimeNs / nanosecondsPerSecond
-			totalCPUTime += cpuTimeSeconds
-
-			c.logDebug("Sample CPU time",
-				zap.Int("sample_index", i),
-				zap.Float64("cpu_time_ns", cpuTimeNs),
-				zap.Float64("cpu_time_seconds", cpuTimeSeconds),
-				zap.Float64("running_total", totalCPUTime))
-		} else {
-			c.logWarn("Sample has no values - this is expected for stack trace profiles", zap.Int("sample_index", i))
-
-			// For stack trace profiles without values, distribute the profile duration
-			// across all samples to estimate CPU time per sample
-			if sampleCount > 0 && defaultProfileDuration > 0 {
-				estimatedCPUTimePerSample := defaultProfileDuration / float64(sampleCount)
-				totalCPUTime += estimatedCPUTimePerSample
-
-				c.logDebug("Using estimated CPU time based on profile duration",
-					zap.Int("sample_index", i),
-					zap.Float64("estimated_cpu_time_seconds", estimatedCPUTimePerSample),
-					zap.Float64("profile_duration_seconds", defaultProfileDuration),
-					zap.Float64("running_total", totalCPUTime))
-			} else {
-				// Fallback to a small default value
-				defaultCPUTime := 0.001 // 1ms default
-				totalCPUTime += defaultCPUTime
-
-				c.logDebug("Using default CPU time for stack trace sample",
-					zap.Int("sample_index", i),
-					zap.Float64("default_cpu_time_seconds", defaultCPUTime),
-					zap.Float64("running_total", totalCPUTime))
-			}
-		}
+		measured, estimated := c.sampleCPUTimeTotals(sample, weight, summary)
+		totalCPUTime += measured
+		estimatedCPUTime += estimated
 	}
 
 	c.logDebug("CPU time calculation completed",
-		zap.Float64("total_cpu_time_seconds", totalCPUTime),
-		zap.Int("samples_processed", sampleCount))
+		zap.Float64("total_cpu_time_seconds", totalCPUTime+estimatedCPUTime),
+		zap.Float64("estimated_cpu_time_seconds", estimatedCPUTime),
+		zap.Int("samples_count", sampleCount))
 
-	return totalCPUTime
+	return totalCPUTime, estimatedCPUTime
 }
 
 // calculateMemoryAllocation calculates memory allocation from profile samples
-func (c *Converter) calculateMemoryAllocation(profiles pprofile.Profiles, profile pprofile.Profile) float64 {
+func (c *Converter) calculateMemoryAllocation(profiles dictionaryProvider, profile pprofile.Profile) float64 {
 	return c.calculateMemoryAllocationForFilter(profiles, profile, nil)
 }
 
 // calculateMemoryAllocationForFilter calculates memory allocation from profile samples with optional filtering
 func (c *Converter) calculateMemoryAllocationForFilter(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	filter map[string]string,
 ) float64 {
-	var totalMemoryAllocation float64
-	sampleCount := profile.Sample().Len()
+	measured, estimated := c.calculateMemoryAllocationTotalsForFilter(profiles, profile, filter)
+	return measured + estimated
+}
 
-	c.logDebug("Calculating memory allocation",
-		zap.Int("samples_count", sampleCount),
-		zap.Any("filter", filter))
+// calculateMemoryAllocationTotalsForFilter returns the memory allocation measured from sample values
+// and the allocation estimated for samples without values, with optional filtering
+func (c *Converter) calculateMemoryAllocationTotalsForFilter(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	filter map[string]string,
+) (float64, float64) {
+	var totalMemoryAllocation, estimatedMemoryAllocation float64
+	sampleCount := profile.Sample().Len()
+	summary := summaryOf(profiles)
 
 	// Sum up memory allocation from all samples
 	for i := 0; i < sampleCount; i++ {
 		sample := profile.Sample().At(i)
-		values := sample.Values()
 
 		// Apply filtering if specified
 		if filter != nil && !c.matchesSampleFilter(profiles, sample, filter) {
-			c.logDebug("Sample filtered out",
-				zap.Int("sample_index", i),
-				zap.Any("filter", filter))
+			summary.filteredSamples.Add(1)
 			continue
 		}
-
-		c.logDebug("Processing sample for memory",
-			zap.Int("sample_index", i),
-			zap.Int("values_count", values.Len()))
-
-		// Log all values in the sample for debugging
-		if values.Len() > 0 {
-			valueStrings := make([]string, values.Len())
-			for v := 0; v < values.Len(); v++ {
-				valueStrings[v] = fmt.Sprintf("values[%d]=%d", v, values.At(v))
-			}
-			c.logDebug("Sample values for memory",
-				zap.Int("sample_index", i),
-				zap.Strings("values", valueStrings))
-		} else {
-			c.logWarn("Sample has no values for memory calculation", zap.Int("sample_index", i))
-		}
-
-		// Look for memory allocation in sample values
-		// For memory allocation, we typically want the second value (index 1)
-		// if it exists, otherwise we might need to look for specific value types
-		if values.Len() > 1 {
-			// Take the second value as memory allocation (in bytes)
-			memoryBytes := float64(values.At(1))
-			totalMemoryAllocation += memoryBytes
-
-			c.logDebug("Sample memory allocation (index 1)",
-				zap.Int("sample_index", i),
-				zap.Float64("memory_bytes", memoryBytes),
-				zap.Float64("running_total", totalMemoryAllocation))
-		} else if values.Len() == 1 {
-			// If only one value exists, it might be memory allocation
-			// This is a fallback for profiles with only memory data
-			memoryBytes := float64(values.At(0))
-			totalMemoryAllocation += memoryBytes
-
-			c.logDebug("Sample memory allocation (fallback to index 0)",
-				zap.Int("sample_index", i),
-				zap.Float64("memory_bytes", memoryBytes),
-				zap.Float64("running_total", totalMemoryAllocation))
-		} else {
-			c.logWarn("Sample has no values for memory calculation - this is expected for stack trace profiles", zap.Int("sample_index", i))
-
-			// For stack trace profiles without values, estimate memory allocation
-			// based on a reasonable default for stack trace samples
-			estimatedMemoryBytes := 2048.0 // 2KB default for stack trace sample
-			totalMemoryAllocation += estimatedMemoryBytes
-
-			c.logDebug("Using estimated memory allocation for stack trace sample",
-				zap.Int("sample_index", i),
-				zap.Float64("estimated_memory_bytes", estimatedMemoryBytes),
-				zap.Float64("running_total", totalMemoryAllocation))
-		}
+		measured, estimated := c.sampleMemoryTotals(sample, summary)
+		totalMemoryAllocation += measured
+		estimatedMemoryAllocation += estimated
 	}
 
 	c.logDebug("Memory allocation calculation completed",
-		zap.Float64("total_memory_bytes", totalMemoryAllocation),
-		zap.Int("samples_processed", sampleCount))
-
-	return totalMemoryAllocation
-}
-
-// extractFromStringTable extracts values from profile string table using regex pattern
-func (c *Converter) extractFromStringTable(profiles pprofile.Profiles, _ string) string {
-	// Access the string table from the profiles dictionary
-	stringTable := profiles.Dictionary().StringTable()
-
-	// For now, return the first string as a placeholder
-	// In a real implementation, you would:
-	// 1. Compile the regex pattern
-	// 2. Match against all strings in the table
-	// 3. Return the first match
-	if stringTable.Len() > 0 {
-		return stringTable.At(0)
-	}
-	return ""
-}
+		zap.Float64("total_memory_bytes", totalMemoryAllocation+estimatedMemoryAllocation),
+		zap.Float64("estimated_memory_bytes", estimatedMemoryAllocation),
+		zap.Int("samples_count", sampleCount))
 
-// extractFromStringTableByIndex extracts values from profile string table by index
-func (c *Converter) extractFromStringTableByIndex(profiles pprofile.Profiles, _ string) string {
-	// Access the string table from the profiles dictionary
-	stringTable := profiles.Dictionary().StringTable()
-
-	// Parse the index string to integer
-	// For now, use index 0 as a placeholder
-	// In a real implementation, you would:
-	// 1. Parse the indexStr to integer using strconv.Atoi
-	// 2. Check bounds to ensure the index is valid
-	// 3. Return the string at the specified index
-	if stringTable.Len() > 0 {
-		return stringTable.At(0) // Placeholder: return first string
-	}
-	return ""
+	return totalMemoryAllocation, estimatedMemoryAllocation
 }
@@ -3,9 +3,11 @@ package profiletometrics
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.uber.org/zap"
@@ -183,65 +185,50 @@ func TestConverter_matchesPatternFilter(t *testing.T) {
 			expectedResult: true,
 		},
 		{
-			name: "Pattern filter enabled",
+			name: "Legacy pattern matches any attribute value",
 			config: ConverterConfig{
 				PatternFilter: PatternFilterConfig{
 					Enabled: true,
 					Pattern: "test.*",
 				},
 			},
-			attributes:     map[string]string{"test": "value"},
-			expectedResult: true, // Current implementation always returns true
+			attributes:     map[string]string{"service.name": "test-service"},
+			expectedResult: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			converter, err := NewConverter(&tt.config)
-			require.NoError(t, err)
-			result := converter.matchesPatternFilter(tt.attributes)
-			assert.Equal(t, tt.expectedResult, result)
-		})
-	}
-}
-
-func TestConverter_matchesProcessFilter(t *testing.T) {
-	tests := []struct {
-		name           string
-		config         ConverterConfig
-		attributes     map[string]string
-		expectedResult bool
-	}{
 		{
-			name: "Process filter disabled",
+			name: "Legacy pattern without matching value",
 			config: ConverterConfig{
-				ProcessFilter: ProcessFilterConfig{
-					Enabled: false,
+				PatternFilter: PatternFilterConfig{
+					Enabled: true,
+					Pattern: "test.*",
 				},
 			},
 			attributes:     map[string]string{"test": "value"},
-			expectedResult: true,
+			expectedResult: false,
 		},
 		{
-			name: "Process filter enabled with process_name",
+			name: "Include rule on attribute key",
 			config: ConverterConfig{
-				ProcessFilter: ProcessFilterConfig{
+				PatternFilter: PatternFilterConfig{
 					Enabled: true,
-					Pattern: "test.*",
+					Rules:   []PatternFilterRule{{AttributeKey: "k8s.namespace.name", Regex: "^prod-", Action: "include"}},
 				},
 			},
-			attributes:     map[string]string{"process_name": "test_process"},
-			expectedResult: true, // Current implementation always returns true
+			attributes:     map[string]string{"k8s.namespace.name": "prod-eu"},
+			expectedResult: true,
 		},
 		{
-			name: "Process filter enabled without process_name",
+			name: "Exclude rule wins over include rule",
 			config: ConverterConfig{
-				ProcessFilter: ProcessFilterConfig{
+				PatternFilter: PatternFilterConfig{
 					Enabled: true,
-					Pattern: "test.*",
+					Rules: []PatternFilterRule{
+						{AttributeKey: "k8s.namespace.name", Regex: "^prod-", Action: "include"},
+						{AttributeKey: "service.name", Regex: "^canary", Action: "exclude"},
+					},
 				},
 			},
-			attributes:     map[string]string{"other": "value"},
+			attributes:     map[string]string{"k8s.namespace.name": "prod-eu", "service.name": "canary-api"},
 			expectedResult: false,
 		},
 	}
@@ -250,7 +237,7 @@ func TestConverter_matchesProcessFilter(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			converter, err := NewConverter(&tt.config)
 			require.NoError(t, err)
-			result := converter.matchesProcessFilter(tt.attributes)
+			result := converter.matchesPatternFilter(tt.attributes)
 			assert.Equal(t, tt.expectedResult, result)
 		})
 	}
@@ -551,10 +538,7 @@ func TestConverter_GetUniqueProcessNames(t *testing.T) {
 	assert.Equal(t, 0, len(processNames))
 }
 
-func TestConverter_ExtractAttributeValue(t *testing.T) {
-	converter, err := NewConverter(&ConverterConfig{})
-	require.NoError(t, err)
-
+func TestExtractAttributeValueCommon(t *testing.T) {
 	profiles := testdata.CreateTestProfile()
 	profile := pprofile.NewProfile()
 
@@ -564,7 +548,7 @@ func TestConverter_ExtractAttributeValue(t *testing.T) {
 		Value: "test_value",
 		Type:  "literal",
 	}
-	value := converter.extractAttributeValue(profiles, profile, attr)
+	value := extractAttributeValueCommon(profiles, profile, attr)
 	assert.Equal(t, "test_value", value)
 
 	// Test regex type (will use empty string for now)
@@ -573,7 +557,7 @@ func TestConverter_ExtractAttributeValue(t *testing.T) {
 		Value: ".*",
 		Type:  "regex",
 	}
-	value2 := converter.extractAttributeValue(profiles, profile, attr2)
+	value2 := extractAttributeValueCommon(profiles, profile, attr2)
 	// May be empty depending on implementation
 	assert.NotNil(t, value2)
 
@@ -583,7 +567,7 @@ func TestConverter_ExtractAttributeValue(t *testing.T) {
 		Value: "default",
 		Type:  "unknown",
 	}
-	value3 := converter.extractAttributeValue(profiles, profile, attr3)
+	value3 := extractAttributeValueCommon(profiles, profile, attr3)
 	assert.Equal(t, "default", value3)
 }
 
@@ -999,7 +983,7 @@ func TestConverter_GenerateThreadMetrics(t *testing.T) {
 	scopeMetrics := pmetric.NewScopeMetrics()
 
 	// Generate thread metrics (should work even without actual thread data)
-	converter.generateThreadMetrics(profiles, profile, attributes, scopeMetrics, "test_thread")
+	converter.generateThreadMetrics(profiles, profile, attributes, scopeMetrics, []string{"test_thread"})
 
 	// Verify metrics were created (even if empty)
 	// The function should not panic
@@ -1027,9 +1011,563 @@ func TestConverter_GenerateProcessMetrics(t *testing.T) {
 	scopeMetrics := pmetric.NewScopeMetrics()
 
 	// Generate process metrics
-	converter.generateProcessMetrics(profiles, profile, attributes, scopeMetrics, "test_process")
+	converter.generateProcessMetrics(profiles, profile, attributes, scopeMetrics, []string{"test_process"})
 
 	// Verify metrics were created (even if empty)
 	// The function should not panic
 	assert.NotNil(t, scopeMetrics)
 }
+
+func TestConverter_OriginAttribute(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   OriginConfig
+		expected string
+		present  bool
+	}{
+		{
+			name:    "Origin disabled",
+			origin:  OriginConfig{Enabled: false},
+			present: false,
+		},
+		{
+			name:     "Origin from scope name only",
+			origin:   OriginConfig{Enabled: true},
+			expected: "test-scope",
+			present:  true,
+		},
+		{
+			name:     "Origin with receiver name and custom key",
+			origin:   OriginConfig{Enabled: true, AttributeKey: "profile.origin", ReceiverName: "otlp/ebpf"},
+			expected: "otlp/ebpf/test-scope",
+			present:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				},
+				Origin: tt.origin,
+			})
+			require.NoError(t, err)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), testdata.CreateTestProfile())
+			require.NoError(t, err)
+
+			dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+			key := tt.origin.AttributeKey
+			if key == "" {
+				key = "origin"
+			}
+			value, exists := dataPoint.Attributes().Get(key)
+			assert.Equal(t, tt.present, exists)
+			if tt.present {
+				assert.Equal(t, tt.expected, value.Str())
+			}
+		})
+	}
+}
+
+func TestConverter_ProfileComments(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		converter, err := NewConverter(&ConverterConfig{
+			Metrics: MetricsConfig{
+				CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			},
+			ProfileComments: enabled,
+		})
+		require.NoError(t, err)
+
+		b := newTestProfileBuilder()
+		b.profile.CommentStrindices().Append(b.str("load test run 42"), b.str("canary"))
+		b.sample(b.stack("main"), nil, 100)
+
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+
+		dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+		value, exists := dataPoint.Attributes().Get("profile.comment")
+		assert.Equal(t, enabled, exists)
+		if enabled {
+			assert.Equal(t, "load test run 42; canary", value.Str())
+		}
+	}
+}
+
+func TestConverter_GenerateExceptionMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Exceptions: ExceptionMetricConfig{
+				Enabled:    true,
+				MetricName: "exception_count",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	stack := b.stack("main", "throw")
+	b.sample(stack, map[string]string{"process.executable.name": "java", "exception.type": "java.io.IOException"}, 100)
+	b.sample(stack, map[string]string{"process.executable.name": "java", "exception.type": "java.io.IOException"}, 100)
+	b.sample(stack, map[string]string{"process.executable.name": "java", "exception.type": "java.lang.NullPointerException"}, 100)
+	b.sample(stack, map[string]string{"process.executable.name": "java"}, 100)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateExceptionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+	require.Equal(t, 1, scopeMetrics.Metrics().Len())
+	metric := scopeMetrics.Metrics().At(0)
+	assert.Equal(t, "exception_count", metric.Name())
+
+	counts := make(map[string]float64)
+	dataPoints := metric.Gauge().DataPoints()
+	for i := 0; i < dataPoints.Len(); i++ {
+		exceptionType, _ := dataPoints.At(i).Attributes().Get("exception.type")
+		counts[exceptionType.Str()] = dataPoints.At(i).DoubleValue()
+	}
+	assert.Equal(t, map[string]float64{"java.io.IOException": 2, "java.lang.NullPointerException": 1}, counts)
+
+	// Exception profiles use sample values as event counts
+	b.withSampleType("exceptions", "count")
+	scopeMetrics = pmetric.NewScopeMetrics()
+	converter.generateExceptionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+	assert.Equal(t, float64(200), scopeMetrics.Metrics().At(0).Gauge().DataPoints().At(0).DoubleValue())
+}
+
+func TestConverter_ConversionSummary(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+		},
+		Estimation: EstimationConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	stack := b.stack("main")
+	b.sample(stack, nil, 1000000000, 2048)
+	b.sample(stack, nil)
+
+	conversion := converter.beginConversion(b.profiles)
+	converter.convertResource(conversion, b.profiles, 0, pmetric.NewResourceMetrics(), nil)
+
+	summary := conversion.summary
+	assert.Equal(t, int64(1), summary.profiles.Load())
+	assert.Equal(t, int64(4), summary.sampleEvaluations.Load())
+	assert.Equal(t, int64(2), summary.samplesWithoutValues.Load())
+	assert.Equal(t, int64(1), summary.estimatedCPUSamples.Load())
+	assert.Equal(t, int64(1), summary.estimatedMemorySamples.Load())
+}
+
+func TestConverter_UseProfileTimestamps(t *testing.T) {
+	profileStart := pcommon.Timestamp(1700000000000000000)
+	profileDuration := pcommon.Timestamp(10 * time.Second)
+
+	tests := []struct {
+		name                 string
+		useProfileTimestamps bool
+		expectedStart        pcommon.Timestamp
+		expectedTimestamp    pcommon.Timestamp
+	}{
+		{
+			name:                 "Profile timestamps enabled",
+			useProfileTimestamps: true,
+			expectedStart:        profileStart,
+			expectedTimestamp:    profileStart + profileDuration,
+		},
+		{
+			name:                 "Profile timestamps disabled",
+			useProfileTimestamps: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				},
+				UseProfileTimestamps: tt.useProfileTimestamps,
+			})
+			require.NoError(t, err)
+
+			profiles := testdata.CreateTestProfile()
+			profile := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+			profile.SetTime(profileStart)
+			profile.SetDuration(profileDuration)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), profiles)
+			require.NoError(t, err)
+
+			dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+			assert.Equal(t, tt.expectedStart, dataPoint.StartTimestamp())
+			if tt.useProfileTimestamps {
+				assert.Equal(t, tt.expectedTimestamp, dataPoint.Timestamp())
+			} else {
+				assert.NotEqual(t, profileStart+profileDuration, dataPoint.Timestamp())
+			}
+		})
+	}
+}
+
+func TestConverter_AggregationTemporality(t *testing.T) {
+	newProfiles := func(cpuNs int64) pprofile.Profiles {
+		b := newTestProfileBuilder()
+		b.sample(b.stack("main"), nil, cpuNs)
+		return b.profiles
+	}
+
+	tests := []struct {
+		name          string
+		temporality   string
+		expectedFirst int // data points emitted by the first conversion
+		expectedValue float64
+		expectedTemp  pmetric.AggregationTemporality
+	}{
+		{
+			name:          "Delta drops the baseline and emits increases",
+			temporality:   "delta",
+			expectedFirst: 0,
+			expectedValue: 1.5,
+			expectedTemp:  pmetric.AggregationTemporalityDelta,
+		},
+		{
+			name:          "Cumulative emits totals as monotonic sums",
+			temporality:   "cumulative",
+			expectedFirst: 1,
+			expectedValue: 2.5,
+			expectedTemp:  pmetric.AggregationTemporalityCumulative,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				},
+				AggregationTemporality: tt.temporality,
+			})
+			require.NoError(t, err)
+
+			first, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles(1000000000))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFirst, first.DataPointCount())
+
+			second, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles(2500000000))
+			require.NoError(t, err)
+			require.Equal(t, 1, second.DataPointCount())
+
+			metric := second.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			require.Equal(t, pmetric.MetricTypeSum, metric.Type())
+			assert.True(t, metric.Sum().IsMonotonic())
+			assert.Equal(t, tt.expectedTemp, metric.Sum().AggregationTemporality())
+			assert.InDelta(t, tt.expectedValue, metric.Sum().DataPoints().At(0).DoubleValue(), 1e-9)
+		})
+	}
+}
+
+func TestNewConverter_InvalidAggregationTemporality(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{AggregationTemporality: "rate"})
+	assert.Error(t, err)
+}
+
+func TestConverter_AggregationMaxSeries(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+		},
+		AggregationTemporality: "cumulative",
+		AggregationMaxSeries:   1,
+	})
+	require.NoError(t, err)
+
+	_, ok := converter.AccumulatorStats()
+	require.True(t, ok)
+	convert := func(serviceName string) {
+		b := newTestProfileBuilder()
+		b.resource.Resource().Attributes().PutStr("service.name", serviceName)
+		b.sample(b.stack("main"), nil, 1000000000)
+		_, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+	}
+
+	convert("checkout")
+	stats, _ := converter.AccumulatorStats()
+	assert.Equal(t, 1, stats.ActiveSeries)
+	assert.Zero(t, stats.Evictions)
+	assert.Positive(t, stats.MemoryBytes)
+
+	// The checkout series is the least recently updated one
+	convert("payments")
+	stats, _ = converter.AccumulatorStats()
+	assert.Equal(t, 1, stats.ActiveSeries)
+	assert.Equal(t, int64(1), stats.Evictions)
+}
+
+func TestConverter_AccumulatorStatsWithoutTemporality(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{})
+	require.NoError(t, err)
+	_, ok := converter.AccumulatorStats()
+	assert.False(t, ok)
+
+	_, err = NewConverter(&ConverterConfig{AggregationTemporality: "delta", AggregationMaxSeries: -1})
+	assert.Error(t, err)
+}
+
+func TestConverter_FunctionTopN(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{
+				Enabled: true,
+				TopN:    FunctionTopNConfig{CPU: 1, Memory: 2},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main", "a"), process, 3000000000, 10)
+	b.sample(b.stack("main", "b"), process, 2000000000, 30)
+	b.sample(b.stack("main", "c"), process, 1000000000, 20)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+	require.Equal(t, 2, scopeMetrics.Metrics().Len())
+
+	valuesByFunction := func(metric pmetric.Metric) map[string]float64 {
+		result := make(map[string]float64)
+		for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+			dp := metric.Gauge().DataPoints().At(i)
+			functionName, _ := dp.Attributes().Get("function.name")
+			result[functionName.Str()] = dp.DoubleValue()
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]float64{"a": 3, "other": 3}, valuesByFunction(scopeMetrics.Metrics().At(0)))
+	assert.Equal(t, map[string]float64{"b": 30, "c": 20, "other": 10}, valuesByFunction(scopeMetrics.Metrics().At(1)))
+}
+
+func TestConverter_FunctionAttribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		attribution string
+		expected    map[string]float64
+	}{
+		{
+			name:        "Self attribution credits the leaf only",
+			attribution: "",
+			expected:    map[string]float64{"/handler": 2, "/parse": 1},
+		},
+		{
+			name:        "Total attribution credits every function once per sample",
+			attribution: "total",
+			expected:    map[string]float64{"total/main": 3, "total/handler": 3, "total/parse": 1},
+		},
+		{
+			name:        "Both attributions",
+			attribution: "both",
+			expected: map[string]float64{
+				"self/handler": 2, "self/parse": 1,
+				"total/main": 3, "total/handler": 3, "total/parse": 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Function: FunctionMetricConfig{Enabled: true, Attribution: tt.attribution},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			process := map[string]string{"process.executable.name": "app"}
+			// main -> handler -> handler (recursion) is counted once for handler
+			b.sample(b.stack("main", "handler", "handler"), process, 2000000000, 0)
+			b.sample(b.stack("main", "handler", "parse"), process, 1000000000, 0)
+
+			scopeMetrics := pmetric.NewScopeMetrics()
+			converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+			result := make(map[string]float64)
+			dataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+			for i := 0; i < dataPoints.Len(); i++ {
+				dp := dataPoints.At(i)
+				functionName, _ := dp.Attributes().Get("function.name")
+				attribution, _ := dp.Attributes().Get("function.attribution")
+				if dp.DoubleValue() > 0 {
+					result[attribution.Str()+"/"+functionName.Str()] = dp.DoubleValue()
+				}
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNewConverter_InvalidFunctionAttribution(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Function: FunctionMetricConfig{Attribution: "leaf"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestConverter_FunctionGroupByThread(t *testing.T) {
+	for _, groupByThread := range []bool{false, true} {
+		converter, err := NewConverter(&ConverterConfig{
+			Metrics: MetricsConfig{
+				CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+				Function: FunctionMetricConfig{Enabled: true, GroupByThread: groupByThread},
+			},
+		})
+		require.NoError(t, err)
+
+		b := newTestProfileBuilder()
+		b.sample(b.stack("main", "work"), map[string]string{"process.executable.name": "app", "thread.name": "pool-1"}, 2000000000, 0)
+		b.sample(b.stack("main", "work"), map[string]string{"process.executable.name": "app", "thread.name": "pool-2"}, 1000000000, 0)
+
+		scopeMetrics := pmetric.NewScopeMetrics()
+		converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+		threads := make(map[string]float64)
+		processPoints := 0
+		dataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			dp := dataPoints.At(i)
+			if threadName, ok := dp.Attributes().Get("thread.name"); ok {
+				_, hasProcess := dp.Attributes().Get("process.name")
+				assert.False(t, hasProcess)
+				threads[threadName.Str()] = dp.DoubleValue()
+				continue
+			}
+			processPoints++
+		}
+
+		assert.Equal(t, 1, processPoints)
+		if groupByThread {
+			assert.Equal(t, map[string]float64{"pool-1": 2, "pool-2": 1}, threads)
+		} else {
+			assert.Empty(t, threads)
+		}
+	}
+}
+
+func TestConverter_ThreadMetrics(t *testing.T) {
+	tests := []struct {
+		name         string
+		thread       ThreadMetricConfig
+		threadFilter ThreadFilterConfig
+		expected     map[string]float64
+	}{
+		{
+			name:     "Thread metrics disabled",
+			thread:   ThreadMetricConfig{Enabled: false},
+			expected: map[string]float64{},
+		},
+		{
+			name:     "Thread metrics for every thread",
+			thread:   ThreadMetricConfig{Enabled: true},
+			expected: map[string]float64{"worker-1": 2, "worker-2": 1, "gc": 1},
+		},
+		{
+			name:         "Thread metrics honor the thread filter",
+			thread:       ThreadMetricConfig{Enabled: true},
+			threadFilter: ThreadFilterConfig{Enabled: true, Pattern: "^worker-"},
+			expected:     map[string]float64{"worker-1": 2, "worker-2": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Thread: tt.thread,
+				},
+				ThreadFilter: tt.threadFilter,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			b.sample(b.stack("main"), map[string]string{"thread.name": "worker-1"}, 2000000000, 0)
+			b.sample(b.stack("main"), map[string]string{"thread.name": "worker-2"}, 1000000000, 0)
+			b.sample(b.stack("main"), map[string]string{"thread.name": "gc"}, 1000000000, 0)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			threads := make(map[string]float64)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				metric := metricSlice.At(i)
+				if metric.Name() != "cpu_time" {
+					continue
+				}
+				for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+					dp := metric.Gauge().DataPoints().At(j)
+					if threadName, ok := dp.Attributes().Get("thread.name"); ok {
+						threads[threadName.Str()] = dp.DoubleValue()
+					}
+				}
+			}
+			assert.Equal(t, tt.expected, threads)
+		})
+	}
+}
+
+func TestNewConverter_InvalidThreadFilter(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{ThreadFilter: ThreadFilterConfig{Enabled: true, Pattern: "("}})
+	assert.Error(t, err)
+}
+
+func TestConverter_ThreadFilterRestrictsAggregation(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		ThreadFilter: ThreadFilterConfig{Enabled: true, Patterns: []string{"^http-", "^grpc-"}},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "app", "thread.name": "http-1"}, 2000000000, 0)
+	b.sample(b.stack("main", "call"), map[string]string{"process.executable.name": "app", "thread.name": "grpc-7"}, 1000000000, 0)
+	b.sample(b.stack("main", "collect"), map[string]string{"process.executable.name": "app", "thread.name": "gc"}, 4000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	global := 0.0
+	functions := make(map[string]bool)
+	cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, "cpu_time", cpuMetric.Name())
+	for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+		dp := cpuMetric.Gauge().DataPoints().At(i)
+		if functionName, ok := dp.Attributes().Get("function.name"); ok {
+			functions[functionName.Str()] = true
+			continue
+		}
+		if _, ok := dp.Attributes().Get("process.name"); !ok {
+			global = dp.DoubleValue()
+		}
+	}
+	assert.Equal(t, 3.0, global)
+	assert.Equal(t, map[string]bool{"serve": true, "call": true}, functions)
+	assert.Equal(t, 3, b.profile.Sample().Len(), "the input profile is not modified")
+}
@@ -0,0 +1,162 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// customFunctionDimension is the dimension resolving to the leaf function of the samples
+const customFunctionDimension = "function.name"
+
+// customMetric is a custom metric definition with its compiled sample type pattern
+type customMetric struct {
+	config     CustomMetricConfig
+	sampleType *regexp.Regexp
+}
+
+// customSeries accumulates the sample values of one custom metric series
+type customSeries struct {
+	attributes map[string]string
+	values     *valueAggregator
+}
+
+// compileCustomMetrics validates the metrics.custom definitions and compiles their sample type
+// patterns, returning nil when none is configured
+func compileCustomMetrics(configs []CustomMetricConfig) ([]customMetric, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	customs := make([]customMetric, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for i, cfg := range configs {
+		if cfg.SampleType == "" {
+			return nil, fmt.Errorf("metrics.custom[%d]: sample_type must be set", i)
+		}
+		sampleType, err := regexp.Compile(cfg.SampleType)
+		if err != nil {
+			return nil, fmt.Errorf("metrics.custom[%d]: invalid sample_type pattern %q: %w", i, cfg.SampleType, err)
+		}
+		if !metricNamePattern.MatchString(cfg.MetricName) {
+			return nil, fmt.Errorf("metrics.custom[%d]: metric_name %q is not a valid metric name", i, cfg.MetricName)
+		}
+		if names[cfg.MetricName] {
+			return nil, fmt.Errorf("metrics.custom[%d]: metric_name %q is defined more than once", i, cfg.MetricName)
+		}
+		names[cfg.MetricName] = true
+		if err := validateAggregation("aggregation", cfg.Aggregation); err != nil {
+			return nil, fmt.Errorf("metrics.custom[%d]: %w", i, err)
+		}
+		for _, dimension := range cfg.Dimensions {
+			if dimension == "" {
+				return nil, fmt.Errorf("metrics.custom[%d]: dimensions must not be empty", i)
+			}
+		}
+		if _, exists := cfg.StaticAttributes[""]; exists {
+			return nil, fmt.Errorf("metrics.custom[%d]: static_attributes must not have an empty key", i)
+		}
+		customs = append(customs, customMetric{config: cfg, sampleType: sampleType})
+	}
+	return customs, nil
+}
+
+// generateCustomMetrics emits the custom metrics whose sample_type pattern matches the profile's
+// sample type: one gauge data point per combination of dimension values, aggregating the first
+// value of the samples
+func (c *Converter) generateCustomMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	sampleType, _ := getProfileSampleTypeCommon(profiles, profile)
+	for _, custom := range c.customMetrics {
+		if custom.sampleType.MatchString(sampleType) {
+			c.generateCustomMetric(profiles, profile, attributes, scopeMetrics, custom.config, sampleType)
+		}
+	}
+}
+
+// generateCustomMetric emits one custom metric of a profile
+func (c *Converter) generateCustomMetric(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+	cfg CustomMetricConfig,
+	sampleType string,
+) {
+	series := make(map[string]*customSeries)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		if sample.Values().Len() == 0 {
+			continue
+		}
+		value := float64(sample.Values().At(0))
+
+		dimensions := make(map[string]string, len(cfg.Dimensions))
+		values := make([]string, len(cfg.Dimensions))
+		for j, dimension := range cfg.Dimensions {
+			key, dimensionValue := c.customDimension(profiles, sample, dimension)
+			if dimensionValue != "" {
+				dimensions[key] = dimensionValue
+			}
+			values[j] = dimensionValue
+		}
+		seriesKey := strings.Join(values, "\x00")
+		s, exists := series[seriesKey]
+		if !exists {
+			s = &customSeries{attributes: dimensions, values: newValueAggregator(cfg.Aggregation)}
+			series[seriesKey] = s
+		}
+		s.values.add(value)
+	}
+	if len(series) == 0 {
+		return
+	}
+
+	seriesKeys := make([]string, 0, len(series))
+	for seriesKey := range series {
+		seriesKeys = append(seriesKeys, seriesKey)
+	}
+	sort.Strings(seriesKeys)
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(cfg.MetricName)
+	metric.SetDescription(fmt.Sprintf("%s of the %s sample values", effectiveAggregation(cfg.Aggregation), sampleType))
+	metric.SetUnit(cfg.Unit)
+	if additiveAggregation(cfg.Aggregation) {
+		c.markAdditive(metric)
+	}
+	gauge := metric.SetEmptyGauge()
+	for _, seriesKey := range seriesKeys {
+		s := series[seriesKey]
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(s.values.value())
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		for k, v := range s.attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+	}
+}
+
+// customDimension resolves a dimension of a sample to its data point attribute key and value:
+// function.name is the leaf function, any other dimension a sample attribute, emitted under the
+// same key as by the built-in metrics
+func (c *Converter) customDimension(profiles dictionaryProvider, sample pprofile.Sample, dimension string) (string, string) {
+	if dimension == customFunctionDimension {
+		return c.codeAttributeKeys().functionName, c.getSampleFunctionName(profiles, sample)
+	}
+	key := dimension
+	if outputKey, exists := sampleAttributeOutputKeys[dimension]; exists {
+		key = outputKey
+	}
+	return key, c.getSampleAttributeValue(profiles, sample, dimension)
+}
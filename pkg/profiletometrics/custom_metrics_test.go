@@ -0,0 +1,126 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// customDataPoints returns the value of each data point of a metric by its attribute map
+func customDataPoints(metrics pmetric.Metrics, name string) []map[string]any {
+	var points []map[string]any
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() != name {
+			continue
+		}
+		dataPoints := metricSlice.At(i).Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			point := dataPoints.At(j).Attributes().AsRaw()
+			point["value"] = dataPoints.At(j).DoubleValue()
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+func TestConverter_CustomMetrics(t *testing.T) {
+	tests := []struct {
+		name        string
+		aggregation string
+		expected    []float64
+	}{
+		{name: "sum by default", expected: []float64{30, 4}},
+		{name: "avg", aggregation: "avg", expected: []float64{15, 4}},
+		{name: "max", aggregation: "max", expected: []float64{20, 4}},
+		{name: "min", aggregation: "min", expected: []float64{10, 4}},
+		{name: "count", aggregation: "count", expected: []float64{2, 1}},
+		{name: "p95", aggregation: "p95", expected: []float64{19.5, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					Custom: []CustomMetricConfig{{
+						SampleType:  "^goroutines?$",
+						MetricName:  "goroutines",
+						Unit:        "{goroutine}",
+						Aggregation: tt.aggregation,
+						Dimensions:  []string{"process.executable.name", "function.name"},
+					}},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType("goroutine", "count")
+			app := map[string]string{"process.executable.name": "app"}
+			b.sample(b.stack("main.main", "main.serve"), app, 10)
+			b.sample(b.stack("main.main", "main.serve"), app, 20)
+			b.sample(b.stack("main.main", "main.poll"), app, 4)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			require.Equal(t, 1, metricSlice.Len())
+			assert.Equal(t, "{goroutine}", metricSlice.At(0).Unit())
+			assert.Equal(t, []map[string]any{
+				{"process.name": "app", "function.name": "main.poll", "value": tt.expected[1]},
+				{"process.name": "app", "function.name": "main.serve", "value": tt.expected[0]},
+			}, customDataPoints(metrics, "goroutines"))
+		})
+	}
+}
+
+func TestConverter_CustomMetricsSampleTypeMismatch(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Custom: []CustomMetricConfig{{SampleType: "^goroutine$", MetricName: "goroutines"}},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main.main"), nil, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Empty(t, customDataPoints(metrics, "goroutines"))
+}
+
+func TestCompileCustomMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  CustomMetricConfig
+		wantErr string
+	}{
+		{name: "valid", config: CustomMetricConfig{SampleType: "alloc_.*", MetricName: "allocs", Aggregation: "max"}},
+		{name: "missing sample type", config: CustomMetricConfig{MetricName: "allocs"}, wantErr: "sample_type must be set"},
+		{name: "invalid pattern", config: CustomMetricConfig{SampleType: "(", MetricName: "allocs"}, wantErr: "invalid sample_type pattern"},
+		{name: "missing metric name", config: CustomMetricConfig{SampleType: "alloc"}, wantErr: `metric_name "" is not a valid metric name`},
+		{name: "invalid aggregation", config: CustomMetricConfig{SampleType: "alloc", MetricName: "allocs", Aggregation: "p99"}, wantErr: "invalid aggregation"},
+		{name: "empty dimension", config: CustomMetricConfig{SampleType: "alloc", MetricName: "allocs", Dimensions: []string{""}}, wantErr: "dimensions must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileCustomMetrics([]CustomMetricConfig{tt.config})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "metrics.custom[0]: "+tt.wantErr)
+			}
+		})
+	}
+
+	_, err := compileCustomMetrics([]CustomMetricConfig{
+		{SampleType: "a", MetricName: "allocs"},
+		{SampleType: "b", MetricName: "allocs"},
+	})
+	assert.ErrorContains(t, err, `metrics.custom[1]: metric_name "allocs" is defined more than once`)
+}
@@ -0,0 +1,60 @@
+package profiletometrics
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// conversionSummary aggregates per-conversion diagnostics so that hot loops over samples
+// only bump counters instead of building zap fields for every sample
+type conversionSummary struct {
+	profiles               atomic.Int64
+	sampleEvaluations      atomic.Int64
+	filteredSamples        atomic.Int64
+	samplesWithoutValues   atomic.Int64
+	estimatedCPUSamples    atomic.Int64
+	estimatedMemorySamples atomic.Int64
+	unresolvedFunctions    atomic.Int64
+	truncatedStacks        atomic.Int64
+	// Ingestion counters count every sample once, unlike the per-evaluation counters above
+	samples                  atomic.Int64
+	droppedSamples           atomic.Int64
+	sampledOutProfiles       atomic.Int64
+	missingValueSamples      atomic.Int64
+	dictionaryLookupFailures atomic.Int64
+}
+
+// fields returns the summary as zap fields for a single debug log line
+func (s *conversionSummary) fields() []zap.Field {
+	return []zap.Field{
+		zap.Int64("profiles", s.profiles.Load()),
+		zap.Int64("sample_evaluations", s.sampleEvaluations.Load()),
+		zap.Int64("filtered_samples", s.filteredSamples.Load()),
+		zap.Int64("samples_without_values", s.samplesWithoutValues.Load()),
+		zap.Int64("estimated_cpu_samples", s.estimatedCPUSamples.Load()),
+		zap.Int64("estimated_memory_samples", s.estimatedMemorySamples.Load()),
+		zap.Int64("unresolved_functions", s.unresolvedFunctions.Load()),
+		zap.Int64("truncated_stacks", s.truncatedStacks.Load()),
+		zap.Int64("samples", s.samples.Load()),
+		zap.Int64("dropped_samples", s.droppedSamples.Load()),
+		zap.Int64("sampled_out_profiles", s.sampledOutProfiles.Load()),
+		zap.Int64("missing_value_samples", s.missingValueSamples.Load()),
+		zap.Int64("dictionary_lookup_failures", s.dictionaryLookupFailures.Load()),
+	}
+}
+
+// debugEnabled reports whether debug logging is active, so callers can skip building expensive fields
+func (c *Converter) debugEnabled() bool {
+	return c.logger != nil && c.logger.Core().Enabled(zapcore.DebugLevel)
+}
+
+// summaryOf returns the diagnostics summary of the conversion a dictionary was handed out by, or
+// a discarded one for dictionaries used outside of a conversion
+func summaryOf(profiles dictionaryProvider) *conversionSummary {
+	if cache, ok := profiles.(*dictionaryCache); ok && cache.summary != nil {
+		return cache.summary
+	}
+	return &conversionSummary{}
+}
@@ -0,0 +1,181 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// Degradation steps, in the order load is shed; each step includes the previous ones
+const (
+	degradationNormal = iota
+	degradationDropFunctionMetrics
+	degradationDropThreadMetrics
+	degradationDownsampleSamples
+	degradationDropProfiles
+)
+
+const (
+	defaultDegradationMetricPrefix        = "profiletometrics.degradation"
+	defaultDegradationRecoveryConversions = 3
+	defaultDegradationDownsampleRatio     = 10
+
+	// degradationStepUnit is the unit of the degradation level gauge
+	degradationStepUnit = "{step}"
+
+	// heapObjectsMetric is the runtime metric read as the memory pressure signal
+	heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+)
+
+// degradationStepNames are the degradation.step attribute values of the steps
+var degradationStepNames = []string{
+	"normal",
+	"drop_function_metrics",
+	"drop_thread_metrics",
+	"downsample_samples",
+	"drop_profiles",
+}
+
+// validateDegradation checks the degradation thresholds
+func validateDegradation(cfg DegradationConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.LatencyThreshold < 0 || cfg.MemoryLimitMiB < 0 || cfg.RecoveryConversions < 0 || cfg.DownsampleRatio < 0 {
+		return fmt.Errorf("degradation thresholds must not be negative")
+	}
+	if cfg.LatencyThreshold == 0 && cfg.MemoryLimitMiB == 0 {
+		return fmt.Errorf("degradation requires latency_threshold or memory_limit_mib when enabled")
+	}
+	return nil
+}
+
+// degradationLadder moves one step up the degradation ladder after every overloaded conversion and
+// one step down after recovery_conversions consecutive healthy ones
+type degradationLadder struct {
+	cfg  DegradationConfig
+	step atomic.Int32
+
+	mu      sync.Mutex
+	healthy int
+	// heapBytes reads the live heap size, replaced in tests
+	heapBytes func() uint64
+}
+
+func newDegradationLadder(cfg DegradationConfig) *degradationLadder {
+	if cfg.RecoveryConversions == 0 {
+		cfg.RecoveryConversions = defaultDegradationRecoveryConversions
+	}
+	if cfg.DownsampleRatio == 0 {
+		cfg.DownsampleRatio = defaultDegradationDownsampleRatio
+	}
+	return &degradationLadder{cfg: cfg, heapBytes: readHeapBytes}
+}
+
+// readHeapBytes returns the bytes occupied by heap objects
+func readHeapBytes() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// overloaded reports whether a conversion latency or the heap size crosses a threshold
+func (l *degradationLadder) overloaded(latency time.Duration) bool {
+	if l.cfg.LatencyThreshold > 0 && latency > l.cfg.LatencyThreshold {
+		return true
+	}
+	return l.cfg.MemoryLimitMiB > 0 && l.heapBytes() > uint64(l.cfg.MemoryLimitMiB)<<20
+}
+
+// record evaluates the signals after a conversion and returns the previous and the new step
+func (l *degradationLadder) record(latency time.Duration) (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous := int(l.step.Load())
+	next := previous
+	if l.overloaded(latency) {
+		l.healthy = 0
+		next = min(previous+1, degradationDropProfiles)
+	} else if previous > degradationNormal {
+		l.healthy++
+		if l.healthy >= l.cfg.RecoveryConversions {
+			l.healthy = 0
+			next = previous - 1
+		}
+	}
+	l.step.Store(int32(next))
+	return previous, next
+}
+
+// degradationStep returns the degradation step in effect, normal when degradation is disabled
+func (c *Converter) degradationStep() int {
+	if c.degradation == nil {
+		return degradationNormal
+	}
+	return int(c.degradation.step.Load())
+}
+
+// downsampleProfile keeps one sample in downsample_ratio, counting the others as dropped
+func (c *Converter) downsampleProfile(profile pprofile.Profile, summary *conversionSummary) pprofile.Profile {
+	ratio := c.degradation.cfg.DownsampleRatio
+	index := 0
+	profile, dropped := filterProfileSamples(profile, func(pprofile.Sample) bool {
+		keep := index%ratio == 0
+		index++
+		return keep
+	})
+	summary.droppedSamples.Add(int64(dropped))
+	return profile
+}
+
+// recordDegradation moves along the degradation ladder after a conversion and emits the step in
+// effect for the next conversion along with the transitions, tagged with degradation.step
+func (c *Converter) recordDegradation(start time.Time, resourceMetrics pmetric.ResourceMetrics) {
+	previous, next := c.degradation.record(time.Since(start))
+	if next != previous {
+		c.logWarn("Profile conversion degradation step changed",
+			zap.String("from", degradationStepNames[previous]),
+			zap.String("to", degradationStepNames[next]))
+	}
+
+	prefix := c.config.Degradation.MetricPrefix
+	if prefix == "" {
+		prefix = defaultDegradationMetricPrefix
+	}
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("profiletometrics")
+	scopeMetrics.Scope().SetVersion(converterVersion)
+	timestamp := pcommon.NewTimestampFromTime(time.Now())
+
+	level := scopeMetrics.Metrics().AppendEmpty()
+	level.SetName(prefix + ".level")
+	level.SetDescription("Degradation step in effect (0 = normal, 4 = profiles dropped)")
+	level.SetUnit(degradationStepUnit)
+	dataPoint := level.SetEmptyGauge().DataPoints().AppendEmpty()
+	dataPoint.SetTimestamp(timestamp)
+	dataPoint.SetDoubleValue(float64(next))
+	dataPoint.Attributes().PutStr("degradation.step", degradationStepNames[next])
+
+	transitions := scopeMetrics.Metrics().AppendEmpty()
+	transitions.SetName(prefix + ".transitions")
+	transitions.SetDescription("Number of degradation step changes")
+	transitions.SetUnit("{transition}")
+	c.markAdditive(transitions)
+	dataPoint = transitions.SetEmptyGauge().DataPoints().AppendEmpty()
+	dataPoint.SetTimestamp(timestamp)
+	if next != previous {
+		dataPoint.SetDoubleValue(1)
+	}
+	dataPoint.Attributes().PutStr("degradation.step", degradationStepNames[next])
+}
@@ -0,0 +1,143 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestValidateDegradation(t *testing.T) {
+	assert.NoError(t, validateDegradation(DegradationConfig{}))
+	assert.NoError(t, validateDegradation(DegradationConfig{Enabled: true, LatencyThreshold: time.Second}))
+	assert.Error(t, validateDegradation(DegradationConfig{Enabled: true}))
+	assert.Error(t, validateDegradation(DegradationConfig{Enabled: true, MemoryLimitMiB: -1, LatencyThreshold: time.Second}))
+
+	_, err := NewConverter(&ConverterConfig{Degradation: DegradationConfig{Enabled: true}})
+	assert.Error(t, err)
+}
+
+func TestDegradationLadder_Record(t *testing.T) {
+	ladder := newDegradationLadder(DegradationConfig{Enabled: true, LatencyThreshold: time.Second, RecoveryConversions: 2})
+
+	// Every overloaded conversion takes one step, up to dropping profiles
+	for step := 1; step <= degradationDropProfiles+1; step++ {
+		_, next := ladder.record(2 * time.Second)
+		assert.Equal(t, min(step, degradationDropProfiles), next)
+	}
+
+	// Recovery takes recovery_conversions healthy conversions per step
+	previous, next := ladder.record(time.Millisecond)
+	assert.Equal(t, degradationDropProfiles, previous)
+	assert.Equal(t, degradationDropProfiles, next)
+	_, next = ladder.record(time.Millisecond)
+	assert.Equal(t, degradationDownsampleSamples, next)
+
+	// An overloaded conversion resets the healthy streak
+	_, next = ladder.record(time.Millisecond)
+	assert.Equal(t, degradationDownsampleSamples, next)
+	_, next = ladder.record(2 * time.Second)
+	assert.Equal(t, degradationDropProfiles, next)
+}
+
+func TestDegradationLadder_MemoryPressure(t *testing.T) {
+	ladder := newDegradationLadder(DegradationConfig{Enabled: true, MemoryLimitMiB: 64})
+	ladder.heapBytes = func() uint64 { return 32 << 20 }
+	_, next := ladder.record(time.Hour)
+	assert.Equal(t, degradationNormal, next, "latency is ignored without a threshold")
+
+	ladder.heapBytes = func() uint64 { return 128 << 20 }
+	_, next = ladder.record(0)
+	assert.Equal(t, degradationDropFunctionMetrics, next)
+}
+
+func TestConverter_Degradation(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Thread:   ThreadMetricConfig{Enabled: true, MetricNameSuffix: ".by_thread"},
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+		},
+		Degradation: DegradationConfig{Enabled: true, MemoryLimitMiB: 1, DownsampleRatio: 2},
+	})
+	require.NoError(t, err)
+	// Keep the heap above the limit so that every conversion takes a step
+	converter.degradation.heapBytes = func() uint64 { return 2 << 20 }
+
+	newProfiles := func() pprofile.Profiles {
+		b := newTestProfileBuilder()
+		for i := 0; i < 4; i++ {
+			b.sample(b.stack("main", "work"), map[string]string{"process.executable.name": "app", "thread.name": "worker"}, 1000000000)
+		}
+		return b.profiles
+	}
+
+	tests := []struct {
+		nextStep        string
+		functionMetrics bool
+		threadMetrics   bool
+		cpuSeconds      float64
+	}{
+		{nextStep: "drop_function_metrics", functionMetrics: true, threadMetrics: true, cpuSeconds: 4},
+		{nextStep: "drop_thread_metrics", threadMetrics: true, cpuSeconds: 4},
+		{nextStep: "downsample_samples", cpuSeconds: 4},
+		{nextStep: "drop_profiles", cpuSeconds: 2},
+		{nextStep: "drop_profiles"},
+	}
+	for i, tt := range tests {
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles())
+		require.NoError(t, err)
+
+		names := degradationMetricNames(metrics)
+		assert.Equal(t, tt.functionMetrics, names["cpu_time.by_function"], "conversion %d", i)
+		assert.Equal(t, tt.threadMetrics, names["cpu_time.by_thread"], "conversion %d", i)
+		cpu, found := degradationGauge(metrics, "cpu_time")
+		if tt.cpuSeconds == 0 {
+			assert.False(t, found, "conversion %d", i)
+		} else {
+			require.True(t, found, "conversion %d", i)
+			assert.InDelta(t, tt.cpuSeconds, cpu.DoubleValue(), 1e-9, "conversion %d", i)
+		}
+
+		level, found := degradationGauge(metrics, "profiletometrics.degradation.level")
+		require.True(t, found)
+		step, _ := level.Attributes().Get("degradation.step")
+		assert.Equal(t, tt.nextStep, step.Str(), "conversion %d", i)
+		_, found = degradationGauge(metrics, "profiletometrics.degradation.transitions")
+		assert.True(t, found)
+	}
+}
+
+// degradationMetricNames returns the names of the emitted metrics
+func degradationMetricNames(metrics pmetric.Metrics) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				names[scopeMetrics.At(j).Metrics().At(k).Name()] = true
+			}
+		}
+	}
+	return names
+}
+
+// degradationGauge returns the first data point of the named gauge
+func degradationGauge(metrics pmetric.Metrics, name string) (pmetric.NumberDataPoint, bool) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() == name && metric.Gauge().DataPoints().Len() > 0 {
+					return metric.Gauge().DataPoints().At(0), true
+				}
+			}
+		}
+	}
+	return pmetric.NumberDataPoint{}, false
+}
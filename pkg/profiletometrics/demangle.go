@@ -0,0 +1,43 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"github.com/ianlancetaylor/demangle"
+)
+
+const (
+	// Modes of symbol.demangle; an empty value means none
+	symbolDemangleNone  = "none"
+	symbolDemangleShort = "short"
+	symbolDemangleFull  = "full"
+)
+
+// validateSymbol checks the symbol options
+func validateSymbol(cfg SymbolConfig) error {
+	switch cfg.Demangle {
+	case "", symbolDemangleNone, symbolDemangleShort, symbolDemangleFull:
+		return nil
+	default:
+		return fmt.Errorf("invalid symbol.demangle %q: must be %q, %q or %q", cfg.Demangle,
+			symbolDemangleNone, symbolDemangleShort, symbolDemangleFull)
+	}
+}
+
+// demangleSymbol renders a mangled C++ (_Z) or Rust (_R, legacy _ZN…17h<hash>E) symbol according to
+// the demangle mode; other names, and symbols that fail to demangle, are returned unchanged
+func demangleSymbol(mode, name string) string {
+	var options []demangle.Option
+	switch mode {
+	case symbolDemangleShort:
+		options = []demangle.Option{demangle.NoParams, demangle.NoTemplateParams, demangle.NoClones}
+	case symbolDemangleFull:
+	default:
+		return name
+	}
+	demangled, err := demangle.ToString(name, options...)
+	if err != nil {
+		return name
+	}
+	return demangled
+}
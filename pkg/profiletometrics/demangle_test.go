@@ -0,0 +1,81 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemangleSymbol(t *testing.T) {
+	tests := []struct {
+		mode     string
+		name     string
+		expected string
+	}{
+		{"full", "_ZN3foo3barEv", "foo::bar()"},
+		{"short", "_ZN3foo3barEv", "foo::bar"},
+		{"full", "_ZNSt6vectorIiSaIiEE9push_backERKi", "std::vector<int, std::allocator<int> >::push_back(int const&)"},
+		{"short", "_ZNSt6vectorIiSaIiEE9push_backERKi", "std::vector::push_back"},
+		{"short", "_ZN4core3fmt5write17h05af221e174051e9E", "core::fmt::write"},
+		{"short", "_RNvCs15kBYyAo9fc_7mycrate7example", "mycrate::example"},
+		{"full", "main.main", "main.main"},
+		{"none", "_ZN3foo3barEv", "_ZN3foo3barEv"},
+		{"", "_ZN3foo3barEv", "_ZN3foo3barEv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, demangleSymbol(tt.mode, tt.name))
+		})
+	}
+}
+
+func TestValidateSymbol(t *testing.T) {
+	assert.NoError(t, validateSymbol(SymbolConfig{Demangle: "short"}))
+	assert.ErrorContains(t, validateSymbol(SymbolConfig{Demangle: "pretty"}), `invalid symbol.demangle "pretty"`)
+}
+
+func TestConverter_DemangleFunctionMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+		},
+		Symbol: SymbolConfig{Demangle: "short"},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "_ZN3foo3barEv"), map[string]string{"process.executable.name": "app"}, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	var functionNames []string
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() != "cpu_time.by_function" {
+			continue
+		}
+		dataPoints := metricSlice.At(i).Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			functionName, _ := dataPoints.At(j).Attributes().Get("function.name")
+			functionNames = append(functionNames, functionName.Str())
+		}
+	}
+	assert.Equal(t, []string{"foo::bar"}, functionNames)
+}
+
+func TestTraceConverter_DemangleSpanNames(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{Symbol: SymbolConfig{Demangle: "full"}})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "_Z3fooi"), map[string]string{"process.executable.name": "app"}, 100)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	leafSpan(t, traces, "foo(int)")
+}
@@ -0,0 +1,168 @@
+package profiletometrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// diagnosticsTopFunctions is the number of functions listed per conversion
+const diagnosticsTopFunctions = 5
+
+// ConversionDiagnostics describes one conversion for troubleshooting
+type ConversionDiagnostics struct {
+	Time             time.Time `json:"time"`
+	DurationMillis   float64   `json:"duration_ms"`
+	ResourceProfiles int       `json:"resource_profiles"`
+	Profiles         int64     `json:"profiles"`
+	Samples          int       `json:"samples"`
+	DroppedSamples   int64     `json:"dropped_samples"`
+	// FilterHitRate is the share of samples dropped by the process, pattern, function and thread filters
+	FilterHitRate float64               `json:"filter_hit_rate"`
+	Metrics       int                   `json:"metrics"`
+	DataPoints    int                   `json:"data_points"`
+	TopFunctions  []FunctionDiagnostics `json:"top_functions"`
+}
+
+// FunctionDiagnostics is the CPU time of a function in a conversion, summed over its data points
+type FunctionDiagnostics struct {
+	Name    string  `json:"name"`
+	CPUTime float64 `json:"cpu_time"`
+}
+
+// diagnosticsHistory keeps the diagnostics of the last conversions, oldest first
+type diagnosticsHistory struct {
+	mu          sync.Mutex
+	size        int
+	conversions []ConversionDiagnostics
+}
+
+// add records a conversion, evicting the oldest one when the history is full
+func (h *diagnosticsHistory) add(diagnostics ConversionDiagnostics) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.conversions) == h.size {
+		h.conversions = h.conversions[1:]
+	}
+	h.conversions = append(h.conversions, diagnostics)
+}
+
+// Diagnostics returns the diagnostics of the last conversions, newest first; it is empty unless
+// diagnostics_history is set
+func (c *Converter) Diagnostics() []ConversionDiagnostics {
+	if c.diagnostics == nil {
+		return nil
+	}
+	c.diagnostics.mu.Lock()
+	defer c.diagnostics.mu.Unlock()
+	conversions := make([]ConversionDiagnostics, 0, len(c.diagnostics.conversions))
+	for i := len(c.diagnostics.conversions) - 1; i >= 0; i-- {
+		conversions = append(conversions, c.diagnostics.conversions[i])
+	}
+	return conversions
+}
+
+// DiagnosticsHandler serves the diagnostics of the last conversions as JSON
+func (c *Converter) DiagnosticsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(c.Diagnostics()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// conversionOutput tallies the metrics emitted by a conversion, possibly over several flushes
+type conversionOutput struct {
+	metrics    int
+	dataPoints int
+	// cpuTimes sums the CPU time function data points per function
+	cpuTimes map[string]float64
+}
+
+// tallyOutput adds emitted metrics to the output of a conversion
+func (c *Converter) tallyOutput(output *conversionOutput, metrics pmetric.Metrics) {
+	output.metrics += metrics.MetricCount()
+	output.dataPoints += metrics.DataPointCount()
+	if output.cpuTimes == nil {
+		output.cpuTimes = make(map[string]float64)
+	}
+
+	functionKey := c.codeAttributeKeys().functionName
+	cpuMetricName, _ := c.functionMetricNames()
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if metric.Name() != cpuMetricName {
+					continue
+				}
+				var dataPoints pmetric.NumberDataPointSlice
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					if functionName, ok := dataPoints.At(l).Attributes().Get(functionKey); ok {
+						output.cpuTimes[functionName.Str()] += dataPoints.At(l).DoubleValue()
+					}
+				}
+			}
+		}
+	}
+}
+
+// recordDiagnostics records the input sizes, filter hit rate, output and top functions of a conversion
+func (c *Converter) recordDiagnostics(
+	start time.Time,
+	profiles pprofile.Profiles,
+	output *conversionOutput,
+	summary *conversionSummary,
+) {
+	diagnostics := ConversionDiagnostics{
+		Time:             start,
+		DurationMillis:   float64(time.Since(start).Microseconds()) / 1000,
+		ResourceProfiles: profiles.ResourceProfiles().Len(),
+		Profiles:         summary.profiles.Load(),
+		Samples:          profiles.SampleCount(),
+		DroppedSamples:   summary.droppedSamples.Load(),
+		Metrics:          output.metrics,
+		DataPoints:       output.dataPoints,
+		TopFunctions:     topFunctionDiagnostics(output.cpuTimes),
+	}
+	if diagnostics.Samples > 0 {
+		diagnostics.FilterHitRate = float64(diagnostics.DroppedSamples) / float64(diagnostics.Samples)
+	}
+	c.diagnostics.add(diagnostics)
+}
+
+// topFunctionDiagnostics returns the hottest functions of a conversion
+func topFunctionDiagnostics(cpuTimes map[string]float64) []FunctionDiagnostics {
+	functions := make([]FunctionDiagnostics, 0, len(cpuTimes))
+	for name, cpuTime := range cpuTimes {
+		functions = append(functions, FunctionDiagnostics{Name: name, CPUTime: cpuTime})
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].CPUTime != functions[j].CPUTime {
+			return functions[i].CPUTime > functions[j].CPUTime
+		}
+		return functions[i].Name < functions[j].Name
+	})
+	if len(functions) > diagnosticsTopFunctions {
+		functions = functions[:diagnosticsTopFunctions]
+	}
+	return functions
+}
@@ -0,0 +1,63 @@
+package profiletometrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_Diagnostics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		FunctionFilter:     FunctionFilterConfig{Enabled: true, Exclude: []string{`^runtime\.`}},
+		DiagnosticsHistory: 2,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, converter.Diagnostics())
+
+	for _, batch := range []int64{1, 2, 3} {
+		b := newTestProfileBuilder()
+		process := map[string]string{"process.executable.name": "app"}
+		b.sample(b.stack("main.main", "main.hot"), process, 3000000000*batch)
+		b.sample(b.stack("main.main", "main.cold"), process, 1000000000*batch)
+		b.sample(b.stack("main.main", "runtime.mallocgc"), process, 1000000000)
+		b.sample(b.stack("main.main", "runtime.gcDrain"), process, 1000000000)
+		_, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+	}
+
+	conversions := converter.Diagnostics()
+	require.Len(t, conversions, 2)
+	latest := conversions[0]
+	assert.Equal(t, 1, latest.ResourceProfiles)
+	assert.Equal(t, int64(1), latest.Profiles)
+	assert.Equal(t, 4, latest.Samples)
+	assert.Equal(t, int64(2), latest.DroppedSamples)
+	assert.InDelta(t, 0.5, latest.FilterHitRate, 1e-9)
+	assert.Positive(t, latest.DataPoints)
+	assert.Equal(t, []FunctionDiagnostics{
+		{Name: "main.hot", CPUTime: 9},
+		{Name: "main.cold", CPUTime: 3},
+	}, latest.TopFunctions)
+	assert.True(t, !latest.Time.Before(conversions[1].Time))
+
+	recorder := httptest.NewRecorder()
+	converter.DiagnosticsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var served []ConversionDiagnostics
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &served))
+	assert.Len(t, served, 2)
+}
+
+func TestNewConverter_NegativeDiagnosticsHistory(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{DiagnosticsHistory: -1})
+	assert.Error(t, err)
+}
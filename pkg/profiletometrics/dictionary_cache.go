@@ -0,0 +1,110 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// dictionaryCache memoizes dictionary lookups for the duration of one conversion: the function name
+// resolved for each stack, and the key and value of each attribute table entry. It wraps the
+// dictionary handed to the per-profile code, so every helper taking a dictionaryProvider benefits;
+// helpers look the cache up with a type assertion. Entries are indexed like the dictionary tables.
+// A cache belongs to a single converter, whose frame selection decides the function of a stack, and
+// is not safe for concurrent use. A dictionary gets one cache per set of function name rules.
+type dictionaryCache struct {
+	dictionaryProvider
+	// summary counts the diagnostics of the conversion the cache belongs to, nil outside of one
+	summary *conversionSummary
+
+	stackFunctions []cachedStackFunction
+	attributes     []cachedAttribute
+	// rules rewrite the function names, memoized in normalizedNames
+	rules           functionNameRules
+	normalizedNames map[string]string
+}
+
+// cachedStackFunction is the function name resolved for a stack
+type cachedStackFunction struct {
+	name     string
+	resolved bool
+}
+
+// cachedAttribute is a resolved attribute table entry; ok is false for entries whose key points
+// outside of the string table
+type cachedAttribute struct {
+	key      string
+	value    pcommon.Value
+	ok       bool
+	resolved bool
+}
+
+// newDictionaryCache wraps a dictionary with an empty cache rewriting function names with rules
+func newDictionaryCache(dictionary dictionaryProvider, rules functionNameRules) *dictionaryCache {
+	return &dictionaryCache{
+		dictionaryProvider: dictionary,
+		stackFunctions:     make([]cachedStackFunction, dictionary.Dictionary().StackTable().Len()),
+		attributes:         make([]cachedAttribute, dictionary.Dictionary().AttributeTable().Len()),
+		rules:              rules,
+	}
+}
+
+// dictionaryCacheKey identifies the cache of a dictionary and function name rules
+type dictionaryCacheKey struct {
+	dictionary dictionaryProvider
+	rules      functionNameRules
+}
+
+// dictionaryCaches hands out one cache per dictionary and function name rules of a conversion
+type dictionaryCaches map[dictionaryCacheKey]*dictionaryCache
+
+// get returns the cache wrapping a dictionary for function name rules, creating it on first use
+func (caches dictionaryCaches) get(dictionary dictionaryProvider, rules functionNameRules) *dictionaryCache {
+	key := dictionaryCacheKey{dictionary: dictionary, rules: rules}
+	cache, exists := caches[key]
+	if !exists {
+		cache = newDictionaryCache(dictionary, rules)
+		caches[key] = cache
+	}
+	return cache
+}
+
+// cachedStackFunctionCommon returns the function name of a stack, resolving it once per conversion
+// when profiles carries a cache
+func cachedStackFunctionCommon(profiles dictionaryProvider, stackIndex int32, resolve func() string) string {
+	cache, ok := profiles.(*dictionaryCache)
+	if !ok || stackIndex < 0 || int(stackIndex) >= len(cache.stackFunctions) {
+		return resolve()
+	}
+	entry := &cache.stackFunctions[stackIndex]
+	if !entry.resolved {
+		*entry = cachedStackFunction{name: resolve(), resolved: true}
+	}
+	return entry.name
+}
+
+// attributeAtCommon returns the key and value of an attribute table entry, or false when the index
+// or the key points outside of the dictionary tables. Entries are resolved once per conversion when
+// profiles carries a cache.
+func attributeAtCommon(profiles dictionaryProvider, attrIndex int32) (string, pcommon.Value, bool) {
+	var entry *cachedAttribute
+	if cache, ok := profiles.(*dictionaryCache); ok && attrIndex >= 0 && int(attrIndex) < len(cache.attributes) {
+		entry = &cache.attributes[attrIndex]
+		if entry.resolved {
+			return entry.key, entry.value, entry.ok
+		}
+	}
+
+	attribute := cachedAttribute{resolved: true}
+	dictionary := profiles.Dictionary()
+	attributeTable := dictionary.AttributeTable()
+	stringTable := dictionary.StringTable()
+	if attrIndex >= 0 && int(attrIndex) < attributeTable.Len() {
+		attr := attributeTable.At(int(attrIndex))
+		if keyIndex := attr.KeyStrindex(); keyIndex >= 0 && int(keyIndex) < stringTable.Len() {
+			attribute.key, attribute.value, attribute.ok = stringTable.At(int(keyIndex)), attr.Value(), true
+		}
+	}
+	if entry != nil {
+		*entry = attribute
+	}
+	return attribute.key, attribute.value, attribute.ok
+}
@@ -0,0 +1,55 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictionaryCache_StackFunctions(t *testing.T) {
+	b := newTestProfileBuilder()
+	stack := b.stack("main", "handler")
+
+	resolves := 0
+	resolve := func() string {
+		resolves++
+		return "handler"
+	}
+	assert.Equal(t, "handler", cachedStackFunctionCommon(b.profiles, stack, resolve))
+	assert.Equal(t, "handler", cachedStackFunctionCommon(b.profiles, stack, resolve))
+	assert.Equal(t, 2, resolves, "without a cache every lookup resolves")
+
+	cache := newDictionaryCache(b.profiles, functionNameRules{})
+	resolves = 0
+	assert.Equal(t, "handler", cachedStackFunctionCommon(cache, stack, resolve))
+	assert.Equal(t, "handler", cachedStackFunctionCommon(cache, stack, resolve))
+	assert.Equal(t, 1, resolves)
+}
+
+func TestDictionaryCache_Attributes(t *testing.T) {
+	b := newTestProfileBuilder()
+	sample := b.sample(b.stack("main"), map[string]string{"process.executable.name": "api", "thread.name": "worker"}, 1)
+	cache := newDictionaryCache(b.profiles, functionNameRules{})
+
+	for _, profiles := range []dictionaryProvider{b.profiles, cache, cache} {
+		assert.Equal(t, "api", getSampleAttributeValueCommon(profiles, sample, "process.executable.name"))
+		assert.Equal(t, "worker", getSampleAttributeValueCommon(profiles, sample, "thread.name"))
+		assert.Empty(t, getSampleAttributeValueCommon(profiles, sample, "missing"))
+	}
+	for _, attribute := range cache.attributes {
+		assert.True(t, attribute.resolved)
+	}
+
+	_, _, ok := attributeAtCommon(cache, 1000)
+	assert.False(t, ok, "out of range indices are not resolved")
+}
+
+func TestDictionaryCaches(t *testing.T) {
+	b := newTestProfileBuilder()
+	caches := make(dictionaryCaches)
+	cache := caches.get(b.profiles, functionNameRules{})
+	require.NotNil(t, cache)
+	assert.Same(t, cache, caches.get(b.profiles, functionNameRules{}))
+	assert.NotSame(t, cache, caches.get(newTestProfileBuilder().profiles, functionNameRules{}))
+}
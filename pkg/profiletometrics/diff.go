@@ -0,0 +1,175 @@
+package profiletometrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	defaultFunctionDiffBaselineTTL      = 10 * time.Minute
+	defaultFunctionDiffDeltaMetricName  = "cpu_time.delta"
+	defaultFunctionDiffChangeMetricName = "cpu_time.change"
+
+	// changeDirectionAttributeKey tells whether the CPU time of a function grew since the baseline
+	changeDirectionAttributeKey = "change.direction"
+	changeDirectionIncrease     = "increase"
+	changeDirectionDecrease     = "decrease"
+	changeDirectionUnchanged    = "unchanged"
+)
+
+// diffBaseline is the CPU time of every function of the previous profile of a resource
+type diffBaseline struct {
+	at     time.Time
+	points map[string]functionDataPoint
+}
+
+// diffTracker retains the previous profile of each resource, identified by its attributes, as the
+// baseline of the next one. Baselines older than the TTL are dropped.
+type diffTracker struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	baselines map[string]diffBaseline
+	now       func() time.Time
+}
+
+// newDiffTracker creates a tracker keeping baselines for the given TTL
+func newDiffTracker(ttl time.Duration) *diffTracker {
+	if ttl <= 0 {
+		ttl = defaultFunctionDiffBaselineTTL
+	}
+	return &diffTracker{ttl: ttl, baselines: make(map[string]diffBaseline), now: time.Now}
+}
+
+// swap stores the points of a profile as the baseline of its resource and returns the previous
+// baseline, nil when there is none within the TTL
+func (d *diffTracker) swap(resourceKey string, points map[string]functionDataPoint) map[string]functionDataPoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for key, baseline := range d.baselines {
+		if now.Sub(baseline.at) > d.ttl {
+			delete(d.baselines, key)
+		}
+	}
+	previous := d.baselines[resourceKey]
+	d.baselines[resourceKey] = diffBaseline{at: now, points: points}
+	return previous.points
+}
+
+// diffResourceKey identifies the resource of a profile by its sorted attributes
+func diffResourceKey(attributes map[string]string) string {
+	pairs := make([]string, 0, len(attributes))
+	for key, value := range attributes {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// functionDiffMetricNames returns the delta and change metric names, defaulting empty names
+func (c *Converter) functionDiffMetricNames() (string, string) {
+	diff := c.config.Metrics.Function.Diff
+	deltaName, changeName := diff.DeltaMetricName, diff.ChangeMetricName
+	if deltaName == "" {
+		deltaName = defaultFunctionDiffDeltaMetricName
+	}
+	if changeName == "" {
+		changeName = defaultFunctionDiffChangeMetricName
+	}
+	return deltaName, changeName
+}
+
+// changeDirection classifies a CPU time delta
+func changeDirection(delta float64) string {
+	switch {
+	case delta > 0:
+		return changeDirectionIncrease
+	case delta < 0:
+		return changeDirectionDecrease
+	default:
+		return changeDirectionUnchanged
+	}
+}
+
+// generateFunctionDiffMetrics compares the CPU time of the profile's (process, function) data points
+// with the previous profile of the same resource and emits the delta and the percentage change of
+// every function seen in either, tagged with change.direction. Functions that disappeared count as
+// a full decrease; functions new since the baseline have no percentage change. Only CPU profiles
+// are compared, without their thread and truncated stack data points.
+func (c *Converter) generateFunctionDiffMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	points []functionDataPoint,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	if sampleType, _ := getProfileSampleTypeCommon(profiles, profile); !isCPUSampleType(sampleType) {
+		return
+	}
+
+	current := make(map[string]functionDataPoint)
+	var keys []string
+	for _, point := range points {
+		if point.threadName != "" || point.truncated {
+			continue
+		}
+		key := point.processName + "\x00" + point.functionName + "\x00" + point.attribution
+		if existing, exists := current[key]; exists {
+			existing.cpuTime += point.cpuTime
+			current[key] = existing
+			continue
+		}
+		keys = append(keys, key)
+		current[key] = point
+	}
+	baseline := c.functionDiffs.swap(diffResourceKey(attributes), current)
+	if baseline == nil {
+		return
+	}
+	var removed []string
+	for key := range baseline {
+		if _, exists := current[key]; !exists {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+
+	deltaName, changeName := c.functionDiffMetricNames()
+	deltaMetric := scopeMetrics.Metrics().AppendEmpty()
+	deltaMetric.SetName(deltaName)
+	deltaMetric.SetDescription("Change of the CPU time of a function since the previous profile")
+	// The delta is signed and describes a single change: it is not marked additive, so it is
+	// neither accumulated by aggregation_temporality nor added up by flush_interval
+	deltaMetric.SetUnit("s")
+	deltaGauge := deltaMetric.SetEmptyGauge()
+	changeMetric := scopeMetrics.Metrics().AppendEmpty()
+	changeMetric.SetName(changeName)
+	changeMetric.SetDescription("Percentage change of the CPU time of a function since the previous profile")
+	changeMetric.SetUnit(percentUnit)
+	changeGauge := changeMetric.SetEmptyGauge()
+
+	appendChange := func(gauge pmetric.Gauge, point functionDataPoint, value float64, direction string) {
+		c.appendFunctionDataPoint(gauge, profile, attributes, point, value)
+		gauge.DataPoints().At(gauge.DataPoints().Len()-1).Attributes().PutStr(changeDirectionAttributeKey, direction)
+	}
+	for _, key := range append(keys, removed...) {
+		point, exists := current[key]
+		previous := baseline[key]
+		if !exists {
+			point = previous
+			point.cpuTime = 0
+		}
+		delta := point.cpuTime - previous.cpuTime
+		direction := changeDirection(delta)
+		appendChange(deltaGauge, point, delta, direction)
+		if previous.cpuTime > 0 {
+			appendChange(changeGauge, point, delta/previous.cpuTime*100, direction)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// diffDataPoints returns the value and change.direction of the named metric's data points by
+// function name
+func diffDataPoints(metrics pmetric.Metrics, name string) map[string][2]any {
+	points := make(map[string][2]any)
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics()
+	for i := 0; i < scopeMetrics.Len(); i++ {
+		for j := 0; j < scopeMetrics.At(i).Metrics().Len(); j++ {
+			metric := scopeMetrics.At(i).Metrics().At(j)
+			if metric.Name() != name {
+				continue
+			}
+			for k := 0; k < metric.Gauge().DataPoints().Len(); k++ {
+				dataPoint := metric.Gauge().DataPoints().At(k)
+				functionName, _ := dataPoint.Attributes().Get("function.name")
+				direction, _ := dataPoint.Attributes().Get(changeDirectionAttributeKey)
+				points[functionName.Str()] = [2]any{dataPoint.DoubleValue(), direction.Str()}
+			}
+		}
+	}
+	return points
+}
+
+func TestConverter_FunctionDiff(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{
+				Enabled: true,
+				Diff:    FunctionDiffConfig{Enabled: true},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	convert := func(cpuSeconds map[string]int64) pmetric.Metrics {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		for _, function := range []string{"encode", "parse", "render", "steady"} {
+			if seconds, ok := cpuSeconds[function]; ok {
+				b.sample(b.stack("main", function), map[string]string{"process.executable.name": "app"}, seconds*int64(time.Second))
+			}
+		}
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		return metrics
+	}
+
+	first := convert(map[string]int64{"encode": 2, "parse": 4, "steady": 1})
+	assert.Empty(t, diffDataPoints(first, "cpu_time.delta"), "the first profile only sets the baseline")
+
+	second := convert(map[string]int64{"encode": 3, "render": 1, "steady": 1})
+	assert.Equal(t, map[string][2]any{
+		"encode": {1.0, "increase"},
+		"render": {1.0, "increase"},
+		"steady": {0.0, "unchanged"},
+		"parse":  {-4.0, "decrease"},
+	}, diffDataPoints(second, "cpu_time.delta"))
+	assert.Equal(t, map[string][2]any{
+		"encode": {50.0, "increase"},
+		"steady": {0.0, "unchanged"},
+		"parse":  {-100.0, "decrease"},
+	}, diffDataPoints(second, "cpu_time.change"), "functions new since the baseline have no percentage change")
+}
+
+func TestConverter_FunctionDiffStaysGauge(t *testing.T) {
+	// convertDiff converts three profiles, the CPU time of the function falling from 4s to 2s and
+	// then 1s, and returns the cpu_time.delta metric of the last conversion, or of the interval
+	// when an aggregator is given
+	convertDiff := func(t *testing.T, converter *Converter, aggregator *IntervalAggregator) pmetric.Metric {
+		var metrics pmetric.Metrics
+		for _, seconds := range []int64{4, 2, 1} {
+			b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+			b.sample(b.stack("main", "parse"), map[string]string{"process.executable.name": "app"}, seconds*int64(time.Second))
+			converted, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+			metrics = converted
+			if aggregator != nil {
+				aggregator.Add(converted)
+			}
+		}
+		if aggregator != nil {
+			metrics = aggregator.Flush()
+		}
+		metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		for i := 0; i < metricSlice.Len(); i++ {
+			if metric := metricSlice.At(i); metric.Name() == "cpu_time.delta" {
+				require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+				return metric
+			}
+		}
+		require.Fail(t, "no cpu_time.delta metric")
+		return pmetric.Metric{}
+	}
+	cfg := ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{MetricName: "cpu_time"},
+			Function: FunctionMetricConfig{Enabled: true, Diff: FunctionDiffConfig{Enabled: true}},
+		},
+	}
+
+	t.Run("aggregation_temporality", func(t *testing.T) {
+		temporalityCfg := cfg
+		temporalityCfg.AggregationTemporality = aggregationTemporalityCumulative
+		converter, err := NewConverter(&temporalityCfg)
+		require.NoError(t, err)
+
+		// A smaller decrease is not a counter reset
+		metric := convertDiff(t, converter, nil)
+		assert.Equal(t, -1.0, metric.Gauge().DataPoints().At(0).DoubleValue())
+	})
+
+	t.Run("flush_interval", func(t *testing.T) {
+		converter, err := NewConverter(&cfg)
+		require.NoError(t, err)
+
+		metric := convertDiff(t, converter, NewIntervalAggregator(converter))
+		assert.Equal(t, -1.0, metric.Gauge().DataPoints().At(0).DoubleValue(), "changes are not added up")
+	})
+}
+
+func TestDiffTracker_BaselineTTL(t *testing.T) {
+	tracker := newDiffTracker(time.Minute)
+	now := time.Unix(1700000000, 0)
+	tracker.now = func() time.Time { return now }
+
+	baseline := map[string]functionDataPoint{"app\x00main\x00": {cpuTime: 1}}
+	assert.Nil(t, tracker.swap("service.name=checkout", baseline))
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, baseline, tracker.swap("service.name=checkout", baseline))
+	now = now.Add(2 * time.Minute)
+	assert.Nil(t, tracker.swap("service.name=checkout", baseline), "expired baselines are dropped")
+}
+
+func TestNewConverter_FunctionDiffRequiresFunctionMetrics(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Function: FunctionMetricConfig{Diff: FunctionDiffConfig{Enabled: true}}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// estimatedMetricSuffix names the metrics holding estimated contributions when reported separately
+const estimatedMetricSuffix = ".estimated"
+
+// defaultEstimatedAllocationBytes is the memory allocation estimated for a sample without values
+const defaultEstimatedAllocationBytes = 2048
+
+// validateEstimation checks the estimation defaults
+func validateEstimation(cfg EstimationConfig) error {
+	if cfg.SampleDuration < 0 {
+		return fmt.Errorf("estimation.sample_duration must not be negative")
+	}
+	if cfg.AllocationBytes < 0 {
+		return fmt.Errorf("estimation.allocation_bytes must not be negative")
+	}
+	return nil
+}
+
+// estimatedSampleCPUTime returns the CPU time in seconds estimated for a sample without values: the
+// configured sample duration, or an equal share of one second among the profile's samples. It is
+// 0 unless estimation is enabled.
+func (c *Converter) estimatedSampleCPUTime(summary *conversionSummary, sampleCount int) float64 {
+	cfg := c.config.Estimation
+	if !cfg.Enabled || sampleCount <= 0 {
+		return 0
+	}
+	summary.estimatedCPUSamples.Add(1)
+	if cfg.SampleDuration > 0 {
+		return cfg.SampleDuration.Seconds()
+	}
+	return 1.0 / float64(sampleCount)
+}
+
+// estimatedSampleMemoryAllocation returns the memory allocation in bytes estimated for a sample
+// without values; it is 0 unless estimation is enabled
+func (c *Converter) estimatedSampleMemoryAllocation(summary *conversionSummary) float64 {
+	cfg := c.config.Estimation
+	if !cfg.Enabled {
+		return 0
+	}
+	summary.estimatedMemorySamples.Add(1)
+	if cfg.AllocationBytes > 0 {
+		return float64(cfg.AllocationBytes)
+	}
+	return defaultEstimatedAllocationBytes
+}
+
+// generateEstimatedGaugeMetrics emits a measured total and its estimated contribution: as one gauge,
+// or, with estimation.separate_metrics, as the measured gauge plus a <name>.estimated gauge
+func (c *Converter) generateEstimatedGaugeMetrics(
+	name, description string,
+	measured, estimated float64,
+	attributes map[string]string,
+	profile pprofile.Profile,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	if !c.config.Estimation.SeparateMetrics {
+		c.markAdditive(c.generateGaugeMetric(name, description, measured+estimated, attributes, profile, scopeMetrics))
+		return
+	}
+	c.markAdditive(c.generateGaugeMetric(name, description, measured, attributes, profile, scopeMetrics))
+	if estimated > 0 {
+		c.markAdditive(c.generateGaugeMetric(name+estimatedMetricSuffix, description+", estimated for samples without values",
+			estimated, attributes, profile, scopeMetrics))
+	}
+}
@@ -0,0 +1,129 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_Estimation(t *testing.T) {
+	tests := []struct {
+		name           string
+		estimation     EstimationConfig
+		expectedCPU    float64
+		expectedMemory float64
+	}{
+		{
+			name:           "disabled",
+			expectedCPU:    1,
+			expectedMemory: 512,
+		},
+		{
+			name:           "share of one second",
+			estimation:     EstimationConfig{Enabled: true},
+			expectedCPU:    1.5,
+			expectedMemory: 512 + 2048,
+		},
+		{
+			name:           "configured defaults",
+			estimation:     EstimationConfig{Enabled: true, SampleDuration: 10 * time.Millisecond, AllocationBytes: 100},
+			expectedCPU:    1.01,
+			expectedMemory: 612,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+				},
+				Estimation: tt.estimation,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			stack := b.stack("main")
+			b.sample(stack, nil, 1000000000, 512)
+			b.sample(stack, nil)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			values := make(map[string]float64)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				values[metricSlice.At(i).Name()] = metricSlice.At(i).Gauge().DataPoints().At(0).DoubleValue()
+			}
+			assert.InDelta(t, tt.expectedCPU, values["cpu_time"], 1e-9)
+			assert.InDelta(t, tt.expectedMemory, values["memory_allocation"], 1e-9)
+		})
+	}
+}
+
+func TestConverter_EstimationSeparateMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Process:  ProcessMetricConfig{Enabled: true},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		Estimation: EstimationConfig{Enabled: true, SampleDuration: 10 * time.Millisecond, AllocationBytes: 100, SeparateMetrics: true},
+		Provenance: true,
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	stack := b.stack("main")
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(stack, app, 1000000000, 512)
+	b.sample(stack, app)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	type series struct {
+		name   string
+		scope  string
+		source string
+	}
+	values := make(map[series]float64)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		dataPoints := metricSlice.At(i).Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			attributes := dataPoints.At(j).Attributes()
+			key := series{name: metricSlice.At(i).Name(), scope: "profile"}
+			if _, ok := attributes.Get("function.name"); ok {
+				key.scope = "function"
+			} else if _, ok := attributes.Get("process.name"); ok {
+				key.scope = "process"
+			}
+			source, _ := attributes.Get(valueSourceAttributeKey)
+			key.source = source.Str()
+			values[key] += dataPoints.At(j).DoubleValue()
+		}
+	}
+	assert.Equal(t, map[series]float64{
+		{"cpu_time", "profile", "measured"}:                     1,
+		{"cpu_time.estimated", "profile", "estimated"}:          0.01,
+		{"memory_allocation", "profile", "measured"}:            512,
+		{"memory_allocation.estimated", "profile", "estimated"}: 100,
+		{"cpu_time", "process", "measured"}:                     1,
+		{"cpu_time.estimated", "process", "estimated"}:          0.01,
+		{"memory_allocation", "process", "measured"}:            512,
+		{"memory_allocation.estimated", "process", "estimated"}: 100,
+		{"cpu_time", "function", "measured"}:                    1,
+		{"memory_allocation", "function", "measured"}:           512,
+	}, values)
+}
+
+func TestNewConverter_InvalidEstimation(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{Estimation: EstimationConfig{Enabled: true, AllocationBytes: -1}})
+	assert.Error(t, err)
+}
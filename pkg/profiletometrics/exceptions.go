@@ -0,0 +1,96 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+const defaultExceptionTypeAttribute = "exception.type"
+
+// exceptionSampleTypes lists sample type names whose values are exception counts
+var exceptionSampleTypes = map[string]bool{
+	"exception":  true,
+	"exceptions": true,
+}
+
+// exceptionKey identifies a (process, exception type) pair
+type exceptionKey struct {
+	processName   string
+	exceptionType string
+}
+
+// generateExceptionMetrics generates exception count metrics grouped by exception type and process
+func (c *Converter) generateExceptionMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	typeAttribute := c.config.Metrics.Exceptions.TypeAttribute
+	if typeAttribute == "" {
+		typeAttribute = defaultExceptionTypeAttribute
+	}
+
+	// Exception profiles carry the event count as sample value; otherwise every sample is one exception
+	sampleType, _ := getProfileSampleTypeCommon(profiles, profile)
+	useSampleValues := exceptionSampleTypes[sampleType]
+
+	counts := make(map[exceptionKey]float64)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		exceptionType := c.getSampleAttributeValue(profiles, sample, typeAttribute)
+		if exceptionType == "" {
+			continue
+		}
+
+		count := 1.0
+		if useSampleValues && sample.Values().Len() > 0 {
+			count = float64(sample.Values().At(0))
+		}
+
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		counts[exceptionKey{processName: processName, exceptionType: exceptionType}] += count
+	}
+
+	if len(counts) == 0 {
+		c.logDebug("No exception samples found in profile", zap.String("type_attribute", typeAttribute))
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.Exceptions.MetricName)
+	metric.SetDescription("Exception count")
+	c.markAdditive(metric)
+	gauge := metric.SetEmptyGauge()
+
+	keys := make([]exceptionKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		return keys[i].exceptionType < keys[j].exceptionType
+	})
+
+	for _, key := range keys {
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(counts[key])
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if key.processName != "" {
+			dataPoint.Attributes().PutStr("process.name", key.processName)
+		}
+		dataPoint.Attributes().PutStr("exception.type", key.exceptionType)
+	}
+
+	c.logDebug("Generated exception metrics",
+		zap.String("metric_name", metric.Name()),
+		zap.Int("data_points", len(keys)))
+}
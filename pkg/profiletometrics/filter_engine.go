@@ -0,0 +1,115 @@
+package profiletometrics
+
+import (
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// filterEngine holds the compiled process, pattern, thread and function filters shared by the
+// metrics and trace converters, so that both keep the same samples and processes
+type filterEngine struct {
+	config *ConverterConfig
+	// patternFilter holds the compiled pattern_filter rules, nil when pattern filtering is off
+	patternFilter *patternFilter
+	// processFilter holds the compiled process_filter patterns, nil when process filtering is off
+	processFilter *processFilter
+	// threadFilters are the compiled thread_filter patterns, nil when thread filtering is off
+	threadFilters []*regexp.Regexp
+	// functionFilter holds the compiled function_filter patterns, nil when function filtering is off
+	functionFilter *functionFilter
+}
+
+// newFilterEngine compiles the filters of the configuration
+func newFilterEngine(cfg *ConverterConfig) (*filterEngine, error) {
+	patternFilter, err := newPatternFilter(cfg.PatternFilter)
+	if err != nil {
+		return nil, err
+	}
+	processFilter, err := newProcessFilter(cfg.ProcessFilter)
+	if err != nil {
+		return nil, err
+	}
+	threadFilters, err := compileThreadFilter(cfg.ThreadFilter)
+	if err != nil {
+		return nil, err
+	}
+	functionFilter, err := newFunctionFilter(cfg.FunctionFilter)
+	if err != nil {
+		return nil, err
+	}
+	return &filterEngine{
+		config:         cfg,
+		patternFilter:  patternFilter,
+		processFilter:  processFilter,
+		threadFilters:  threadFilters,
+		functionFilter: functionFilter,
+	}, nil
+}
+
+// filtersProcesses reports whether process_filter restricts the processes
+func (f *filterEngine) filtersProcesses() bool {
+	return f.processFilter != nil
+}
+
+// allowedProcessNames returns the process names passing process_filter
+func (f *filterEngine) allowedProcessNames(processNames []string) []string {
+	if f.processFilter == nil {
+		return processNames
+	}
+	return f.processFilter.allowedProcessNames(processNames)
+}
+
+// matchesAttributes reports whether profile attributes pass pattern_filter
+func (f *filterEngine) matchesAttributes(attributes map[string]string) bool {
+	return f.patternFilter == nil || f.patternFilter.matchesAttributes(attributes)
+}
+
+// allowsFunction reports whether a function passes the include and exclude patterns of function_filter
+func (f *filterEngine) allowsFunction(functionName string) bool {
+	return f.functionFilter == nil || f.functionFilter.allows(functionName)
+}
+
+// matchesThread reports whether a sample's thread.name matches any thread filter pattern
+func (f *filterEngine) matchesThread(profiles dictionaryProvider, sample pprofile.Sample) bool {
+	if len(f.threadFilters) == 0 {
+		return true
+	}
+	var buffer [4]string
+	threadNames := appendSampleAttributeValuesCommon(buffer[:0], f.config, profiles, sample, "thread.name")
+	if len(threadNames) == 0 {
+		threadNames = append(threadNames, "")
+	}
+	for _, threadName := range threadNames {
+		for _, re := range f.threadFilters {
+			if re.MatchString(threadName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterSamples returns a copy of the profile restricted to the samples passing the sample-level
+// filters (pattern, function exclude and thread filters) and the number of samples dropped. The
+// profile itself is returned when no sample-level filter is configured. functionName resolves the
+// function a sample is attributed to.
+func (f *filterEngine) filterSamples(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	functionName func(pprofile.Sample) string,
+) (pprofile.Profile, int) {
+	if f.patternFilter == nil && len(f.threadFilters) == 0 && f.functionFilter == nil {
+		return profile, 0
+	}
+	return filterProfileSamples(profile, func(sample pprofile.Sample) bool {
+		if f.patternFilter != nil && !f.patternFilter.matchesSample(profiles, sample, attributes) {
+			return false
+		}
+		if f.functionFilter != nil && f.functionFilter.excludes(functionName(sample)) {
+			return false
+		}
+		return f.matchesThread(profiles, sample)
+	})
+}
@@ -0,0 +1,92 @@
+package profiletometrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterEngine_MetricsAndTracesParity(t *testing.T) {
+	config := &ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Process:  ProcessMetricConfig{Enabled: true},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		ProcessFilter:  ProcessFilterConfig{Enabled: true, Patterns: []string{"^app$"}},
+		ThreadFilter:   ThreadFilterConfig{Enabled: true, Pattern: "^main$"},
+		FunctionFilter: FunctionFilterConfig{Enabled: true, Exclude: []string{`^runtime\.`}},
+		SpanOutput:     TraceSpanConfig{SpanNameTemplate: "{process}/{function}"},
+	}
+	converter, err := NewConverter(config)
+	require.NoError(t, err)
+	traceConverter, err := NewTraceConverter(config)
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main.main", "app.Handle"), map[string]string{"process.executable.name": "app", "thread.name": "main"}, 2000000000)
+	b.sample(b.stack("main.main", "app.Sweep"), map[string]string{"process.executable.name": "app", "thread.name": "gc"}, 1000000000)
+	b.sample(b.stack("main.main", "runtime.mallocgc"), map[string]string{"process.executable.name": "app", "thread.name": "main"}, 1000000000)
+	b.sample(b.stack("main.main", "worker.Run"), map[string]string{"process.executable.name": "worker", "thread.name": "main"}, 1000000000)
+
+	// The processes of the per-process metrics, and the functions of these processes
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	metricProcesses := make(map[string]bool)
+	processFunctions := make(map[string]bool)
+	scopeMetrics := metrics.ResourceMetrics().At(0).ScopeMetrics()
+	for i := 0; i < scopeMetrics.Len(); i++ {
+		for j := 0; j < scopeMetrics.At(i).Metrics().Len(); j++ {
+			dataPoints, ok := numberDataPoints(scopeMetrics.At(i).Metrics().At(j))
+			if !ok {
+				continue
+			}
+			for k := 0; k < dataPoints.Len(); k++ {
+				attributes := dataPoints.At(k).Attributes()
+				processName, _ := attributes.Get("process.name")
+				if functionName, ok := attributes.Get("function.name"); ok {
+					processFunctions[processName.Str()+"/"+functionName.Str()] = true
+				} else if processName.Str() != "" {
+					metricProcesses[processName.Str()] = true
+				}
+			}
+		}
+	}
+	metricFunctions := make(map[string]bool)
+	for key := range processFunctions {
+		if metricProcesses[key[:strings.Index(key, "/")]] {
+			metricFunctions[key] = true
+		}
+	}
+
+	// The leaf functions of the traces, named after their process
+	traces, err := traceConverter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	traceFunctions := make(map[string]bool)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		if self, ok := spans.At(i).Attributes().Get(selfTimeAttributeKey); ok && self.Int() > 0 {
+			traceFunctions[spans.At(i).Name()] = true
+		}
+	}
+
+	// Only app's main thread sample outside the runtime passes the filters, in both converters
+	assert.Equal(t, map[string]bool{"app": true}, metricProcesses)
+	assert.Equal(t, map[string]bool{"app/app.Handle": true}, metricFunctions)
+	assert.Equal(t, metricFunctions, traceFunctions)
+}
+
+func TestFilterEngine_AllowedProcessNames(t *testing.T) {
+	filters, err := newFilterEngine(&ConverterConfig{})
+	require.NoError(t, err)
+	assert.False(t, filters.filtersProcesses())
+	assert.Equal(t, []string{"app", "worker"}, filters.allowedProcessNames([]string{"app", "worker"}))
+
+	filters, err = newFilterEngine(&ConverterConfig{ProcessFilter: ProcessFilterConfig{Enabled: true, Pattern: "^app"}})
+	require.NoError(t, err)
+	assert.True(t, filters.filtersProcesses())
+	assert.Equal(t, []string{"app"}, filters.allowedProcessNames([]string{"app", "worker"}))
+}
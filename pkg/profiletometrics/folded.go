@@ -0,0 +1,80 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// foldedStackAttributeKey holds the collapsed stack of a sample on span events
+	foldedStackAttributeKey = "stack.folded"
+
+	// defaultFoldedStackSeparator separates frames, as in Brendan Gregg's collapsed stack format
+	defaultFoldedStackSeparator = ";"
+
+	// foldedStackElision replaces the root-side frames dropped to respect max_length
+	foldedStackElision = "..."
+)
+
+// validateFoldedStack checks the folded stack options
+func validateFoldedStack(cfg FoldedStackConfig) error {
+	if cfg.MaxLength < 0 {
+		return fmt.Errorf("folded_stack.max_length must not be negative")
+	}
+	return nil
+}
+
+// stackFunctionNamesCommon returns the function names of a stack root first, expanding inline
+// chains so that inlined functions follow the function they were inlined into. Frames without a
+// function name are skipped.
+func stackFunctionNamesCommon(profiles dictionaryProvider, stackIndex int32) []string {
+	locationIndices, ok := stackLocationIndicesCommon(profiles, stackIndex)
+	if !ok {
+		return nil
+	}
+	var frames []string
+	for i := 0; i < locationIndices.Len(); i++ {
+		location, ok := locationAtCommon(profiles, locationIndices.At(i))
+		if !ok {
+			continue
+		}
+		inlineFrames := locationFramesCommon(profiles, location)
+		for j := len(inlineFrames) - 1; j >= 0; j-- {
+			if inlineFrames[j].functionName != "" {
+				frames = append(frames, inlineFrames[j].functionName)
+			}
+		}
+	}
+	return frames
+}
+
+// foldStackCommon collapses a stack into "root;caller;leaf", expanding inline chains so that
+// inlined functions follow the function they were inlined into. When maxLength is set, frames
+// closest to the root are replaced with "..." until the stack fits, keeping at least the leaf.
+func foldStackCommon(profiles dictionaryProvider, stackIndex int32, separator string, maxLength int) string {
+	return foldFramesCommon(stackFunctionNamesCommon(profiles, stackIndex), separator, maxLength)
+}
+
+// foldFramesCommon collapses root-first function names like foldStackCommon
+func foldFramesCommon(frames []string, separator string, maxLength int) string {
+	if separator == "" {
+		separator = defaultFoldedStackSeparator
+	}
+	folded := strings.Join(frames, separator)
+	if maxLength <= 0 || len(folded) <= maxLength || len(frames) == 0 {
+		return folded
+	}
+
+	// Keep the leaf-most frames that fit next to the elision marker
+	length := len(foldedStackElision)
+	first := len(frames)
+	for first > 0 && length+len(separator)+len(frames[first-1]) <= maxLength {
+		first--
+		length += len(separator) + len(frames[first])
+	}
+	if first == len(frames) {
+		// Even the leaf alone does not fit
+		return frames[len(frames)-1]
+	}
+	return foldedStackElision + separator + strings.Join(frames[first:], separator)
+}
@@ -0,0 +1,71 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldStackCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	stack := b.stack("main.main", "main.handle", "encoding/json.Marshal")
+
+	// The leaf location of the inline stack holds bytes.grow inlined into main.encode
+	inlineStack := b.stack("main.main", "main.encode")
+	grow := b.location("bytes.grow", "").Line().At(0).FunctionIndex()
+	encode := b.location("main.encode", "")
+	encodeFunction := encode.Line().At(0).FunctionIndex()
+	encode.Line().At(0).SetFunctionIndex(grow)
+	encode.Line().AppendEmpty().SetFunctionIndex(encodeFunction)
+
+	tests := []struct {
+		name       string
+		stackIndex int32
+		separator  string
+		maxLength  int
+		expected   string
+	}{
+		{name: "root first", stackIndex: stack, expected: "main.main;main.handle;encoding/json.Marshal"},
+		{name: "custom separator", stackIndex: stack, separator: " <- ", expected: "main.main <- main.handle <- encoding/json.Marshal"},
+		{name: "inline chain", stackIndex: inlineStack, expected: "main.main;main.encode;bytes.grow"},
+		{name: "fits max length", stackIndex: stack, maxLength: 43, expected: "main.main;main.handle;encoding/json.Marshal"},
+		{name: "root frames elided", stackIndex: stack, maxLength: 40, expected: "...;main.handle;encoding/json.Marshal"},
+		{name: "leaf kept", stackIndex: stack, maxLength: 5, expected: "encoding/json.Marshal"},
+		{name: "invalid stack", stackIndex: 42, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, foldStackCommon(b.profiles, tt.stackIndex, tt.separator, tt.maxLength))
+		})
+	}
+}
+
+func TestTraceConverter_FoldedStackEvents(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		FoldedStack: FoldedStackConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.handle"), map[string]string{"process.executable.name": "app"}, 1000000000)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Positive(t, spans.Len())
+	for i := 0; i < spans.Len(); i++ {
+		event := spans.At(i).Events().At(0)
+		folded, ok := event.Attributes().Get(foldedStackAttributeKey)
+		require.True(t, ok)
+		assert.Equal(t, "main.main;main.handle", folded.Str())
+	}
+}
+
+func TestNewConverter_InvalidFoldedStack(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{FoldedStack: FoldedStackConfig{MaxLength: -1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// frameCategoryAttributeKey is the data point attribute holding the class of a frame type
+	frameCategoryAttributeKey = "frame.category"
+
+	// Frame categories: kernel code, native user code, and interpreted or JIT-compiled code
+	frameCategoryKernel      = "kernel"
+	frameCategoryUser        = "user"
+	frameCategoryInterpreted = "interpreted"
+
+	// unknownFrameType is reported for leaf frames without a profile.frame.type attribute
+	unknownFrameType = "unknown"
+)
+
+// frameTypeKey identifies the frame type and category of a process
+type frameTypeKey struct {
+	processName string
+	frameType   string
+	category    string
+}
+
+// classifyFrameType returns the profile.frame.type of a location and its category. Locations
+// without the attribute are kernel code when mapped to the kernel image, interpreted when mapped to
+// an interpreter binary, and user code otherwise.
+func (c *Converter) classifyFrameType(profiles dictionaryProvider, location pprofile.Location) (string, string) {
+	frameType := getAttributeValueCommon(profiles, location.AttributeIndices(), frameTypeAttributeKey)
+	if frameType == "" {
+		frameType = unknownFrameType
+	}
+	switch {
+	case c.isKernelLocation(profiles, location):
+		return frameType, frameCategoryKernel
+	case c.isInterpretedLocation(profiles, location):
+		return frameType, frameCategoryInterpreted
+	default:
+		return frameType, frameCategoryUser
+	}
+}
+
+// generateFrameTypeMetrics emits, for each process, the percent share of CPU time whose leaf frame
+// has each frame type, with the profile.frame.type and frame.category attributes. The actual leaf is
+// used regardless of frame_selection, so kernel frames are counted as kernel time.
+func (c *Converter) generateFrameTypeMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	processTotals := make(map[string]float64)
+	totals := make(map[frameTypeKey]float64)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok || locationIndices.Len() == 0 {
+			continue
+		}
+		location, ok := locationAtCommon(profiles, locationIndices.At(locationIndices.Len()-1))
+		if !ok {
+			continue
+		}
+		frameType, category := c.classifyFrameType(profiles, location)
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		processTotals[processName] += cpuTime
+		totals[frameTypeKey{processName: processName, frameType: frameType, category: category}] += cpuTime
+	}
+	if len(totals) == 0 {
+		return
+	}
+
+	keys := make([]frameTypeKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		if keys[i].frameType != keys[j].frameType {
+			return keys[i].frameType < keys[j].frameType
+		}
+		return keys[i].category < keys[j].category
+	})
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.FrameType.MetricName)
+	metric.SetDescription("Share of the process CPU time spent in each frame type")
+	metric.SetUnit(percentUnit)
+	gauge := metric.SetEmptyGauge()
+	for _, key := range keys {
+		share := 0.0
+		if processTotals[key.processName] > 0 {
+			share = totals[key] / processTotals[key.processName] * 100
+		}
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(share)
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if key.processName != "" {
+			dataPoint.Attributes().PutStr("process.name", key.processName)
+		}
+		dataPoint.Attributes().PutStr(frameTypeAttributeKey, key.frameType)
+		dataPoint.Attributes().PutStr(frameCategoryAttributeKey, key.category)
+	}
+}
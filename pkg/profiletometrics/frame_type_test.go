@@ -0,0 +1,58 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// withLeafFrameType tags the leaf frame of a stack with a profile.frame.type
+func withLeafFrameType(b *testProfileBuilder, stackIndex int32, frameType string) int32 {
+	locationIndices := b.profiles.Dictionary().StackTable().At(int(stackIndex)).LocationIndices()
+	location := b.profiles.Dictionary().LocationTable().At(int(locationIndices.At(locationIndices.Len() - 1)))
+	location.AttributeIndices().Append(b.attribute(frameTypeAttributeKey, frameType))
+	return stackIndex
+}
+
+func TestConverter_FrameTypeMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			FrameType: FrameTypeMetricConfig{Enabled: true, MetricName: "cpu_share_by_frame_type"},
+		},
+		// The actual leaf decides even when frame_selection picks another frame
+		FrameSelection: FrameSelectionConfig{Mode: "root"},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(withLeafFrameType(b, b.stack("main", "read", "vfs_read"), "kernel"), app, 1000000000)
+	b.sample(withLeafFrameType(b, b.stack("main", "handle"), "native"), app, 2000000000)
+	b.sample(withLeafFrameType(b, b.stack("main", "Cart.checkout"), "jvm"), app, 1000000000)
+	b.sample(b.stack("worker.run"), map[string]string{"process.executable.name": "db"}, 500000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	assert.Equal(t, "cpu_share_by_frame_type", metric.Name())
+	require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+	assert.Equal(t, "%", metric.Unit())
+
+	var points []map[string]any
+	dataPoints := metric.Gauge().DataPoints()
+	for i := 0; i < dataPoints.Len(); i++ {
+		point := dataPoints.At(i).Attributes().AsRaw()
+		point["value"] = dataPoints.At(i).DoubleValue()
+		points = append(points, point)
+	}
+	assert.Equal(t, []map[string]any{
+		{"process.name": "app", "profile.frame.type": "jvm", "frame.category": "interpreted", "value": 25.0},
+		{"process.name": "app", "profile.frame.type": "kernel", "frame.category": "kernel", "value": 25.0},
+		{"process.name": "app", "profile.frame.type": "native", "frame.category": "user", "value": 50.0},
+		{"process.name": "db", "profile.frame.type": "unknown", "frame.category": "user", "value": 100.0},
+	}, points)
+}
@@ -0,0 +1,88 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// Frame selection modes; an empty value means leaf
+	frameSelectionLeaf              = "leaf"
+	frameSelectionRoot              = "root"
+	frameSelectionFirstUserFrame    = "first_user_frame"
+	frameSelectionSkipRuntimeFrames = "skip_runtime_frames"
+)
+
+// defaultRuntimeFramePatterns match runtime and standard library frames of common languages
+var defaultRuntimeFramePatterns = []string{
+	`^runtime\.`,
+	`^(java|javax|jdk|sun)[./]`,
+	`^(libc|libpthread|ld-linux)`,
+	`^_PyEval_|^PyEval_|^_Py_`,
+}
+
+// compileFrameSelection validates the frame selection mode and compiles its skip patterns
+func compileFrameSelection(cfg FrameSelectionConfig) ([]*regexp.Regexp, error) {
+	var patterns []string
+	switch cfg.Mode {
+	case "", frameSelectionLeaf, frameSelectionRoot:
+		return nil, nil
+	case frameSelectionFirstUserFrame:
+		patterns = append(append(patterns, defaultRuntimeFramePatterns...), cfg.Patterns...)
+	case frameSelectionSkipRuntimeFrames:
+		if len(cfg.Patterns) == 0 {
+			return nil, fmt.Errorf("frame_selection.patterns must be set for mode %q", frameSelectionSkipRuntimeFrames)
+		}
+		patterns = cfg.Patterns
+	default:
+		return nil, fmt.Errorf("invalid frame_selection.mode %q: must be %q, %q, %q or %q", cfg.Mode,
+			frameSelectionLeaf, frameSelectionRoot, frameSelectionFirstUserFrame, frameSelectionSkipRuntimeFrames)
+	}
+
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame_selection pattern %q: %w", pattern, err)
+		}
+		regexes = append(regexes, re)
+	}
+	return regexes, nil
+}
+
+// selectFrame returns the location index identifying the owning function of a stack
+// following the repo convention that the last location is the leaf
+func (c *Converter) selectFrame(profiles dictionaryProvider, locationIndices pcommon.Int32Slice) int32 {
+	leaf := locationIndices.At(locationIndices.Len() - 1)
+	if c.config.FrameSelection.Mode == frameSelectionRoot {
+		return locationIndices.At(0)
+	}
+	if len(c.skipFramePatterns) == 0 {
+		return leaf
+	}
+
+	for i := locationIndices.Len() - 1; i >= 0; i-- {
+		locationIndex := locationIndices.At(i)
+		location, ok := locationAtCommon(profiles, locationIndex)
+		if !ok {
+			continue
+		}
+		if !c.isSkippedFrame(c.getLocationFunctionName(profiles, location)) {
+			return locationIndex
+		}
+	}
+	// Every frame is a runtime frame: fall back to the leaf
+	return leaf
+}
+
+// isSkippedFrame reports whether a function name matches the frame_selection skip patterns
+func (c *Converter) isSkippedFrame(functionName string) bool {
+	for _, re := range c.skipFramePatterns {
+		if re.MatchString(functionName) {
+			return true
+		}
+	}
+	return false
+}
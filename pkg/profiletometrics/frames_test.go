@@ -0,0 +1,77 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_FrameSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection FrameSelectionConfig
+		stack     []string
+		expected  string
+	}{
+		{
+			name:     "Leaf by default",
+			stack:    []string{"main.main", "main.handler", "runtime.mallocgc"},
+			expected: "runtime.mallocgc",
+		},
+		{
+			name:      "Root",
+			selection: FrameSelectionConfig{Mode: "root"},
+			stack:     []string{"main.main", "main.handler", "runtime.mallocgc"},
+			expected:  "main.main",
+		},
+		{
+			name:      "First user frame skips Go runtime frames",
+			selection: FrameSelectionConfig{Mode: "first_user_frame"},
+			stack:     []string{"main.main", "main.handler", "runtime.growslice", "runtime.mallocgc"},
+			expected:  "main.handler",
+		},
+		{
+			name:      "First user frame skips JDK frames",
+			selection: FrameSelectionConfig{Mode: "first_user_frame"},
+			stack:     []string{"com.example.Service.run", "java.util.HashMap.get", "jdk.internal.misc.Unsafe.park"},
+			expected:  "com.example.Service.run",
+		},
+		{
+			name:      "Skip runtime frames with custom patterns",
+			selection: FrameSelectionConfig{Mode: "skip_runtime_frames", Patterns: []string{`^vendor\.`}},
+			stack:     []string{"app.main", "vendor.lib.Do"},
+			expected:  "app.main",
+		},
+		{
+			name:      "Falls back to the leaf when every frame is skipped",
+			selection: FrameSelectionConfig{Mode: "first_user_frame"},
+			stack:     []string{"runtime.goexit", "runtime.mcall"},
+			expected:  "runtime.mcall",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{FrameSelection: tt.selection})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			sample := b.sample(b.stack(tt.stack...), nil, 1)
+
+			assert.Equal(t, tt.expected, converter.getSampleFunctionName(b.profiles, sample))
+		})
+	}
+}
+
+func TestNewConverter_InvalidFrameSelection(t *testing.T) {
+	tests := []FrameSelectionConfig{
+		{Mode: "middle"},
+		{Mode: "skip_runtime_frames"},
+		{Mode: "first_user_frame", Patterns: []string{"["}},
+	}
+	for _, selection := range tests {
+		_, err := NewConverter(&ConverterConfig{FrameSelection: selection})
+		assert.Error(t, err, selection.Mode)
+	}
+}
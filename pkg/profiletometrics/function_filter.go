@@ -0,0 +1,82 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// functionFilter restricts function metrics to function names matching include patterns and not
+// matching exclude patterns
+type functionFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newFunctionFilter compiles the function filter, returning nil when the filter is disabled
+func newFunctionFilter(cfg FunctionFilterConfig) (*functionFilter, error) {
+	if !cfg.Enabled || (len(cfg.Include) == 0 && len(cfg.Exclude) == 0) {
+		return nil, nil
+	}
+
+	compile := func(patterns []string) ([]*regexp.Regexp, error) {
+		regexes := make([]*regexp.Regexp, 0, len(patterns))
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid function_filter pattern %q: %w", pattern, err)
+			}
+			regexes = append(regexes, re)
+		}
+		return regexes, nil
+	}
+
+	include, err := compile(cfg.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compile(cfg.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &functionFilter{include: include, exclude: exclude}, nil
+}
+
+// excludes reports whether a function name matches an exclude pattern
+func (f *functionFilter) excludes(functionName string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(functionName) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a function passes the include and exclude patterns
+func (f *functionFilter) allows(functionName string) bool {
+	if f.excludes(functionName) {
+		return false
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(functionName) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFunctionDataPoints drops data points of functions not allowed by the function filter
+func (c *Converter) filterFunctionDataPoints(points []functionDataPoint) []functionDataPoint {
+	if c.filters.functionFilter == nil {
+		return points
+	}
+	allowed := points[:0]
+	for _, point := range points {
+		if c.filters.allowsFunction(point.functionName) {
+			allowed = append(allowed, point)
+		}
+	}
+	return allowed
+}
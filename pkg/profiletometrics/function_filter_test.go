@@ -0,0 +1,58 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_FunctionFilter(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true, Attribution: "total"},
+		},
+		FunctionFilter: FunctionFilterConfig{
+			Enabled: true,
+			Include: []string{`^github\.com/mycompany/`},
+			Exclude: []string{`^runtime\.`},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main.main", "github.com/mycompany/api.Handle"), process, 2000000000, 0)
+	b.sample(b.stack("main.main", "github.com/mycompany/api.Handle", "runtime.mallocgc"), process, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	global := 0.0
+	functions := make(map[string]float64)
+	cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+		dp := cpuMetric.Gauge().DataPoints().At(i)
+		if functionName, ok := dp.Attributes().Get("function.name"); ok {
+			functions[functionName.Str()] = dp.DoubleValue()
+			continue
+		}
+		if _, ok := dp.Attributes().Get("process.name"); !ok {
+			global = dp.DoubleValue()
+		}
+	}
+
+	// The runtime.mallocgc sample is dropped entirely; main.main is not included
+	assert.Equal(t, 2.0, global)
+	assert.Equal(t, map[string]float64{"github.com/mycompany/api.Handle": 2}, functions)
+}
+
+func TestNewFunctionFilter_InvalidPattern(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		FunctionFilter: FunctionFilterConfig{Enabled: true, Exclude: []string{"("}},
+	})
+	assert.Error(t, err)
+}
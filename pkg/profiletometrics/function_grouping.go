@@ -0,0 +1,180 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Function aggregation levels; an empty value means function
+	functionGroupByFunction = "function"
+	functionGroupByFile     = "file"
+	functionGroupByPackage  = "package"
+	functionGroupByModule   = "module"
+
+	// unknownGroupName names the group of functions whose file, package or module cannot be derived
+	unknownGroupName = "unknown"
+)
+
+// validateFunctionGroupBy checks the configured function aggregation level
+func validateFunctionGroupBy(groupBy string) error {
+	switch groupBy {
+	case "", functionGroupByFunction, functionGroupByFile, functionGroupByPackage, functionGroupByModule:
+		return nil
+	default:
+		return fmt.Errorf("invalid metrics.function.group_by %q: must be %q, %q, %q or %q", groupBy,
+			functionGroupByFunction, functionGroupByFile, functionGroupByPackage, functionGroupByModule)
+	}
+}
+
+// functionGroupAttributeKey returns the attribute naming the data points' aggregation level
+func (c *Converter) functionGroupAttributeKey() string {
+	switch c.config.Metrics.Function.GroupBy {
+	case functionGroupByFile:
+		return c.codeAttributeKeys().fileName
+	case functionGroupByPackage:
+		return "package.name"
+	case functionGroupByModule:
+		return "module.name"
+	default:
+		return c.codeAttributeKeys().functionName
+	}
+}
+
+// groupFunctionDataPoints aggregates function data points to the configured group_by level; the
+// group name replaces the function name. Total values of a group can exceed the group's real
+// total when several of its functions share a stack.
+func (c *Converter) groupFunctionDataPoints(points []functionDataPoint) []functionDataPoint {
+	groupBy := c.config.Metrics.Function.GroupBy
+	if groupBy == "" || groupBy == functionGroupByFunction {
+		return points
+	}
+
+	type groupKey struct {
+		processName string
+		threadName  string
+		groupName   string
+		attribution string
+		truncated   bool
+	}
+	byKey := make(map[groupKey]*functionDataPoint)
+	for _, point := range points {
+		var groupName string
+		switch groupBy {
+		case functionGroupByFile:
+			groupName = point.fileName
+		case functionGroupByPackage:
+			groupName = functionPackageName(point.functionName)
+		case functionGroupByModule:
+			groupName = functionModuleName(point.functionName)
+		}
+		if groupName == "" {
+			groupName = unknownGroupName
+		}
+
+		key := groupKey{
+			processName: point.processName,
+			threadName:  point.threadName,
+			groupName:   groupName,
+			attribution: point.attribution,
+			truncated:   point.truncated,
+		}
+		group, exists := byKey[key]
+		if !exists {
+			// File and code path attributes only describe individual functions
+			group = &functionDataPoint{
+				processName:  point.processName,
+				threadName:   point.threadName,
+				functionName: groupName,
+				attribution:  point.attribution,
+				truncated:    point.truncated,
+			}
+			byKey[key] = group
+		}
+		group.cpuTime += point.cpuTime
+		group.memory += point.memory
+	}
+
+	grouped := make([]functionDataPoint, 0, len(byKey))
+	for _, point := range byKey {
+		grouped = append(grouped, *point)
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		a, b := grouped[i], grouped[j]
+		if a.processName != b.processName {
+			return a.processName < b.processName
+		}
+		if a.threadName != b.threadName {
+			return a.threadName < b.threadName
+		}
+		if a.functionName != b.functionName {
+			return a.functionName < b.functionName
+		}
+		if a.attribution != b.attribution {
+			return a.attribution < b.attribution
+		}
+		return !a.truncated && b.truncated
+	})
+	return grouped
+}
+
+// functionPackageName derives the package of a qualified function name:
+//   - Go: "github.com/org/repo/pkg.(*T).Method" -> "github.com/org/repo/pkg"
+//   - Java: "com.example.service.OrderService.place" -> "com.example.service"
+//   - C++/Rust: "std::vector<int>::push_back" -> "std::vector<int>"
+func functionPackageName(functionName string) string {
+	if i := strings.LastIndex(functionName, "::"); i >= 0 {
+		return functionName[:i]
+	}
+
+	// Go import paths contain slashes; the package ends at the first dot after the last slash
+	if i := strings.LastIndex(functionName, "/"); i >= 0 {
+		if j := strings.Index(functionName[i:], "."); j >= 0 {
+			return functionName[:i+j]
+		}
+		return functionName
+	}
+
+	parts := strings.Split(functionName, ".")
+	switch {
+	case len(parts) < 2:
+		return ""
+	case len(parts) >= 3 && isUpperCaseName(parts[len(parts)-2]):
+		// Java-style "package.Class.method" (also Go "pkg.Type.Method")
+		return strings.Join(parts[:len(parts)-2], ".")
+	default:
+		return parts[0]
+	}
+}
+
+// functionModuleName derives the module of a qualified function name: the repository root for Go
+// import paths on a host ("github.com/org/repo"), the top-level package otherwise ("net", or
+// "com.example" for Java)
+func functionModuleName(functionName string) string {
+	packageName := functionPackageName(functionName)
+	if packageName == "" {
+		return ""
+	}
+	if i := strings.Index(packageName, "::"); i >= 0 {
+		return packageName[:i]
+	}
+	if strings.Contains(packageName, "/") {
+		segments := strings.Split(packageName, "/")
+		if strings.Contains(segments[0], ".") && len(segments) >= 3 {
+			return strings.Join(segments[:3], "/")
+		}
+		return segments[0]
+	}
+	if parts := strings.Split(packageName, "."); len(parts) > 2 {
+		return strings.Join(parts[:2], ".")
+	}
+	return packageName
+}
+
+// isUpperCaseName reports whether a name starts with an upper-case letter, as classes and Go
+// exported types do
+func isUpperCaseName(name string) bool {
+	name = strings.TrimLeft(name, "(*")
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
@@ -0,0 +1,109 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionPackageAndModuleName(t *testing.T) {
+	tests := []struct {
+		functionName string
+		packageName  string
+		moduleName   string
+	}{
+		{"github.com/mycompany/api/handlers.(*Server).Handle", "github.com/mycompany/api/handlers", "github.com/mycompany/api"},
+		{"net/http.(*conn).serve", "net/http", "net"},
+		{"runtime.mallocgc", "runtime", "runtime"},
+		{"main.(*T).Method", "main", "main"},
+		{"main.main.func1", "main", "main"},
+		{"com.example.service.OrderService.place", "com.example.service", "com.example"},
+		{"java.util.HashMap.get", "java.util", "java.util"},
+		{"std::vector<int>::push_back", "std::vector<int>", "std"},
+		{"malloc", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.functionName, func(t *testing.T) {
+			assert.Equal(t, tt.packageName, functionPackageName(tt.functionName))
+			assert.Equal(t, tt.moduleName, functionModuleName(tt.functionName))
+		})
+	}
+}
+
+func TestConverter_FunctionGroupBy(t *testing.T) {
+	tests := []struct {
+		groupBy      string
+		attributeKey string
+		expected     map[string]float64
+	}{
+		{
+			groupBy:      "package",
+			attributeKey: "package.name",
+			expected:     map[string]float64{"github.com/mycompany/api/handlers": 3, "runtime": 1, "unknown": 1},
+		},
+		{
+			groupBy:      "module",
+			attributeKey: "module.name",
+			expected:     map[string]float64{"github.com/mycompany/api": 3, "runtime": 1, "unknown": 1},
+		},
+		{
+			groupBy:      "file",
+			attributeKey: "file.name",
+			expected:     map[string]float64{"handlers.go": 3, "unknown": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Function: FunctionMetricConfig{Enabled: true, GroupBy: tt.groupBy},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			process := map[string]string{"process.executable.name": "app"}
+			for _, leaf := range []struct {
+				name  string
+				value int64
+			}{
+				{"github.com/mycompany/api/handlers.(*Server).Handle", 2000000000},
+				{"github.com/mycompany/api/handlers.encode", 1000000000},
+			} {
+				stack := b.profiles.Dictionary().StackTable().AppendEmpty()
+				stack.LocationIndices().Append(b.function("main.main", ""), b.function(leaf.name, "handlers.go"))
+				b.sample(int32(b.profiles.Dictionary().StackTable().Len()-1), process, leaf.value, 0)
+			}
+			b.sample(b.stack("main.main", "runtime.mallocgc"), process, 1000000000, 0)
+			b.sample(b.stack("main.main", "malloc"), process, 1000000000, 0)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			groups := make(map[string]float64)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			cpuMetric := metricSlice.At(0)
+			for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+				dp := cpuMetric.Gauge().DataPoints().At(i)
+				_, hasFunction := dp.Attributes().Get("function.name")
+				assert.False(t, hasFunction)
+				if group, ok := dp.Attributes().Get(tt.attributeKey); ok {
+					groups[group.Str()] = dp.DoubleValue()
+				}
+			}
+			assert.Equal(t, tt.expected, groups)
+		})
+	}
+}
+
+func TestNewConverter_InvalidFunctionGroupBy(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Function: FunctionMetricConfig{GroupBy: "class"}},
+	})
+	assert.Error(t, err)
+}
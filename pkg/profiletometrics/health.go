@@ -0,0 +1,107 @@
+package profiletometrics
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// defaultIngestionMetricPrefix prefixes the ingestion health metric names
+const defaultIngestionMetricPrefix = "profiletometrics.ingestion"
+
+// inspectSampleHealth counts the samples of a profile, those without values and the dictionary
+// references of each sample that cannot be resolved
+func (c *Converter) inspectSampleHealth(profiles dictionaryProvider, profile pprofile.Profile, summary *conversionSummary) {
+	summary.samples.Add(int64(profile.Sample().Len()))
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		if sample.Values().Len() == 0 {
+			summary.missingValueSamples.Add(1)
+		}
+		summary.dictionaryLookupFailures.Add(int64(countDictionaryLookupFailures(profiles, sample)))
+	}
+}
+
+// countDictionaryLookupFailures returns the number of attribute, stack, location, function and
+// string references of a sample that point outside of the dictionary tables
+func countDictionaryLookupFailures(profiles dictionaryProvider, sample pprofile.Sample) int {
+	dictionary := profiles.Dictionary()
+	stringTable := dictionary.StringTable()
+	failures := 0
+	outOfRange := func(index int32, length int) bool {
+		if index < 0 || int(index) >= length {
+			failures++
+			return true
+		}
+		return false
+	}
+
+	attributeTable := dictionary.AttributeTable()
+	for i := 0; i < sample.AttributeIndices().Len(); i++ {
+		attrIndex := sample.AttributeIndices().At(i)
+		if !outOfRange(attrIndex, attributeTable.Len()) {
+			outOfRange(attributeTable.At(int(attrIndex)).KeyStrindex(), stringTable.Len())
+		}
+	}
+
+	stackTable := dictionary.StackTable()
+	if outOfRange(sample.StackIndex(), stackTable.Len()) {
+		return failures
+	}
+	locationTable := dictionary.LocationTable()
+	functionTable := dictionary.FunctionTable()
+	locationIndices := stackTable.At(int(sample.StackIndex())).LocationIndices()
+	for i := 0; i < locationIndices.Len(); i++ {
+		locationIndex := locationIndices.At(i)
+		if outOfRange(locationIndex, locationTable.Len()) {
+			continue
+		}
+		lines := locationTable.At(int(locationIndex)).Line()
+		for j := 0; j < lines.Len(); j++ {
+			functionIndex := lines.At(j).FunctionIndex()
+			if !outOfRange(functionIndex, functionTable.Len()) {
+				outOfRange(functionTable.At(int(functionIndex)).NameStrindex(), stringTable.Len())
+			}
+		}
+	}
+	return failures
+}
+
+// generateIngestionMetrics emits the ingestion health counters of a conversion: profiles and
+// samples processed, profiles skipped by sampling, samples dropped by filters, samples without
+// values and dictionary lookup failures
+func (c *Converter) generateIngestionMetrics(summary *conversionSummary, resourceMetrics pmetric.ResourceMetrics) {
+	prefix := c.config.Metrics.Ingestion.MetricPrefix
+	if prefix == "" {
+		prefix = defaultIngestionMetricPrefix
+	}
+
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("profiletometrics")
+	scopeMetrics.Scope().SetVersion(converterVersion)
+	timestamp := pcommon.NewTimestampFromTime(time.Now())
+	for _, counter := range []struct {
+		name        string
+		description string
+		unit        string
+		value       int64
+	}{
+		{"profiles", "Number of profiles processed", "{profile}", summary.profiles.Load()},
+		{"profiles.sampled_out", "Number of profiles skipped by sampling", "{profile}", summary.sampledOutProfiles.Load()},
+		{"samples", "Number of samples processed", "{sample}", summary.samples.Load()},
+		{"samples.dropped", "Number of samples dropped by filters", "{sample}", summary.droppedSamples.Load()},
+		{"samples.missing_values", "Number of samples without values", "{sample}", summary.missingValueSamples.Load()},
+		{"dictionary.lookup_failures", "Number of unresolvable dictionary references", "{lookup}", summary.dictionaryLookupFailures.Load()},
+	} {
+		metric := scopeMetrics.Metrics().AppendEmpty()
+		metric.SetName(prefix + "." + counter.name)
+		metric.SetDescription(counter.description)
+		metric.SetUnit(counter.unit)
+		c.markAdditive(metric)
+		dataPoint := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+		dataPoint.SetTimestamp(timestamp)
+		dataPoint.SetDoubleValue(float64(counter.value))
+	}
+}
@@ -0,0 +1,133 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_IngestionMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Ingestion: IngestionMetricConfig{Enabled: true},
+		},
+		FunctionFilter: FunctionFilterConfig{
+			Enabled: true,
+			Exclude: []string{`^runtime\.`},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.work"), nil, 1000000000)
+	b.sample(b.stack("main.main", "runtime.mallocgc"), nil, 1000000000)
+	b.sample(b.stack("main.main"), nil)
+	// A stack referencing a missing location and a sample referencing a missing attribute
+	broken := b.profiles.Dictionary().StackTable().AppendEmpty()
+	broken.LocationIndices().Append(b.function("main.main", ""), 99)
+	sample := b.sample(int32(b.profiles.Dictionary().StackTable().Len()-1), nil, 1)
+	sample.AttributeIndices().Append(42)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	resourceMetrics := metrics.ResourceMetrics().At(0)
+	for i := 0; i < resourceMetrics.ScopeMetrics().Len(); i++ {
+		metricSlice := resourceMetrics.ScopeMetrics().At(i).Metrics()
+		for j := 0; j < metricSlice.Len(); j++ {
+			metric := metricSlice.At(j)
+			if metric.Type() == pmetric.MetricTypeGauge && metric.Name() != "cpu_time" {
+				values[metric.Name()] = metric.Gauge().DataPoints().At(0).DoubleValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"profiletometrics.ingestion.profiles":                   1,
+		"profiletometrics.ingestion.profiles.sampled_out":       0,
+		"profiletometrics.ingestion.samples":                    4,
+		"profiletometrics.ingestion.samples.dropped":            1,
+		"profiletometrics.ingestion.samples.missing_values":     1,
+		"profiletometrics.ingestion.dictionary.lookup_failures": 2,
+	}, values)
+}
+
+func TestConverter_IngestionMetricsConcurrentConversions(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Ingestion: IngestionMetricConfig{Enabled: true},
+		},
+		FunctionFilter: FunctionFilterConfig{
+			Enabled: true,
+			Exclude: []string{`^runtime\.`},
+		},
+	})
+	require.NoError(t, err)
+
+	// Each conversion drops a thousand samples per index, and must only count its own
+	const conversions, samplesPerIndex = 8, 1000
+	dropped := make([][]float64, conversions)
+	var wg sync.WaitGroup
+	for i := 0; i < conversions; i++ {
+		b := newTestProfileBuilder()
+		b.sample(b.stack("main.main"), nil, 1000000000)
+		stack := b.stack("main.main", "runtime.mallocgc")
+		for j := 0; j < i*samplesPerIndex; j++ {
+			b.sample(stack, nil, 1000000000)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for round := 0; round < 5; round++ {
+				metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+				if !assert.NoError(t, err) {
+					return
+				}
+				dropped[i] = append(dropped[i], ingestionMetricValue(metrics, "profiletometrics.ingestion.samples.dropped"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, values := range dropped {
+		for _, value := range values {
+			assert.Equal(t, float64(i*samplesPerIndex), value, fmt.Sprintf("conversion %d", i))
+		}
+	}
+}
+
+// ingestionMetricValue returns the value of a gauge of the output, or -1 when it is missing
+func ingestionMetricValue(metrics pmetric.Metrics, name string) float64 {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				if metric := scopeMetrics.At(j).Metrics().At(k); metric.Name() == name {
+					return metric.Gauge().DataPoints().At(0).DoubleValue()
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func TestConverter_IngestionMetricsDisabled(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main"), nil, 1000000000)
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Equal(t, 1, metrics.MetricCount())
+}
@@ -0,0 +1,63 @@
+package profiletometrics
+
+const (
+	// functionHeatAttributeKey classifies function data points by their share of the process total
+	functionHeatAttributeKey = "function.heat"
+
+	functionHeatHot  = "hot"
+	functionHeatWarm = "warm"
+	functionHeatCold = "cold"
+
+	defaultHotThresholdPercent  = 10.0
+	defaultWarmThresholdPercent = 1.0
+)
+
+// functionHeatLevels classifies each point as hot, warm or cold by its percentage of the total of its
+// process (or thread). Self totals are the sum of the group; total-attribution values overlap, so their
+// total is the largest value (the root frames carry every sample).
+func (c *Converter) functionHeatLevels(points []functionDataPoint, value func(functionDataPoint) float64) []string {
+	cfg := c.config.Metrics.Function.HeatBuckets
+	hotThreshold := cfg.HotThresholdPercent
+	if hotThreshold <= 0 {
+		hotThreshold = defaultHotThresholdPercent
+	}
+	warmThreshold := cfg.WarmThresholdPercent
+	if warmThreshold <= 0 {
+		warmThreshold = defaultWarmThresholdPercent
+	}
+
+	type groupKey struct {
+		processName string
+		threadName  string
+		attribution string
+	}
+	totals := make(map[groupKey]float64)
+	for _, point := range points {
+		key := groupKey{processName: point.processName, threadName: point.threadName, attribution: point.attribution}
+		if point.attribution == functionAttributionTotal {
+			if value(point) > totals[key] {
+				totals[key] = value(point)
+			}
+			continue
+		}
+		totals[key] += value(point)
+	}
+
+	levels := make([]string, len(points))
+	for i, point := range points {
+		total := totals[groupKey{processName: point.processName, threadName: point.threadName, attribution: point.attribution}]
+		percent := 0.0
+		if total > 0 {
+			percent = value(point) / total * 100
+		}
+		switch {
+		case percent >= hotThreshold:
+			levels[i] = functionHeatHot
+		case percent >= warmThreshold:
+			levels[i] = functionHeatWarm
+		default:
+			levels[i] = functionHeatCold
+		}
+	}
+	return levels
+}
@@ -0,0 +1,65 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_FunctionHeatBuckets(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{
+				Enabled:     true,
+				HeatBuckets: HeatBucketConfig{Enabled: true, HotThresholdPercent: 50, WarmThresholdPercent: 5},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main", "hot"), process, 800, 0)
+	b.sample(b.stack("main", "warm"), process, 190, 0)
+	b.sample(b.stack("main", "cold"), process, 10, 0)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+	heat := make(map[string]string)
+	dataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+	for i := 0; i < dataPoints.Len(); i++ {
+		functionName, _ := dataPoints.At(i).Attributes().Get("function.name")
+		level, _ := dataPoints.At(i).Attributes().Get("function.heat")
+		heat[functionName.Str()] = level.Str()
+	}
+	assert.Equal(t, map[string]string{"hot": "hot", "warm": "warm", "cold": "cold"}, heat)
+}
+
+func TestConverter_FunctionHeatLevelsTotalAttribution(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	points := []functionDataPoint{
+		{processName: "app", functionName: "main", cpuTime: 100, attribution: functionAttributionTotal},
+		{processName: "app", functionName: "handler", cpuTime: 90, attribution: functionAttributionTotal},
+		{processName: "app", functionName: "log", cpuTime: 0.5, attribution: functionAttributionTotal},
+	}
+	levels := converter.functionHeatLevels(points, func(p functionDataPoint) float64 { return p.cpuTime })
+	assert.Equal(t, []string{"hot", "hot", "cold"}, levels)
+}
+
+func TestNewConverter_InvalidHeatThresholds(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{
+				HeatBuckets: HeatBucketConfig{Enabled: true, HotThresholdPercent: 5, WarmThresholdPercent: 10},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -1,106 +1,153 @@
 package profiletometrics
 
 import (
+	"strings"
+	"sync"
+
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pprofile"
 )
 
-// getSampleAttributeValueCommon returns the string value for a given attribute key in a sample.
-func getSampleAttributeValueCommon(profiles pprofile.Profiles, sample pprofile.Sample, key string) string {
-	attributeIndices := sample.AttributeIndices()
-	if attributeIndices.Len() == 0 {
-		return ""
-	}
-
-	dictionary := profiles.Dictionary()
-	attributeTable := dictionary.AttributeTable()
-	stringTable := dictionary.StringTable()
-
-	for i := 0; i < attributeIndices.Len(); i++ {
-		attrIndex := attributeIndices.At(i)
-		if attrIndex < 0 || int(attrIndex) >= attributeTable.Len() {
-			continue
-		}
+// dictionaryProvider gives access to the profiles dictionary; it is implemented by pprofile.Profiles
+// and by profilesDictionary where only the dictionary is at hand (e.g. OTTL transform contexts)
+type dictionaryProvider interface {
+	Dictionary() pprofile.ProfilesDictionary
+}
 
-		attr := attributeTable.At(int(attrIndex))
+// profilesDictionary adapts a bare dictionary to dictionaryProvider
+type profilesDictionary pprofile.ProfilesDictionary
 
-		keyIndex := attr.KeyStrindex()
-		if keyIndex < 0 || int(keyIndex) >= stringTable.Len() {
-			continue
-		}
-
-		attrKey := stringTable.At(int(keyIndex))
-		if attrKey == key {
-			value := attr.Value()
-			return value.AsString()
-		}
-	}
+// Dictionary returns the wrapped dictionary
+func (d profilesDictionary) Dictionary() pprofile.ProfilesDictionary {
+	return pprofile.ProfilesDictionary(d)
+}
 
-	return ""
+// getSampleAttributeValueCommon returns the string value for a given attribute key in a sample.
+func getSampleAttributeValueCommon(profiles dictionaryProvider, sample pprofile.Sample, key string) string {
+	return getAttributeValueCommon(profiles, sample.AttributeIndices(), key)
 }
 
-// getLocationFileNameCommon returns the filename for the first line's function of a location.
-func getLocationFileNameCommon(profiles pprofile.Profiles, location pprofile.Location) string {
-	lines := location.Line()
-	if lines.Len() == 0 {
+// getAttributeValueCommon returns the string value for a given attribute key among attribute table
+// indices; the elements of array values are joined with commas.
+func getAttributeValueCommon(profiles dictionaryProvider, attributeIndices pcommon.Int32Slice, key string) string {
+	value, ok := lookupAttributeCommon(profiles, attributeIndices, key)
+	if !ok {
 		return ""
 	}
+	return strings.Join(attributeValueStrings(value), ",")
+}
 
-	line := lines.At(0)
-	functionIndex := line.FunctionIndex()
-	if functionIndex < 0 {
-		return ""
-	}
+// hasAttributeCommon reports whether attribute table indices reference the given attribute key
+func hasAttributeCommon(profiles dictionaryProvider, attributeIndices pcommon.Int32Slice, key string) bool {
+	_, ok := lookupAttributeCommon(profiles, attributeIndices, key)
+	return ok
+}
 
-	dictionary := profiles.Dictionary()
-	functionTable := dictionary.FunctionTable()
-	if int(functionIndex) >= functionTable.Len() {
-		return ""
+// lookupAttributeCommon returns the value of a given attribute key among attribute table indices.
+func lookupAttributeCommon(profiles dictionaryProvider, attributeIndices pcommon.Int32Slice, key string) (pcommon.Value, bool) {
+	if attributeIndices.Len() == 0 {
+		return pcommon.Value{}, false
 	}
 
-	function := functionTable.At(int(functionIndex))
-	filenameIndex := function.FilenameStrindex()
-
-	stringTable := dictionary.StringTable()
-	if filenameIndex < 0 || int(filenameIndex) >= stringTable.Len() {
-		return ""
+	for i := 0; i < attributeIndices.Len(); i++ {
+		attrKey, value, ok := attributeAtCommon(profiles, attributeIndices.At(i))
+		if ok && attrKey == key {
+			return value, true
+		}
 	}
 
-	return stringTable.At(int(filenameIndex))
+	return pcommon.Value{}, false
 }
 
-// getUniqueAttributeValuesCommon collects unique values of a sample attribute key across a profile.
-func getUniqueAttributeValuesCommon(profiles pprofile.Profiles, profile pprofile.Profile, key string) []string {
-	values := make(map[string]bool)
+// getUniqueAttributeValuesCommon collects unique values of a sample attribute key across a profile,
+// following the array_attributes policy.
+func getUniqueAttributeValuesCommon(cfg *ConverterConfig, profiles dictionaryProvider, profile pprofile.Profile, key string) []string {
+	values := uniqueValuesPool.Get().(map[string]struct{})
+	defer func() {
+		clear(values)
+		uniqueValuesPool.Put(values)
+	}()
+
+	var buffer []string
 	for i := 0; i < profile.Sample().Len(); i++ {
-		sample := profile.Sample().At(i)
-		v := getSampleAttributeValueCommon(profiles, sample, key)
-		if v != "" {
-			values[v] = true
+		buffer = appendSampleAttributeValuesCommon(buffer[:0], cfg, profiles, profile.Sample().At(i), key)
+		for _, v := range buffer {
+			if v != "" {
+				values[v] = struct{}{}
+			}
 		}
 	}
-	var out []string
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(values))
 	for v := range values {
 		out = append(out, v)
 	}
 	return out
 }
 
-// iterateProfilesCommon walks resource/scope/profile and calls back with extracted resource attributes
+// uniqueValuesPool recycles the sets getUniqueAttributeValuesCommon collects values in, which are
+// built for every profile of every batch
+var uniqueValuesPool = sync.Pool{New: func() any { return make(map[string]struct{}) }}
+
+// resourceDictionaryCommon returns the dictionary owning the profiles of a resource. pdata carries a
+// single dictionary per batch; resolving it per resource keeps every lookup on the owning
+// dictionary should merged batches carry per-resource dictionaries.
+func resourceDictionaryCommon(profiles pprofile.Profiles, _ int) dictionaryProvider {
+	return profiles
+}
+
+// iterateProfilesCommon walks resource/scope/profile and calls back with the dictionary owning the
+// profile and the extracted resource attributes
 func iterateProfilesCommon(
 	profiles pprofile.Profiles,
 	extractResourceAttributes func(pcommon.Resource) map[string]string,
-	onProfile func(resourceIndex, scopeIndex, profileIndex int, profile pprofile.Profile, resourceAttributes map[string]string),
+	onProfile func(dictionary dictionaryProvider, resourceIndex, scopeIndex, profileIndex int, profile pprofile.Profile, resourceAttributes map[string]string),
 ) {
 	for i := 0; i < profiles.ResourceProfiles().Len(); i++ {
 		resourceProfile := profiles.ResourceProfiles().At(i)
+		dictionary := resourceDictionaryCommon(profiles, i)
 		resourceAttributes := extractResourceAttributes(resourceProfile.Resource())
 		for j := 0; j < resourceProfile.ScopeProfiles().Len(); j++ {
 			scopeProfile := resourceProfile.ScopeProfiles().At(j)
 			for k := 0; k < scopeProfile.Profiles().Len(); k++ {
 				profile := scopeProfile.Profiles().At(k)
-				onProfile(i, j, k, profile, resourceAttributes)
+				onProfile(dictionary, i, j, k, profile, resourceAttributes)
 			}
 		}
 	}
 }
+
+// getProfileSampleTypeCommon returns the sample type name and unit of a profile from the string table.
+func getProfileSampleTypeCommon(profiles dictionaryProvider, profile pprofile.Profile) (string, string) {
+	stringTable := profiles.Dictionary().StringTable()
+	sampleType := profile.SampleType()
+
+	var typeName, unit string
+	if idx := sampleType.TypeStrindex(); idx > 0 && int(idx) < stringTable.Len() {
+		typeName = stringTable.At(int(idx))
+	}
+	if idx := sampleType.UnitStrindex(); idx > 0 && int(idx) < stringTable.Len() {
+		unit = stringTable.At(int(idx))
+	}
+	return typeName, unit
+}
+
+// getProfileCommentsCommon returns the non-empty comments of a profile from the string table.
+func getProfileCommentsCommon(profiles dictionaryProvider, profile pprofile.Profile) []string {
+	stringTable := profiles.Dictionary().StringTable()
+	commentIndices := profile.CommentStrindices()
+
+	comments := make([]string, 0, commentIndices.Len())
+	for i := 0; i < commentIndices.Len(); i++ {
+		idx := commentIndices.At(i)
+		if idx <= 0 || int(idx) >= stringTable.Len() {
+			continue
+		}
+		if comment := stringTable.At(int(idx)); comment != "" {
+			comments = append(comments, comment)
+		}
+	}
+	return comments
+}
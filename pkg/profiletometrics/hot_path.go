@@ -0,0 +1,103 @@
+package profiletometrics
+
+import (
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// defaultHotPathMaxLength caps the folded hot path attribute when max_path_length is not set
+const defaultHotPathMaxLength = 1024
+
+// generateHotPathMetrics emits one data point per process carrying its most expensive root-to-leaf
+// path, folded like folded_stack, valued with the path's percent share of the process CPU time.
+// Stacks are compared by their function names, so stacks differing only by line or address add up.
+func (c *Converter) generateHotPathMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	cfg := c.config.Metrics.HotPath
+	maxLength := cfg.MaxPathLength
+	if maxLength == 0 {
+		maxLength = defaultHotPathMaxLength
+	}
+
+	// Paths are keyed by their full folded stack so that the length cap never merges distinct paths
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	folds := make(map[int32]string)
+	frames := make(map[string][]string)
+	processTotals := make(map[string]float64)
+	pathTotals := make(map[string]map[string]float64)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		if sample.StackIndex() < 0 {
+			continue
+		}
+		folded, exists := folds[sample.StackIndex()]
+		if !exists {
+			names := stackFunctionNamesCommon(profiles, sample.StackIndex())
+			folded = strings.Join(names, defaultFoldedStackSeparator)
+			folds[sample.StackIndex()] = folded
+			frames[folded] = names
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		processTotals[processName] += cpuTime
+		if folded == "" {
+			continue
+		}
+		if pathTotals[processName] == nil {
+			pathTotals[processName] = make(map[string]float64)
+		}
+		pathTotals[processName][folded] += cpuTime
+	}
+
+	processNames := make([]string, 0, len(pathTotals))
+	for processName := range pathTotals {
+		processNames = append(processNames, processName)
+	}
+	sort.Strings(processNames)
+	if len(processNames) == 0 {
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(cfg.MetricName)
+	metric.SetDescription("Share of the process CPU time spent in its most expensive root-to-leaf path")
+	metric.SetUnit(percentUnit)
+	gauge := metric.SetEmptyGauge()
+	for _, processName := range processNames {
+		// Ties are broken by path to keep the selection deterministic
+		var hotPath string
+		var hotCPUTime float64
+		for path, cpuTime := range pathTotals[processName] {
+			if hotPath == "" || cpuTime > hotCPUTime || (cpuTime == hotCPUTime && path < hotPath) {
+				hotPath, hotCPUTime = path, cpuTime
+			}
+		}
+
+		share := 0.0
+		if processTotals[processName] > 0 {
+			share = hotCPUTime / processTotals[processName] * 100
+		}
+
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(share)
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if processName != "" {
+			dataPoint.Attributes().PutStr("process.name", processName)
+		}
+		dataPoint.Attributes().PutStr(foldedStackAttributeKey, foldFramesCommon(frames[hotPath], c.config.FoldedStack.Separator, maxLength))
+		if cfg.StackHash {
+			dataPoint.Attributes().PutStr(stackHashAttributeKey, stackHash(frames[hotPath]))
+		}
+	}
+}
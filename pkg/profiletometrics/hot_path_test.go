@@ -0,0 +1,84 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func hotPathMetric(t *testing.T, metrics pmetric.Metrics) pmetric.Metric {
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() == "hot_path_cpu_share" {
+			return metricSlice.At(i)
+		}
+	}
+	require.FailNow(t, "hot path metric not found")
+	return pmetric.Metric{}
+}
+
+func TestConverter_HotPathMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:  MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			HotPath: HotPathMetricConfig{Enabled: true, MetricName: "hot_path_cpu_share"},
+		},
+		AggregationTemporality: "cumulative",
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main.main", "main.serve", "main.encode"), app, 2000000000, 0)
+	b.sample(b.stack("main.main", "main.decode"), app, 1000000000, 0)
+	// A second stack with the same functions adds up with the first
+	b.sample(b.stack("main.main", "main.serve", "main.encode"), app, 1000000000, 0)
+	b.sample(b.stack("worker.run"), map[string]string{"process.executable.name": "db"}, 500000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	hotPath := hotPathMetric(t, metrics)
+	// Shares stay gauges regardless of the aggregation temporality
+	require.Equal(t, pmetric.MetricTypeGauge, hotPath.Type())
+	assert.Equal(t, "%", hotPath.Unit())
+
+	dataPoints := hotPath.Gauge().DataPoints()
+	require.Equal(t, 2, dataPoints.Len())
+	assert.Equal(t, map[string]any{"process.name": "app", "stack.folded": "main.main;main.serve;main.encode"}, dataPoints.At(0).Attributes().AsRaw())
+	assert.Equal(t, 75.0, dataPoints.At(0).DoubleValue())
+	assert.Equal(t, map[string]any{"process.name": "db", "stack.folded": "worker.run"}, dataPoints.At(1).Attributes().AsRaw())
+	assert.Equal(t, 100.0, dataPoints.At(1).DoubleValue())
+}
+
+func TestConverter_HotPathMaxPathLength(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			HotPath: HotPathMetricConfig{Enabled: true, MetricName: "hot_path_cpu_share", MaxPathLength: 20},
+		},
+		FoldedStack: FoldedStackConfig{Separator: "|"},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.serve", "main.encode"), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	dataPoints := hotPathMetric(t, metrics).Gauge().DataPoints()
+	require.Equal(t, 1, dataPoints.Len())
+	folded, _ := dataPoints.At(0).Attributes().Get("stack.folded")
+	// Root-side frames are elided to fit the limit
+	assert.Equal(t, "...|main.encode", folded.Str())
+	assert.Equal(t, 100.0, dataPoints.At(0).DoubleValue())
+}
+
+func TestConverterConfig_ValidateHotPathMaxPathLength(t *testing.T) {
+	cfg := &ConverterConfig{Metrics: MetricsConfig{HotPath: HotPathMetricConfig{MaxPathLength: -1}}}
+	assert.ErrorContains(t, cfg.Validate(), "metrics.hot_path.max_path_length must not be negative")
+}
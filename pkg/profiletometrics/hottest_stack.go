@@ -0,0 +1,103 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// percentUnit is the unit of share metrics; they describe a point in time and always stay gauges
+const percentUnit = "%"
+
+// hottestStack is the stack owning the most CPU time of a process
+type hottestStack struct {
+	stackIndex int32
+	cpuTime    float64
+}
+
+// generateHottestStackMetrics emits one data point per process carrying the leaf function and file
+// of its hottest stack, valued with the stack's percent share of the process CPU time
+func (c *Converter) generateHottestStackMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	processTotals := make(map[string]float64)
+	stackTotals := make(map[string]map[int32]float64)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		if sample.StackIndex() < 0 {
+			continue
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		processTotals[processName] += cpuTime
+		if stackTotals[processName] == nil {
+			stackTotals[processName] = make(map[int32]float64)
+		}
+		stackTotals[processName][sample.StackIndex()] += cpuTime
+	}
+
+	processNames := make([]string, 0, len(stackTotals))
+	for processName := range stackTotals {
+		processNames = append(processNames, processName)
+	}
+	sort.Strings(processNames)
+	if len(processNames) == 0 {
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.HottestStack.MetricName)
+	metric.SetDescription("Share of the process CPU time spent in its hottest stack")
+	metric.SetUnit(percentUnit)
+	gauge := metric.SetEmptyGauge()
+
+	keys := c.codeAttributeKeys()
+	for _, processName := range processNames {
+		var hottest hottestStack
+		hottest.stackIndex = -1
+		for stackIndex, cpuTime := range stackTotals[processName] {
+			// Ties are broken by stack index to keep the selection deterministic
+			if hottest.stackIndex < 0 || cpuTime > hottest.cpuTime ||
+				(cpuTime == hottest.cpuTime && stackIndex < hottest.stackIndex) {
+				hottest = hottestStack{stackIndex: stackIndex, cpuTime: cpuTime}
+			}
+		}
+
+		share := 0.0
+		if processTotals[processName] > 0 {
+			share = hottest.cpuTime / processTotals[processName] * 100
+		}
+
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(share)
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		dataPoint.Attributes().PutStr("process.name", processName)
+
+		// Resolve the leaf through a sample sharing the stack, honoring frame_selection
+		sample := pprofile.NewSample()
+		sample.SetStackIndex(hottest.stackIndex)
+		if location, ok := c.getSampleTopLocation(profiles, sample); ok {
+			if functionName := c.getLocationFunctionName(profiles, location); functionName != "" {
+				dataPoint.Attributes().PutStr(keys.functionName, functionName)
+			}
+			if fileName := c.getLocationFileName(profiles, location); fileName != "" {
+				dataPoint.Attributes().PutStr(keys.fileName, fileName)
+			}
+		}
+		if c.config.Metrics.HottestStack.StackHash {
+			if frames := stackFunctionNamesCommon(profiles, hottest.stackIndex); len(frames) > 0 {
+				dataPoint.Attributes().PutStr(stackHashAttributeKey, stackHash(frames))
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_HottestStackMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:          CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:       MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			HottestStack: HottestStackMetricConfig{Enabled: true, MetricName: "hottest_stack_share"},
+		},
+		AggregationTemporality: "cumulative",
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	app := map[string]string{"process.executable.name": "app"}
+	hot := b.stack("main.main", "main.encode")
+	b.sample(hot, app, 2000000000, 0)
+	b.sample(b.stack("main.main", "main.decode"), app, 1000000000, 0)
+	b.sample(hot, app, 1000000000, 0)
+	b.sample(b.stack("worker.run"), map[string]string{"process.executable.name": "db"}, 500000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	var hottest pmetric.Metric
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() == "hottest_stack_share" {
+			hottest = metricSlice.At(i)
+		}
+	}
+	// Shares stay gauges regardless of the aggregation temporality
+	require.Equal(t, pmetric.MetricTypeGauge, hottest.Type())
+	assert.Equal(t, "%", hottest.Unit())
+
+	dataPoints := hottest.Gauge().DataPoints()
+	require.Equal(t, 2, dataPoints.Len())
+	assert.Equal(t, map[string]any{"process.name": "app", "function.name": "main.encode"}, dataPoints.At(0).Attributes().AsRaw())
+	assert.Equal(t, 75.0, dataPoints.At(0).DoubleValue())
+	assert.Equal(t, map[string]any{"process.name": "db", "function.name": "worker.run"}, dataPoints.At(1).Attributes().AsRaw())
+	assert.Equal(t, 100.0, dataPoints.At(1).DoubleValue())
+}
@@ -0,0 +1,174 @@
+package profiletometrics
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// IntervalAggregator consolidates the metrics of successive conversions into one batch per flush.
+// Data points of the same series (resource, scope, metric and attributes) are merged: delta sums
+// and the gauges the converter marked additive (CPU time, allocations, counts) add up, while every
+// other metric (shares, ratios, slopes, depths, live bytes, cumulative sums) keeps its latest value.
+// A merged point keeps the earliest start and the latest timestamp. It is safe for concurrent use.
+type IntervalAggregator struct {
+	mu sync.Mutex
+	// converter tells the additive gauges apart; without one only delta sums add up
+	converter *Converter
+	pending   pmetric.Metrics
+	// The maps index the handles of pending by series key, so adding a batch does not rescan it
+	resources map[string]pmetric.ResourceMetrics
+	scopes    map[string]pmetric.ScopeMetrics
+	metrics   map[string]pmetric.Metric
+	points    map[string]pmetric.NumberDataPoint
+}
+
+// NewIntervalAggregator creates an empty aggregator for the metrics of the given converter
+func NewIntervalAggregator(converter *Converter) *IntervalAggregator {
+	a := &IntervalAggregator{converter: converter}
+	a.reset()
+	return a
+}
+
+// reset starts a new interval
+func (a *IntervalAggregator) reset() {
+	a.pending = pmetric.NewMetrics()
+	a.resources = make(map[string]pmetric.ResourceMetrics)
+	a.scopes = make(map[string]pmetric.ScopeMetrics)
+	a.metrics = make(map[string]pmetric.Metric)
+	a.points = make(map[string]pmetric.NumberDataPoint)
+}
+
+// Add merges the metrics of a conversion into the current interval
+func (a *IntervalAggregator) Add(metrics pmetric.Metrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		resourceMetrics := metrics.ResourceMetrics().At(i)
+		resourceKey := attributesKey(resourceMetrics.Resource().Attributes())
+		targetResource, exists := a.resources[resourceKey]
+		if !exists {
+			targetResource = a.pending.ResourceMetrics().AppendEmpty()
+			resourceMetrics.Resource().CopyTo(targetResource.Resource())
+			targetResource.SetSchemaUrl(resourceMetrics.SchemaUrl())
+			a.resources[resourceKey] = targetResource
+		}
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			scopeMetrics := resourceMetrics.ScopeMetrics().At(j)
+			scopeKey := resourceKey + "\x00" + scopeMetrics.Scope().Name() + "\x00" + scopeMetrics.Scope().Version()
+			targetScope, exists := a.scopes[scopeKey]
+			if !exists {
+				targetScope = targetResource.ScopeMetrics().AppendEmpty()
+				scopeMetrics.Scope().CopyTo(targetScope.Scope())
+				targetScope.SetSchemaUrl(scopeMetrics.SchemaUrl())
+				a.scopes[scopeKey] = targetScope
+			}
+			for k := 0; k < scopeMetrics.Metrics().Len(); k++ {
+				a.addMetric(scopeKey, scopeMetrics.Metrics().At(k), targetScope)
+			}
+		}
+	}
+}
+
+// addMetric merges the data points of a metric into the metric of the same name and type
+func (a *IntervalAggregator) addMetric(scopeKey string, metric pmetric.Metric, targetScope pmetric.ScopeMetrics) {
+	var dataPoints pmetric.NumberDataPointSlice
+	metricKey := scopeKey + "\x00" + metric.Name() + "\x00" + metric.Type().String()
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dataPoints = metric.Gauge().DataPoints()
+	case pmetric.MetricTypeSum:
+		dataPoints = metric.Sum().DataPoints()
+		metricKey += "\x00" + metric.Sum().AggregationTemporality().String()
+	default:
+		// Only number data points are merged; other metric types are passed through
+		metric.CopyTo(targetScope.Metrics().AppendEmpty())
+		return
+	}
+
+	target, exists := a.metrics[metricKey]
+	if !exists {
+		target = targetScope.Metrics().AppendEmpty()
+		target.SetName(metric.Name())
+		target.SetDescription(metric.Description())
+		target.SetUnit(metric.Unit())
+		if metric.Type() == pmetric.MetricTypeGauge {
+			target.SetEmptyGauge()
+		} else {
+			sum := target.SetEmptySum()
+			sum.SetAggregationTemporality(metric.Sum().AggregationTemporality())
+			sum.SetIsMonotonic(metric.Sum().IsMonotonic())
+		}
+		a.metrics[metricKey] = target
+	}
+	var targetPoints pmetric.NumberDataPointSlice
+	if target.Type() == pmetric.MetricTypeGauge {
+		targetPoints = target.Gauge().DataPoints()
+	} else {
+		targetPoints = target.Sum().DataPoints()
+	}
+
+	latestOnly := !a.additive(metric)
+	for i := 0; i < dataPoints.Len(); i++ {
+		dataPoint := dataPoints.At(i)
+		pointKey := metricKey + "\x00" + attributesKey(dataPoint.Attributes())
+		merged, exists := a.points[pointKey]
+		if !exists {
+			merged = targetPoints.AppendEmpty()
+			dataPoint.CopyTo(merged)
+			a.points[pointKey] = merged
+			continue
+		}
+		mergeDataPoint(merged, dataPoint, latestOnly)
+	}
+}
+
+// additive reports whether the values of a metric add up across conversions
+func (a *IntervalAggregator) additive(metric pmetric.Metric) bool {
+	if a.converter == nil {
+		return metric.Type() == pmetric.MetricTypeSum && metric.Sum().AggregationTemporality() == pmetric.AggregationTemporalityDelta
+	}
+	return a.converter.additiveMetric(metric)
+}
+
+// mergeDataPoint folds a data point into the merged point of its series
+func mergeDataPoint(merged, dataPoint pmetric.NumberDataPoint, latestOnly bool) {
+	if start := dataPoint.StartTimestamp(); start != 0 && (merged.StartTimestamp() == 0 || start < merged.StartTimestamp()) {
+		merged.SetStartTimestamp(start)
+	}
+	later := dataPoint.Timestamp() >= merged.Timestamp()
+	if later {
+		merged.SetTimestamp(dataPoint.Timestamp())
+	}
+	switch {
+	case latestOnly && !later:
+		// An older point does not replace the latest value
+	case latestOnly && dataPoint.ValueType() == pmetric.NumberDataPointValueTypeInt:
+		merged.SetIntValue(dataPoint.IntValue())
+	case latestOnly:
+		merged.SetDoubleValue(dataPoint.DoubleValue())
+	case merged.ValueType() == pmetric.NumberDataPointValueTypeInt && dataPoint.ValueType() == pmetric.NumberDataPointValueTypeInt:
+		merged.SetIntValue(merged.IntValue() + dataPoint.IntValue())
+	default:
+		merged.SetDoubleValue(numberValue(merged) + numberValue(dataPoint))
+	}
+}
+
+// numberValue returns the value of a data point as a float
+func numberValue(dataPoint pmetric.NumberDataPoint) float64 {
+	if dataPoint.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(dataPoint.IntValue())
+	}
+	return dataPoint.DoubleValue()
+}
+
+// Flush returns the metrics consolidated since the previous flush and starts a new interval
+func (a *IntervalAggregator) Flush() pmetric.Metrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	metrics := a.pending
+	a.reset()
+	return metrics
+}
@@ -0,0 +1,151 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// newIntervalMetrics builds one conversion's output: an additive CPU time gauge per process, a
+// utilization gauge, an average CPU time gauge and a cumulative and a delta sum, all stamped with
+// the given timestamp; the CPU time gauge is marked additive by the given converter
+func newIntervalMetrics(converter *Converter, timestamp pcommon.Timestamp, value float64) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	resourceMetrics.Resource().Attributes().PutStr("service.name", "checkout")
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("profiletometrics")
+
+	cpuTime := scopeMetrics.Metrics().AppendEmpty()
+	cpuTime.SetName("cpu_time")
+	cpuTime.SetUnit("s")
+	converter.markAdditive(cpuTime)
+	cpuTimes := cpuTime.SetEmptyGauge().DataPoints()
+	for _, process := range []string{"api", "worker"} {
+		dataPoint := cpuTimes.AppendEmpty()
+		dataPoint.Attributes().PutStr("process.name", process)
+		dataPoint.SetStartTimestamp(timestamp - 10)
+		dataPoint.SetTimestamp(timestamp)
+		dataPoint.SetDoubleValue(value)
+	}
+
+	utilization := scopeMetrics.Metrics().AppendEmpty()
+	utilization.SetName("cpu_utilization")
+	utilization.SetUnit(percentUnit)
+	dataPoint := utilization.SetEmptyGauge().DataPoints().AppendEmpty()
+	dataPoint.SetTimestamp(timestamp)
+	dataPoint.SetDoubleValue(value * 10)
+
+	// Same unit as the CPU time, but not additive
+	average := scopeMetrics.Metrics().AppendEmpty()
+	average.SetName("cpu_time.avg")
+	average.SetUnit("s")
+	dataPoint = average.SetEmptyGauge().DataPoints().AppendEmpty()
+	dataPoint.SetTimestamp(timestamp)
+	dataPoint.SetDoubleValue(value / 2)
+
+	for _, temporality := range []pmetric.AggregationTemporality{
+		pmetric.AggregationTemporalityCumulative, pmetric.AggregationTemporalityDelta,
+	} {
+		sum := scopeMetrics.Metrics().AppendEmpty()
+		sum.SetName("samples." + temporality.String())
+		sum.SetEmptySum().SetAggregationTemporality(temporality)
+		dataPoint := sum.Sum().DataPoints().AppendEmpty()
+		dataPoint.SetTimestamp(timestamp)
+		dataPoint.SetIntValue(int64(value))
+	}
+	return metrics
+}
+
+func TestIntervalAggregator(t *testing.T) {
+	converter := &Converter{}
+	aggregator := NewIntervalAggregator(converter)
+	aggregator.Add(newIntervalMetrics(converter, 2000, 3))
+	aggregator.Add(newIntervalMetrics(converter, 1000, 1))
+
+	metrics := aggregator.Flush()
+	require.Equal(t, 1, metrics.ResourceMetrics().Len())
+	require.Equal(t, 1, metrics.ResourceMetrics().At(0).ScopeMetrics().Len())
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 5, metricSlice.Len())
+
+	cpuTime := metricSlice.At(0).Gauge().DataPoints()
+	require.Equal(t, 2, cpuTime.Len(), "each process keeps its own series")
+	for i := 0; i < cpuTime.Len(); i++ {
+		assert.Equal(t, 4.0, cpuTime.At(i).DoubleValue(), "additive gauges add up")
+		assert.Equal(t, pcommon.Timestamp(990), cpuTime.At(i).StartTimestamp())
+		assert.Equal(t, pcommon.Timestamp(2000), cpuTime.At(i).Timestamp())
+	}
+	assert.Equal(t, 30.0, metricSlice.At(1).Gauge().DataPoints().At(0).DoubleValue(),
+		"point-in-time gauges keep the latest value")
+	assert.Equal(t, 1.5, metricSlice.At(2).Gauge().DataPoints().At(0).DoubleValue(),
+		"gauges not marked additive keep the latest value")
+	assert.Equal(t, int64(3), metricSlice.At(3).Sum().DataPoints().At(0).IntValue(),
+		"cumulative sums keep the latest value")
+	assert.Equal(t, int64(4), metricSlice.At(4).Sum().DataPoints().At(0).IntValue(), "delta sums add up")
+	assert.Zero(t, metricSlice.At(0).Metadata().Len(), "additivity is not exported")
+
+	assert.Zero(t, aggregator.Flush().ResourceMetrics().Len(), "a flush starts a new interval")
+}
+
+func TestIntervalAggregator_ConvertedMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Stack: StackMetricConfig{
+				Enabled: true, AverageDepthMetricName: "stack.depth.avg", MaxDepthMetricName: "stack.depth.max",
+				TruncatedMetricName: "stack.truncated",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	aggregator := NewIntervalAggregator(converter)
+	for _, stack := range [][]string{{"main", "a", "b"}, {"main"}} {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.sample(b.stack(stack...), nil, 1000000000)
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		assertNoMetadata(t, metrics)
+		aggregator.Add(metrics)
+	}
+
+	values := make(map[string]float64)
+	flushed := aggregator.Flush()
+	assertNoMetadata(t, flushed)
+	metricSlice := flushed.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		values[metricSlice.At(i).Name()] = metricSlice.At(i).Gauge().DataPoints().At(0).DoubleValue()
+	}
+	assert.Equal(t, 2.0, values["cpu_time"], "CPU time adds up")
+	assert.Equal(t, 1.0, values["stack.depth.avg"], "depths keep the latest value")
+	assert.Equal(t, 1.0, values["stack.depth.max"], "depths keep the latest value")
+}
+
+func TestIntervalAggregator_WithoutConverter(t *testing.T) {
+	aggregator := NewIntervalAggregator(nil)
+	aggregator.Add(newIntervalMetrics(&Converter{}, 1000, 1))
+	aggregator.Add(newIntervalMetrics(&Converter{}, 2000, 3))
+
+	metricSlice := aggregator.Flush().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, 3.0, metricSlice.At(0).Gauge().DataPoints().At(0).DoubleValue(), "gauges keep the latest value")
+	assert.Equal(t, int64(4), metricSlice.At(4).Sum().DataPoints().At(0).IntValue(), "delta sums add up")
+}
+
+// assertNoMetadata checks that no emitted metric carries metadata
+func assertNoMetadata(t *testing.T, metrics pmetric.Metrics) {
+	t.Helper()
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				assert.Zero(t, metric.Metadata().Len(), metric.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package profiletometrics
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// JFR event types converted into profiles
+const (
+	jfrExecutionSampleEvent       = "jdk.ExecutionSample"
+	jfrAllocationInNewTLABEvent   = "jdk.ObjectAllocationInNewTLAB"
+	jfrAllocationOutsideTLABEvent = "jdk.ObjectAllocationOutsideTLAB"
+	jfrAllocationSampleEvent      = "jdk.ObjectAllocationSample"
+	jfrMonitorEnterEvent          = "jdk.JavaMonitorEnter"
+	jfrActiveSettingEvent         = "jdk.ActiveSetting"
+)
+
+const (
+	// jfrDefaultExecutionSamplePeriod is the Java sampling period of JFR's default settings, used
+	// when the recording does not carry its jdk.ExecutionSample period
+	jfrDefaultExecutionSamplePeriod = 20 * time.Millisecond
+
+	// jfrFrameType is the profile.frame.type of Java frames, as reported by the eBPF profiler
+	jfrFrameType = "jvm"
+)
+
+// jfrSettingUnits maps the duration units of JFR settings (e.g. "20 ms") to nanoseconds
+var jfrSettingUnits = map[string]int64{
+	"ns": 1,
+	"us": int64(time.Microsecond),
+	"ms": int64(time.Millisecond),
+	"s":  int64(time.Second),
+	"m":  int64(time.Minute),
+	"h":  int64(time.Hour),
+	"d":  24 * int64(time.Hour),
+}
+
+// ImportJFR decodes a Java Flight Recorder recording into pprofile.Profiles with one profile per
+// recorded kind: execution samples (samples/count weighted with the sampling period), allocations
+// (alloc_space/bytes) and monitor blocking (delay/nanoseconds). Stack frames are named
+// <class>.<method>, put leaf last and tagged as jvm frames; samples carry the Java thread.name.
+func ImportJFR(data []byte) (pprofile.Profiles, error) {
+	chunks, err := parseJFR(data)
+	if err != nil {
+		return pprofile.Profiles{}, err
+	}
+	imp := newJFRImporter()
+	for _, chunk := range chunks {
+		imp.addChunk(chunk)
+	}
+	return imp.profiles, nil
+}
+
+// ConvertJFRBytesToMetrics converts a JFR recording to metrics through the same path as OTLP profiles
+func (c *Converter) ConvertJFRBytesToMetrics(ctx context.Context, data []byte) (pmetric.Metrics, error) {
+	profiles, err := ImportJFR(data)
+	if err != nil {
+		return pmetric.NewMetrics(), err
+	}
+	return c.ConvertProfilesToMetrics(ctx, profiles)
+}
+
+// jfrLocationKey identifies a Java frame: a method at a line, with its frame type
+type jfrLocationKey struct {
+	function  string
+	line      int64
+	frameType string
+}
+
+// jfrImporter converts JFR events into pprofile.Profiles, interning dictionary entries once
+type jfrImporter struct {
+	*dictionaryBuilder
+	scope      pprofile.ScopeProfiles
+	kinds      map[string]pprofile.Profile
+	functions  map[string]int32
+	locations  map[jfrLocationKey]int32
+	startNanos int64
+	endNanos   int64
+}
+
+func newJFRImporter() *jfrImporter {
+	d := newDictionaryBuilder()
+	return &jfrImporter{
+		dictionaryBuilder: d,
+		scope:             d.profiles.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty(),
+		kinds:             make(map[string]pprofile.Profile),
+		functions:         make(map[string]int32),
+		locations:         make(map[jfrLocationKey]int32),
+	}
+}
+
+// addChunk converts the events of a chunk
+func (imp *jfrImporter) addChunk(chunk *jfrChunk) {
+	if imp.startNanos == 0 || chunk.startNanos < imp.startNanos {
+		imp.startNanos = chunk.startNanos
+	}
+	if end := chunk.startNanos + chunk.durationNanos; end > imp.endNanos {
+		imp.endNanos = end
+	}
+	period := jfrExecutionSamplePeriod(chunk)
+
+	for _, event := range chunk.events {
+		switch event.typeName {
+		case jfrExecutionSampleEvent:
+			profile := imp.profile("samples", "count")
+			profile.SetPeriod(period)
+			profile.PeriodType().SetTypeStrindex(imp.str("cpu"))
+			profile.PeriodType().SetUnitStrindex(imp.str("nanoseconds"))
+			imp.addSample(chunk, profile, event, "sampledThread", 1)
+		case jfrAllocationInNewTLABEvent, jfrAllocationOutsideTLABEvent:
+			imp.addSample(chunk, imp.profile("alloc_space", "bytes"), event, "eventThread",
+				chunk.int(event.fields["allocationSize"]))
+		case jfrAllocationSampleEvent:
+			imp.addSample(chunk, imp.profile("alloc_space", "bytes"), event, "eventThread",
+				chunk.int(event.fields["weight"]))
+		case jfrMonitorEnterEvent:
+			imp.addSample(chunk, imp.profile("delay", "nanoseconds"), event, "eventThread",
+				int64(chunk.tickDuration(chunk.int(event.fields["duration"]))))
+		}
+	}
+
+	for _, profile := range imp.kinds {
+		profile.SetTime(pcommon.Timestamp(imp.startNanos))
+		profile.SetDuration(pcommon.Timestamp(imp.endNanos - imp.startNanos))
+	}
+}
+
+// profile returns the profile of a sample type, created on first use
+func (imp *jfrImporter) profile(sampleType, unit string) pprofile.Profile {
+	if profile, exists := imp.kinds[sampleType]; exists {
+		return profile
+	}
+	profile := imp.scope.Profiles().AppendEmpty()
+	profile.SampleType().SetTypeStrindex(imp.str(sampleType))
+	profile.SampleType().SetUnitStrindex(imp.str(unit))
+	imp.kinds[sampleType] = profile
+	return profile
+}
+
+// addSample appends a sample with the event's stack, thread and time
+func (imp *jfrImporter) addSample(chunk *jfrChunk, profile pprofile.Profile, event jfrEvent, threadField string, value int64) {
+	sample := profile.Sample().AppendEmpty()
+	sample.Values().Append(value)
+	sample.SetStackIndex(imp.sampleStack(chunk, chunk.object(event.fields["stackTrace"])))
+	sample.TimestampsUnixNano().Append(uint64(chunk.nanos(chunk.int(event.fields["startTime"]))))
+
+	thread := chunk.object(event.fields[threadField])
+	threadName := chunk.string(thread["javaName"])
+	if threadName == "" {
+		threadName = chunk.string(thread["osName"])
+	}
+	if threadName != "" {
+		sample.AttributeIndices().Append(imp.attribute("thread.name", pcommon.NewValueStr(threadName), ""))
+	}
+}
+
+// sampleStack interns the frames of a JFR stack trace, reversed so the leaf comes last
+func (imp *jfrImporter) sampleStack(chunk *jfrChunk, stackTrace jfrObject) int32 {
+	frames, _ := stackTrace["frames"].([]any)
+	if len(frames) == 0 {
+		return 0
+	}
+	indices := make([]int32, 0, len(frames))
+	for i := len(frames) - 1; i >= 0; i-- {
+		frame := chunk.object(frames[i])
+		method := chunk.object(frame["method"])
+		className := strings.ReplaceAll(chunk.string(chunk.object(chunk.object(method["type"])["name"])["string"]), "/", ".")
+		methodName := chunk.string(chunk.object(method["name"])["string"])
+		functionName := methodName
+		if className != "" {
+			functionName = className + "." + methodName
+		}
+		frameType := jfrFrameType
+		if strings.EqualFold(chunk.string(chunk.object(frame["type"])["description"]), "Native") {
+			frameType = "native"
+		}
+		indices = append(indices, imp.location(jfrLocationKey{
+			function:  functionName,
+			line:      chunk.int(frame["lineNumber"]),
+			frameType: frameType,
+		}))
+	}
+	return imp.stack(indices)
+}
+
+// location interns a Java frame and its function
+func (imp *jfrImporter) location(key jfrLocationKey) int32 {
+	if index, exists := imp.locations[key]; exists {
+		return index
+	}
+	functionIndex, exists := imp.functions[key.function]
+	if !exists {
+		functionTable := imp.profiles.Dictionary().FunctionTable()
+		functionTable.AppendEmpty().SetNameStrindex(imp.str(key.function))
+		functionIndex = int32(functionTable.Len() - 1)
+		imp.functions[key.function] = functionIndex
+	}
+
+	locationTable := imp.profiles.Dictionary().LocationTable()
+	location := locationTable.AppendEmpty()
+	line := location.Line().AppendEmpty()
+	line.SetFunctionIndex(functionIndex)
+	line.SetLine(key.line)
+	location.AttributeIndices().Append(imp.attribute(frameTypeAttributeKey, pcommon.NewValueStr(key.frameType), ""))
+	index := int32(locationTable.Len() - 1)
+	imp.locations[key] = index
+	return index
+}
+
+// jfrExecutionSamplePeriod returns the jdk.ExecutionSample period recorded by jdk.ActiveSetting
+// events, in nanoseconds, or JFR's default period
+func jfrExecutionSamplePeriod(chunk *jfrChunk) int64 {
+	var executionSampleID int64 = -1
+	for id, class := range chunk.classes {
+		if class.name == jfrExecutionSampleEvent {
+			executionSampleID = id
+		}
+	}
+	for _, event := range chunk.events {
+		if event.typeName != jfrActiveSettingEvent || chunk.int(event.fields["id"]) != executionSampleID ||
+			chunk.string(event.fields["name"]) != "period" {
+			continue
+		}
+		if period, ok := parseJFRDuration(chunk.string(event.fields["value"])); ok && period > 0 {
+			return period
+		}
+	}
+	return int64(jfrDefaultExecutionSamplePeriod)
+}
+
+// parseJFRDuration parses a JFR duration setting such as "20 ms"
+func parseJFRDuration(value string) (int64, bool) {
+	parts := strings.Fields(value)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	amount, err := strconv.ParseInt(parts[0], 10, 64)
+	unit, ok := jfrSettingUnits[parts[1]]
+	if err != nil || !ok {
+		return 0, false
+	}
+	return amount * unit, true
+}
@@ -0,0 +1,374 @@
+package profiletometrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// jfrTestBuffer encodes JFR values with compressed integers
+type jfrTestBuffer struct {
+	bytes.Buffer
+}
+
+func (b *jfrTestBuffer) varLong(v int64) {
+	u := uint64(v)
+	for i := 0; i < 8; i++ {
+		if u < 0x80 {
+			b.WriteByte(byte(u))
+			return
+		}
+		b.WriteByte(byte(u&0x7f) | 0x80)
+		u >>= 7
+	}
+	b.WriteByte(byte(u))
+}
+
+func (b *jfrTestBuffer) utf8(s string) {
+	b.WriteByte(jfrStringUTF8)
+	b.varLong(int64(len(s)))
+	b.WriteString(s)
+}
+
+func (b *jfrTestBuffer) charArray(s string) {
+	b.WriteByte(jfrStringCharArray)
+	b.varLong(int64(len(s)))
+	for _, r := range s {
+		b.varLong(int64(r))
+	}
+}
+
+// jfrTestField declares a field of a test class: its name, class id, constant pool and array flags
+type jfrTestField struct {
+	name    string
+	classID int64
+	pool    bool
+	array   bool
+}
+
+// jfrTestClass declares a test class: its id, name and fields
+type jfrTestClass struct {
+	id     int64
+	name   string
+	fields []jfrTestField
+}
+
+// jfrTestClasses are the JFR types used by the test recording
+var jfrTestClasses = []jfrTestClass{
+	{10, "long", nil},
+	{11, "int", nil},
+	{12, "boolean", nil},
+	{13, "java.lang.String", nil},
+	{14, "jdk.types.Symbol", []jfrTestField{{name: "string", classID: 13}}},
+	{15, "java.lang.Class", []jfrTestField{{name: "name", classID: 14, pool: true}}},
+	{16, "jdk.types.Method", []jfrTestField{{name: "type", classID: 15, pool: true}, {name: "name", classID: 14, pool: true}}},
+	{17, "jdk.types.FrameType", []jfrTestField{{name: "description", classID: 13}}},
+	{18, "jdk.types.StackFrame", []jfrTestField{
+		{name: "method", classID: 16, pool: true}, {name: "lineNumber", classID: 11}, {name: "type", classID: 17, pool: true},
+	}},
+	{19, "jdk.types.StackTrace", []jfrTestField{{name: "truncated", classID: 12}, {name: "frames", classID: 18, array: true}}},
+	{20, "java.lang.Thread", []jfrTestField{{name: "javaName", classID: 13}}},
+	{30, "jdk.ExecutionSample", []jfrTestField{
+		{name: "startTime", classID: 10}, {name: "sampledThread", classID: 20, pool: true}, {name: "stackTrace", classID: 19, pool: true},
+	}},
+	{31, "jdk.ObjectAllocationSample", []jfrTestField{
+		{name: "startTime", classID: 10}, {name: "eventThread", classID: 20, pool: true},
+		{name: "stackTrace", classID: 19, pool: true}, {name: "weight", classID: 10},
+	}},
+	{32, "jdk.JavaMonitorEnter", []jfrTestField{
+		{name: "startTime", classID: 10}, {name: "duration", classID: 10},
+		{name: "eventThread", classID: 20, pool: true}, {name: "stackTrace", classID: 19, pool: true},
+	}},
+	{33, "jdk.ActiveSetting", []jfrTestField{
+		{name: "startTime", classID: 10}, {name: "id", classID: 10}, {name: "name", classID: 13}, {name: "value", classID: 13},
+	}},
+}
+
+// jfrTestEvent frames an event body with its size and type
+func jfrTestEvent(typeID int64, body func(b *jfrTestBuffer)) []byte {
+	var content jfrTestBuffer
+	content.varLong(typeID)
+	body(&content)
+	// The size includes its own compressed encoding
+	for n := 1; ; n++ {
+		var size jfrTestBuffer
+		size.varLong(int64(content.Len() + n))
+		if size.Len() == n {
+			return append(size.Bytes(), content.Bytes()...)
+		}
+	}
+}
+
+// jfrTestMetadata encodes the metadata event declaring the classes
+func jfrTestMetadata(classes []jfrTestClass) []byte {
+	var pool []string
+	index := func(s string) int64 {
+		for i, existing := range pool {
+			if existing == s {
+				return int64(i)
+			}
+		}
+		pool = append(pool, s)
+		return int64(len(pool) - 1)
+	}
+
+	var tree jfrTestBuffer
+	element := func(name string, attributes [][2]string, children int) {
+		tree.varLong(index(name))
+		tree.varLong(int64(len(attributes)))
+		for _, attribute := range attributes {
+			tree.varLong(index(attribute[0]))
+			tree.varLong(index(attribute[1]))
+		}
+		tree.varLong(int64(children))
+	}
+	element("root", nil, 2)
+	element("metadata", nil, len(classes))
+	for _, class := range classes {
+		element("class", [][2]string{{"id", strconv.FormatInt(class.id, 10)}, {"name", class.name}}, len(class.fields))
+		for _, field := range class.fields {
+			attributes := [][2]string{{"name", field.name}, {"class", strconv.FormatInt(field.classID, 10)}}
+			if field.pool {
+				attributes = append(attributes, [2]string{"constantPool", "true"})
+			}
+			if field.array {
+				attributes = append(attributes, [2]string{"dimension", "1"})
+			}
+			element("field", attributes, 0)
+		}
+	}
+	element("region", nil, 0)
+
+	return jfrTestEvent(jfrMetadataEventType, func(b *jfrTestBuffer) {
+		b.varLong(0) // start time
+		b.varLong(0) // duration
+		b.varLong(1) // metadata id
+		b.varLong(int64(len(pool)))
+		for _, s := range pool {
+			b.utf8(s)
+		}
+		b.Write(tree.Bytes())
+	})
+}
+
+// jfrTestConstantPools encodes the symbols, methods, stack trace and thread of the test recording:
+// com.example.App.main -> handle -> hot on thread worker-1
+func jfrTestConstantPools() []byte {
+	return jfrTestEvent(jfrConstantPoolEventType, func(b *jfrTestBuffer) {
+		b.varLong(0) // start time
+		b.varLong(0) // duration
+		b.varLong(0) // delta
+		b.WriteByte(0)
+		b.varLong(6) // pools
+
+		symbols := []string{"com/example/App", "main", "handle", "hot"}
+		b.varLong(14)
+		b.varLong(int64(len(symbols)))
+		for i, symbol := range symbols {
+			b.varLong(int64(i + 1))
+			b.utf8(symbol)
+		}
+
+		b.varLong(15)
+		b.varLong(1)
+		b.varLong(1) // key
+		b.varLong(1) // name symbol
+
+		b.varLong(16)
+		b.varLong(3)
+		for i := int64(1); i <= 3; i++ {
+			b.varLong(i)     // key
+			b.varLong(1)     // class
+			b.varLong(i + 1) // name symbol
+		}
+
+		b.varLong(17)
+		b.varLong(2)
+		b.varLong(1)
+		b.utf8("JIT compiled")
+		b.varLong(2)
+		b.utf8("Native")
+
+		// Frames are listed leaf first; the leaf is a native frame
+		b.varLong(19)
+		b.varLong(1)
+		b.varLong(1) // key
+		b.WriteByte(0)
+		b.varLong(3)
+		for _, frame := range []struct{ method, line, frameType int64 }{{3, 30, 2}, {2, 20, 1}, {1, 10, 1}} {
+			b.varLong(frame.method)
+			b.varLong(frame.line)
+			b.varLong(frame.frameType)
+		}
+
+		b.varLong(20)
+		b.varLong(1)
+		b.varLong(1)
+		b.charArray("worker-1")
+	})
+}
+
+// newTestJFRRecording returns a single-chunk recording with three execution samples, an allocation
+// sample of 4096 bytes, a monitor blocked for 500 ticks (500µs) and a 10 ms sampling period
+func newTestJFRRecording() []byte {
+	const (
+		startNanos     = 1700000000000000000
+		startTicks     = 1000
+		ticksPerSecond = 1000000
+	)
+	var events []byte
+	events = append(events, jfrTestConstantPools()...)
+	for i := int64(0); i < 3; i++ {
+		events = append(events, jfrTestEvent(30, func(b *jfrTestBuffer) {
+			b.varLong(startTicks + 10*i)
+			b.varLong(1)
+			b.varLong(1)
+		})...)
+	}
+	events = append(events, jfrTestEvent(31, func(b *jfrTestBuffer) {
+		b.varLong(startTicks)
+		b.varLong(1)
+		b.varLong(1)
+		b.varLong(4096)
+	})...)
+	events = append(events, jfrTestEvent(32, func(b *jfrTestBuffer) {
+		b.varLong(startTicks)
+		b.varLong(500)
+		b.varLong(1)
+		b.varLong(1)
+	})...)
+	events = append(events, jfrTestEvent(33, func(b *jfrTestBuffer) {
+		b.varLong(startTicks)
+		b.varLong(30)
+		b.utf8("period")
+		b.utf8("10 ms")
+	})...)
+	return jfrTestChunk(events, jfrTestClasses)
+}
+
+// jfrTestChunk frames events with the metadata declaring the classes in a single-chunk recording
+func jfrTestChunk(events []byte, classes []jfrTestClass) []byte {
+	const (
+		startNanos     = 1700000000000000000
+		startTicks     = 1000
+		ticksPerSecond = 1000000
+	)
+	metadataOffset := jfrChunkHeaderSize + len(events)
+	events = append(events, jfrTestMetadata(classes)...)
+
+	header := make([]byte, jfrChunkHeaderSize)
+	copy(header, jfrMagic)
+	binary.BigEndian.PutUint16(header[4:], 2)
+	binary.BigEndian.PutUint16(header[6:], 1)
+	binary.BigEndian.PutUint64(header[8:], uint64(jfrChunkHeaderSize+len(events)))
+	binary.BigEndian.PutUint64(header[16:], jfrChunkHeaderSize)
+	binary.BigEndian.PutUint64(header[24:], uint64(metadataOffset))
+	binary.BigEndian.PutUint64(header[32:], startNanos)
+	binary.BigEndian.PutUint64(header[40:], 1000000000)
+	binary.BigEndian.PutUint64(header[48:], startTicks)
+	binary.BigEndian.PutUint64(header[56:], ticksPerSecond)
+	binary.BigEndian.PutUint32(header[64:], jfrFeatureCompressedInts)
+	return append(header, events...)
+}
+
+// jfrTestProfile returns the imported profile of a sample type
+func jfrTestProfile(t *testing.T, profiles pprofile.Profiles, sampleType string) pprofile.Profile {
+	profileSlice := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles()
+	for i := 0; i < profileSlice.Len(); i++ {
+		if name, _ := getProfileSampleTypeCommon(profiles, profileSlice.At(i)); name == sampleType {
+			return profileSlice.At(i)
+		}
+	}
+	require.Failf(t, "profile not found", "sample type %q", sampleType)
+	return pprofile.Profile{}
+}
+
+func TestImportJFR(t *testing.T) {
+	profiles, err := ImportJFR(newTestJFRRecording())
+	require.NoError(t, err)
+	require.Equal(t, 3, profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().Len())
+
+	cpu := jfrTestProfile(t, profiles, "samples")
+	assert.Equal(t, int64(10000000), cpu.Period())
+	require.Equal(t, 3, cpu.Sample().Len())
+	sample := cpu.Sample().At(1)
+	assert.Equal(t, []int64{1}, sample.Values().AsRaw())
+	assert.Equal(t, []uint64{1700000000000010000}, sample.TimestampsUnixNano().AsRaw())
+	assert.Equal(t, "worker-1", getSampleAttributeValueCommon(profiles, sample, "thread.name"))
+
+	// The leaf comes last and keeps its frame type
+	leaf, ok := leafLocationCommon(profiles, sample.StackIndex())
+	require.True(t, ok)
+	name, _ := functionNameCommon(profiles, locationFunctionIndexCommon(leaf))
+	assert.Equal(t, "com.example.App.hot", name)
+	assert.Equal(t, int64(30), leaf.Line().At(0).Line())
+	assert.Equal(t, "native", getAttributeValueCommon(profiles, leaf.AttributeIndices(), frameTypeAttributeKey))
+	locationIndices, _ := stackLocationIndicesCommon(profiles, sample.StackIndex())
+	root, _ := locationAtCommon(profiles, locationIndices.At(0))
+	name, _ = functionNameCommon(profiles, locationFunctionIndexCommon(root))
+	assert.Equal(t, "com.example.App.main", name)
+	assert.Equal(t, jfrFrameType, getAttributeValueCommon(profiles, root.AttributeIndices(), frameTypeAttributeKey))
+
+	allocations := jfrTestProfile(t, profiles, "alloc_space")
+	require.Equal(t, 1, allocations.Sample().Len())
+	assert.Equal(t, []int64{4096}, allocations.Sample().At(0).Values().AsRaw())
+
+	delay := jfrTestProfile(t, profiles, "delay")
+	require.Equal(t, 1, delay.Sample().Len())
+	assert.Equal(t, []int64{500000}, delay.Sample().At(0).Values().AsRaw())
+}
+
+func TestImportJFR_InvalidRecording(t *testing.T) {
+	_, err := ImportJFR([]byte("not a recording"))
+	assert.Error(t, err)
+
+	recording := newTestJFRRecording()
+	_, err = ImportJFR(recording[:len(recording)-10])
+	assert.Error(t, err)
+}
+
+func TestImportJFR_SelfReferencingClass(t *testing.T) {
+	// An inline field of its own class nests objects without reading any byte
+	classes := []jfrTestClass{{40, "com.example.Node", []jfrTestField{{name: "next", classID: 40}}}}
+	recording := jfrTestChunk(jfrTestEvent(40, func(*jfrTestBuffer) {}), classes)
+
+	_, err := ImportJFR(recording)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nested too deeply")
+}
+
+func TestParseJFRDuration(t *testing.T) {
+	period, ok := parseJFRDuration("20 ms")
+	require.True(t, ok)
+	assert.Equal(t, int64(20000000), period)
+
+	_, ok = parseJFRDuration("everyChunk")
+	assert.False(t, ok)
+}
+
+func TestConverter_ConvertJFRBytesToMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+	})
+	require.NoError(t, err)
+
+	metrics, err := converter.ConvertJFRBytesToMetrics(context.Background(), newTestJFRRecording())
+	require.NoError(t, err)
+
+	// Three samples weighted with the 10 ms period
+	var cpuTime float64
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() == "cpu_time" {
+			cpuTime = metricSlice.At(i).Gauge().DataPoints().At(0).DoubleValue()
+			break
+		}
+	}
+	assert.InDelta(t, 0.03, cpuTime, 1e-9)
+}
@@ -0,0 +1,510 @@
+package profiletometrics
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+)
+
+// JFR chunk layout (JDK 11+ recordings, format 2.x)
+const (
+	jfrMagic                 = "FLR\x00"
+	jfrChunkHeaderSize       = 68
+	jfrFeatureCompressedInts = 1
+
+	jfrMetadataEventType     = 0
+	jfrConstantPoolEventType = 1
+)
+
+// JFR string encodings
+const (
+	jfrStringNull         = 0
+	jfrStringEmpty        = 1
+	jfrStringConstantPool = 2
+	jfrStringUTF8         = 3
+	jfrStringCharArray    = 4
+	jfrStringLatin1       = 5
+)
+
+// errJFRTruncated reports a read past the end of a chunk
+var errJFRTruncated = errors.New("truncated JFR chunk")
+
+// jfrReader reads the big-endian and LEB128-compressed values of a JFR chunk. The first error sticks:
+// further reads return zero values, so callers check err once per event.
+type jfrReader struct {
+	data       []byte
+	pos        int
+	compressed bool
+	err        error
+}
+
+func (r *jfrReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *jfrReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.fail(errJFRTruncated)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *jfrReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *jfrReader) fixedInt32() int32 {
+	if b := r.bytes(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *jfrReader) fixedInt64() int64 {
+	if b := r.bytes(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// varLong reads a compressed long: seven bits per byte, the ninth byte holding eight
+func (r *jfrReader) varLong() int64 {
+	if !r.compressed {
+		return r.fixedInt64()
+	}
+	var value uint64
+	for i := 0; i < 9; i++ {
+		b := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		if i == 8 {
+			return int64(value | uint64(b)<<56)
+		}
+		value |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			break
+		}
+	}
+	return int64(value)
+}
+
+func (r *jfrReader) varInt() int32 {
+	if !r.compressed {
+		return r.fixedInt32()
+	}
+	return int32(r.varLong())
+}
+
+// count reads a length, bounded by the bytes left so corrupt input cannot allocate huge slices
+func (r *jfrReader) count() int {
+	n := int(r.varInt())
+	if n < 0 || n > len(r.data)-r.pos {
+		r.fail(fmt.Errorf("invalid JFR length %d", n))
+		return 0
+	}
+	return n
+}
+
+// jfrField is a field of a JFR class
+type jfrField struct {
+	name         string
+	classID      int64
+	constantPool bool
+	array        bool
+}
+
+// jfrClass is a JFR type from the chunk metadata
+type jfrClass struct {
+	id     int64
+	name   string
+	fields []jfrField
+}
+
+// jfrObject holds the field values of a JFR event or constant by field name
+type jfrObject map[string]any
+
+// jfrRef is a reference to a constant of a class's constant pool
+type jfrRef struct {
+	classID int64
+	key     int64
+}
+
+// jfrEvent is a decoded JFR event
+type jfrEvent struct {
+	typeName string
+	fields   jfrObject
+}
+
+// jfrChunk is a parsed JFR chunk: its clock, types, constant pools and events
+type jfrChunk struct {
+	startNanos     int64
+	durationNanos  int64
+	startTicks     int64
+	ticksPerSecond int64
+	classes        map[int64]*jfrClass
+	stringClassID  int64
+	pools          map[int64]map[int64]any
+	events         []jfrEvent
+}
+
+// parseJFR parses every chunk of a JFR recording
+func parseJFR(data []byte) ([]*jfrChunk, error) {
+	var chunks []*jfrChunk
+	for len(data) > 0 {
+		chunk, size, err := parseJFRChunk(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JFR chunk %d: %w", len(chunks), err)
+		}
+		chunks = append(chunks, chunk)
+		data = data[size:]
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("empty JFR recording")
+	}
+	return chunks, nil
+}
+
+// parseJFRChunk parses the chunk at the start of data and returns it with its size
+func parseJFRChunk(data []byte) (*jfrChunk, int, error) {
+	if len(data) < jfrChunkHeaderSize || string(data[:4]) != jfrMagic {
+		return nil, 0, errors.New("not a JFR chunk")
+	}
+	header := &jfrReader{data: data, pos: 4}
+	if major := binary.BigEndian.Uint16(header.bytes(2)); major < 2 {
+		return nil, 0, fmt.Errorf("unsupported JFR version %d", major)
+	}
+	header.bytes(2) // minor version
+	chunkSize := header.fixedInt64()
+	header.fixedInt64() // constant pool offset: pools are read while scanning the events
+	metadataOffset := header.fixedInt64()
+	chunk := &jfrChunk{
+		startNanos:     header.fixedInt64(),
+		durationNanos:  header.fixedInt64(),
+		startTicks:     header.fixedInt64(),
+		ticksPerSecond: header.fixedInt64(),
+		classes:        make(map[int64]*jfrClass),
+		pools:          make(map[int64]map[int64]any),
+	}
+	features := header.fixedInt32()
+	if chunkSize < jfrChunkHeaderSize || chunkSize > int64(len(data)) {
+		return nil, 0, fmt.Errorf("invalid JFR chunk size %d", chunkSize)
+	}
+	if metadataOffset < jfrChunkHeaderSize || metadataOffset >= chunkSize {
+		return nil, 0, fmt.Errorf("invalid JFR metadata offset %d", metadataOffset)
+	}
+
+	r := &jfrReader{data: data[:chunkSize], compressed: features&jfrFeatureCompressedInts != 0}
+	r.pos = int(metadataOffset)
+	if err := chunk.readMetadata(r); err != nil {
+		return nil, 0, err
+	}
+
+	// Constant pools precede the events referencing them only by offset, so events are decoded
+	// once every pool is known
+	type rawEvent struct {
+		class *jfrClass
+		pos   int
+	}
+	var rawEvents []rawEvent
+	for pos := jfrChunkHeaderSize; pos < int(chunkSize); {
+		r.pos = pos
+		size := int(r.varInt())
+		typeID := r.varLong()
+		if r.err != nil {
+			return nil, 0, r.err
+		}
+		if size <= 0 || pos+size > int(chunkSize) {
+			return nil, 0, fmt.Errorf("invalid JFR event size %d at offset %d", size, pos)
+		}
+		switch typeID {
+		case jfrMetadataEventType:
+		case jfrConstantPoolEventType:
+			if err := chunk.readConstantPools(r); err != nil {
+				return nil, 0, err
+			}
+		default:
+			if class, ok := chunk.classes[typeID]; ok {
+				rawEvents = append(rawEvents, rawEvent{class: class, pos: r.pos})
+			}
+		}
+		pos += size
+	}
+	for _, raw := range rawEvents {
+		r.pos = raw.pos
+		fields := chunk.readObject(r, raw.class, 0)
+		if r.err != nil {
+			return nil, 0, fmt.Errorf("failed to read %s event: %w", raw.class.name, r.err)
+		}
+		chunk.events = append(chunk.events, jfrEvent{typeName: raw.class.name, fields: fields})
+	}
+	return chunk, int(chunkSize), nil
+}
+
+// jfrElement is a node of the metadata element tree
+type jfrElement struct {
+	name       string
+	attributes map[string]string
+	children   []*jfrElement
+}
+
+// readMetadata reads the metadata event and the classes it declares
+func (c *jfrChunk) readMetadata(r *jfrReader) error {
+	r.varInt() // size
+	if typeID := r.varLong(); typeID != jfrMetadataEventType {
+		return fmt.Errorf("unexpected JFR metadata event type %d", typeID)
+	}
+	r.varLong() // start time
+	r.varLong() // duration
+	r.varLong() // metadata id
+	pool := make([]string, r.count())
+	for i := range pool {
+		pool[i] = c.readString(r)
+	}
+	root := readJFRElement(r, pool, 0)
+	if r.err != nil {
+		return fmt.Errorf("failed to read JFR metadata: %w", r.err)
+	}
+
+	for _, metadata := range root.children {
+		if metadata.name != "metadata" {
+			continue
+		}
+		for _, element := range metadata.children {
+			if element.name != "class" {
+				continue
+			}
+			id, err := strconv.ParseInt(element.attributes["id"], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid JFR class id %q", element.attributes["id"])
+			}
+			class := &jfrClass{id: id, name: element.attributes["name"]}
+			for _, field := range element.children {
+				if field.name != "field" {
+					continue
+				}
+				classID, err := strconv.ParseInt(field.attributes["class"], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid JFR field class %q", field.attributes["class"])
+				}
+				class.fields = append(class.fields, jfrField{
+					name:         field.attributes["name"],
+					classID:      classID,
+					constantPool: field.attributes["constantPool"] == "true",
+					array:        field.attributes["dimension"] == "1",
+				})
+			}
+			c.classes[id] = class
+			if class.name == "java.lang.String" {
+				c.stringClassID = id
+			}
+		}
+	}
+	return nil
+}
+
+// jfrMaxDepth bounds the nesting of metadata elements and inline objects, which untrusted
+// recordings could otherwise make recurse without end
+const jfrMaxDepth = 32
+
+// readJFRElement reads a metadata element and its children, whose names and attributes index the
+// metadata string pool
+func readJFRElement(r *jfrReader, pool []string, depth int) *jfrElement {
+	str := func() string {
+		index := int(r.varInt())
+		if index < 0 || index >= len(pool) {
+			r.fail(fmt.Errorf("invalid JFR metadata string index %d", index))
+			return ""
+		}
+		return pool[index]
+	}
+	if depth > jfrMaxDepth {
+		r.fail(errors.New("JFR metadata nested too deeply"))
+		return &jfrElement{}
+	}
+
+	element := &jfrElement{name: str(), attributes: make(map[string]string)}
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		key := str()
+		element.attributes[key] = str()
+	}
+	for n := r.count(); n > 0 && r.err == nil; n-- {
+		element.children = append(element.children, readJFRElement(r, pool, depth+1))
+	}
+	return element
+}
+
+// readConstantPools reads the constants of a constant pool event
+func (c *jfrChunk) readConstantPools(r *jfrReader) error {
+	r.varLong() // start time
+	r.varLong() // duration
+	r.varLong() // delta to the previous constant pool event
+	r.byte()    // flush
+	for pools := r.count(); pools > 0 && r.err == nil; pools-- {
+		classID := r.varLong()
+		class, ok := c.classes[classID]
+		if !ok {
+			return fmt.Errorf("JFR constant pool of unknown class %d", classID)
+		}
+		pool := c.pools[classID]
+		if pool == nil {
+			pool = make(map[int64]any)
+			c.pools[classID] = pool
+		}
+		for constants := r.count(); constants > 0 && r.err == nil; constants-- {
+			key := r.varLong()
+			pool[key] = c.readValue(r, class, 0)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to read JFR constant pool: %w", r.err)
+	}
+	return nil
+}
+
+// readObject reads the fields of a class; depth is the number of objects it is nested in
+func (c *jfrChunk) readObject(r *jfrReader, class *jfrClass, depth int) jfrObject {
+	if depth > jfrMaxDepth {
+		r.fail(fmt.Errorf("JFR object of class %s nested too deeply", class.name))
+		return nil
+	}
+	object := make(jfrObject, len(class.fields))
+	for _, field := range class.fields {
+		if field.array {
+			values := make([]any, r.count())
+			for i := range values {
+				values[i] = c.readField(r, field, depth)
+			}
+			object[field.name] = values
+			continue
+		}
+		object[field.name] = c.readField(r, field, depth)
+	}
+	return object
+}
+
+// readField reads the value of a field of an object at the given depth
+func (c *jfrChunk) readField(r *jfrReader, field jfrField, depth int) any {
+	if field.constantPool {
+		return jfrRef{classID: field.classID, key: r.varLong()}
+	}
+	class, ok := c.classes[field.classID]
+	if !ok {
+		r.fail(fmt.Errorf("JFR field %s of unknown class %d", field.name, field.classID))
+		return nil
+	}
+	return c.readValue(r, class, depth+1)
+}
+
+// readValue reads a value of a primitive type, a string or, inline, an object nested depth deep
+func (c *jfrChunk) readValue(r *jfrReader, class *jfrClass, depth int) any {
+	switch class.name {
+	case "boolean":
+		return r.byte() != 0
+	case "byte":
+		return int64(int8(r.byte()))
+	case "short", "char", "int":
+		return int64(r.varInt())
+	case "long":
+		return r.varLong()
+	case "float":
+		return float64(math.Float32frombits(uint32(r.fixedInt32())))
+	case "double":
+		return math.Float64frombits(uint64(r.fixedInt64()))
+	case "java.lang.String":
+		return c.readStringValue(r)
+	default:
+		return c.readObject(r, class, depth)
+	}
+}
+
+// readString reads a string that cannot reference the constant pool
+func (c *jfrChunk) readString(r *jfrReader) string {
+	value, _ := c.readStringValue(r).(string)
+	return value
+}
+
+// readStringValue reads a string, or a reference to a string constant
+func (c *jfrChunk) readStringValue(r *jfrReader) any {
+	switch encoding := r.byte(); encoding {
+	case jfrStringNull, jfrStringEmpty:
+		return ""
+	case jfrStringConstantPool:
+		return jfrRef{classID: c.stringClassID, key: r.varLong()}
+	case jfrStringUTF8:
+		return string(r.bytes(r.count()))
+	case jfrStringCharArray:
+		chars := make([]uint16, r.count())
+		for i := range chars {
+			chars[i] = uint16(r.varInt())
+		}
+		return string(utf16.Decode(chars))
+	case jfrStringLatin1:
+		latin1 := r.bytes(r.count())
+		runes := make([]rune, len(latin1))
+		for i, b := range latin1 {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		r.fail(fmt.Errorf("unknown JFR string encoding %d", encoding))
+		return ""
+	}
+}
+
+// resolve follows constant pool references; unknown constants resolve to nil
+func (c *jfrChunk) resolve(value any) any {
+	for i := 0; i < 8; i++ {
+		ref, ok := value.(jfrRef)
+		if !ok {
+			return value
+		}
+		value = c.pools[ref.classID][ref.key]
+	}
+	return nil
+}
+
+// object resolves a field value to an object
+func (c *jfrChunk) object(value any) jfrObject {
+	object, _ := c.resolve(value).(jfrObject)
+	return object
+}
+
+// string resolves a field value to a string
+func (c *jfrChunk) string(value any) string {
+	str, _ := c.resolve(value).(string)
+	return str
+}
+
+// int resolves a field value to an integer
+func (c *jfrChunk) int(value any) int64 {
+	number, _ := c.resolve(value).(int64)
+	return number
+}
+
+// nanos converts a tick timestamp to Unix nanoseconds
+func (c *jfrChunk) nanos(ticks int64) int64 {
+	return c.startNanos + int64(c.tickDuration(ticks-c.startTicks))
+}
+
+// tickDuration converts a tick count to nanoseconds
+func (c *jfrChunk) tickDuration(ticks int64) float64 {
+	if c.ticksPerSecond <= 0 {
+		return float64(ticks)
+	}
+	return float64(ticks) * nanosecondsPerSecond / float64(c.ticksPerSecond)
+}
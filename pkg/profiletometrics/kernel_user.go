@@ -0,0 +1,120 @@
+package profiletometrics
+
+import (
+	"path"
+	"regexp"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// kernelMappingPattern matches the basename of kernel image mappings: vmlinux (optionally
+// versioned) and perf-style pseudo mappings such as [kernel.kallsyms]
+var kernelMappingPattern = regexp.MustCompile(`^(vmlinux([-.].*)?|\[kernel[^\]]*\])$`)
+
+// isKernelLocation reports whether a location is kernel code, based on the frame type attribute or,
+// when absent, on the mapping being the kernel image
+func (c *Converter) isKernelLocation(profiles dictionaryProvider, location pprofile.Location) bool {
+	if frameType := getAttributeValueCommon(profiles, location.AttributeIndices(), frameTypeAttributeKey); frameType != "" {
+		return frameType == frameCategoryKernel
+	}
+	mappingFile := c.getLocationMappingFileName(profiles, location)
+	return mappingFile != "" && kernelMappingPattern.MatchString(path.Base(mappingFile))
+}
+
+// kernelUserTotals is the CPU time of a group of samples whose leaf frame is in the kernel and in user space
+type kernelUserTotals struct {
+	kernel float64
+	user   float64
+}
+
+// generateKernelUserMetrics emits the CPU time of CPU profiles split between samples whose leaf
+// frame is kernel code and user code, for the profile and per process like the CPU time metrics
+func (c *Converter) generateKernelUserMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+	matchedProcessNames []string,
+) {
+	if sampleType, _ := getProfileSampleTypeCommon(profiles, profile); !isCPUSampleType(sampleType) {
+		return
+	}
+
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	var profileTotals kernelUserTotals
+	processTotals := make(map[string]*kernelUserTotals)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok || locationIndices.Len() == 0 {
+			continue
+		}
+		location, ok := locationAtCommon(profiles, locationIndices.At(locationIndices.Len()-1))
+		if !ok {
+			continue
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		totals, exists := processTotals[processName]
+		if !exists {
+			totals = &kernelUserTotals{}
+			processTotals[processName] = totals
+		}
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		if c.isKernelLocation(profiles, location) {
+			profileTotals.kernel += cpuTime
+			totals.kernel += cpuTime
+		} else {
+			profileTotals.user += cpuTime
+			totals.user += cpuTime
+		}
+	}
+
+	processNames := matchedProcessNames
+	if !c.config.ProcessFilter.Enabled {
+		processNames = c.getUniqueProcessNames(profiles, profile)
+	}
+	sort.Strings(processNames)
+
+	cfg := c.config.Metrics.KernelUser
+	kernelGauge := c.appendSecondsGauge(scopeMetrics, cfg.KernelMetricName, "CPU time in seconds spent in kernel code")
+	userGauge := c.appendSecondsGauge(scopeMetrics, cfg.UserMetricName, "CPU time in seconds spent in user code")
+	appendTotals := func(totals kernelUserTotals, processName string) {
+		for _, point := range []struct {
+			gauge pmetric.Gauge
+			value float64
+		}{{kernelGauge, totals.kernel}, {userGauge, totals.user}} {
+			dataPoint := point.gauge.DataPoints().AppendEmpty()
+			c.setDataPointTimestamps(dataPoint, profile)
+			dataPoint.SetDoubleValue(point.value)
+			for k, v := range attributes {
+				dataPoint.Attributes().PutStr(k, v)
+			}
+			if processName != "" {
+				dataPoint.Attributes().PutStr("process.name", processName)
+			}
+		}
+	}
+	if c.emitsGlobalMetrics() {
+		appendTotals(profileTotals, "")
+	}
+	for _, processName := range processNames {
+		var totals kernelUserTotals
+		if processTotals[processName] != nil {
+			totals = *processTotals[processName]
+		}
+		appendTotals(totals, processName)
+	}
+}
+
+// appendSecondsGauge appends an empty gauge in seconds
+func (c *Converter) appendSecondsGauge(scopeMetrics pmetric.ScopeMetrics, name, description string) pmetric.Gauge {
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetDescription(description)
+	metric.SetUnit("s")
+	c.markAdditive(metric)
+	return metric.SetEmptyGauge()
+}
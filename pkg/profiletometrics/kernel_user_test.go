@@ -0,0 +1,83 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// withLeafMapping maps the leaf frame of a stack to a binary
+func withLeafMapping(b *testProfileBuilder, stackIndex int32, filename string) int32 {
+	locationIndices := b.profiles.Dictionary().StackTable().At(int(stackIndex)).LocationIndices()
+	location := b.profiles.Dictionary().LocationTable().At(int(locationIndices.At(locationIndices.Len() - 1)))
+	location.SetMappingIndex(b.mapping(filename))
+	return stackIndex
+}
+
+func TestConverter_KernelUserMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			KernelUser: KernelUserMetricConfig{Enabled: true, KernelMetricName: "cpu.time.kernel", UserMetricName: "cpu.time.user"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(withLeafFrameType(b, b.stack("main", "read", "vfs_read"), "kernel"), app, 1000000000)
+	b.sample(withLeafMapping(b, b.stack("main", "write", "ksys_write"), "/boot/vmlinux-6.1.0"), app, 2000000000)
+	b.sample(withLeafFrameType(b, b.stack("main", "handle"), "native"), app, 3000000000)
+	b.sample(withLeafMapping(b, b.stack("worker.run", "do_syscall_64"), "[kernel.kallsyms]"), map[string]string{"process.executable.name": "db"}, 500000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+		assert.Equal(t, "s", metric.Unit())
+		dataPoints := metric.Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			processName, _ := dataPoints.At(j).Attributes().Get("process.name")
+			values[metric.Name()+"/"+processName.Str()] = dataPoints.At(j).DoubleValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"cpu.time.kernel/":    3.5,
+		"cpu.time.user/":      3,
+		"cpu.time.kernel/app": 3,
+		"cpu.time.user/app":   3,
+		"cpu.time.kernel/db":  0.5,
+		"cpu.time.user/db":    0,
+	}, values)
+}
+
+func TestConverter_KernelUserMetricsSkipNonCPUProfiles(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			KernelUser: KernelUserMetricConfig{Enabled: true, KernelMetricName: "cpu.time.kernel", UserMetricName: "cpu.time.user"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("alloc_space", "bytes")
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "app"}, 4096)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Equal(t, 0, metrics.DataPointCount())
+}
+
+func TestKernelMappingPattern(t *testing.T) {
+	for _, name := range []string{"vmlinux", "vmlinux-6.1.0-13-amd64", "[kernel.kallsyms]", "[kernel]"} {
+		assert.True(t, kernelMappingPattern.MatchString(name), name)
+	}
+	for _, name := range []string{"[vdso]", "libc.so.6", "vmlinuxtool", "server"} {
+		assert.False(t, kernelMappingPattern.MatchString(name), name)
+	}
+}
@@ -0,0 +1,89 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// codeLineNumberAttributeKey is the data point attribute holding the source line of a function
+const codeLineNumberAttributeKey = "code.lineno"
+
+// getLocationLineNumber returns the source line of the function identifying a location, or 0
+// when the location carries no line information
+func getLocationLineNumber(location pprofile.Location) int64 {
+	if location.Line().Len() == 0 {
+		return 0
+	}
+	return location.Line().At(0).Line()
+}
+
+// calculateFunctionLineDataPoints aggregates self values per (process, function, line) for
+// hot-line analysis
+func (c *Converter) calculateFunctionLineDataPoints(profiles dictionaryProvider, profile pprofile.Profile) []functionDataPoint {
+	type functionLineKey struct {
+		processFunctionKey
+		lineNumber int64
+	}
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	byKey := make(map[functionLineKey]*functionDataPoint)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		location, ok := c.getSampleTopLocation(profiles, sample)
+		if !ok {
+			continue
+		}
+		functionName := c.getLocationFunctionName(profiles, location)
+		if functionName == "" {
+			continue
+		}
+
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		key := functionLineKey{
+			processFunctionKey: processFunctionKey{processName: processName, functionName: functionName},
+			lineNumber:         getLocationLineNumber(location),
+		}
+		point, exists := byKey[key]
+		if !exists {
+			point = &functionDataPoint{
+				processName:  processName,
+				functionName: functionName,
+				fileName:     c.getLocationFileName(profiles, location),
+				lineNumber:   key.lineNumber,
+				attribution:  functionAttributionSelf,
+			}
+			if c.config.Metrics.Function.CodeFilePath {
+				point.codeFilePath = c.getLocationCodeFilePath(profiles, location)
+			}
+			byKey[key] = point
+		}
+		point.cpuTime += c.sampleCPUTime(sample, weight, summary)
+		point.memory += c.sampleMemoryAllocation(sample, summary)
+	}
+
+	points := make([]functionDataPoint, 0, len(byKey))
+	for _, point := range byKey {
+		points = append(points, *point)
+	}
+	sortFunctionLineDataPoints(points)
+	return points
+}
+
+// sortFunctionLineDataPoints orders data points by process, thread, function and line
+func sortFunctionLineDataPoints(points []functionDataPoint) {
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if a.processName != b.processName {
+			return a.processName < b.processName
+		}
+		if a.threadName != b.threadName {
+			return a.threadName < b.threadName
+		}
+		if a.functionName != b.functionName {
+			return a.functionName < b.functionName
+		}
+		return a.lineNumber < b.lineNumber
+	})
+}
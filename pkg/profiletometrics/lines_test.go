@@ -0,0 +1,63 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_FunctionLineNumbers(t *testing.T) {
+	for _, attribution := range []string{"self", "total"} {
+		t.Run(attribution, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Function: FunctionMetricConfig{
+						Enabled:            true,
+						Attribution:        attribution,
+						IncludeLineNumbers: true,
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			dictionary := b.profiles.Dictionary()
+			mainLocation := b.function("main.main", "main.go")
+			functionIndex := dictionary.LocationTable().At(int(b.function("main.encode", "encode.go"))).Line().At(0).FunctionIndex()
+			process := map[string]string{"process.executable.name": "app"}
+			for _, sample := range []struct {
+				line  int64
+				value int64
+			}{{42, 2000000000}, {57, 1000000000}, {42, 500000000}} {
+				location := dictionary.LocationTable().AppendEmpty()
+				line := location.Line().AppendEmpty()
+				line.SetFunctionIndex(functionIndex)
+				line.SetLine(sample.line)
+				stack := dictionary.StackTable().AppendEmpty()
+				stack.LocationIndices().Append(mainLocation, int32(dictionary.LocationTable().Len()-1))
+				b.sample(int32(dictionary.StackTable().Len()-1), process, sample.value, 0)
+			}
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			lines := make(map[int64]float64)
+			cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+				dp := cpuMetric.Gauge().DataPoints().At(i)
+				functionName, ok := dp.Attributes().Get("function.name")
+				if !ok || functionName.Str() != "main.encode" {
+					continue
+				}
+				lineNumber, ok := dp.Attributes().Get("code.lineno")
+				require.True(t, ok)
+				lines[lineNumber.Int()] = dp.DoubleValue()
+			}
+			assert.Equal(t, map[int64]float64{42: 2.5, 57: 1}, lines)
+		})
+	}
+}
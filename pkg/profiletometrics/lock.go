@@ -0,0 +1,131 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+const (
+	lockSampleKindNone = iota
+	lockSampleKindDelay
+	lockSampleKindCount
+)
+
+// lockDelaySampleTypes lists sample type names reporting contention time in nanoseconds
+// (Go mutex/block profiles use "delay", async-profiler and Pyroscope use the *_duration variants)
+var lockDelaySampleTypes = map[string]bool{
+	"delay":          true,
+	"lock_time":      true,
+	"mutex_duration": true,
+	"block_duration": true,
+}
+
+// lockCountSampleTypes lists sample type names reporting the number of contention events
+var lockCountSampleTypes = map[string]bool{
+	"contentions": true,
+	"lock_count":  true,
+	"mutex_count": true,
+	"block_count": true,
+}
+
+// processFunctionKey identifies a (process, function) pair
+type processFunctionKey struct {
+	processName  string
+	functionName string
+}
+
+// getLockSampleKind classifies the profile's sample type for lock contention metrics
+func (c *Converter) getLockSampleKind(profiles dictionaryProvider, profile pprofile.Profile) int {
+	sampleType, _ := getProfileSampleTypeCommon(profiles, profile)
+	switch {
+	case lockDelaySampleTypes[sampleType]:
+		return lockSampleKindDelay
+	case lockCountSampleTypes[sampleType]:
+		return lockSampleKindCount
+	default:
+		return lockSampleKindNone
+	}
+}
+
+// generateLockMetrics generates lock contention metrics per process and function for mutex/block profiles
+func (c *Converter) generateLockMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	kind := c.getLockSampleKind(profiles, profile)
+	if kind == lockSampleKindNone {
+		c.logDebug("Profile sample type is not a lock contention type - skipping lock metrics")
+		return
+	}
+
+	totals := make(map[processFunctionKey]float64)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		values := sample.Values()
+		if values.Len() == 0 {
+			continue
+		}
+
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		if processName == "" {
+			continue
+		}
+		functionName := c.getSampleFunctionName(profiles, sample)
+		if functionName == "" {
+			continue
+		}
+
+		value := float64(values.At(0))
+		if kind == lockSampleKindDelay {
+			value /= nanosecondsPerSecond
+		}
+		totals[processFunctionKey{processName: processName, functionName: functionName}] += value
+	}
+
+	if len(totals) == 0 {
+		c.logDebug("No lock contention samples found in profile")
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	if kind == lockSampleKindDelay {
+		metric.SetName(c.config.Metrics.Lock.ContentionTimeMetricName)
+		metric.SetDescription("Lock contention time in seconds")
+	} else {
+		metric.SetName(c.config.Metrics.Lock.ContentionCountMetricName)
+		metric.SetDescription("Lock contention events")
+	}
+	c.markAdditive(metric)
+	gauge := metric.SetEmptyGauge()
+
+	keys := make([]processFunctionKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		return keys[i].functionName < keys[j].functionName
+	})
+
+	for _, key := range keys {
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(totals[key])
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		dataPoint.Attributes().PutStr("process.name", key.processName)
+		dataPoint.Attributes().PutStr(c.codeAttributeKeys().functionName, key.functionName)
+	}
+
+	c.logDebug("Generated lock contention metrics",
+		zap.String("metric_name", metric.Name()),
+		zap.Int("data_points", len(keys)))
+}
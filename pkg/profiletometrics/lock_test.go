@@ -0,0 +1,87 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_GenerateLockMetrics(t *testing.T) {
+	config := &ConverterConfig{
+		Metrics: MetricsConfig{
+			Lock: LockMetricConfig{
+				Enabled:                   true,
+				ContentionTimeMetricName:  "lock_contention_time",
+				ContentionCountMetricName: "lock_contention_count",
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		sampleType   string
+		expectedName string
+		expected     map[string]float64
+	}{
+		{
+			name:         "delay samples are converted to seconds",
+			sampleType:   "delay",
+			expectedName: "lock_contention_time",
+			expected:     map[string]float64{"sync.(*Mutex).Lock": 1.5, "runtime.chanrecv": 0.25},
+		},
+		{
+			name:         "contention samples are counted",
+			sampleType:   "contentions",
+			expectedName: "lock_contention_count",
+			expected:     map[string]float64{"sync.(*Mutex).Lock": 1.5e9, "runtime.chanrecv": 2.5e8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(config)
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType(tt.sampleType, "nanoseconds")
+			mutexStack := b.stack("main", "sync.(*Mutex).Lock")
+			chanStack := b.stack("main", "runtime.chanrecv")
+			process := map[string]string{"process.executable.name": "app"}
+			b.sample(mutexStack, process, 1000000000)
+			b.sample(mutexStack, process, 500000000)
+			b.sample(chanStack, process, 250000000)
+
+			scopeMetrics := pmetric.NewScopeMetrics()
+			converter.generateLockMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+			require.Equal(t, 1, scopeMetrics.Metrics().Len())
+			metric := scopeMetrics.Metrics().At(0)
+			assert.Equal(t, tt.expectedName, metric.Name())
+
+			dataPoints := metric.Gauge().DataPoints()
+			require.Equal(t, len(tt.expected), dataPoints.Len())
+			for i := 0; i < dataPoints.Len(); i++ {
+				dp := dataPoints.At(i)
+				processName, _ := dp.Attributes().Get("process.name")
+				assert.Equal(t, "app", processName.Str())
+				functionName, _ := dp.Attributes().Get("function.name")
+				assert.InDelta(t, tt.expected[functionName.Str()], dp.DoubleValue(), 1e-9)
+			}
+		})
+	}
+}
+
+func TestConverter_GenerateLockMetricsIgnoresOtherSampleTypes(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Lock: LockMetricConfig{Enabled: true}},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "app"}, 1000)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateLockMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+	assert.Equal(t, 0, scopeMetrics.Metrics().Len())
+}
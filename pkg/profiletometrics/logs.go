@@ -0,0 +1,156 @@
+package profiletometrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// Log payload formats
+const (
+	LogPayloadFormatAuto  = "auto"
+	LogPayloadFormatPprof = "pprof"
+	LogPayloadFormatOTLP  = "otlp"
+)
+
+const (
+	// defaultLogPayloadField reads payloads from the log record body
+	defaultLogPayloadField = "body"
+
+	// logPayloadAttributePrefix selects a log record attribute as the payload field
+	logPayloadAttributePrefix = "attributes."
+)
+
+// gzipMagic starts gzip-compressed payloads, i.e. pprof files
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// validateLogs checks the log payload field and format
+func validateLogs(cfg LogsConfig) error {
+	if field := cfg.PayloadField; field != "" && field != defaultLogPayloadField &&
+		(!strings.HasPrefix(field, logPayloadAttributePrefix) || field == logPayloadAttributePrefix) {
+		return fmt.Errorf("invalid logs.payload_field %q: must be %q or %q<key>",
+			field, defaultLogPayloadField, logPayloadAttributePrefix)
+	}
+	return validatePayloadFormat("logs.payload_format", cfg.PayloadFormat)
+}
+
+// validatePayloadFormat checks the format of embedded profile payloads
+func validatePayloadFormat(option, format string) error {
+	switch format {
+	case "", LogPayloadFormatAuto, LogPayloadFormatPprof, LogPayloadFormatOTLP:
+		return nil
+	default:
+		return fmt.Errorf("invalid %s %q: must be %q, %q or %q",
+			option, format, LogPayloadFormatAuto, LogPayloadFormatPprof, LogPayloadFormatOTLP)
+	}
+}
+
+// ConvertLogsToMetrics decodes the pprof or OTLP profile payloads embedded in log records (see
+// LogsConfig) and converts them to metrics. pprof payloads take the resource attributes of their log;
+// records without a payload are ignored and undecodable payloads are skipped with a warning.
+func (c *Converter) ConvertLogsToMetrics(ctx context.Context, logs plog.Logs) (pmetric.Metrics, error) {
+	metrics := pmetric.NewMetrics()
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		resourceLogs := logs.ResourceLogs().At(i)
+		for j := 0; j < resourceLogs.ScopeLogs().Len(); j++ {
+			records := resourceLogs.ScopeLogs().At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				payload, ok, err := c.logRecordPayload(records.At(k))
+				if err == nil && ok {
+					var profiles pprofile.Profiles
+					profiles, err = decodeProfilePayload(payload, c.config.Logs.PayloadFormat, resourceLogs.Resource())
+					if err == nil {
+						var converted pmetric.Metrics
+						if converted, err = c.ConvertProfilesToMetrics(ctx, profiles); err != nil {
+							return metrics, err
+						}
+						converted.ResourceMetrics().MoveAndAppendTo(metrics.ResourceMetrics())
+					}
+				}
+				if err != nil {
+					c.logWarn("Skipping log record with an undecodable profile payload",
+						zap.Int("resource_index", i), zap.Int("scope_index", j), zap.Int("record_index", k), zap.Error(err))
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// logRecordPayload returns the payload bytes of a log record, or false when the field is absent or empty
+func (c *Converter) logRecordPayload(record plog.LogRecord) ([]byte, bool, error) {
+	value := record.Body()
+	if field := c.config.Logs.PayloadField; strings.HasPrefix(field, logPayloadAttributePrefix) {
+		var exists bool
+		if value, exists = record.Attributes().Get(strings.TrimPrefix(field, logPayloadAttributePrefix)); !exists {
+			return nil, false, nil
+		}
+	}
+	return profilePayloadBytes(value)
+}
+
+// profilePayloadBytes returns the bytes of a raw or base64-encoded payload, or false when it is empty
+// or neither bytes nor a string
+func profilePayloadBytes(value pcommon.Value) ([]byte, bool, error) {
+	switch value.Type() {
+	case pcommon.ValueTypeBytes:
+		return value.Bytes().AsRaw(), value.Bytes().Len() > 0, nil
+	case pcommon.ValueTypeStr:
+		if value.Str() == "" {
+			return nil, false, nil
+		}
+		payload, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value.Str()))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid base64 payload: %w", err)
+		}
+		return payload, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// decodeProfilePayload decodes a pprof or OTLP profile payload; pprof payloads take the given
+// resource attributes. In auto mode gzip-compressed payloads are pprof; other payloads are tried as
+// OTLP first and as uncompressed pprof otherwise.
+func decodeProfilePayload(payload []byte, format string, resource pcommon.Resource) (pprofile.Profiles, error) {
+	if format == "" || format == LogPayloadFormatAuto {
+		if bytes.HasPrefix(payload, gzipMagic) {
+			format = LogPayloadFormatPprof
+		} else if profiles, err := decodeOTLPProfiles(payload); err == nil {
+			return profiles, nil
+		} else {
+			format = LogPayloadFormatPprof
+		}
+	}
+
+	if format == LogPayloadFormatOTLP {
+		return decodeOTLPProfiles(payload)
+	}
+	profiles, err := ImportPprof(payload)
+	if err != nil {
+		return pprofile.Profiles{}, err
+	}
+	resource.Attributes().CopyTo(profiles.ResourceProfiles().At(0).Resource().Attributes())
+	return profiles, nil
+}
+
+// decodeOTLPProfiles decodes an OTLP ExportProfilesServiceRequest holding at least one profile
+func decodeOTLPProfiles(payload []byte) (pprofile.Profiles, error) {
+	profiles, err := (&pprofile.ProtoUnmarshaler{}).UnmarshalProfiles(payload)
+	if err != nil {
+		return pprofile.Profiles{}, fmt.Errorf("failed to decode OTLP profiles: %w", err)
+	}
+	if profiles.ResourceProfiles().Len() == 0 {
+		return pprofile.Profiles{}, errors.New("OTLP payload holds no profiles")
+	}
+	return profiles, nil
+}
@@ -0,0 +1,99 @@
+package profiletometrics
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func newLogsTestConverter(t *testing.T, cfg LogsConfig) *Converter {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+		Logs:    cfg,
+	})
+	require.NoError(t, err)
+	return converter
+}
+
+// logsCPUTime sums the cpu_time data points of converted logs
+func logsCPUTime(metrics pmetric.Metrics) float64 {
+	var total float64
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				if metric := scopeMetrics.At(j).Metrics().At(k); metric.Name() == "cpu_time" {
+					total += metric.Gauge().DataPoints().At(0).DoubleValue()
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestConverter_ConvertLogsToMetrics_PprofBody(t *testing.T) {
+	converter := newLogsTestConverter(t, LogsConfig{})
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr(base64.StdEncoding.EncodeToString(encodePprof(t, newGoCPUPprof())))
+	records.AppendEmpty().Body().SetStr("not base64!")
+	records.AppendEmpty().Body().SetStr("")
+
+	metrics, err := converter.ConvertLogsToMetrics(context.Background(), logs)
+	require.NoError(t, err)
+	// 40 samples weighted with the 10ms period; the invalid and empty records are skipped
+	assert.InDelta(t, 0.4, logsCPUTime(metrics), 1e-9)
+}
+
+func TestConverter_ConvertLogsToMetrics_OTLPAttribute(t *testing.T) {
+	converter := newLogsTestConverter(t, LogsConfig{PayloadField: "attributes.profile", PayloadFormat: LogPayloadFormatOTLP})
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "hot"), nil, 2000000000)
+	payload, err := (&pprofile.ProtoMarshaler{}).MarshalProfiles(b.profiles)
+	require.NoError(t, err)
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	record := records.AppendEmpty()
+	record.Body().SetStr("profile attached")
+	record.Attributes().PutEmptyBytes("profile").FromRaw(payload)
+	// Records without the attribute are ignored
+	records.AppendEmpty().Body().SetStr("plain log line")
+
+	metrics, err := converter.ConvertLogsToMetrics(context.Background(), logs)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, logsCPUTime(metrics), 1e-9)
+}
+
+func TestConverter_ConvertLogsToMetrics_AutoDetectsOTLP(t *testing.T) {
+	converter := newLogsTestConverter(t, LogsConfig{PayloadFormat: LogPayloadFormatAuto})
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main"), nil, 500000000)
+	payload, err := (&pprofile.ProtoMarshaler{}).MarshalProfiles(b.profiles)
+	require.NoError(t, err)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().
+		SetStr(base64.StdEncoding.EncodeToString(payload))
+
+	metrics, err := converter.ConvertLogsToMetrics(context.Background(), logs)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, logsCPUTime(metrics), 1e-9)
+}
+
+func TestValidateLogs(t *testing.T) {
+	assert.NoError(t, validateLogs(LogsConfig{}))
+	assert.NoError(t, validateLogs(LogsConfig{PayloadField: "attributes.pprof", PayloadFormat: LogPayloadFormatPprof}))
+	assert.Error(t, validateLogs(LogsConfig{PayloadField: "attributes."}))
+	assert.Error(t, validateLogs(LogsConfig{PayloadField: "severity"}))
+	assert.Error(t, validateLogs(LogsConfig{PayloadFormat: "jfr"}))
+}
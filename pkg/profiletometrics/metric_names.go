@@ -0,0 +1,94 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// generatorMetricNames are the CPU and memory metric names of a generator and the attributes
+// identifying its data points
+type generatorMetricNames struct {
+	generator string
+	shape     string
+	cpu       string
+	memory    string
+}
+
+// processMetricNames returns the CPU and memory metric names of process metrics: the process names
+// when set, the CPU and memory names otherwise, followed by the process suffix
+func (c *Converter) processMetricNames() (string, string) {
+	metrics := c.config.Metrics
+	cpu, memory := metrics.Process.CPUMetricName, metrics.Process.MemoryMetricName
+	if cpu == "" {
+		cpu = metrics.CPU.MetricName
+	}
+	if memory == "" {
+		memory = metrics.Memory.MetricName
+	}
+	return cpu + metrics.Process.MetricNameSuffix, memory + metrics.Process.MetricNameSuffix
+}
+
+// threadMetricNames returns the CPU and memory metric names of thread metrics
+func (c *Converter) threadMetricNames() (string, string) {
+	metrics := c.config.Metrics
+	return metrics.CPU.MetricName + metrics.Thread.MetricNameSuffix, metrics.Memory.MetricName + metrics.Thread.MetricNameSuffix
+}
+
+// functionMetricNames returns the CPU and memory metric names of function metrics
+func (c *Converter) functionMetricNames() (string, string) {
+	metrics := c.config.Metrics
+	return metrics.CPU.MetricName + metrics.Function.MetricNameSuffix, metrics.Memory.MetricName + metrics.Function.MetricNameSuffix
+}
+
+// MetricNameCollisions describes the metric names emitted by several enabled generators (global,
+// process, thread, container, function) with different attribute shapes, which some backends reject
+// or merge. Setting metric_name_suffix on the process, thread, container or function metrics
+// resolves a collision.
+func (c *Converter) MetricNameCollisions() []string {
+	metrics := c.config.Metrics
+	var generators []generatorMetricNames
+	if c.emitsGlobalMetrics() {
+		global := generatorMetricNames{generator: "global", shape: "profile attributes"}
+		if metrics.CPU.Enabled {
+			global.cpu = metrics.CPU.MetricName
+		}
+		if metrics.Memory.Enabled {
+			global.memory = metrics.Memory.MetricName
+		}
+		generators = append(generators, global)
+	}
+	if metrics.Process.Enabled {
+		cpu, memory := c.processMetricNames()
+		generators = append(generators, generatorMetricNames{"process", "process.name", cpu, memory})
+	}
+	if metrics.Thread.Enabled {
+		cpu, memory := c.threadMetricNames()
+		generators = append(generators, generatorMetricNames{"thread", "thread.name", cpu, memory})
+	}
+	if metrics.GroupBy == metricsGroupByContainer && c.emitsGlobalMetrics() {
+		cpu, memory := c.containerMetricNames()
+		generators = append(generators, generatorMetricNames{"container", "container.id", cpu, memory})
+	}
+	if metrics.Function.Enabled {
+		cpu, memory := c.functionMetricNames()
+		generators = append(generators, generatorMetricNames{"function", "function.name", cpu, memory})
+	}
+
+	users := make(map[string][]string)
+	for _, names := range generators {
+		for _, name := range []string{names.cpu, names.memory} {
+			if name != "" {
+				users[name] = append(users[name], fmt.Sprintf("%s (%s)", names.generator, names.shape))
+			}
+		}
+	}
+	var collisions []string
+	for name, generatorNames := range users {
+		if len(generatorNames) > 1 {
+			collisions = append(collisions, fmt.Sprintf("metric %q is emitted by %s", name, strings.Join(generatorNames, ", ")))
+		}
+	}
+	sort.Strings(collisions)
+	return collisions
+}
@@ -0,0 +1,60 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_MetricNameSuffixes(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+			Process:  ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+			Thread:   ThreadMetricConfig{Enabled: true, MetricNameSuffix: ".by_thread"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, converter.MetricNameCollisions())
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app", "thread.name": "worker"}, 100, 10)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	names := make(map[string]bool)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		names[metricSlice.At(i).Name()] = true
+	}
+	for _, name := range []string{
+		"cpu_time", "memory_allocation",
+		"cpu_time.by_process", "memory_allocation.by_process",
+		"cpu_time.by_thread", "memory_allocation.by_thread",
+		"cpu_time.by_function", "memory_allocation.by_function",
+	} {
+		assert.True(t, names[name], name)
+	}
+}
+
+func TestConverter_MetricNameCollisions(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true},
+			Process:  ProcessMetricConfig{Enabled: true, CPUMetricName: "process_cpu_time"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`metric "cpu_time" is emitted by global (profile attributes), function (function.name)`,
+		`metric "memory_allocation" is emitted by global (profile attributes), process (process.name), function (function.name)`,
+	}, converter.MetricNameCollisions())
+}
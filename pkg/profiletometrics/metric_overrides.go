@@ -0,0 +1,186 @@
+package profiletometrics
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// metricOverrides returns the configured overrides by metric name, nil when none is configured
+func (c *Converter) metricOverrides() map[string]MetricOverrides {
+	metrics := c.config.Metrics
+	diffDelta, diffChange := c.functionDiffMetricNames()
+	anomalyScore := c.functionAnomalyMetricName()
+
+	entries := []struct {
+		names     []string
+		overrides MetricOverrides
+	}{
+		{c.cpuTimeMetricNames(), metrics.CPU.MetricOverrides},
+		{c.memoryMetricNames(), metrics.Memory.MetricOverrides},
+		{[]string{metrics.CPUUtilization.MetricName}, metrics.CPUUtilization.MetricOverrides},
+		{[]string{metrics.Function.Slope.MetricName}, metrics.Function.Slope.MetricOverrides},
+		{[]string{diffDelta, diffChange}, metrics.Function.Diff.MetricOverrides},
+		{[]string{anomalyScore}, metrics.Function.Anomaly.MetricOverrides},
+		{[]string{metrics.HottestStack.MetricName}, metrics.HottestStack.MetricOverrides},
+		{[]string{metrics.HotPath.MetricName}, metrics.HotPath.MetricOverrides},
+		{[]string{metrics.Symbolization.MetricName}, metrics.Symbolization.MetricOverrides},
+		{[]string{metrics.CodeOrigin.MetricName}, metrics.CodeOrigin.MetricOverrides},
+		{[]string{metrics.FrameType.MetricName}, metrics.FrameType.MetricOverrides},
+		{[]string{metrics.KernelUser.KernelMetricName, metrics.KernelUser.UserMetricName}, metrics.KernelUser.MetricOverrides},
+		{[]string{metrics.CallGraph.MetricName}, metrics.CallGraph.MetricOverrides},
+		{[]string{metrics.TraceCorrelation.MetricName}, metrics.TraceCorrelation.MetricOverrides},
+		{[]string{metrics.Exceptions.MetricName}, metrics.Exceptions.MetricOverrides},
+	}
+
+	for _, custom := range metrics.Custom {
+		entries = append(entries, struct {
+			names     []string
+			overrides MetricOverrides
+		}{[]string{custom.MetricName}, custom.MetricOverrides})
+	}
+
+	var overrides map[string]MetricOverrides
+	for _, entry := range entries {
+		if entry.overrides.Description == "" && len(entry.overrides.StaticAttributes) == 0 {
+			continue
+		}
+		if overrides == nil {
+			overrides = make(map[string]MetricOverrides)
+		}
+		for _, name := range entry.names {
+			if name != "" {
+				overrides[name] = entry.overrides
+			}
+		}
+	}
+	return overrides
+}
+
+// cpuTimeMetricNames returns the names of the CPU time metrics of profiles, processes, threads,
+// containers and functions
+func (c *Converter) cpuTimeMetricNames() []string {
+	processCPU, _ := c.processMetricNames()
+	threadCPU, _ := c.threadMetricNames()
+	containerCPU, _ := c.containerMetricNames()
+	functionCPU, _ := c.functionMetricNames()
+	return []string{c.config.Metrics.CPU.MetricName, processCPU, threadCPU, containerCPU, functionCPU}
+}
+
+// memoryMetricNames returns the names of the memory allocation metrics of profiles, processes,
+// threads, containers and functions
+func (c *Converter) memoryMetricNames() []string {
+	_, processMemory := c.processMetricNames()
+	_, threadMemory := c.threadMetricNames()
+	_, containerMemory := c.containerMetricNames()
+	_, functionMemory := c.functionMetricNames()
+	return []string{c.config.Metrics.Memory.MetricName, processMemory, threadMemory, containerMemory, functionMemory}
+}
+
+// metricUnits returns the configured units of the CPU time and memory metrics by metric name,
+// nil when no unit is configured
+func (c *Converter) metricUnits() map[string]string {
+	var units map[string]string
+	for _, entry := range []struct {
+		names []string
+		unit  string
+	}{
+		{c.cpuTimeMetricNames(), c.config.Metrics.CPU.Unit},
+		{c.memoryMetricNames(), c.config.Metrics.Memory.Unit},
+	} {
+		if entry.unit == "" {
+			continue
+		}
+		if units == nil {
+			units = make(map[string]string)
+		}
+		for _, name := range entry.names {
+			if name != "" {
+				units[name] = entry.unit
+			}
+		}
+	}
+	return units
+}
+
+// applyMetricUnits sets the configured units of the emitted metrics
+func applyMetricUnits(metrics pmetric.Metrics, units map[string]string) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				if unit, exists := units[metricSlice.At(k).Name()]; exists {
+					metricSlice.At(k).SetUnit(unit)
+				}
+			}
+		}
+	}
+}
+
+// applyMetricOverrides sets the configured descriptions and static attributes of the emitted metrics
+func applyMetricOverrides(metrics pmetric.Metrics, overrides map[string]MetricOverrides) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				override, exists := overrides[metric.Name()]
+				if !exists {
+					continue
+				}
+				if override.Description != "" {
+					metric.SetDescription(override.Description)
+				}
+				var dataPoints pmetric.NumberDataPointSlice
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					for key, value := range override.StaticAttributes {
+						dataPoints.At(l).Attributes().PutStr(key, value)
+					}
+				}
+			}
+		}
+	}
+}
+
+// validateMetricOverrides checks that the static attributes have keys
+func (cfg *ConverterConfig) validateMetricOverrides() error {
+	metrics := cfg.Metrics
+	entries := []struct {
+		option    string
+		overrides MetricOverrides
+	}{
+		{"metrics.cpu", metrics.CPU.MetricOverrides},
+		{"metrics.memory", metrics.Memory.MetricOverrides},
+		{"metrics.cpu_utilization", metrics.CPUUtilization.MetricOverrides},
+		{"metrics.function.slope", metrics.Function.Slope.MetricOverrides},
+		{"metrics.function.diff", metrics.Function.Diff.MetricOverrides},
+		{"metrics.function.anomaly", metrics.Function.Anomaly.MetricOverrides},
+		{"metrics.hottest_stack", metrics.HottestStack.MetricOverrides},
+		{"metrics.hot_path", metrics.HotPath.MetricOverrides},
+		{"metrics.symbolization", metrics.Symbolization.MetricOverrides},
+		{"metrics.code_origin", metrics.CodeOrigin.MetricOverrides},
+		{"metrics.frame_type", metrics.FrameType.MetricOverrides},
+		{"metrics.kernel_user", metrics.KernelUser.MetricOverrides},
+		{"metrics.callgraph", metrics.CallGraph.MetricOverrides},
+		{"metrics.trace_correlation", metrics.TraceCorrelation.MetricOverrides},
+		{"metrics.exceptions", metrics.Exceptions.MetricOverrides},
+	}
+	var errs []error
+	for _, entry := range entries {
+		if _, exists := entry.overrides.StaticAttributes[""]; exists {
+			errs = append(errs, fmt.Errorf("%s.static_attributes must not have an empty key", entry.option))
+		}
+	}
+	return errors.Join(errs...)
+}
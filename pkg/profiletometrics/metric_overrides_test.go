@@ -0,0 +1,84 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_MetricOverrides(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU: CPUMetricConfig{
+				Enabled:    true,
+				MetricName: "cpu_time",
+				Unit:       "s",
+				MetricOverrides: MetricOverrides{
+					Description:      "On-CPU time of the checkout service",
+					StaticAttributes: map[string]string{"team": "payments", "env": "prod"},
+				},
+			},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "checkout"}, 1000000000, 2048)
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	found := make(map[string]bool)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				found[metric.Name()] = true
+				switch metric.Name() {
+				case "cpu_time", "cpu_time.by_function":
+					assert.Equal(t, "On-CPU time of the checkout service", metric.Description(), metric.Name())
+					assertStaticAttributes(t, metric, map[string]string{"team": "payments", "env": "prod"})
+					assert.Equal(t, "s", metric.Unit(), metric.Name())
+				case "memory_allocation", "memory_allocation.by_function":
+					assert.Equal(t, "Memory allocation in bytes", metric.Description(), metric.Name())
+					assertStaticAttributes(t, metric, nil)
+					assert.Empty(t, metric.Unit(), metric.Name())
+				}
+			}
+		}
+	}
+	assert.True(t, found["cpu_time"])
+	assert.True(t, found["cpu_time.by_function"])
+}
+
+// assertStaticAttributes checks that every data point of a gauge carries the static attributes, or
+// none of the given keys when they are nil
+func assertStaticAttributes(t *testing.T, metric pmetric.Metric, expected map[string]string) {
+	t.Helper()
+	dataPoints := metric.Gauge().DataPoints()
+	require.Positive(t, dataPoints.Len())
+	for i := 0; i < dataPoints.Len(); i++ {
+		attributes := dataPoints.At(i).Attributes()
+		if expected == nil {
+			_, ok := attributes.Get("team")
+			assert.False(t, ok, metric.Name())
+			continue
+		}
+		for key, value := range expected {
+			actual, ok := attributes.Get(key)
+			require.True(t, ok, "%s %s", metric.Name(), key)
+			assert.Equal(t, value, actual.Str())
+		}
+	}
+}
+
+func TestValidateMetricOverrides(t *testing.T) {
+	cfg := &ConverterConfig{}
+	cfg.Metrics.Exceptions.StaticAttributes = map[string]string{"": "x"}
+	assert.ErrorContains(t, cfg.Validate(), "metrics.exceptions.static_attributes")
+}
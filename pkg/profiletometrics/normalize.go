@@ -0,0 +1,185 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Languages of the function name normalization rules
+	normalizationLanguageGo     = "go"
+	normalizationLanguageJava   = "java"
+	normalizationLanguagePython = "python"
+)
+
+var (
+	// javaLambdaPattern matches the generated part of lambda class names: $Lambda$123 and the hidden
+	// class address of $$Lambda$123/0x0000000800c0b000
+	javaLambdaPattern = regexp.MustCompile(`\$Lambda\$\d+(/0x[0-9a-fA-F]+)?`)
+	// goReceiverPattern matches pointer receivers such as (*Server)
+	goReceiverPattern = regexp.MustCompile(`\(\*([^()]+)\)`)
+	// pythonLocationPattern matches a trailing source location: "handle (/app/views.py:42)"
+	pythonLocationPattern = regexp.MustCompile(`\s+\([^()]*\.py(:\d+)?\)$`)
+	// pythonPathPattern matches a leading source path: "/app/views.py:handle"
+	pythonPathPattern = regexp.MustCompile(`^[^\s:]*\.py:`)
+)
+
+// normalizationLanguages maps the runtime.name values to the language of their rules, lower case
+var normalizationLanguages = map[string]string{
+	"go":                              normalizationLanguageGo,
+	"gc":                              normalizationLanguageGo,
+	"java":                            normalizationLanguageJava,
+	"jvm":                             normalizationLanguageJava,
+	"openjdk runtime environment":     normalizationLanguageJava,
+	"java(tm) se runtime environment": normalizationLanguageJava,
+	"python":                          normalizationLanguagePython,
+	"cpython":                         normalizationLanguagePython,
+	"pypy":                            normalizationLanguagePython,
+}
+
+// validateNormalization checks the normalization options
+func validateNormalization(cfg NormalizationConfig) error {
+	switch cfg.DefaultRuntime {
+	case "", normalizationLanguageGo, normalizationLanguageJava, normalizationLanguagePython:
+		return nil
+	default:
+		return fmt.Errorf("invalid normalization.default_runtime %q: must be %q, %q or %q", cfg.DefaultRuntime,
+			normalizationLanguageGo, normalizationLanguageJava, normalizationLanguagePython)
+	}
+}
+
+// normalizationLanguage returns the language whose rules normalize the function names of a profile:
+// the one of its runtime.name, else default_runtime. It is empty when normalization is disabled or
+// the runtime has no rules.
+func normalizationLanguage(cfg NormalizationConfig, runtimeName func() string) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	if name := runtimeName(); name != "" {
+		return normalizationLanguages[strings.ToLower(name)]
+	}
+	return cfg.DefaultRuntime
+}
+
+// runtimeNameCommon returns the runtime.name of a profile, looked up on the resource then on the profile
+func runtimeNameCommon(profiles dictionaryProvider, profile pprofile.Profile, resourceAttributes map[string]string) string {
+	if name := resourceAttributes[runtimeNameAttributeKey]; name != "" {
+		return name
+	}
+	return getAttributeValueCommon(profiles, profile.AttributeIndices(), runtimeNameAttributeKey)
+}
+
+// normalizeFunctionName applies the rules of a language to a function name
+func normalizeFunctionName(language, name string) string {
+	switch language {
+	case normalizationLanguageJava:
+		return stripTemplateArguments(javaLambdaPattern.ReplaceAllString(name, "$$Lambda"))
+	case normalizationLanguageGo:
+		return goReceiverPattern.ReplaceAllString(stripGoTypeArguments(name), "$1")
+	case normalizationLanguagePython:
+		return pythonPathPattern.ReplaceAllString(pythonLocationPattern.ReplaceAllString(name, ""), "")
+	default:
+		return name
+	}
+}
+
+// stripTemplateArguments removes the generic arguments of a name, e.g. Cache<String, Entry>.get
+// becomes Cache.get; the JVM's special methods <init> and <clinit> are kept
+func stripTemplateArguments(name string) string {
+	if !strings.Contains(name, "<") {
+		return name
+	}
+	var result strings.Builder
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '<' && depth == 0 && (strings.HasPrefix(name[i:], "<init>") || strings.HasPrefix(name[i:], "<clinit>")):
+			end := strings.IndexByte(name[i:], '>')
+			result.WriteString(name[i : i+end+1])
+			i += end
+		case name[i] == '<':
+			depth++
+		case name[i] == '>' && depth > 0:
+			depth--
+		case depth == 0:
+			result.WriteByte(name[i])
+		}
+	}
+	return result.String()
+}
+
+// stripGoTypeArguments collapses the type arguments of generic instantiations, e.g.
+// Map[go.shape.int,go.shape.string].Get becomes Map[...].Get
+func stripGoTypeArguments(name string) string {
+	if !strings.Contains(name, "[") {
+		return name
+	}
+	var result strings.Builder
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '[':
+			if depth == 0 {
+				result.WriteString("[...]")
+			}
+			depth++
+		case name[i] == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			result.WriteByte(name[i])
+		}
+	}
+	return result.String()
+}
+
+// functionNameRules selects how the function names of a profile are rewritten: demangled with a
+// symbol.demangle mode, then normalized with the rules of a language
+type functionNameRules struct {
+	demangle string
+	language string
+}
+
+// functionNameRulesFor returns the function name rules of a profile
+func functionNameRulesFor(
+	cfg *ConverterConfig,
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	resourceAttributes map[string]string,
+) functionNameRules {
+	demangleMode := cfg.Symbol.Demangle
+	if demangleMode == symbolDemangleNone {
+		demangleMode = ""
+	}
+	return functionNameRules{
+		demangle: demangleMode,
+		language: normalizationLanguage(cfg.Normalization, func() string {
+			return runtimeNameCommon(profiles, profile, resourceAttributes)
+		}),
+	}
+}
+
+// apply rewrites a function name
+func (r functionNameRules) apply(name string) string {
+	return normalizeFunctionName(r.language, demangleSymbol(r.demangle, name))
+}
+
+// normalizedFunctionNameCommon returns a function name rewritten with the rules of the dictionary
+// cache, memoized per conversion
+func normalizedFunctionNameCommon(profiles dictionaryProvider, name string) string {
+	cache, ok := profiles.(*dictionaryCache)
+	if !ok || cache.rules == (functionNameRules{}) || name == "" {
+		return name
+	}
+	if normalized, exists := cache.normalizedNames[name]; exists {
+		return normalized
+	}
+	if cache.normalizedNames == nil {
+		cache.normalizedNames = make(map[string]string)
+	}
+	normalized := cache.rules.apply(name)
+	cache.normalizedNames[name] = normalized
+	return normalized
+}
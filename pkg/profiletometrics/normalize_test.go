@@ -0,0 +1,132 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestNormalizeFunctionName(t *testing.T) {
+	tests := []struct {
+		language string
+		name     string
+		expected string
+	}{
+		{"java", "com.shop.Cart$$Lambda$123/0x0000000800c0b000.apply", "com.shop.Cart$$Lambda.apply"},
+		{"java", "com.shop.Cart$Lambda$7.run", "com.shop.Cart$Lambda.run"},
+		{"java", "com.shop.Cache<java.lang.String, com.shop.Entry<T>>.get", "com.shop.Cache.get"},
+		{"java", "com.shop.Cart.<init>", "com.shop.Cart.<init>"},
+		{"java", "com.shop.Cart.<clinit>", "com.shop.Cart.<clinit>"},
+		{"go", "net/http.(*Server).Serve", "net/http.Server.Serve"},
+		{"go", "main.(*Map[go.shape.int,go.shape.string]).Get", "main.Map[...].Get"},
+		{"go", "main.main.func1", "main.main.func1"},
+		{"python", "handle (/usr/lib/python3.11/site-packages/app/views.py:42)", "handle"},
+		{"python", "/app/views.py:handle", "handle"},
+		{"python", "views.handle", "views.handle"},
+		{"", "net/http.(*Server).Serve", "net/http.(*Server).Serve"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizeFunctionName(tt.language, tt.name))
+		})
+	}
+}
+
+func TestNormalizationLanguage(t *testing.T) {
+	runtime := func(name string) func() string { return func() string { return name } }
+	enabled := NormalizationConfig{Enabled: true, DefaultRuntime: "python"}
+
+	assert.Equal(t, "", normalizationLanguage(NormalizationConfig{}, runtime("go")))
+	assert.Equal(t, "go", normalizationLanguage(enabled, runtime("go")))
+	assert.Equal(t, "java", normalizationLanguage(enabled, runtime("OpenJDK Runtime Environment")))
+	assert.Equal(t, "python", normalizationLanguage(enabled, runtime("CPython")))
+	assert.Equal(t, "", normalizationLanguage(enabled, runtime("node")))
+	assert.Equal(t, "python", normalizationLanguage(enabled, runtime("")))
+}
+
+func TestValidateNormalization(t *testing.T) {
+	assert.NoError(t, validateNormalization(NormalizationConfig{DefaultRuntime: "java"}))
+	assert.ErrorContains(t, validateNormalization(NormalizationConfig{DefaultRuntime: "ruby"}),
+		`invalid normalization.default_runtime "ruby"`)
+}
+
+func TestConverter_Normalization(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization NormalizationConfig
+		runtimeName   string
+		expected      map[string]float64
+	}{
+		{
+			name:          "runtime name selects the rules",
+			normalization: NormalizationConfig{Enabled: true},
+			runtimeName:   "java",
+			expected:      map[string]float64{"com.shop.Cart$$Lambda.apply": 3},
+		},
+		{
+			name:          "default runtime",
+			normalization: NormalizationConfig{Enabled: true, DefaultRuntime: "java"},
+			expected:      map[string]float64{"com.shop.Cart$$Lambda.apply": 3},
+		},
+		{
+			name:          "other runtime rules",
+			normalization: NormalizationConfig{Enabled: true},
+			runtimeName:   "go",
+			expected: map[string]float64{
+				"com.shop.Cart$$Lambda$1/0x01.apply": 1,
+				"com.shop.Cart$$Lambda$2/0x02.apply": 2,
+			},
+		},
+		{
+			name:        "disabled",
+			runtimeName: "java",
+			expected: map[string]float64{
+				"com.shop.Cart$$Lambda$1/0x01.apply": 1,
+				"com.shop.Cart$$Lambda$2/0x02.apply": 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+				},
+				Normalization: tt.normalization,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+			if tt.runtimeName != "" {
+				b.resource.Resource().Attributes().PutStr(runtimeNameAttributeKey, tt.runtimeName)
+			}
+			app := map[string]string{"process.executable.name": "app"}
+			b.sample(b.stack("main", "com.shop.Cart$$Lambda$1/0x01.apply"), app, 1000000000)
+			b.sample(b.stack("main", "com.shop.Cart$$Lambda$2/0x02.apply"), app, 2000000000)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			functions := make(map[string]float64)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				if metricSlice.At(i).Name() != "cpu_time.by_function" {
+					continue
+				}
+				require.Equal(t, pmetric.MetricTypeGauge, metricSlice.At(i).Type())
+				dataPoints := metricSlice.At(i).Gauge().DataPoints()
+				for j := 0; j < dataPoints.Len(); j++ {
+					functionName, _ := dataPoints.At(j).Attributes().Get("function.name")
+					functions[functionName.Str()] += dataPoints.At(j).DoubleValue()
+				}
+			}
+			assert.Equal(t, tt.expected, functions)
+		})
+	}
+}
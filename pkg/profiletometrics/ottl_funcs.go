@@ -0,0 +1,94 @@
+package profiletometrics
+
+import (
+	"context"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlprofile"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// NewProfileFunctions returns OTTL functions for the profile context, for use with the transform
+// processor on the profiles signal (e.g. transformprocessor.WithProfileFunctions):
+//
+//   - ProfileLeafFunction(): name of the function with the most CPU time, honoring frame_selection
+//   - ProfileCPUSeconds(): total CPU time of the profile in seconds
+//   - ProfileMemoryBytes(): total memory allocation of the profile in bytes
+//
+// The functions share the converter's stack resolution and value semantics, so a nil or empty
+// config yields the same results as the connector defaults.
+func NewProfileFunctions(cfg *ConverterConfig) ([]ottl.Factory[ottlprofile.TransformContext], error) {
+	if cfg == nil {
+		cfg = &ConverterConfig{}
+	}
+	converter, err := NewConverter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ottl.Factory[ottlprofile.TransformContext]{
+		newProfileFunction("ProfileLeafFunction", func(tCtx ottlprofile.TransformContext) any {
+			return converter.profileLeafFunction(profilesDictionary(tCtx.GetProfilesDictionary()), tCtx.GetProfile())
+		}),
+		newProfileFunction("ProfileCPUSeconds", func(tCtx ottlprofile.TransformContext) any {
+			return converter.profileCPUSeconds(profilesDictionary(tCtx.GetProfilesDictionary()), tCtx.GetProfile())
+		}),
+		newProfileFunction("ProfileMemoryBytes", func(tCtx ottlprofile.TransformContext) any {
+			return converter.profileMemoryBytes(profilesDictionary(tCtx.GetProfilesDictionary()), tCtx.GetProfile())
+		}),
+	}, nil
+}
+
+// newProfileFunction creates an OTTL factory for a function without arguments
+func newProfileFunction(name string, eval func(ottlprofile.TransformContext) any) ottl.Factory[ottlprofile.TransformContext] {
+	return ottl.NewFactory(name, nil, func(ottl.FunctionContext, ottl.Arguments) (ottl.ExprFunc[ottlprofile.TransformContext], error) {
+		return func(_ context.Context, tCtx ottlprofile.TransformContext) (any, error) {
+			return eval(tCtx), nil
+		}, nil
+	})
+}
+
+// profileLeafFunction returns the function owning the most CPU time in a profile, or "" when no
+// sample resolves to a function; ties are broken by name to keep the result deterministic
+func (c *Converter) profileLeafFunction(dictionary dictionaryProvider, profile pprofile.Profile) string {
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(dictionary, profile)
+	summary := summaryOf(dictionary)
+	cpuTimes := make(map[string]float64)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		if functionName := c.getSampleFunctionName(dictionary, sample); functionName != "" {
+			cpuTimes[functionName] += c.sampleCPUTime(sample, weight, summary)
+		}
+	}
+
+	var hottest string
+	for functionName, cpuTime := range cpuTimes {
+		if hottest == "" || cpuTime > cpuTimes[hottest] || (cpuTime == cpuTimes[hottest] && functionName < hottest) {
+			hottest = functionName
+		}
+	}
+	return hottest
+}
+
+// profileCPUSeconds returns the total CPU time of a profile in seconds
+func (c *Converter) profileCPUSeconds(dictionary dictionaryProvider, profile pprofile.Profile) float64 {
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(dictionary, profile)
+	summary := summaryOf(dictionary)
+	var total float64
+	for i := 0; i < sampleCount; i++ {
+		total += c.sampleCPUTime(profile.Sample().At(i), weight, summary)
+	}
+	return total
+}
+
+// profileMemoryBytes returns the total memory allocation of a profile in bytes
+func (c *Converter) profileMemoryBytes(dictionary dictionaryProvider, profile pprofile.Profile) float64 {
+	var total float64
+	summary := summaryOf(dictionary)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		total += c.sampleMemoryAllocation(profile.Sample().At(i), summary)
+	}
+	return total
+}
@@ -0,0 +1,53 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlprofile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestNewProfileFunctions(t *testing.T) {
+	functions, err := NewProfileFunctions(&ConverterConfig{
+		FrameSelection: FrameSelectionConfig{Mode: "first_user_frame"},
+	})
+	require.NoError(t, err)
+
+	parser, err := ottlprofile.NewParser(ottl.CreateFactoryMap(functions...), componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.handle"), nil, 1000000000, 512)
+	b.sample(b.stack("main.main", "main.encode", "runtime.mallocgc"), nil, 2000000000, 1024)
+	tCtx := ottlprofile.NewTransformContext(b.profile, b.profiles.Dictionary(),
+		b.scope.Scope(), b.resource.Resource(), b.scope, b.resource)
+
+	tests := []struct {
+		expression string
+		expected   any
+	}{
+		{expression: "ProfileLeafFunction()", expected: "main.encode"},
+		{expression: "ProfileCPUSeconds()", expected: 3.0},
+		{expression: "ProfileMemoryBytes()", expected: 1536.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := parser.ParseValueExpression(tt.expression)
+			require.NoError(t, err)
+			value, err := expression.Eval(context.Background(), tCtx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestNewProfileFunctions_InvalidConfig(t *testing.T) {
+	_, err := NewProfileFunctions(&ConverterConfig{
+		FrameSelection: FrameSelectionConfig{Mode: "unknown"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package profiletometrics
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// Output layouts; an empty value means merged
+	outputLayoutMerged      = "merged"
+	outputLayoutPerResource = "per_resource"
+
+	// Resource attribute placements; an empty value follows output_layout
+	resourceAttributePlacementResource  = "resource"
+	resourceAttributePlacementDatapoint = "datapoint"
+	resourceAttributePlacementBoth      = "both"
+)
+
+// validateOutputLayout checks the configured output layout
+func validateOutputLayout(layout string) error {
+	switch layout {
+	case "", outputLayoutMerged, outputLayoutPerResource:
+		return nil
+	default:
+		return fmt.Errorf("invalid output_layout %q: must be %q or %q", layout, outputLayoutMerged, outputLayoutPerResource)
+	}
+}
+
+// validateResourceAttributePlacement checks the configured placement; the merged layout has a single
+// Resource and can only place resource attributes on the data points
+func validateResourceAttributePlacement(placement, layout string) error {
+	switch placement {
+	case "", resourceAttributePlacementDatapoint:
+		return nil
+	case resourceAttributePlacementResource, resourceAttributePlacementBoth:
+		if layout != outputLayoutPerResource {
+			return fmt.Errorf("resource_attribute_placement %q requires output_layout %q", placement, outputLayoutPerResource)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid resource_attribute_placement %q: must be %q, %q or %q", placement,
+			resourceAttributePlacementResource, resourceAttributePlacementDatapoint, resourceAttributePlacementBoth)
+	}
+}
+
+// resourceLayout routes the metrics of each input resource to its own ResourceMetrics, for the
+// per_resource output layout, placing the selected resource attributes per
+// resource_attribute_placement
+type resourceLayout struct {
+	cfg       *ConverterConfig
+	placement string
+	metrics   pmetric.Metrics
+	targets   map[int]pmetric.ResourceMetrics
+}
+
+// newResourceLayout returns the per-resource routing, or nil for the merged layout
+func newResourceLayout(cfg *ConverterConfig, metrics pmetric.Metrics) *resourceLayout {
+	if cfg.OutputLayout != outputLayoutPerResource {
+		return nil
+	}
+	placement := cfg.ResourceAttributePlacement
+	if placement == "" {
+		placement = resourceAttributePlacementResource
+	}
+	return &resourceLayout{cfg: cfg, placement: placement, metrics: metrics, targets: make(map[int]pmetric.ResourceMetrics)}
+}
+
+// resourceMetrics returns the ResourceMetrics of an input resource, created on first use with the
+// resource attributes kept by attribute_selection unless they are placed on data points only
+func (l *resourceLayout) resourceMetrics(resourceIndex int, resource pcommon.Resource) pmetric.ResourceMetrics {
+	if target, exists := l.targets[resourceIndex]; exists {
+		return target
+	}
+	target := l.metrics.ResourceMetrics().AppendEmpty()
+	if l.placement != resourceAttributePlacementDatapoint {
+		resource.Attributes().CopyTo(target.Resource().Attributes())
+		target.Resource().Attributes().RemoveIf(func(key string, _ pcommon.Value) bool {
+			return !attributeSelected(l.cfg.AttributeSelection, key)
+		})
+	}
+	l.targets[resourceIndex] = target
+	return target
+}
+
+// dataPointAttributes returns the attributes of the data points of a profile: with the resource
+// placement, the profile attributes without the resource attributes carried by the Resource;
+// attributes overridden by the attribute rules stay on the data points
+func (l *resourceLayout) dataPointAttributes(profileAttributes, resourceAttributes map[string]string) map[string]string {
+	if l.placement != resourceAttributePlacementResource {
+		return profileAttributes
+	}
+	attributes := make(map[string]string, len(profileAttributes))
+	for k, v := range profileAttributes {
+		if resourceValue, exists := resourceAttributes[k]; !exists || resourceValue != v {
+			attributes[k] = v
+		}
+	}
+	return attributes
+}
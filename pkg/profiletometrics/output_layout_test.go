@@ -0,0 +1,149 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestValidateOutputLayout(t *testing.T) {
+	for _, layout := range []string{"", "merged", "per_resource"} {
+		assert.NoError(t, validateOutputLayout(layout), layout)
+	}
+	assert.Error(t, validateOutputLayout("per_profile"))
+
+	_, err := NewConverter(&ConverterConfig{
+		OutputLayout:              "per_resource",
+		GroupByResourceAttributes: []string{"k8s.pod.name"},
+	})
+	assert.Error(t, err, "group_by_resource_attributes merges resources")
+}
+
+func TestValidateResourceAttributePlacement(t *testing.T) {
+	for _, placement := range []string{"", "resource", "datapoint", "both"} {
+		assert.NoError(t, validateResourceAttributePlacement(placement, "per_resource"), placement)
+	}
+	assert.NoError(t, validateResourceAttributePlacement("datapoint", "merged"))
+	assert.Error(t, validateResourceAttributePlacement("resource", "merged"), "merged has a single Resource")
+	assert.Error(t, validateResourceAttributePlacement("both", ""))
+	assert.Error(t, validateResourceAttributePlacement("scope", "per_resource"))
+}
+
+func TestConverter_OutputLayout(t *testing.T) {
+	newProfiles := func() pprofile.Profiles {
+		b := newTestProfileBuilder()
+		b.resource.Resource().Attributes().PutStr("k8s.pod.name", "api-0")
+		b.resource.Resource().Attributes().PutStr("host.name", "node-1")
+		b.sample(b.stack("main"), nil, 1000000000)
+		b.scope.Profiles().AppendEmpty()
+		b.profile.CopyTo(b.scope.Profiles().At(1))
+
+		other := b.profiles.ResourceProfiles().AppendEmpty()
+		b.resource.CopyTo(other)
+		other.Resource().Attributes().PutStr("k8s.pod.name", "api-1")
+		return b.profiles
+	}
+	newConfig := func(layout string, placement ...string) *ConverterConfig {
+		cfg := &ConverterConfig{
+			Metrics: MetricsConfig{
+				CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			},
+			Attributes:         []AttributeConfig{{Key: "profile.kind", Type: "literal", Value: "cpu"}},
+			AttributeSelection: AttributeSelectionConfig{Exclude: []string{"host.*"}},
+			OutputLayout:       layout,
+		}
+		if len(placement) > 0 {
+			cfg.ResourceAttributePlacement = placement[0]
+		}
+		return cfg
+	}
+
+	t.Run("Merged", func(t *testing.T) {
+		converter, err := NewConverter(newConfig("merged"))
+		require.NoError(t, err)
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles())
+		require.NoError(t, err)
+
+		require.Equal(t, 1, metrics.ResourceMetrics().Len())
+		resourceMetrics := metrics.ResourceMetrics().At(0)
+		assert.Equal(t, 0, resourceMetrics.Resource().Attributes().Len())
+		require.Equal(t, 1, resourceMetrics.ScopeMetrics().Len())
+		metricSlice := resourceMetrics.ScopeMetrics().At(0).Metrics()
+		require.Equal(t, 1, metricSlice.Len(), "one definition per metric")
+
+		dataPoints := metricSlice.At(0).Gauge().DataPoints()
+		require.Equal(t, 4, dataPoints.Len())
+		for i := 0; i < dataPoints.Len(); i++ {
+			_, hasPod := dataPoints.At(i).Attributes().Get("k8s.pod.name")
+			assert.True(t, hasPod)
+		}
+	})
+
+	t.Run("Per resource", func(t *testing.T) {
+		converter, err := NewConverter(newConfig("per_resource"))
+		require.NoError(t, err)
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles())
+		require.NoError(t, err)
+
+		require.Equal(t, 2, metrics.ResourceMetrics().Len())
+		for i, pod := range []string{"api-0", "api-1"} {
+			resourceMetrics := metrics.ResourceMetrics().At(i)
+			assert.Equal(t, map[string]any{"k8s.pod.name": pod}, resourceMetrics.Resource().Attributes().AsRaw(),
+				"resource attributes dropped by attribute_selection are not kept")
+
+			dataPoints := outputLayoutDataPoints(resourceMetrics, "cpu_time")
+			require.Equal(t, 2, dataPoints.Len())
+			for j := 0; j < dataPoints.Len(); j++ {
+				assert.Equal(t, map[string]any{"profile.kind": "cpu"}, dataPoints.At(j).Attributes().AsRaw())
+			}
+		}
+	})
+
+	for _, tt := range []struct {
+		placement          string
+		expectedResource   map[string]any
+		expectedDataPoints map[string]any
+	}{
+		{
+			placement:          "datapoint",
+			expectedResource:   map[string]any{},
+			expectedDataPoints: map[string]any{"k8s.pod.name": "api-0", "profile.kind": "cpu"},
+		},
+		{
+			placement:          "both",
+			expectedResource:   map[string]any{"k8s.pod.name": "api-0"},
+			expectedDataPoints: map[string]any{"k8s.pod.name": "api-0", "profile.kind": "cpu"},
+		},
+	} {
+		t.Run("Placement "+tt.placement, func(t *testing.T) {
+			converter, err := NewConverter(newConfig("per_resource", tt.placement))
+			require.NoError(t, err)
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles())
+			require.NoError(t, err)
+
+			require.Equal(t, 2, metrics.ResourceMetrics().Len())
+			resourceMetrics := metrics.ResourceMetrics().At(0)
+			assert.Equal(t, tt.expectedResource, resourceMetrics.Resource().Attributes().AsRaw())
+			dataPoints := outputLayoutDataPoints(resourceMetrics, "cpu_time")
+			require.Equal(t, 2, dataPoints.Len())
+			assert.Equal(t, tt.expectedDataPoints, dataPoints.At(0).Attributes().AsRaw())
+		})
+	}
+}
+
+// outputLayoutDataPoints returns the data points of the named metric within a resource
+func outputLayoutDataPoints(resourceMetrics pmetric.ResourceMetrics, name string) pmetric.NumberDataPointSlice {
+	for i := 0; i < resourceMetrics.ScopeMetrics().Len(); i++ {
+		metricSlice := resourceMetrics.ScopeMetrics().At(i).Metrics()
+		for j := 0; j < metricSlice.Len(); j++ {
+			if metricSlice.At(j).Name() == name {
+				return metricSlice.At(j).Gauge().DataPoints()
+			}
+		}
+	}
+	return pmetric.NewNumberDataPointSlice()
+}
@@ -0,0 +1,42 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ownerTeamAttributeKey is the data point attribute holding the team owning a function
+const ownerTeamAttributeKey = "owner.team"
+
+// compileOwnershipRules validates ownership rules and orders them longest prefix first, so the
+// most specific rule wins
+func compileOwnershipRules(rules []OwnershipRule) ([]OwnershipRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	compiled := make([]OwnershipRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Prefix == "" || rule.Team == "" {
+			return nil, fmt.Errorf("ownership[%d]: prefix and team must be set", i)
+		}
+		compiled = append(compiled, rule)
+	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return len(compiled[i].Prefix) > len(compiled[j].Prefix)
+	})
+	return compiled, nil
+}
+
+// functionOwnerTeam returns the team owning a function data point, matching the ownership prefixes
+// against the function (or group) name, then its source file and code path
+func (c *Converter) functionOwnerTeam(point functionDataPoint) string {
+	for _, rule := range c.ownershipRules {
+		for _, candidate := range []string{point.functionName, point.fileName, point.codeFilePath} {
+			if candidate != "" && strings.HasPrefix(candidate, rule.Prefix) {
+				return rule.Team
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_Ownership(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		Ownership: []OwnershipRule{
+			{Prefix: "github.com/mycompany/", Team: "platform"},
+			{Prefix: "github.com/mycompany/payments/", Team: "payments"},
+			{Prefix: "/srv/app/checkout/", Team: "checkout"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	for _, leaf := range []struct{ name, file string }{
+		{"github.com/mycompany/payments/charge.Run", ""},
+		{"github.com/mycompany/api.Handle", ""},
+		{"checkout", "/srv/app/checkout/cart.py"},
+		{"runtime.mallocgc", ""},
+	} {
+		stack := b.profiles.Dictionary().StackTable().AppendEmpty()
+		stack.LocationIndices().Append(b.function(leaf.name, leaf.file))
+		b.sample(int32(b.profiles.Dictionary().StackTable().Len()-1), process, 1000000000, 0)
+	}
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	owners := make(map[string]string)
+	cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+		attributes := cpuMetric.Gauge().DataPoints().At(i).Attributes()
+		functionName, ok := attributes.Get("function.name")
+		if !ok {
+			continue
+		}
+		owners[functionName.Str()] = ""
+		if team, ok := attributes.Get("owner.team"); ok {
+			owners[functionName.Str()] = team.Str()
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"github.com/mycompany/payments/charge.Run": "payments",
+		"github.com/mycompany/api.Handle":          "platform",
+		"checkout":                                 "checkout",
+		"runtime.mallocgc":                         "",
+	}, owners)
+}
+
+func TestNewConverter_InvalidOwnership(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{Ownership: []OwnershipRule{{Prefix: "github.com/mycompany/"}}})
+	assert.Error(t, err)
+}
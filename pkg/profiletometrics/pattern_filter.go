@@ -0,0 +1,152 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Pattern filter rule actions
+	patternFilterActionInclude = "include"
+	patternFilterActionExclude = "exclude"
+)
+
+// compiledPatternRule is a pattern filter rule with its compiled regex
+type compiledPatternRule struct {
+	attributeKey string // empty matches any attribute value
+	regex        *regexp.Regexp
+	include      bool
+}
+
+// patternFilter evaluates include/exclude rules against resource, profile and sample attributes.
+// A sample is dropped when any exclude rule matches, or when include rules exist and none matches.
+type patternFilter struct {
+	rules      []compiledPatternRule
+	hasInclude bool
+}
+
+// newPatternFilter compiles the pattern filter rules, returning nil when the filter is disabled.
+// The legacy single Pattern is an include rule matching any attribute value.
+func newPatternFilter(cfg PatternFilterConfig) (*patternFilter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	rules := cfg.Rules
+	if cfg.Pattern != "" {
+		rules = append([]PatternFilterRule{{Regex: cfg.Pattern, Action: patternFilterActionInclude}}, rules...)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	filter := &patternFilter{rules: make([]compiledPatternRule, 0, len(rules))}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern_filter regex %q: %w", rule.Regex, err)
+		}
+		compiled := compiledPatternRule{attributeKey: rule.AttributeKey, regex: re}
+		switch rule.Action {
+		case "", patternFilterActionInclude:
+			compiled.include = true
+			filter.hasInclude = true
+		case patternFilterActionExclude:
+		default:
+			return nil, fmt.Errorf("invalid pattern_filter action %q: must be %q or %q",
+				rule.Action, patternFilterActionInclude, patternFilterActionExclude)
+		}
+		filter.rules = append(filter.rules, compiled)
+	}
+	return filter, nil
+}
+
+// matches evaluates the rules against attribute values looked up with lookup (for a key) and
+// all (for rules without attribute key)
+func (f *patternFilter) matches(lookup func(key string) string, all func(yield func(value string) bool)) bool {
+	included := !f.hasInclude
+	for _, rule := range f.rules {
+		if !rule.matches(lookup, all) {
+			continue
+		}
+		if !rule.include {
+			return false
+		}
+		included = true
+	}
+	return included
+}
+
+// matches reports whether the rule's regex matches the targeted attribute (or any attribute)
+func (r compiledPatternRule) matches(lookup func(key string) string, all func(yield func(value string) bool)) bool {
+	if r.attributeKey != "" {
+		return r.regex.MatchString(lookup(r.attributeKey))
+	}
+	matched := false
+	all(func(value string) bool {
+		matched = r.regex.MatchString(value)
+		return !matched
+	})
+	return matched
+}
+
+// matchesAttributes evaluates the rules against a resource/profile attribute map
+func (f *patternFilter) matchesAttributes(attributes map[string]string) bool {
+	return f.matches(
+		func(key string) string { return attributes[key] },
+		func(yield func(string) bool) {
+			for _, value := range attributes {
+				if !yield(value) {
+					return
+				}
+			}
+		},
+	)
+}
+
+// matchesSample evaluates the rules against a sample's attributes, falling back to the
+// resource/profile attributes for keys the sample does not carry
+func (f *patternFilter) matchesSample(profiles dictionaryProvider, sample pprofile.Sample, attributes map[string]string) bool {
+	return f.matches(
+		func(key string) string {
+			if value := getSampleAttributeValueCommon(profiles, sample, key); value != "" {
+				return value
+			}
+			return attributes[key]
+		},
+		func(yield func(string) bool) {
+			attributeTable := profiles.Dictionary().AttributeTable()
+			for i := 0; i < sample.AttributeIndices().Len(); i++ {
+				attrIndex := sample.AttributeIndices().At(i)
+				if attrIndex < 0 || int(attrIndex) >= attributeTable.Len() {
+					continue
+				}
+				if !yield(attributeTable.At(int(attrIndex)).Value().AsString()) {
+					return
+				}
+			}
+			for _, value := range attributes {
+				if !yield(value) {
+					return
+				}
+			}
+		},
+	)
+}
+
+// filterProfileSamples returns a copy of the profile holding only the samples for which keep
+// returns true, along with the number of dropped samples
+func filterProfileSamples(profile pprofile.Profile, keep func(pprofile.Sample) bool) (pprofile.Profile, int) {
+	filtered := pprofile.NewProfile()
+	profile.CopyTo(filtered)
+	dropped := 0
+	filtered.Sample().RemoveIf(func(sample pprofile.Sample) bool {
+		if keep(sample) {
+			return false
+		}
+		dropped++
+		return true
+	})
+	return filtered, dropped
+}
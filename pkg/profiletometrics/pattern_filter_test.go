@@ -0,0 +1,85 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_PatternFilterRules(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:  MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Process: ProcessMetricConfig{Enabled: true},
+		},
+		PatternFilter: PatternFilterConfig{
+			Enabled: true,
+			Rules: []PatternFilterRule{
+				{AttributeKey: "service.name", Regex: "^checkout$", Action: "include"},
+				{AttributeKey: "process.executable.name", Regex: "^sidecar", Action: "exclude"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.resource.Resource().Attributes().PutStr("service.name", "checkout")
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "checkout"}, 2000000000, 0)
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "sidecar-proxy"}, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	processes := make(map[string]float64)
+	global := 0.0
+	cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+		dp := cpuMetric.Gauge().DataPoints().At(i)
+		if processName, ok := dp.Attributes().Get("process.name"); ok {
+			processes[processName.Str()] = dp.DoubleValue()
+			continue
+		}
+		global = dp.DoubleValue()
+	}
+	assert.Equal(t, map[string]float64{"checkout": 2}, processes)
+	assert.Equal(t, 2.0, global)
+}
+
+func TestTraceConverter_PatternFilterRules(t *testing.T) {
+	traceConverter, err := NewTraceConverter(&ConverterConfig{
+		PatternFilter: PatternFilterConfig{
+			Enabled: true,
+			Rules:   []PatternFilterRule{{AttributeKey: "process.executable.name", Regex: "^sidecar", Action: "exclude"}},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "checkout"}, 1)
+	b.sample(b.stack("main", "proxy"), map[string]string{"process.executable.name": "sidecar-proxy"}, 1)
+
+	traces, err := traceConverter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Greater(t, spans.Len(), 0)
+	for i := 0; i < spans.Len(); i++ {
+		assert.NotEqual(t, "proxy", spans.At(i).Name())
+	}
+}
+
+func TestNewPatternFilter_Invalid(t *testing.T) {
+	tests := []PatternFilterConfig{
+		{Enabled: true, Rules: []PatternFilterRule{{Regex: "("}}},
+		{Enabled: true, Rules: []PatternFilterRule{{Regex: ".*", Action: "drop"}}},
+	}
+	for _, cfg := range tests {
+		_, err := NewConverter(&ConverterConfig{PatternFilter: cfg})
+		assert.Error(t, err)
+		_, err = NewTraceConverter(&ConverterConfig{PatternFilter: cfg})
+		assert.Error(t, err)
+	}
+}
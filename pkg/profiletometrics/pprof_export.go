@@ -0,0 +1,395 @@
+package profiletometrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	defaultPprofSampleType = "samples"
+	defaultPprofSampleUnit = "count"
+	unknownProcessName     = "unknown"
+)
+
+// PprofExport is a standard pprof profile holding the samples of one process and sample type
+type PprofExport struct {
+	ProcessName string
+	SampleType  string
+	Profile     *profile.Profile
+}
+
+// ExportPprof converts pprofile.Profiles back into standard pprof profiles, one per process
+// (process.executable.name sample attribute) and sample type. Frames are written leaf first as
+// pprof expects, using the same leaf convention as the converter so attribution can be cross-checked
+// with `go tool pprof`.
+func ExportPprof(profiles pprofile.Profiles) ([]PprofExport, error) {
+	type exportKey struct {
+		processName string
+		sampleType  string
+	}
+	grouped := make(map[exportKey][]*profile.Profile)
+
+	for i := 0; i < profiles.ResourceProfiles().Len(); i++ {
+		scopeProfiles := profiles.ResourceProfiles().At(i).ScopeProfiles()
+		for j := 0; j < scopeProfiles.Len(); j++ {
+			profileSlice := scopeProfiles.At(j).Profiles()
+			for k := 0; k < profileSlice.Len(); k++ {
+				src := profileSlice.At(k)
+				dictionary := resourceDictionaryCommon(profiles, i)
+				sampleType, _ := getProfileSampleTypeCommon(dictionary, src)
+				for processName, converted := range newPprofBuilder(dictionary, src).build() {
+					key := exportKey{processName: processName, sampleType: sampleType}
+					grouped[key] = append(grouped[key], converted)
+				}
+			}
+		}
+	}
+
+	keys := make([]exportKey, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		return keys[i].sampleType < keys[j].sampleType
+	})
+
+	exports := make([]PprofExport, 0, len(keys))
+	for _, key := range keys {
+		merged, err := profile.Merge(grouped[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge pprof profiles for process %q: %w", key.processName, err)
+		}
+		if err := merged.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid pprof profile for process %q: %w", key.processName, err)
+		}
+		exports = append(exports, PprofExport{ProcessName: key.processName, SampleType: key.sampleType, Profile: merged})
+	}
+	return exports, nil
+}
+
+// Pprof file compression modes
+const (
+	PprofCompressionGzip = "gzip"
+	PprofCompressionNone = "none"
+)
+
+// ErrPprofFileTooLarge reports a pprof file skipped because it exceeds PprofWriteOptions.MaxFileBytes
+var ErrPprofFileTooLarge = errors.New("pprof file exceeds the maximum size")
+
+// PprofWriteOptions bounds the pprof files written by WritePprofFilesWithOptions
+type PprofWriteOptions struct {
+	// Compression is PprofCompressionGzip (default) or PprofCompressionNone
+	Compression string
+	// MaxFileBytes skips files larger than this many bytes once encoded; 0 disables the limit
+	MaxFileBytes int64
+}
+
+// ValidatePprofCompression checks a pprof file compression mode
+func ValidatePprofCompression(compression string) error {
+	switch compression {
+	case "", PprofCompressionGzip, PprofCompressionNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid pprof compression %q: must be %q or %q",
+			compression, PprofCompressionGzip, PprofCompressionNone)
+	}
+}
+
+// WritePprofFiles exports the profiles as gzipped pprof files (<process>.<sample_type>.<unix_nanos>.pb.gz)
+// in dir and returns the written paths
+func WritePprofFiles(profiles pprofile.Profiles, dir string) ([]string, error) {
+	return WritePprofFilesWithOptions(profiles, dir, PprofWriteOptions{})
+}
+
+// WritePprofFilesWithOptions exports the profiles as pprof files in dir, compressed as configured
+// (.pb.gz or .pb), and returns the written paths. Files over the maximum size are skipped and
+// reported with ErrPprofFileTooLarge once the other files are written.
+func WritePprofFilesWithOptions(profiles pprofile.Profiles, dir string, opts PprofWriteOptions) ([]string, error) {
+	if err := ValidatePprofCompression(opts.Compression); err != nil {
+		return nil, err
+	}
+	exports, err := ExportPprof(profiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create pprof export directory: %w", err)
+	}
+
+	extension := "pb.gz"
+	if opts.Compression == PprofCompressionNone {
+		extension = "pb"
+	}
+	stamp := time.Now().UnixNano()
+	paths := make([]string, 0, len(exports))
+	var oversized []error
+	for _, export := range exports {
+		processName := export.ProcessName
+		if processName == "" {
+			processName = unknownProcessName
+		}
+		sampleType := export.SampleType
+		if sampleType == "" {
+			sampleType = defaultPprofSampleType
+		}
+
+		// Encode in memory first so oversized profiles never reach the disk
+		var encoded bytes.Buffer
+		if opts.Compression == PprofCompressionNone {
+			err = export.Profile.WriteUncompressed(&encoded)
+		} else {
+			err = export.Profile.Write(&encoded)
+		}
+		if err != nil {
+			return paths, fmt.Errorf("failed to encode pprof profile: %w", err)
+		}
+		if opts.MaxFileBytes > 0 && int64(encoded.Len()) > opts.MaxFileBytes {
+			oversized = append(oversized, fmt.Errorf("%w: process %q, sample type %q: %d bytes > %d bytes",
+				ErrPprofFileTooLarge, processName, sampleType, encoded.Len(), opts.MaxFileBytes))
+			continue
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.%d.%s",
+			sanitizeMetricName(processName), sanitizeMetricName(sampleType), stamp, extension))
+		if err := os.WriteFile(path, encoded.Bytes(), 0o600); err != nil {
+			return paths, fmt.Errorf("failed to write pprof file: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, errors.Join(oversized...)
+}
+
+// pprofBuilder converts the samples of one pprofile.Profile into pprof profiles per process
+type pprofBuilder struct {
+	profiles  dictionaryProvider
+	src       pprofile.Profile
+	strings   pcommon.StringSlice
+	mappings  map[int32]*profile.Mapping
+	functions map[int32]*profile.Function
+	locations map[int32]*profile.Location
+}
+
+func newPprofBuilder(profiles dictionaryProvider, src pprofile.Profile) *pprofBuilder {
+	return &pprofBuilder{
+		profiles:  profiles,
+		src:       src,
+		strings:   profiles.Dictionary().StringTable(),
+		mappings:  make(map[int32]*profile.Mapping),
+		functions: make(map[int32]*profile.Function),
+		locations: make(map[int32]*profile.Location),
+	}
+}
+
+// str resolves a string table index
+func (b *pprofBuilder) str(index int32) string {
+	if index < 0 || int(index) >= b.strings.Len() {
+		return ""
+	}
+	return b.strings.At(int(index))
+}
+
+// build returns one pprof profile per process name
+func (b *pprofBuilder) build() map[string]*profile.Profile {
+	numValues := 1
+	for i := 0; i < b.src.Sample().Len(); i++ {
+		if n := b.src.Sample().At(i).Values().Len(); n > numValues {
+			numValues = n
+		}
+	}
+
+	result := make(map[string]*profile.Profile)
+	for i := 0; i < b.src.Sample().Len(); i++ {
+		sample := b.src.Sample().At(i)
+		processName := getSampleAttributeValueCommon(b.profiles, sample, "process.executable.name")
+
+		target, exists := result[processName]
+		if !exists {
+			target = b.newProfile(numValues)
+			result[processName] = target
+		}
+		target.Sample = append(target.Sample, b.convertSample(sample, numValues))
+	}
+
+	// Every profile references the shared mapping/function/location objects that it actually uses
+	for _, target := range result {
+		b.attachTables(target)
+	}
+	return result
+}
+
+// newProfile creates an empty pprof profile with the source profile's metadata
+func (b *pprofBuilder) newProfile(numValues int) *profile.Profile {
+	sampleTypeName, sampleTypeUnit := getProfileSampleTypeCommon(b.profiles, b.src)
+	if sampleTypeName == "" {
+		sampleTypeName, sampleTypeUnit = defaultPprofSampleType, defaultPprofSampleUnit
+	}
+
+	p := &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: sampleTypeName, Unit: sampleTypeUnit}},
+		TimeNanos:     int64(b.src.Time()),
+		DurationNanos: int64(b.src.Duration()),
+		Period:        b.src.Period(),
+	}
+	for i := 1; i < numValues; i++ {
+		p.SampleType = append(p.SampleType, &profile.ValueType{Type: fmt.Sprintf("value%d", i)})
+	}
+	if periodType := b.src.PeriodType(); periodType.TypeStrindex() > 0 {
+		p.PeriodType = &profile.ValueType{Type: b.str(periodType.TypeStrindex()), Unit: b.str(periodType.UnitStrindex())}
+	}
+	p.Comments = getProfileCommentsCommon(b.profiles, b.src)
+	return p
+}
+
+// convertSample converts a sample, reversing frames so the leaf comes first
+func (b *pprofBuilder) convertSample(sample pprofile.Sample, numValues int) *profile.Sample {
+	converted := &profile.Sample{Value: make([]int64, numValues)}
+	values := sample.Values()
+	if values.Len() == 0 {
+		converted.Value[0] = 1 // stack-only samples count as one observation
+	}
+	for i := 0; i < values.Len(); i++ {
+		converted.Value[i] = values.At(i)
+	}
+
+	if locationIndices, ok := stackLocationIndicesCommon(b.profiles, sample.StackIndex()); ok {
+		for i := locationIndices.Len() - 1; i >= 0; i-- {
+			if location := b.location(locationIndices.At(i)); location != nil {
+				converted.Location = append(converted.Location, location)
+			}
+		}
+	}
+
+	attributeTable := b.profiles.Dictionary().AttributeTable()
+	for i := 0; i < sample.AttributeIndices().Len(); i++ {
+		attrIndex := sample.AttributeIndices().At(i)
+		if attrIndex < 0 || int(attrIndex) >= attributeTable.Len() {
+			continue
+		}
+		attr := attributeTable.At(int(attrIndex))
+		key := b.str(attr.KeyStrindex())
+		if key == "" {
+			continue
+		}
+		if attr.Value().Type() == pcommon.ValueTypeInt {
+			if converted.NumLabel == nil {
+				converted.NumLabel = make(map[string][]int64)
+			}
+			converted.NumLabel[key] = append(converted.NumLabel[key], attr.Value().Int())
+			continue
+		}
+		if converted.Label == nil {
+			converted.Label = make(map[string][]string)
+		}
+		converted.Label[key] = append(converted.Label[key], attr.Value().AsString())
+	}
+	return converted
+}
+
+// location converts a dictionary location (with its mapping and functions) once
+func (b *pprofBuilder) location(index int32) *profile.Location {
+	if location, exists := b.locations[index]; exists {
+		return location
+	}
+	locationTable := b.profiles.Dictionary().LocationTable()
+	if index < 0 || int(index) >= locationTable.Len() {
+		return nil
+	}
+
+	src := locationTable.At(int(index))
+	location := &profile.Location{
+		ID:      uint64(index) + 1,
+		Address: src.Address(),
+		Mapping: b.mapping(src.MappingIndex()),
+	}
+	for i := 0; i < src.Line().Len(); i++ {
+		line := src.Line().At(i)
+		if function := b.function(line.FunctionIndex()); function != nil {
+			location.Line = append(location.Line, profile.Line{Function: function, Line: line.Line(), Column: line.Column()})
+		}
+	}
+	b.locations[index] = location
+	return location
+}
+
+// function converts a dictionary function once
+func (b *pprofBuilder) function(index int32) *profile.Function {
+	if function, exists := b.functions[index]; exists {
+		return function
+	}
+	functionTable := b.profiles.Dictionary().FunctionTable()
+	if index < 0 || int(index) >= functionTable.Len() {
+		return nil
+	}
+
+	src := functionTable.At(int(index))
+	function := &profile.Function{
+		ID:         uint64(index) + 1,
+		Name:       b.str(src.NameStrindex()),
+		SystemName: b.str(src.SystemNameStrindex()),
+		Filename:   b.str(src.FilenameStrindex()),
+		StartLine:  src.StartLine(),
+	}
+	b.functions[index] = function
+	return function
+}
+
+// mapping converts a dictionary mapping once
+func (b *pprofBuilder) mapping(index int32) *profile.Mapping {
+	if mapping, exists := b.mappings[index]; exists {
+		return mapping
+	}
+	mappingTable := b.profiles.Dictionary().MappingTable()
+	if index < 0 || int(index) >= mappingTable.Len() {
+		return nil
+	}
+
+	src := mappingTable.At(int(index))
+	mapping := &profile.Mapping{
+		ID:     uint64(index) + 1,
+		Start:  src.MemoryStart(),
+		Limit:  src.MemoryLimit(),
+		Offset: src.FileOffset(),
+		File:   b.str(src.FilenameStrindex()),
+	}
+	b.mappings[index] = mapping
+	return mapping
+}
+
+// attachTables fills the mapping/location/function tables of a profile from its samples
+func (b *pprofBuilder) attachTables(p *profile.Profile) {
+	seenMappings := make(map[*profile.Mapping]bool)
+	seenLocations := make(map[*profile.Location]bool)
+	seenFunctions := make(map[*profile.Function]bool)
+
+	for _, sample := range p.Sample {
+		for _, location := range sample.Location {
+			if seenLocations[location] {
+				continue
+			}
+			seenLocations[location] = true
+			p.Location = append(p.Location, location)
+			if location.Mapping != nil && !seenMappings[location.Mapping] {
+				seenMappings[location.Mapping] = true
+				p.Mapping = append(p.Mapping, location.Mapping)
+			}
+			for _, line := range location.Line {
+				if !seenFunctions[line.Function] {
+					seenFunctions[line.Function] = true
+					p.Function = append(p.Function, line.Function)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestExportPprof_OneProfilePerProcess(t *testing.T) {
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.profile.SetPeriod(10000000)
+	b.sample(b.stack("main", "handler", "hot"), map[string]string{"process.executable.name": "app"}, 300)
+	b.sample(b.stack("main", "cold"), map[string]string{"process.executable.name": "app"}, 100)
+	b.sample(b.stack("main", "worker"), map[string]string{"process.executable.name": "db"}, 50)
+
+	exports, err := ExportPprof(b.profiles)
+	require.NoError(t, err)
+	require.Len(t, exports, 2)
+
+	app := exports[0]
+	assert.Equal(t, "app", app.ProcessName)
+	assert.Equal(t, "cpu", app.SampleType)
+	assert.Equal(t, "cpu", app.Profile.SampleType[0].Type)
+	assert.Equal(t, "nanoseconds", app.Profile.SampleType[0].Unit)
+	assert.Equal(t, int64(10000000), app.Profile.Period)
+	require.Len(t, app.Profile.Sample, 2)
+
+	// pprof expects the leaf first, matching the converter's leaf
+	hottest := app.Profile.Sample[0]
+	if hottest.Value[0] != 300 {
+		hottest = app.Profile.Sample[1]
+	}
+	assert.Equal(t, "hot", hottest.Location[0].Line[0].Function.Name)
+	assert.Equal(t, "main", hottest.Location[len(hottest.Location)-1].Line[0].Function.Name)
+	assert.Equal(t, []string{"app"}, hottest.Label["process.executable.name"])
+
+	assert.Equal(t, "db", exports[1].ProcessName)
+	require.Len(t, exports[1].Profile.Sample, 1)
+}
+
+func TestWritePprofFiles_RoundTrip(t *testing.T) {
+	b := newTestProfileBuilder().withSampleType("samples", "count")
+	b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, 3)
+
+	paths, err := WritePprofFiles(b.profiles, t.TempDir())
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	file, err := os.Open(paths[0])
+	require.NoError(t, err)
+	defer file.Close()
+
+	parsed, err := profile.Parse(file)
+	require.NoError(t, err)
+	require.Len(t, parsed.Sample, 1)
+	assert.Equal(t, int64(3), parsed.Sample[0].Value[0])
+	assert.Equal(t, "hot", parsed.Sample[0].Location[0].Line[0].Function.Name)
+}
+
+func TestWritePprofFilesWithOptions_Uncompressed(t *testing.T) {
+	b := newTestProfileBuilder().withSampleType("samples", "count")
+	b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, 3)
+
+	paths, err := WritePprofFilesWithOptions(b.profiles, t.TempDir(), PprofWriteOptions{Compression: PprofCompressionNone})
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	assert.True(t, strings.HasSuffix(paths[0], ".pb"))
+
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	parsed, err := profile.ParseUncompressed(data)
+	require.NoError(t, err)
+	require.Len(t, parsed.Sample, 1)
+}
+
+func TestWritePprofFilesWithOptions_MaxFileBytes(t *testing.T) {
+	b := newTestProfileBuilder().withSampleType("samples", "count")
+	b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, 3)
+	huge := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		huge = append(huge, fmt.Sprintf("github.com/example/service/internal/handlers.Frame%d", i))
+	}
+	b.sample(b.stack(huge...), map[string]string{"process.executable.name": "db"}, 1)
+
+	dir := t.TempDir()
+	paths, err := WritePprofFilesWithOptions(b.profiles, dir, PprofWriteOptions{MaxFileBytes: 1024})
+	require.ErrorIs(t, err, ErrPprofFileTooLarge)
+	assert.Contains(t, err.Error(), `"db"`)
+	require.Len(t, paths, 1)
+	assert.Contains(t, filepath.Base(paths[0]), "app.")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWritePprofFilesWithOptions_InvalidCompression(t *testing.T) {
+	_, err := WritePprofFilesWithOptions(pprofile.NewProfiles(), t.TempDir(), PprofWriteOptions{Compression: "zstd"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// ImportPprof decodes a standard pprof payload (pprof.proto, gzipped or not) into pprofile.Profiles
+// holding a single profile. The profile takes the first pprof sample type and keeps every sample value
+// in pprof order, so a Go CPU profile (samples, cpu) weighs samples with its period and a Go heap
+// profile (alloc_objects, alloc_space, ...) reports alloc_space as memory. Frames are reversed to put
+// the leaf last, and labels become sample attributes.
+func ImportPprof(data []byte) (pprofile.Profiles, error) {
+	parsed, err := profile.ParseData(data)
+	if err != nil {
+		return pprofile.Profiles{}, fmt.Errorf("failed to parse pprof profile: %w", err)
+	}
+	return newPprofImporter().build(parsed), nil
+}
+
+// ConvertPprofBytesToMetrics converts a standard pprof payload to metrics through the same path as
+// OTLP profiles
+func (c *Converter) ConvertPprofBytesToMetrics(ctx context.Context, data []byte) (pmetric.Metrics, error) {
+	profiles, err := ImportPprof(data)
+	if err != nil {
+		return pmetric.NewMetrics(), err
+	}
+	return c.ConvertProfilesToMetrics(ctx, profiles)
+}
+
+// dictionaryBuilder interns the strings, stacks and attributes of a new pprofile.Profiles dictionary
+type dictionaryBuilder struct {
+	profiles   pprofile.Profiles
+	strings    map[string]int32
+	stacks     map[string]int32
+	attributes map[string]int32
+}
+
+func newDictionaryBuilder() *dictionaryBuilder {
+	d := &dictionaryBuilder{
+		profiles:   pprofile.NewProfiles(),
+		strings:    make(map[string]int32),
+		stacks:     make(map[string]int32),
+		attributes: make(map[string]int32),
+	}
+	// Index 0 of the string, mapping and stack tables is the zero value
+	dictionary := d.profiles.Dictionary()
+	d.str("")
+	dictionary.MappingTable().AppendEmpty()
+	dictionary.StackTable().AppendEmpty()
+	return d
+}
+
+// str interns a string in the dictionary string table
+func (d *dictionaryBuilder) str(s string) int32 {
+	if index, exists := d.strings[s]; exists {
+		return index
+	}
+	stringTable := d.profiles.Dictionary().StringTable()
+	stringTable.Append(s)
+	index := int32(stringTable.Len() - 1)
+	d.strings[s] = index
+	return index
+}
+
+// stack interns a stack of location indices, leaf last
+func (d *dictionaryBuilder) stack(locationIndices []int32) int32 {
+	var key strings.Builder
+	for _, index := range locationIndices {
+		key.WriteString(strconv.Itoa(int(index)))
+		key.WriteByte(',')
+	}
+	if index, exists := d.stacks[key.String()]; exists {
+		return index
+	}
+	stackTable := d.profiles.Dictionary().StackTable()
+	stackTable.AppendEmpty().LocationIndices().FromRaw(locationIndices)
+	index := int32(stackTable.Len() - 1)
+	d.stacks[key.String()] = index
+	return index
+}
+
+// attribute interns an attribute in the dictionary attribute table
+func (d *dictionaryBuilder) attribute(key string, value pcommon.Value, unit string) int32 {
+	internKey := key + "\x00" + value.Type().String() + "\x00" + value.AsString() + "\x00" + unit
+	if index, exists := d.attributes[internKey]; exists {
+		return index
+	}
+	attributeTable := d.profiles.Dictionary().AttributeTable()
+	attr := attributeTable.AppendEmpty()
+	attr.SetKeyStrindex(d.str(key))
+	value.CopyTo(attr.Value())
+	if unit != "" {
+		attr.SetUnitStrindex(d.str(unit))
+	}
+	index := int32(attributeTable.Len() - 1)
+	d.attributes[internKey] = index
+	return index
+}
+
+// pprofImporter converts a pprof profile into pprofile.Profiles, interning dictionary entries once
+type pprofImporter struct {
+	*dictionaryBuilder
+	mappings  map[uint64]int32
+	functions map[uint64]int32
+	locations map[uint64]int32
+}
+
+func newPprofImporter() *pprofImporter {
+	return &pprofImporter{
+		dictionaryBuilder: newDictionaryBuilder(),
+		mappings:          make(map[uint64]int32),
+		functions:         make(map[uint64]int32),
+		locations:         make(map[uint64]int32),
+	}
+}
+
+// build converts the pprof profile and its samples
+func (imp *pprofImporter) build(src *profile.Profile) pprofile.Profiles {
+	dst := imp.profiles.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty().Profiles().AppendEmpty()
+	if len(src.SampleType) > 0 {
+		dst.SampleType().SetTypeStrindex(imp.str(src.SampleType[0].Type))
+		dst.SampleType().SetUnitStrindex(imp.str(src.SampleType[0].Unit))
+	}
+	if src.PeriodType != nil {
+		dst.PeriodType().SetTypeStrindex(imp.str(src.PeriodType.Type))
+		dst.PeriodType().SetUnitStrindex(imp.str(src.PeriodType.Unit))
+	}
+	dst.SetPeriod(src.Period)
+	dst.SetTime(pcommon.Timestamp(src.TimeNanos))
+	dst.SetDuration(pcommon.Timestamp(src.DurationNanos))
+	for _, comment := range src.Comments {
+		dst.CommentStrindices().Append(imp.str(comment))
+	}
+
+	for _, sample := range src.Sample {
+		converted := dst.Sample().AppendEmpty()
+		converted.Values().FromRaw(sample.Value)
+		converted.SetStackIndex(imp.sampleStack(sample.Location))
+		for key, values := range sample.Label {
+			for _, value := range values {
+				converted.AttributeIndices().Append(imp.attribute(key, pcommon.NewValueStr(value), ""))
+			}
+		}
+		for key, values := range sample.NumLabel {
+			unit := ""
+			if units := sample.NumUnit[key]; len(units) > 0 {
+				unit = units[0]
+			}
+			for _, value := range values {
+				converted.AttributeIndices().Append(imp.attribute(key, pcommon.NewValueInt(value), unit))
+			}
+		}
+	}
+	return imp.profiles
+}
+
+// sampleStack interns the stack of pprof locations, reversed so the leaf comes last
+func (imp *pprofImporter) sampleStack(locations []*profile.Location) int32 {
+	indices := make([]int32, 0, len(locations))
+	for i := len(locations) - 1; i >= 0; i-- {
+		indices = append(indices, imp.location(locations[i]))
+	}
+	return imp.stack(indices)
+}
+
+// location converts a pprof location (with its mapping and functions) once
+func (imp *pprofImporter) location(src *profile.Location) int32 {
+	if index, exists := imp.locations[src.ID]; exists {
+		return index
+	}
+	locationTable := imp.profiles.Dictionary().LocationTable()
+	location := locationTable.AppendEmpty()
+	location.SetAddress(src.Address)
+	location.SetMappingIndex(imp.mapping(src.Mapping))
+	for _, line := range src.Line {
+		if line.Function == nil {
+			continue
+		}
+		converted := location.Line().AppendEmpty()
+		converted.SetFunctionIndex(imp.function(line.Function))
+		converted.SetLine(line.Line)
+		converted.SetColumn(line.Column)
+	}
+	index := int32(locationTable.Len() - 1)
+	imp.locations[src.ID] = index
+	return index
+}
+
+// function converts a pprof function once
+func (imp *pprofImporter) function(src *profile.Function) int32 {
+	if index, exists := imp.functions[src.ID]; exists {
+		return index
+	}
+	functionTable := imp.profiles.Dictionary().FunctionTable()
+	function := functionTable.AppendEmpty()
+	function.SetNameStrindex(imp.str(src.Name))
+	function.SetSystemNameStrindex(imp.str(src.SystemName))
+	function.SetFilenameStrindex(imp.str(src.Filename))
+	function.SetStartLine(src.StartLine)
+	index := int32(functionTable.Len() - 1)
+	imp.functions[src.ID] = index
+	return index
+}
+
+// mapping converts a pprof mapping once; locations without one use the zero mapping
+func (imp *pprofImporter) mapping(src *profile.Mapping) int32 {
+	if src == nil {
+		return 0
+	}
+	if index, exists := imp.mappings[src.ID]; exists {
+		return index
+	}
+	mappingTable := imp.profiles.Dictionary().MappingTable()
+	mapping := mappingTable.AppendEmpty()
+	mapping.SetMemoryStart(src.Start)
+	mapping.SetMemoryLimit(src.Limit)
+	mapping.SetFileOffset(src.Offset)
+	mapping.SetFilenameStrindex(imp.str(src.File))
+	index := int32(mappingTable.Len() - 1)
+	imp.mappings[src.ID] = index
+	return index
+}
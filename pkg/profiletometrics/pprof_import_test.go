@@ -0,0 +1,134 @@
+package profiletometrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// newGoCPUPprof returns a pprof profile shaped like a Go CPU profile: main -> handler -> hot
+func newGoCPUPprof() *profile.Profile {
+	mapping := &profile.Mapping{ID: 1, Start: 0x400000, Limit: 0x800000, File: "/usr/bin/app"}
+	functions := []*profile.Function{
+		{ID: 1, Name: "main.main", Filename: "main.go"},
+		{ID: 2, Name: "main.handler", Filename: "handler.go"},
+		{ID: 3, Name: "main.hot", Filename: "hot.go"},
+	}
+	locations := make([]*profile.Location, 0, len(functions))
+	for i, function := range functions {
+		locations = append(locations, &profile.Location{
+			ID:      uint64(i + 1),
+			Mapping: mapping,
+			Address: 0x401000 + uint64(i),
+			Line:    []profile.Line{{Function: function, Line: int64(10 * (i + 1))}},
+		})
+	}
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+		TimeNanos:  1700000000000000000,
+		Sample: []*profile.Sample{
+			{
+				// pprof lists the leaf first
+				Location: []*profile.Location{locations[2], locations[1], locations[0]},
+				Value:    []int64{30, 300000000},
+				Label:    map[string][]string{"process.executable.name": {"app"}},
+				NumLabel: map[string][]int64{"bytes": {512}},
+				NumUnit:  map[string][]string{"bytes": {"bytes"}},
+			},
+			{
+				Location: []*profile.Location{locations[1], locations[0]},
+				Value:    []int64{10, 100000000},
+				Label:    map[string][]string{"process.executable.name": {"app"}},
+			},
+		},
+		Mapping:  []*profile.Mapping{mapping},
+		Location: locations,
+		Function: functions,
+	}
+}
+
+func encodePprof(t *testing.T, p *profile.Profile) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestImportPprof(t *testing.T) {
+	profiles, err := ImportPprof(encodePprof(t, newGoCPUPprof()))
+	require.NoError(t, err)
+	require.Equal(t, 1, profiles.ResourceProfiles().Len())
+
+	imported := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0)
+	sampleType, unit := getProfileSampleTypeCommon(profiles, imported)
+	assert.Equal(t, "samples", sampleType)
+	assert.Equal(t, "count", unit)
+	assert.Equal(t, int64(10000000), imported.Period())
+	assert.Equal(t, pcommon.Timestamp(1700000000000000000), imported.Time())
+	require.Equal(t, 2, imported.Sample().Len())
+
+	sample := imported.Sample().At(0)
+	assert.Equal(t, []int64{30, 300000000}, sample.Values().AsRaw())
+	assert.Equal(t, "app", getSampleAttributeValueCommon(profiles, sample, "process.executable.name"))
+	bytesLabel, ok := lookupAttributeCommon(profiles, sample.AttributeIndices(), "bytes")
+	require.True(t, ok)
+	assert.Equal(t, int64(512), bytesLabel.Int())
+
+	// The leaf is last, as the converter expects
+	leaf, ok := leafLocationCommon(profiles, sample.StackIndex())
+	require.True(t, ok)
+	name, ok := functionNameCommon(profiles, locationFunctionIndexCommon(leaf))
+	require.True(t, ok)
+	assert.Equal(t, "main.hot", name)
+	assert.Equal(t, "hot.go", getLocationFileNameCommon(profiles, leaf))
+}
+
+func TestImportPprof_SharesDictionaryEntries(t *testing.T) {
+	p := newGoCPUPprof()
+	p.Sample = append(p.Sample, &profile.Sample{
+		Location: p.Sample[0].Location,
+		Value:    []int64{5, 50000000},
+		Label:    map[string][]string{"process.executable.name": {"app"}},
+	})
+
+	profiles, err := ImportPprof(encodePprof(t, p))
+	require.NoError(t, err)
+
+	dictionary := profiles.Dictionary()
+	assert.Equal(t, 3, dictionary.FunctionTable().Len())
+	assert.Equal(t, 3, dictionary.LocationTable().Len())
+	// Zero mapping plus the binary mapping
+	assert.Equal(t, 2, dictionary.MappingTable().Len())
+
+	samples := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Sample()
+	assert.Equal(t, samples.At(0).StackIndex(), samples.At(2).StackIndex())
+}
+
+func TestImportPprof_InvalidPayload(t *testing.T) {
+	_, err := ImportPprof([]byte("not a profile"))
+	assert.Error(t, err)
+}
+
+func TestConverter_ConvertPprofBytesToMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+	})
+	require.NoError(t, err)
+
+	metrics, err := converter.ConvertPprofBytesToMetrics(context.Background(), encodePprof(t, newGoCPUPprof()))
+	require.NoError(t, err)
+
+	metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	assert.Equal(t, "cpu_time", metric.Name())
+	// 40 samples weighted with the 10ms period
+	assert.InDelta(t, 0.4, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9)
+
+	_, err = converter.ConvertPprofBytesToMetrics(context.Background(), nil)
+	assert.Error(t, err)
+}
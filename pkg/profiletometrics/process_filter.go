@@ -0,0 +1,83 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// processFilter restricts metrics to process names matching an include pattern and no exclude
+// pattern, compiled once at converter construction
+type processFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newProcessFilter compiles the process filter, returning nil when the filter is disabled. The
+// patterns list takes precedence over the single pattern kept for backward compatibility.
+func newProcessFilter(cfg ProcessFilterConfig) (*processFilter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	patterns := cfg.Patterns
+	if len(patterns) == 0 && cfg.Pattern != "" {
+		patterns = []string{cfg.Pattern}
+	}
+
+	compile := func(option string, patterns []string) ([]*regexp.Regexp, error) {
+		regexes := make([]*regexp.Regexp, 0, len(patterns))
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid process_filter.%s pattern %q: %w", option, pattern, err)
+			}
+			regexes = append(regexes, re)
+		}
+		return regexes, nil
+	}
+
+	include, err := compile("patterns", patterns)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compile("exclude_patterns", cfg.ExcludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	return &processFilter{include: include, exclude: exclude}, nil
+}
+
+// allows reports whether a process name passes the include and exclude patterns; without include
+// patterns every process not excluded passes
+func (f *processFilter) allows(processName string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(processName) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(processName) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedProcessNames returns the process names passing the filter
+func (f *processFilter) allowedProcessNames(processNames []string) []string {
+	var allowed []string
+	for _, name := range processNames {
+		if f.allows(name) {
+			allowed = append(allowed, name)
+		}
+	}
+	return allowed
+}
+
+// emitsGlobalMetrics reports whether the unfiltered global metrics are generated: always without
+// process_filter, and with it when keep_global_metrics is set
+func (c *Converter) emitsGlobalMetrics() bool {
+	return !c.config.ProcessFilter.Enabled || c.config.ProcessFilter.KeepGlobalMetrics
+}
@@ -0,0 +1,187 @@
+package profiletometrics
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ProcessFilterConfig
+		expected []string
+	}{
+		{
+			name:     "Patterns",
+			cfg:      ProcessFilterConfig{Enabled: true, Patterns: []string{"^java", "^python"}},
+			expected: []string{"java", "java-batch", "python3"},
+		},
+		{
+			name:     "Single pattern",
+			cfg:      ProcessFilterConfig{Enabled: true, Pattern: "^java"},
+			expected: []string{"java", "java-batch"},
+		},
+		{
+			name:     "Patterns take precedence over pattern",
+			cfg:      ProcessFilterConfig{Enabled: true, Pattern: "^java", Patterns: []string{"^python"}},
+			expected: []string{"python3"},
+		},
+		{
+			name:     "Exclude wins over include",
+			cfg:      ProcessFilterConfig{Enabled: true, Patterns: []string{"^java"}, ExcludePatterns: []string{"-batch$"}},
+			expected: []string{"java"},
+		},
+		{
+			name:     "Exclude only",
+			cfg:      ProcessFilterConfig{Enabled: true, ExcludePatterns: []string{"^java"}},
+			expected: []string{"node", "python3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newProcessFilter(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filter.allowedProcessNames([]string{"java", "java-batch", "node", "python3"}))
+		})
+	}
+}
+
+func TestNewProcessFilter(t *testing.T) {
+	filter, err := newProcessFilter(ProcessFilterConfig{Patterns: []string{"("}})
+	require.NoError(t, err)
+	assert.Nil(t, filter, "a disabled filter is not compiled")
+
+	for _, cfg := range []ProcessFilterConfig{
+		{Enabled: true, Patterns: []string{"("}},
+		{Enabled: true, Pattern: "["},
+		{Enabled: true, ExcludePatterns: []string{"("}},
+	} {
+		_, err := NewConverter(&ConverterConfig{ProcessFilter: cfg})
+		assert.Error(t, err)
+		_, err = NewTraceConverter(&ConverterConfig{ProcessFilter: cfg})
+		assert.Error(t, err)
+	}
+}
+
+func TestConverter_ProcessFilterExclude(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Process: ProcessMetricConfig{Enabled: true},
+		},
+		ProcessFilter: ProcessFilterConfig{Enabled: true, ExcludePatterns: []string{"^agent$"}},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	stack := b.stack("main")
+	b.sample(stack, map[string]string{"process.executable.name": "api"}, 1000000000)
+	b.sample(stack, map[string]string{"process.executable.name": "agent"}, 1000000000)
+	b.sample(stack, map[string]string{"process.executable.name": "worker"}, 1000000000)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	var processNames []string
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() != "cpu_time" {
+					continue
+				}
+				dataPoints := metric.Gauge().DataPoints()
+				for l := 0; l < dataPoints.Len(); l++ {
+					if processName, ok := dataPoints.At(l).Attributes().Get("process.name"); ok {
+						processNames = append(processNames, processName.Str())
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(processNames)
+	assert.Equal(t, []string{"api", "worker"}, processNames)
+}
+
+func TestConverter_ProcessFilterKeepGlobalMetrics(t *testing.T) {
+	newProfiles := func(processNames ...string) *testProfileBuilder {
+		b := newTestProfileBuilder()
+		stack := b.stack("main")
+		for _, processName := range processNames {
+			b.sample(stack, map[string]string{"process.executable.name": processName}, 1000000000)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name              string
+		keepGlobal        bool
+		processNames      []string
+		expectedGlobal    []float64
+		expectedProcesses []string
+	}{
+		{
+			name:              "Global metrics skipped by default",
+			processNames:      []string{"api", "agent"},
+			expectedProcesses: []string{"api"},
+		},
+		{
+			name:              "Global metrics kept",
+			keepGlobal:        true,
+			processNames:      []string{"api", "agent"},
+			expectedGlobal:    []float64{2},
+			expectedProcesses: []string{"api"},
+		},
+		{
+			name:           "Global metrics kept without matched processes",
+			keepGlobal:     true,
+			processNames:   []string{"agent"},
+			expectedGlobal: []float64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Process: ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".process"},
+				},
+				ProcessFilter: ProcessFilterConfig{Enabled: true, Patterns: []string{"^api$"}, KeepGlobalMetrics: tt.keepGlobal},
+			})
+			require.NoError(t, err)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles(tt.processNames...).profiles)
+			require.NoError(t, err)
+
+			var globalValues []float64
+			var processNames []string
+			for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+				scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+				for j := 0; j < scopeMetrics.Len(); j++ {
+					for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+						metric := scopeMetrics.At(j).Metrics().At(k)
+						dataPoints := metric.Gauge().DataPoints()
+						for l := 0; l < dataPoints.Len(); l++ {
+							switch metric.Name() {
+							case "cpu_time":
+								globalValues = append(globalValues, dataPoints.At(l).DoubleValue())
+							case "cpu_time.process":
+								processName, _ := dataPoints.At(l).Attributes().Get("process.name")
+								processNames = append(processNames, processName.Str())
+							}
+						}
+					}
+				}
+			}
+			assert.Equal(t, tt.expectedGlobal, globalValues)
+			assert.Equal(t, tt.expectedProcesses, processNames)
+		})
+	}
+}
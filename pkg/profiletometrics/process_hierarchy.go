@@ -0,0 +1,81 @@
+package profiletometrics
+
+import (
+	"slices"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// processHierarchyAttributeKeys are the attributes telling apart processes of the same executable
+var processHierarchyAttributeKeys = [...]string{"process.pid", "process.parent_pid", "process.command_line"}
+
+// processIdentity is a process name with its pid, parent pid and command line, empty when unknown
+type processIdentity struct {
+	name   string
+	values [len(processHierarchyAttributeKeys)]string
+}
+
+// generateProcessHierarchyMetrics emits the process metrics per process identity: the hierarchy
+// attributes are read from the sample attributes, falling back to the profile and resource ones
+func (c *Converter) generateProcessHierarchyMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+	processNames []string,
+	cpuMetricName string,
+	memoryMetricName string,
+) {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	totals := make(map[processIdentity]*sampleTotals)
+	identities := make(map[string][]processIdentity)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		identity := processIdentity{name: c.getSampleAttributeValue(profiles, sample, "process.executable.name")}
+		if identity.name == "" {
+			continue
+		}
+		for j, key := range processHierarchyAttributeKeys {
+			identity.values[j] = getSampleAttributeValueCommon(profiles, sample, key)
+			if identity.values[j] == "" {
+				identity.values[j] = attributes[key]
+			}
+		}
+		group, exists := totals[identity]
+		if !exists {
+			group = c.newSampleTotals()
+			totals[identity] = group
+			identities[identity.name] = append(identities[identity.name], identity)
+		}
+		var sampleValues sampleTotals
+		sampleValues.add(c, sample, weight, summary)
+		group.merge(sampleValues)
+	}
+
+	for _, processName := range processNames {
+		processIdentities := identities[processName]
+		if len(processIdentities) == 0 {
+			c.generateEntityMetrics(profile, attributes, scopeMetrics, "process.name", processName, nil,
+				cpuMetricName, memoryMetricName)
+			continue
+		}
+		slices.SortFunc(processIdentities, func(a, b processIdentity) int {
+			return slices.Compare(a.values[:], b.values[:])
+		})
+		for _, identity := range processIdentities {
+			attrs := make(map[string]string, len(attributes)+len(identity.values))
+			for k, v := range attributes {
+				attrs[k] = v
+			}
+			for j, key := range processHierarchyAttributeKeys {
+				if identity.values[j] != "" {
+					attrs[key] = identity.values[j]
+				}
+			}
+			c.generateEntityMetrics(profile, attrs, scopeMetrics, "process.name", processName, totals[identity],
+				cpuMetricName, memoryMetricName)
+		}
+	}
+}
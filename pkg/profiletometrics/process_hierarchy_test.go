@@ -0,0 +1,113 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_ProcessHierarchyAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		expected []map[string]string
+	}{
+		{
+			name:    "Same-named processes are merged by default",
+			enabled: false,
+			expected: []map[string]string{
+				{"process.name": "python", "process.command_line": "python worker.py"},
+			},
+		},
+		{
+			name:    "Hierarchy attributes split same-named processes",
+			enabled: true,
+			expected: []map[string]string{
+				{"process.name": "python", "process.pid": "100", "process.parent_pid": "1", "process.command_line": "python api.py"},
+				{"process.name": "python", "process.pid": "200", "process.parent_pid": "1", "process.command_line": "python worker.py"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:     CPUMetricConfig{MetricName: "cpu_time"},
+					Memory:  MemoryMetricConfig{MetricName: "memory_allocation"},
+					Process: ProcessMetricConfig{Enabled: true, HierarchyAttributes: tt.enabled},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			// The command line of the resource is overridden by the sample attributes
+			b.resource.Resource().Attributes().PutStr("process.command_line", "python worker.py")
+			b.sample(b.stack("main"), map[string]string{
+				"process.executable.name": "python", "process.pid": "100", "process.parent_pid": "1",
+				"process.command_line": "python api.py",
+			}, 1000000000, 1024)
+			b.sample(b.stack("main"), map[string]string{
+				"process.executable.name": "python", "process.pid": "200", "process.parent_pid": "1",
+			}, 2000000000, 2048)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			require.Equal(t, 2, metricSlice.Len())
+			for i := 0; i < metricSlice.Len(); i++ {
+				dataPoints := metricSlice.At(i).Gauge().DataPoints()
+				require.Equal(t, len(tt.expected), dataPoints.Len(), metricSlice.At(i).Name())
+				for j, expected := range tt.expected {
+					attributes := dataPoints.At(j).Attributes()
+					for key, value := range expected {
+						actual, exists := attributes.Get(key)
+						require.True(t, exists, key)
+						assert.Equal(t, value, actual.Str(), key)
+					}
+					if !tt.enabled {
+						_, exists := attributes.Get("process.pid")
+						assert.False(t, exists)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConverter_ProcessHierarchyAttributesTotals(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{MetricName: "cpu_time"},
+			Memory:  MemoryMetricConfig{MetricName: "memory_allocation"},
+			Process: ProcessMetricConfig{Enabled: true, HierarchyAttributes: true},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "java", "process.pid": "7"}, 1000000000, 1024)
+	b.sample(b.stack("run"), map[string]string{"process.executable.name": "java", "process.pid": "7"}, 1000000000, 1024)
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "java"}, 500000000, 512)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	cpu := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, "cpu_time", cpu.Name())
+	dataPoints := cpu.Gauge().DataPoints()
+	require.Equal(t, 2, dataPoints.Len())
+
+	// Samples without a pid keep their own series
+	_, exists := dataPoints.At(0).Attributes().Get("process.pid")
+	assert.False(t, exists)
+	assert.InDelta(t, 0.5, dataPoints.At(0).DoubleValue(), 1e-9)
+
+	pid, exists := dataPoints.At(1).Attributes().Get("process.pid")
+	require.True(t, exists)
+	assert.Equal(t, "7", pid.Str())
+	assert.InDelta(t, 2.0, dataPoints.At(1).DoubleValue(), 1e-9)
+}
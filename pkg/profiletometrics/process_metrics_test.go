@@ -0,0 +1,64 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_ProcessMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		process  ProcessMetricConfig
+		expected map[string]int
+	}{
+		{
+			name:     "Process metrics disabled",
+			process:  ProcessMetricConfig{Enabled: false},
+			expected: map[string]int{"cpu_time": 1, "memory_allocation": 1},
+		},
+		{
+			name:     "Process metrics reuse the metric names",
+			process:  ProcessMetricConfig{Enabled: true},
+			expected: map[string]int{"cpu_time": 3, "memory_allocation": 3},
+		},
+		{
+			name:    "Process metrics with distinct names",
+			process: ProcessMetricConfig{Enabled: true, CPUMetricName: "process_cpu_time", MemoryMetricName: "process_memory_allocation"},
+			expected: map[string]int{
+				"cpu_time": 1, "memory_allocation": 1,
+				"process_cpu_time": 2, "process_memory_allocation": 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory:  MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Process: tt.process,
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			b.sample(b.stack("main"), map[string]string{"process.executable.name": "app"}, 1000000000, 1024)
+			b.sample(b.stack("main"), map[string]string{"process.executable.name": "db"}, 1000000000, 1024)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			dataPoints := make(map[string]int)
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				metric := metricSlice.At(i)
+				dataPoints[metric.Name()] += metric.Gauge().DataPoints().Len()
+			}
+			assert.Equal(t, tt.expected, dataPoints)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// testProfileBuilder builds a single-profile pprofile.Profiles with a populated dictionary
+type testProfileBuilder struct {
+	profiles  pprofile.Profiles
+	resource  pprofile.ResourceProfiles
+	scope     pprofile.ScopeProfiles
+	profile   pprofile.Profile
+	strings   map[string]int32
+	functions map[string]int32
+}
+
+func newTestProfileBuilder() *testProfileBuilder {
+	profiles := pprofile.NewProfiles()
+	resource := profiles.ResourceProfiles().AppendEmpty()
+	scope := resource.ScopeProfiles().AppendEmpty()
+	b := &testProfileBuilder{
+		profiles:  profiles,
+		resource:  resource,
+		scope:     scope,
+		profile:   scope.Profiles().AppendEmpty(),
+		strings:   make(map[string]int32),
+		functions: make(map[string]int32),
+	}
+	// The string table must start with the empty string
+	b.str("")
+	return b
+}
+
+// str interns a string in the dictionary string table
+func (b *testProfileBuilder) str(s string) int32 {
+	if idx, ok := b.strings[s]; ok {
+		return idx
+	}
+	stringTable := b.profiles.Dictionary().StringTable()
+	stringTable.Append(s)
+	idx := int32(stringTable.Len() - 1)
+	b.strings[s] = idx
+	return idx
+}
+
+// withSampleType sets the profile sample type
+func (b *testProfileBuilder) withSampleType(typeName, unit string) *testProfileBuilder {
+	b.profile.SampleType().SetTypeStrindex(b.str(typeName))
+	b.profile.SampleType().SetUnitStrindex(b.str(unit))
+	return b
+}
+
+// function interns a function (and a single-line location pointing at it), returning the location index
+func (b *testProfileBuilder) function(name, filename string) int32 {
+	key := name + "\x00" + filename
+	if idx, ok := b.functions[key]; ok {
+		return idx
+	}
+	dictionary := b.profiles.Dictionary()
+	fn := dictionary.FunctionTable().AppendEmpty()
+	fn.SetNameStrindex(b.str(name))
+	if filename != "" {
+		fn.SetFilenameStrindex(b.str(filename))
+	}
+	location := dictionary.LocationTable().AppendEmpty()
+	location.Line().AppendEmpty().SetFunctionIndex(int32(dictionary.FunctionTable().Len() - 1))
+	idx := int32(dictionary.LocationTable().Len() - 1)
+	b.functions[key] = idx
+	return idx
+}
+
+// stack adds a stack of the given function names; following the converter convention the last frame is the leaf
+func (b *testProfileBuilder) stack(functionNames ...string) int32 {
+	stack := b.profiles.Dictionary().StackTable().AppendEmpty()
+	for _, name := range functionNames {
+		stack.LocationIndices().Append(b.function(name, ""))
+	}
+	return int32(b.profiles.Dictionary().StackTable().Len() - 1)
+}
+
+// attribute adds a string attribute to the attribute table, returning its index
+func (b *testProfileBuilder) attribute(key, value string) int32 {
+	attributeTable := b.profiles.Dictionary().AttributeTable()
+	attr := attributeTable.AppendEmpty()
+	attr.SetKeyStrindex(b.str(key))
+	attr.Value().SetStr(value)
+	return int32(attributeTable.Len() - 1)
+}
+
+// sample appends a sample with the given stack, string attributes and values
+func (b *testProfileBuilder) sample(stackIndex int32, attributes map[string]string, values ...int64) pprofile.Sample {
+	sample := b.profile.Sample().AppendEmpty()
+	sample.SetStackIndex(stackIndex)
+	for key, value := range attributes {
+		sample.AttributeIndices().Append(b.attribute(key, value))
+	}
+	for _, v := range values {
+		sample.Values().Append(v)
+	}
+	return sample
+}
+
+// mapping adds a binary mapping with the given filename, returning its index
+func (b *testProfileBuilder) mapping(filename string) int32 {
+	mappingTable := b.profiles.Dictionary().MappingTable()
+	mappingTable.AppendEmpty().SetFilenameStrindex(b.str(filename))
+	return int32(mappingTable.Len() - 1)
+}
+
+// location returns the dictionary location of a function added with function()
+func (b *testProfileBuilder) location(name, filename string) pprofile.Location {
+	return b.profiles.Dictionary().LocationTable().At(int(b.function(name, filename)))
+}
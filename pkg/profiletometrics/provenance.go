@@ -0,0 +1,73 @@
+package profiletometrics
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// converterVersion is the version of the conversion, also used as instrumentation scope version
+	converterVersion = "1.0.0"
+
+	// Provenance attributes describing how a data point was produced
+	converterVersionAttributeKey = "converter.version"
+	attributionModeAttributeKey  = "attribution.mode"
+	valueSourceAttributeKey      = "value.source"
+
+	// Value sources: measured from sample values, or estimated for samples without values
+	valueSourceMeasured  = "measured"
+	valueSourceEstimated = "estimated"
+)
+
+// profileValueSource reports whether the values of a profile were estimated: estimation is enabled
+// and at least one of its samples carries no values. With estimation.separate_metrics, only the
+// <metric>.estimated metrics hold estimates.
+func (c *Converter) profileValueSource(profile pprofile.Profile, metricName string) string {
+	if !c.config.Estimation.Enabled {
+		return valueSourceMeasured
+	}
+	if c.config.Estimation.SeparateMetrics {
+		if strings.HasSuffix(metricName, estimatedMetricSuffix) {
+			return valueSourceEstimated
+		}
+		return valueSourceMeasured
+	}
+	for i := 0; i < profile.Sample().Len(); i++ {
+		if profile.Sample().At(i).Values().Len() == 0 {
+			return valueSourceEstimated
+		}
+	}
+	return valueSourceMeasured
+}
+
+// stampProvenance adds converter.version, attribution.mode and value.source to every data point
+// generated from a profile
+func (c *Converter) stampProvenance(profile pprofile.Profile, scopeMetrics pmetric.ScopeMetrics) {
+	attributionMode := c.config.Metrics.Function.Attribution
+	if attributionMode == "" {
+		attributionMode = functionAttributionSelf
+	}
+
+	metricSlice := scopeMetrics.Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		valueSource := c.profileValueSource(profile, metric.Name())
+		var dataPoints pmetric.NumberDataPointSlice
+		switch metric.Type() {
+		case pmetric.MetricTypeGauge:
+			dataPoints = metric.Gauge().DataPoints()
+		case pmetric.MetricTypeSum:
+			dataPoints = metric.Sum().DataPoints()
+		default:
+			continue
+		}
+		for j := 0; j < dataPoints.Len(); j++ {
+			attributes := dataPoints.At(j).Attributes()
+			attributes.PutStr(converterVersionAttributeKey, converterVersion)
+			attributes.PutStr(attributionModeAttributeKey, attributionMode)
+			attributes.PutStr(valueSourceAttributeKey, valueSource)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_Provenance(t *testing.T) {
+	tests := []struct {
+		name                string
+		attribution         string
+		estimation          bool
+		expectedAttribution string
+		expectedValueSource string
+	}{
+		{
+			name:                "measured",
+			expectedAttribution: "self",
+			expectedValueSource: "measured",
+		},
+		{
+			name:                "estimated",
+			attribution:         "total",
+			estimation:          true,
+			expectedAttribution: "total",
+			expectedValueSource: "estimated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Function: FunctionMetricConfig{Enabled: true, Attribution: tt.attribution},
+				},
+				Estimation: EstimationConfig{Enabled: tt.estimation},
+				Provenance: true,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			stack := b.stack("main")
+			b.sample(stack, nil, 1000000000)
+			b.sample(stack, nil)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			require.Positive(t, metricSlice.Len())
+			for i := 0; i < metricSlice.Len(); i++ {
+				dataPoints := metricSlice.At(i).Gauge().DataPoints()
+				for j := 0; j < dataPoints.Len(); j++ {
+					attributes := dataPoints.At(j).Attributes().AsRaw()
+					assert.Equal(t, converterVersion, attributes[converterVersionAttributeKey])
+					assert.Equal(t, tt.expectedAttribution, attributes[attributionModeAttributeKey])
+					assert.Equal(t, tt.expectedValueSource, attributes[valueSourceAttributeKey])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// rollUpResourceGroups merges the CPU and memory data points of the processes and resources sharing
+// the group_by_resource_attributes values: process.name and the other resource attributes are
+// dropped and the values of data points left with the same attributes are summed. Data points keep
+// their remaining breakdowns (thread, function) and the widest time range of the merged points;
+// the emptied metrics are removed by compaction.
+func (c *Converter) rollUpResourceGroups(profiles pprofile.Profiles, metrics pmetric.Metrics) {
+	groupKeys := make(map[string]bool, len(c.config.GroupByResourceAttributes))
+	for _, key := range c.config.GroupByResourceAttributes {
+		groupKeys[key] = true
+	}
+	dropped := map[string]bool{"process.name": true}
+	for i := 0; i < profiles.ResourceProfiles().Len(); i++ {
+		profiles.ResourceProfiles().At(i).Resource().Attributes().Range(func(key string, _ pcommon.Value) bool {
+			if !groupKeys[key] {
+				dropped[key] = true
+			}
+			return true
+		})
+	}
+
+	// Profiles emit their metrics separately: gather the data points of each metric first
+	names := c.additiveMetricNames()
+	targets := make(map[string]pmetric.Metric)
+	var order []string
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				if !names[metric.Name()] {
+					continue
+				}
+				key := metricDescriptorKey(metric)
+				if target, exists := targets[key]; exists {
+					moveMetricDataPoints(metric, target)
+					continue
+				}
+				targets[key] = metric
+				order = append(order, key)
+			}
+		}
+	}
+
+	for _, key := range order {
+		switch metric := targets[key]; metric.Type() {
+		case pmetric.MetricTypeGauge:
+			rollUpDataPoints(metric.Gauge().DataPoints(), dropped)
+		case pmetric.MetricTypeSum:
+			rollUpDataPoints(metric.Sum().DataPoints(), dropped)
+		}
+	}
+}
+
+// additiveMetricNames returns the names of the CPU time and memory allocation metrics, whose values
+// add up across processes
+func (c *Converter) additiveMetricNames() map[string]bool {
+	names := map[string]bool{
+		c.config.Metrics.CPU.MetricName:    true,
+		c.config.Metrics.Memory.MetricName: true,
+	}
+	for _, metricNames := range []func() (string, string){c.processMetricNames, c.threadMetricNames, c.functionMetricNames} {
+		cpu, memory := metricNames()
+		names[cpu], names[memory] = true, true
+	}
+	delete(names, "")
+	return names
+}
+
+// rollUpDataPoints removes the dropped attributes and sums data points left with the same attributes
+func rollUpDataPoints(dataPoints pmetric.NumberDataPointSlice, dropped map[string]bool) {
+	groups := make(map[string]pmetric.NumberDataPoint)
+	dataPoints.RemoveIf(func(dataPoint pmetric.NumberDataPoint) bool {
+		dataPoint.Attributes().RemoveIf(func(key string, _ pcommon.Value) bool {
+			return dropped[key]
+		})
+		key := attributesKey(dataPoint.Attributes())
+		group, exists := groups[key]
+		if !exists {
+			groups[key] = dataPoint
+			return false
+		}
+		group.SetDoubleValue(group.DoubleValue() + dataPoint.DoubleValue())
+		if start := dataPoint.StartTimestamp(); start != 0 && (group.StartTimestamp() == 0 || start < group.StartTimestamp()) {
+			group.SetStartTimestamp(start)
+		}
+		if dataPoint.Timestamp() > group.Timestamp() {
+			group.SetTimestamp(dataPoint.Timestamp())
+		}
+		return true
+	})
+}
@@ -0,0 +1,88 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// newResourceGroupsTestProfiles returns two containers of pod p1 and one container of pod p2, each
+// running an app and a sidecar process
+func newResourceGroupsTestProfiles() *testProfileBuilder {
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "serve"), map[string]string{"process.executable.name": "app"}, int64(time.Second))
+	b.sample(b.stack("main", "proxy"), map[string]string{"process.executable.name": "sidecar"}, int64(500*time.Millisecond))
+
+	for i, resource := range []struct{ pod, container string }{{"p1", "c1"}, {"p1", "c2"}, {"p2", "c3"}} {
+		target := b.resource
+		if i > 0 {
+			target = b.profiles.ResourceProfiles().AppendEmpty()
+			b.resource.CopyTo(target)
+		}
+		target.Resource().Attributes().PutStr("k8s.pod.name", resource.pod)
+		target.Resource().Attributes().PutStr("container.id", resource.container)
+	}
+	return b
+}
+
+// groupedValues returns the data point values of a metric keyed by k8s.pod.name
+func groupedValues(t *testing.T, metrics pmetric.Metrics, name string) map[string]float64 {
+	values := make(map[string]float64)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() != name {
+					continue
+				}
+				for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+					attributes := metric.Gauge().DataPoints().At(l).Attributes()
+					assert.NotContains(t, attributes.AsRaw(), "process.name")
+					assert.NotContains(t, attributes.AsRaw(), "container.id")
+					pod, _ := attributes.Get("k8s.pod.name")
+					values[pod.Str()] += metric.Gauge().DataPoints().At(l).DoubleValue()
+				}
+			}
+		}
+	}
+	return values
+}
+
+func TestConverter_GroupByResourceAttributes(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Process: ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+		},
+		GroupByResourceAttributes: []string{"k8s.pod.name"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newResourceGroupsTestProfiles().profiles)
+	require.NoError(t, err)
+
+	expected := map[string]float64{"p1": 3, "p2": 1.5}
+	assert.Equal(t, expected, groupedValues(t, metrics, "cpu_time"))
+	assert.Equal(t, expected, groupedValues(t, metrics, "cpu_time.by_process"))
+
+	// One data point per pod
+	for i := 0; i < metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().Len(); i++ {
+		assert.Equal(t, 2, metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(i).Gauge().DataPoints().Len())
+	}
+}
+
+func TestConverter_WithoutResourceGroups(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+	})
+	require.NoError(t, err)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newResourceGroupsTestProfiles().profiles)
+	require.NoError(t, err)
+	assert.Equal(t, 3, metrics.DataPointCount())
+}
@@ -0,0 +1,68 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// SplitFunctionMetrics moves function-level data points (those carrying the function name, or the
+// metrics.function.group_by attribute) out of metrics into a separate payload, so they can be
+// routed to their own pipelines. Both payloads are compacted.
+func (c *Converter) SplitFunctionMetrics(metrics pmetric.Metrics) pmetric.Metrics {
+	functionKeys := []string{c.codeAttributeKeys().functionName, c.functionGroupAttributeKey()}
+	isFunctionDataPoint := func(dataPoint pmetric.NumberDataPoint) bool {
+		for _, key := range functionKeys {
+			if _, ok := dataPoint.Attributes().Get(key); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	functionMetrics := pmetric.NewMetrics()
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		resourceMetrics := metrics.ResourceMetrics().At(i)
+		functionResourceMetrics := functionMetrics.ResourceMetrics().AppendEmpty()
+		resourceMetrics.Resource().CopyTo(functionResourceMetrics.Resource())
+		functionResourceMetrics.SetSchemaUrl(resourceMetrics.SchemaUrl())
+
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			scopeMetrics := resourceMetrics.ScopeMetrics().At(j)
+			functionScopeMetrics := functionResourceMetrics.ScopeMetrics().AppendEmpty()
+			scopeMetrics.Scope().CopyTo(functionScopeMetrics.Scope())
+			functionScopeMetrics.SetSchemaUrl(scopeMetrics.SchemaUrl())
+
+			for k := 0; k < scopeMetrics.Metrics().Len(); k++ {
+				metric := scopeMetrics.Metrics().At(k)
+				var dataPoints, functionDataPoints pmetric.NumberDataPointSlice
+				functionMetric := functionScopeMetrics.Metrics().AppendEmpty()
+				functionMetric.SetName(metric.Name())
+				functionMetric.SetDescription(metric.Description())
+				functionMetric.SetUnit(metric.Unit())
+				switch metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+					functionDataPoints = functionMetric.SetEmptyGauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+					sum := functionMetric.SetEmptySum()
+					sum.SetAggregationTemporality(metric.Sum().AggregationTemporality())
+					sum.SetIsMonotonic(metric.Sum().IsMonotonic())
+					functionDataPoints = sum.DataPoints()
+				default:
+					continue
+				}
+				dataPoints.RemoveIf(func(dataPoint pmetric.NumberDataPoint) bool {
+					if !isFunctionDataPoint(dataPoint) {
+						return false
+					}
+					dataPoint.MoveTo(functionDataPoints.AppendEmpty())
+					return true
+				})
+			}
+		}
+	}
+
+	compactMetrics(metrics)
+	compactMetrics(functionMetrics)
+	return functionMetrics
+}
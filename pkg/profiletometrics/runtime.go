@@ -0,0 +1,166 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// liveBytesUnit is the unit of live heap metrics; live bytes go up and down and stay gauges
+	liveBytesUnit = "By"
+
+	// Runtime resource attributes copied onto runtime metrics
+	runtimeNameAttributeKey    = "runtime.name"
+	runtimeVersionAttributeKey = "runtime.version"
+)
+
+// liveHeapSampleTypes lists sample type names reporting the bytes live on the heap
+var liveHeapSampleTypes = map[string]bool{
+	"live_heap":   true,
+	"heap_live":   true,
+	"inuse_space": true,
+}
+
+// gcCPUSampleTypes lists sample type names reporting CPU time spent in garbage collection, in nanoseconds
+var gcCPUSampleTypes = map[string]bool{
+	"gc_cpu":  true,
+	"gc_time": true,
+}
+
+// runtimeTotals aggregates the runtime profiles of a resource
+type runtimeTotals struct {
+	attributes     map[string]string
+	heapProfile    pprofile.Profile
+	heapBytes      float64
+	hasHeap        bool
+	gcProfile      pprofile.Profile
+	gcCPUSeconds   float64
+	hasGC          bool
+	cpuTimeSeconds float64
+}
+
+// isCPUSampleType reports whether a sample type carries CPU samples, i.e. none of the allocation,
+// lock, exception or runtime sample types
+func isCPUSampleType(sampleType string) bool {
+	return !memorySampleTypes[sampleType] && !lockDelaySampleTypes[sampleType] && !lockCountSampleTypes[sampleType] &&
+		!exceptionSampleTypes[sampleType] && !liveHeapSampleTypes[sampleType] && !gcCPUSampleTypes[sampleType]
+}
+
+// generateRuntimeMetrics emits, per resource, the live heap bytes of its latest live heap profile
+// and the share of its CPU time spent in garbage collection (gc_cpu samples against the CPU samples
+// of the same resource). Data points carry the profile attributes, including runtime.name and
+// runtime.version, which are also looked up on the profile when the resource lacks them.
+func (c *Converter) generateRuntimeMetrics(profiles pprofile.Profiles, resourceMetrics pmetric.ResourceMetrics) {
+	byResource := make(map[int]*runtimeTotals)
+	iterateProfilesCommon(profiles, c.extractResourceAttributes,
+		func(dictionary dictionaryProvider, resourceIndex, scopeIndex, _ int, profile pprofile.Profile, resourceAttributes map[string]string) {
+			totals, exists := byResource[resourceIndex]
+			if !exists {
+				totals = &runtimeTotals{
+					attributes: c.extractProfileAttributes(dictionary, scopeOfProfile(profiles, resourceIndex, scopeIndex), profile, resourceAttributes),
+				}
+				byResource[resourceIndex] = totals
+			}
+			for _, key := range []string{runtimeNameAttributeKey, runtimeVersionAttributeKey} {
+				if _, ok := totals.attributes[key]; !ok {
+					if value := getAttributeValueCommon(dictionary, profile.AttributeIndices(), key); value != "" {
+						totals.attributes[key] = value
+					}
+				}
+			}
+
+			sampleType, _ := getProfileSampleTypeCommon(dictionary, profile)
+			switch {
+			case liveHeapSampleTypes[sampleType]:
+				// Live heap profiles are snapshots: only the latest one counts
+				if !totals.hasHeap || profile.Time() >= totals.heapProfile.Time() {
+					totals.heapProfile = profile
+					totals.heapBytes = sumFirstSampleValues(profile)
+					totals.hasHeap = true
+				}
+			case gcCPUSampleTypes[sampleType]:
+				totals.gcProfile = profile
+				totals.gcCPUSeconds += sumFirstSampleValues(profile) / nanosecondsPerSecond
+				totals.hasGC = true
+			case isCPUSampleType(sampleType):
+				totals.cpuTimeSeconds += c.profileCPUSeconds(dictionary, profile)
+			}
+		})
+
+	resourceIndices := make([]int, 0, len(byResource))
+	for resourceIndex := range byResource {
+		resourceIndices = append(resourceIndices, resourceIndex)
+	}
+	sort.Ints(resourceIndices)
+
+	var heapPoints, gcPoints []runtimeDataPoint
+	for _, resourceIndex := range resourceIndices {
+		totals := byResource[resourceIndex]
+		if totals.hasHeap {
+			heapPoints = append(heapPoints, runtimeDataPoint{totals.heapBytes, totals.heapProfile, totals.attributes})
+		}
+		if totals.hasGC && totals.cpuTimeSeconds > 0 {
+			// GC time is part of the CPU samples; the share is capped for profiles of different lengths
+			share := totals.gcCPUSeconds / totals.cpuTimeSeconds * 100
+			if share > 100 {
+				share = 100
+			}
+			gcPoints = append(gcPoints, runtimeDataPoint{share, totals.gcProfile, totals.attributes})
+		}
+	}
+	if len(heapPoints) == 0 && len(gcPoints) == 0 {
+		return
+	}
+
+	cfg := c.config.Metrics.Runtime
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("profiletometrics")
+	scopeMetrics.Scope().SetVersion(converterVersion)
+	c.appendRuntimeMetric(scopeMetrics, cfg.HeapLiveMetricName, "Bytes live on the heap", liveBytesUnit, heapPoints)
+	c.appendRuntimeMetric(scopeMetrics, cfg.GCCPUShareMetricName, "Share of CPU time spent in garbage collection",
+		percentUnit, gcPoints)
+}
+
+// runtimeDataPoint is a runtime metric value of a resource
+type runtimeDataPoint struct {
+	value      float64
+	profile    pprofile.Profile
+	attributes map[string]string
+}
+
+// appendRuntimeMetric appends a gauge with the given data points, if any
+func (c *Converter) appendRuntimeMetric(
+	scopeMetrics pmetric.ScopeMetrics,
+	name, description, unit string,
+	points []runtimeDataPoint,
+) {
+	if len(points) == 0 {
+		return
+	}
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetDescription(description)
+	metric.SetUnit(unit)
+	gauge := metric.SetEmptyGauge()
+	for _, point := range points {
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, point.profile)
+		dataPoint.SetDoubleValue(point.value)
+		for k, v := range point.attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+	}
+}
+
+// sumFirstSampleValues sums the first value of every sample of a profile
+func sumFirstSampleValues(profile pprofile.Profile) float64 {
+	var total float64
+	for i := 0; i < profile.Sample().Len(); i++ {
+		if values := profile.Sample().At(i).Values(); values.Len() > 0 {
+			total += float64(values.At(0))
+		}
+	}
+	return total
+}
@@ -0,0 +1,83 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_RuntimeMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Runtime: RuntimeMetricConfig{
+				Enabled:              true,
+				HeapLiveMetricName:   "runtime.heap.live",
+				GCCPUShareMetricName: "runtime.gc.cpu_share",
+			},
+		},
+		AggregationTemporality: "cumulative",
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.resource.Resource().Attributes().PutStr(runtimeNameAttributeKey, "go")
+	b.profile.AttributeIndices().Append(b.attribute(runtimeVersionAttributeKey, "go1.24"))
+	b.sample(b.stack("main.main", "main.work"), nil, 3000000000)
+	b.sample(b.stack("runtime.gcBgMarkWorker"), nil, 1000000000)
+
+	b.profile = b.scope.Profiles().AppendEmpty()
+	b.withSampleType("gc_cpu", "nanoseconds")
+	b.sample(b.stack("runtime.gcBgMarkWorker"), nil, 1000000000)
+
+	b.profile = b.scope.Profiles().AppendEmpty()
+	b.withSampleType("live_heap", "bytes")
+	b.sample(b.stack("main.main", "main.alloc"), nil, 4096)
+	b.sample(b.stack("main.main"), nil, 1024)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	found := make(map[string]pmetric.Metric)
+	resourceMetrics := metrics.ResourceMetrics().At(0)
+	for i := 0; i < resourceMetrics.ScopeMetrics().Len(); i++ {
+		metricSlice := resourceMetrics.ScopeMetrics().At(i).Metrics()
+		for j := 0; j < metricSlice.Len(); j++ {
+			found[metricSlice.At(j).Name()] = metricSlice.At(j)
+		}
+	}
+
+	heap, ok := found["runtime.heap.live"]
+	require.True(t, ok)
+	assert.Equal(t, liveBytesUnit, heap.Unit())
+	require.Equal(t, pmetric.MetricTypeGauge, heap.Type())
+	dp := heap.Gauge().DataPoints().At(0)
+	assert.Equal(t, 5120.0, dp.DoubleValue())
+	runtimeName, _ := dp.Attributes().Get(runtimeNameAttributeKey)
+	assert.Equal(t, "go", runtimeName.Str())
+	runtimeVersion, _ := dp.Attributes().Get(runtimeVersionAttributeKey)
+	assert.Equal(t, "go1.24", runtimeVersion.Str())
+
+	gc, ok := found["runtime.gc.cpu_share"]
+	require.True(t, ok)
+	assert.Equal(t, percentUnit, gc.Unit())
+	require.Equal(t, pmetric.MetricTypeGauge, gc.Type())
+	assert.Equal(t, 25.0, gc.Gauge().DataPoints().At(0).DoubleValue())
+}
+
+func TestConverter_RuntimeMetricsWithoutRuntimeSampleTypes(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Runtime: RuntimeMetricConfig{Enabled: true, HeapLiveMetricName: "runtime.heap.live"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main.main"), nil, 1000000000)
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Equal(t, 0, metrics.MetricCount())
+}
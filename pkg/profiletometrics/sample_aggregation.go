@@ -0,0 +1,196 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// sampleTotals sums the CPU time and memory allocation of a group of samples, keeping the values
+// measured from sample values apart from those estimated for samples without values. The per-sample
+// values are also reduced with the cpu and memory aggregations when they are not sums.
+type sampleTotals struct {
+	measuredCPUTime  float64
+	estimatedCPUTime float64
+	measuredMemory   float64
+	estimatedMemory  float64
+	cpuValues        *valueAggregator
+	memoryValues     *valueAggregator
+	// separateEstimates leaves the samples without values out of the aggregated values
+	separateEstimates bool
+}
+
+// newSampleTotals returns empty totals reducing the per-sample values with the configured
+// aggregations that are not sums
+func (c *Converter) newSampleTotals() *sampleTotals {
+	totals := &sampleTotals{separateEstimates: c.config.Estimation.SeparateMetrics}
+	if aggregation := c.config.Metrics.CPU.Aggregation; !summedAggregation(aggregation) {
+		totals.cpuValues = newValueAggregator(aggregation)
+	}
+	if aggregation := c.config.Metrics.Memory.Aggregation; !summedAggregation(aggregation) {
+		totals.memoryValues = newValueAggregator(aggregation)
+	}
+	return totals
+}
+
+// merge adds the totals of a single sample
+func (t *sampleTotals) merge(sample sampleTotals) {
+	t.measuredCPUTime += sample.measuredCPUTime
+	t.estimatedCPUTime += sample.estimatedCPUTime
+	t.measuredMemory += sample.measuredMemory
+	t.estimatedMemory += sample.estimatedMemory
+	if t.cpuValues != nil && !(t.separateEstimates && sample.estimatedCPUTime > 0) {
+		t.cpuValues.add(sample.measuredCPUTime + sample.estimatedCPUTime)
+	}
+	if t.memoryValues != nil && !(t.separateEstimates && sample.estimatedMemory > 0) {
+		t.memoryValues.add(sample.measuredMemory + sample.estimatedMemory)
+	}
+}
+
+// add sums the CPU time and memory allocation of a sample
+func (t *sampleTotals) add(c *Converter, sample pprofile.Sample, weight cpuWeight, summary *conversionSummary) {
+	measured, estimated := c.sampleCPUTimeTotals(sample, weight, summary)
+	t.measuredCPUTime += measured
+	t.estimatedCPUTime += estimated
+	measured, estimated = c.sampleMemoryTotals(sample, summary)
+	t.measuredMemory += measured
+	t.estimatedMemory += estimated
+}
+
+// sampleCPUTimeTotals returns the CPU time measured from a sample value, or the CPU time estimated
+// for a sample without values
+func (c *Converter) sampleCPUTimeTotals(sample pprofile.Sample, weight cpuWeight, summary *conversionSummary) (float64, float64) {
+	summary.sampleEvaluations.Add(1)
+	// Take the first value as CPU time, weighted by the profile's sample type and period
+	if values := sample.Values(); values.Len() > 0 {
+		return weight.seconds(values.At(0)), 0
+	}
+	// Stack trace profiles have no values: their CPU time is only estimated when enabled
+	summary.samplesWithoutValues.Add(1)
+	return 0, c.estimatedSampleCPUTime(summary, weight.sampleCount)
+}
+
+// sampleMemoryTotals returns the memory allocation measured from a sample value, or the allocation
+// estimated for a sample without values
+func (c *Converter) sampleMemoryTotals(sample pprofile.Sample, summary *conversionSummary) (float64, float64) {
+	summary.sampleEvaluations.Add(1)
+	// The second value is the allocation; a single value is treated as memory for memory-only profiles
+	switch values := sample.Values(); {
+	case values.Len() > 1:
+		return float64(values.At(1)), 0
+	case values.Len() == 1:
+		return float64(values.At(0)), 0
+	default:
+		// Stack trace profiles have no values: their allocation is only estimated when enabled
+		summary.samplesWithoutValues.Add(1)
+		return 0, c.estimatedSampleMemoryAllocation(summary)
+	}
+}
+
+// aggregateSamplesBy sums the samples of a profile per value of a sample attribute in a single pass.
+// Samples without the attribute are skipped; with array_attributes explode, a sample counts toward
+// each of its values.
+func (c *Converter) aggregateSamplesBy(profiles dictionaryProvider, profile pprofile.Profile, key string) map[string]*sampleTotals {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	totals := make(map[string]*sampleTotals)
+	var values []string
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		var sampleValues sampleTotals
+		added := false
+		values = appendSampleAttributeValuesCommon(values[:0], c.config, profiles, sample, key)
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			if !added {
+				sampleValues.add(c, sample, weight, summary)
+				added = true
+			}
+			group, exists := totals[value]
+			if !exists {
+				group = c.newSampleTotals()
+				totals[value] = group
+			}
+			group.merge(sampleValues)
+		}
+	}
+	return totals
+}
+
+// aggregateSamples sums every sample of a profile
+func (c *Converter) aggregateSamples(profiles dictionaryProvider, profile pprofile.Profile) *sampleTotals {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	totals := c.newSampleTotals()
+	for i := 0; i < profile.Sample().Len(); i++ {
+		var sampleValues sampleTotals
+		sampleValues.add(c, profile.Sample().At(i), weight, summary)
+		totals.merge(sampleValues)
+	}
+	return totals
+}
+
+// aggregateProcessFunctions sums the self values of the leaf function of every sample per
+// (process, function) pair in a single pass, sorted by process then function. The file name and
+// code.filepath of a function are taken from the first sample resolving them.
+func (c *Converter) aggregateProcessFunctions(profiles dictionaryProvider, profile pprofile.Profile) []functionDataPoint {
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	byKey := make(map[processFunctionKey]*functionDataPoint)
+	fileNames := make(map[string]string)
+	codeFilePaths := make(map[string]string)
+	var processNames []string
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		functionName := c.getSampleFunctionName(profiles, sample)
+		if functionName == "" {
+			continue
+		}
+		processNames = appendSampleAttributeValuesCommon(processNames[:0], c.config, profiles, sample, "process.executable.name")
+		if len(processNames) == 0 {
+			continue
+		}
+		if fileNames[functionName] == "" {
+			fileNames[functionName] = c.getSampleFileName(profiles, sample)
+		}
+		if c.config.Metrics.Function.CodeFilePath && codeFilePaths[functionName] == "" {
+			codeFilePaths[functionName] = c.getSampleCodeFilePath(profiles, sample)
+		}
+
+		cpuTime := c.sampleCPUTime(sample, weight, summary)
+		memory := c.sampleMemoryAllocation(sample, summary)
+		for _, processName := range processNames {
+			if processName == "" {
+				continue
+			}
+			key := processFunctionKey{processName: processName, functionName: functionName}
+			point, exists := byKey[key]
+			if !exists {
+				point = &functionDataPoint{
+					processName:  processName,
+					functionName: functionName,
+					attribution:  functionAttributionSelf,
+				}
+				byKey[key] = point
+			}
+			point.cpuTime += cpuTime
+			point.memory += memory
+		}
+	}
+
+	points := make([]functionDataPoint, 0, len(byKey))
+	for _, point := range byKey {
+		point.fileName = fileNames[point.functionName]
+		point.codeFilePath = codeFilePaths[point.functionName]
+		points = append(points, *point)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].processName != points[j].processName {
+			return points[i].processName < points[j].processName
+		}
+		return points[i].functionName < points[j].functionName
+	})
+	return points
+}
@@ -0,0 +1,48 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// compileThreadFilter compiles the thread filter pattern and patterns, returning nil when disabled
+func compileThreadFilter(cfg ThreadFilterConfig) ([]*regexp.Regexp, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	patterns := cfg.Patterns
+	if cfg.Pattern != "" {
+		patterns = append([]string{cfg.Pattern}, patterns...)
+	}
+
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid thread_filter pattern %q: %w", pattern, err)
+		}
+		regexes = append(regexes, re)
+	}
+	return regexes, nil
+}
+
+// applySampleFilters returns a copy of the profile restricted to the samples passing the sample-level
+// filters (pattern, function and thread filters), so that global, per-process, per-thread and per-function
+// aggregation all see the same samples. The profile itself is returned when no sample-level filter is configured.
+func (c *Converter) applySampleFilters(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+) pprofile.Profile {
+	filtered, dropped := c.filters.filterSamples(profiles, profile, attributes, func(sample pprofile.Sample) string {
+		return c.getSampleFunctionName(profiles, sample)
+	})
+	if dropped > 0 {
+		summary := summaryOf(profiles)
+		summary.filteredSamples.Add(int64(dropped))
+		summary.droppedSamples.Add(int64(dropped))
+	}
+	return filtered
+}
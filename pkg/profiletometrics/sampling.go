@@ -0,0 +1,78 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// validateSampling checks the profile sampling ratio and rate
+func validateSampling(cfg ProfileSamplingConfig) error {
+	if cfg.Ratio < 0 || cfg.Ratio > 1 {
+		return fmt.Errorf("sampling.ratio must be between 0 and 1, got %v", cfg.Ratio)
+	}
+	if cfg.MaxProfilesPerSecond < 0 {
+		return fmt.Errorf("sampling.max_profiles_per_second must not be negative")
+	}
+	return nil
+}
+
+// profileSampler decides which incoming profiles are converted. The ratio keeps an exact share of
+// the profiles, evenly spread, rather than a random one so that aggregates stay comparable between
+// batches; the rate limit is a token bucket holding up to one second of profiles.
+type profileSampler struct {
+	ratio float64
+	rate  float64
+
+	mu      sync.Mutex
+	offered uint64
+	tokens  float64
+	last    time.Time
+	// now reads the clock, replaced in tests
+	now func() time.Time
+}
+
+// newProfileSampler returns the sampler of the configuration, nil when every profile is kept
+func newProfileSampler(cfg ProfileSamplingConfig) *profileSampler {
+	ratio := cfg.Ratio
+	if ratio == 0 {
+		ratio = 1
+	}
+	if ratio == 1 && cfg.MaxProfilesPerSecond == 0 {
+		return nil
+	}
+	return &profileSampler{
+		ratio:  ratio,
+		rate:   cfg.MaxProfilesPerSecond,
+		tokens: max(cfg.MaxProfilesPerSecond, 1),
+		now:    time.Now,
+	}
+}
+
+// keep reports whether the next profile is converted: it must fall within the ratio, then find a
+// token in the bucket
+func (s *profileSampler) keep() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ratio < 1 {
+		// Keep the profile whenever the kept share crosses the next whole profile
+		s.offered++
+		if math.Floor(float64(s.offered)*s.ratio) == math.Floor(float64(s.offered-1)*s.ratio) {
+			return false
+		}
+	}
+	if s.rate > 0 {
+		now := s.now()
+		if !s.last.IsZero() {
+			s.tokens = min(s.tokens+now.Sub(s.last).Seconds()*s.rate, max(s.rate, 1))
+		}
+		s.last = now
+		if s.tokens < 1 {
+			return false
+		}
+		s.tokens--
+	}
+	return true
+}
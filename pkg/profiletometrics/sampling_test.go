@@ -0,0 +1,92 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileSampler_Ratio(t *testing.T) {
+	sampler := newProfileSampler(ProfileSamplingConfig{Ratio: 0.25})
+	require.NotNil(t, sampler)
+
+	var kept []int
+	for i := 0; i < 12; i++ {
+		if sampler.keep() {
+			kept = append(kept, i)
+		}
+	}
+	assert.Equal(t, []int{3, 7, 11}, kept, "one profile in four is kept, evenly spread")
+}
+
+func TestProfileSampler_MaxProfilesPerSecond(t *testing.T) {
+	sampler := newProfileSampler(ProfileSamplingConfig{MaxProfilesPerSecond: 2})
+	require.NotNil(t, sampler)
+	now := time.Unix(1700000000, 0)
+	sampler.now = func() time.Time { return now }
+
+	keep := func(n int) int {
+		kept := 0
+		for i := 0; i < n; i++ {
+			if sampler.keep() {
+				kept++
+			}
+		}
+		return kept
+	}
+	assert.Equal(t, 2, keep(10), "a burst is capped at one second of profiles")
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, 1, keep(10))
+	now = now.Add(time.Minute)
+	assert.Equal(t, 2, keep(10), "idle time does not accumulate beyond one second of profiles")
+}
+
+func TestProfileSampler_Disabled(t *testing.T) {
+	assert.Nil(t, newProfileSampler(ProfileSamplingConfig{}))
+	assert.Nil(t, newProfileSampler(ProfileSamplingConfig{Ratio: 1}))
+}
+
+func TestConverter_Sampling(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Ingestion: IngestionMetricConfig{Enabled: true},
+		},
+		Sampling: ProfileSamplingConfig{Ratio: 0.5},
+	})
+	require.NoError(t, err)
+
+	converted := 0
+	for i := 0; i < 4; i++ {
+		b := newTestProfileBuilder()
+		b.sample(b.stack("main"), nil, 1000000000)
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+
+		values := make(map[string]float64)
+		metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics()
+		for j := 0; j < metricSlice.Len(); j++ {
+			for k := 0; k < metricSlice.At(j).Metrics().Len(); k++ {
+				metric := metricSlice.At(j).Metrics().At(k)
+				values[metric.Name()] += metric.Gauge().DataPoints().At(0).DoubleValue()
+			}
+		}
+		if _, ok := values["cpu_time"]; ok {
+			converted++
+			assert.Equal(t, float64(0), values["profiletometrics.ingestion.profiles.sampled_out"])
+		} else {
+			assert.Equal(t, float64(1), values["profiletometrics.ingestion.profiles.sampled_out"])
+		}
+	}
+	assert.Equal(t, 2, converted)
+}
+
+func TestNewConverter_InvalidSampling(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{Sampling: ProfileSamplingConfig{Ratio: 1.5}})
+	assert.Error(t, err)
+	_, err = NewConverter(&ConverterConfig{Sampling: ProfileSamplingConfig{MaxProfilesPerSecond: -1}})
+	assert.Error(t, err)
+}
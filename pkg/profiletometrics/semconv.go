@@ -0,0 +1,40 @@
+package profiletometrics
+
+// codeAttributeKeys are the attribute keys describing code locations on data points and spans
+type codeAttributeKeys struct {
+	functionName string
+	fileName     string
+	codeFilePath string
+	lineNumber   string
+}
+
+// legacyCodeAttributeKeys are the historical keys existing dashboards rely on
+var legacyCodeAttributeKeys = codeAttributeKeys{
+	functionName: "function.name",
+	fileName:     "file.name",
+	codeFilePath: codeFilePathAttributeKey,
+	lineNumber:   codeLineNumberAttributeKey,
+}
+
+// semconvCodeAttributeKeys follow the OpenTelemetry code.* semantic conventions; the source file
+// and the code artifact share code.file.path, the artifact (source file or mapped binary) being
+// written last
+var semconvCodeAttributeKeys = codeAttributeKeys{
+	functionName: "code.function.name",
+	fileName:     "code.file.path",
+	codeFilePath: "code.file.path",
+	lineNumber:   "code.line.number",
+}
+
+// codeAttributeKeysFor returns the code attribute keys selected by semconv_attributes
+func codeAttributeKeysFor(cfg *ConverterConfig) codeAttributeKeys {
+	if cfg.SemconvAttributes {
+		return semconvCodeAttributeKeys
+	}
+	return legacyCodeAttributeKeys
+}
+
+// codeAttributeKeys returns the code attribute keys of the converter's data points
+func (c *Converter) codeAttributeKeys() codeAttributeKeys {
+	return codeAttributeKeysFor(c.config)
+}
@@ -0,0 +1,66 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_SemconvAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		semconv  bool
+		expected map[string]any
+	}{
+		{
+			name:     "Legacy keys",
+			semconv:  false,
+			expected: map[string]any{"function.name": "main.encode", "file.name": "encode.go", "code.lineno": int64(42)},
+		},
+		{
+			name:     "Semantic convention keys",
+			semconv:  true,
+			expected: map[string]any{"code.function.name": "main.encode", "code.file.path": "encode.go", "code.line.number": int64(42)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+					Function: FunctionMetricConfig{Enabled: true, IncludeLineNumbers: true},
+				},
+				SemconvAttributes: tt.semconv,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			dictionary := b.profiles.Dictionary()
+			location := dictionary.LocationTable().At(int(b.function("main.encode", "encode.go")))
+			location.Line().At(0).SetLine(42)
+			stack := dictionary.StackTable().AppendEmpty()
+			stack.LocationIndices().Append(b.function("main.encode", "encode.go"))
+			b.sample(int32(dictionary.StackTable().Len()-1), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			cpuMetric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			var found bool
+			for i := 0; i < cpuMetric.Gauge().DataPoints().Len(); i++ {
+				attributes := cpuMetric.Gauge().DataPoints().At(i).Attributes().AsRaw()
+				if _, ok := attributes["process.name"]; !ok || len(attributes) == 1 {
+					continue
+				}
+				found = true
+				delete(attributes, "process.name")
+				assert.Equal(t, tt.expected, attributes)
+			}
+			assert.True(t, found)
+		})
+	}
+}
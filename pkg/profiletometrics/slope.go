@@ -0,0 +1,170 @@
+package profiletometrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	defaultFunctionSlopeWindow = 5 * time.Minute
+
+	// slopeUnit is the unit of CPU seconds gained per second
+	slopeUnit = "s/s"
+)
+
+// slopeObservation is the value of a series at a point in time
+type slopeObservation struct {
+	at    time.Time
+	value float64
+}
+
+// slopeTracker keeps the recent observations of each series and computes their slope. Series not
+// observed within the window are forgotten.
+type slopeTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	series map[string][]slopeObservation
+	now    func() time.Time
+}
+
+// newSlopeTracker creates a tracker keeping the observations of the given window
+func newSlopeTracker(window time.Duration) *slopeTracker {
+	if window <= 0 {
+		window = defaultFunctionSlopeWindow
+	}
+	return &slopeTracker{window: window, series: make(map[string][]slopeObservation), now: time.Now}
+}
+
+// observe records the values of a batch at the given time and returns the slope, per second, of
+// every series with observations at two distinct times within the window. Values observed at the
+// same time add up.
+func (s *slopeTracker) observe(at time.Time, values map[string]float64) map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range values {
+		observations := s.series[key]
+		if n := len(observations); n > 0 && observations[n-1].at.Equal(at) {
+			observations[n-1].value += value
+		} else {
+			observations = append(observations, slopeObservation{at: at, value: value})
+		}
+		s.series[key] = observations
+	}
+
+	slopes := make(map[string]float64, len(values))
+	for key, observations := range s.series {
+		first := 0
+		for first < len(observations) && at.Sub(observations[first].at) > s.window {
+			first++
+		}
+		observations = observations[first:]
+		if len(observations) == 0 {
+			delete(s.series, key)
+			continue
+		}
+		s.series[key] = observations
+		if _, observed := values[key]; observed {
+			if slope, ok := leastSquaresSlope(observations); ok {
+				slopes[key] = slope
+			}
+		}
+	}
+	return slopes
+}
+
+// leastSquaresSlope fits a line through the observations and returns its slope per second
+func leastSquaresSlope(observations []slopeObservation) (float64, bool) {
+	if len(observations) < 2 {
+		return 0, false
+	}
+	origin := observations[0].at
+	var sumX, sumY float64
+	for _, observation := range observations {
+		sumX += observation.at.Sub(origin).Seconds()
+		sumY += observation.value
+	}
+	n := float64(len(observations))
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, variance float64
+	for _, observation := range observations {
+		dx := observation.at.Sub(origin).Seconds() - meanX
+		covariance += dx * (observation.value - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return 0, false
+	}
+	return covariance / variance, true
+}
+
+// functionSlopeKey identifies the series of a function data point within the profile attributes
+func functionSlopeKey(attributes map[string]string, point functionDataPoint) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(point.processName + "\x00" + point.functionName + "\x00" + point.attribution)
+	for _, key := range keys {
+		b.WriteString("\x00" + key + "=" + attributes[key])
+	}
+	return b.String()
+}
+
+// generateFunctionSlopeMetrics records the CPU time of the profile's (process, function) data points
+// and emits the slope of every function observed at least twice within the window. Only CPU profiles
+// are tracked, without their thread and truncated stack data points.
+func (c *Converter) generateFunctionSlopeMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	points []functionDataPoint,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	if sampleType, _ := getProfileSampleTypeCommon(profiles, profile); !isCPUSampleType(sampleType) {
+		return
+	}
+	at := c.functionSlopes.now()
+	if profile.Time() != 0 {
+		at = profile.Time().AsTime().Add(time.Duration(profile.Duration()))
+	}
+
+	values := make(map[string]float64)
+	tracked := make(map[string]functionDataPoint)
+	var keys []string
+	for _, point := range points {
+		if point.threadName != "" || point.truncated {
+			continue
+		}
+		key := functionSlopeKey(attributes, point)
+		if _, exists := tracked[key]; !exists {
+			keys = append(keys, key)
+			tracked[key] = point
+		}
+		values[key] += point.cpuTime
+	}
+	slopes := c.functionSlopes.observe(at, values)
+	if len(slopes) == 0 {
+		return
+	}
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.Function.Slope.MetricName)
+	metric.SetDescription("Growth rate of the CPU time of a function over the slope window")
+	metric.SetUnit(slopeUnit)
+	gauge := metric.SetEmptyGauge()
+	for _, key := range keys {
+		if slope, ok := slopes[key]; ok {
+			c.appendFunctionDataPoint(gauge, profile, attributes, tracked[key], slope)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// slopeDataPoints returns the cpu_time.slope data points of converted metrics
+func slopeDataPoints(metrics pmetric.Metrics) []pmetric.NumberDataPoint {
+	var dataPoints []pmetric.NumberDataPoint
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				if metric := scopeMetrics.At(j).Metrics().At(k); metric.Name() == "cpu_time.slope" {
+					for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+						dataPoints = append(dataPoints, metric.Gauge().DataPoints().At(l))
+					}
+				}
+			}
+		}
+	}
+	return dataPoints
+}
+
+func TestSlopeTracker_Observe(t *testing.T) {
+	tracker := newSlopeTracker(time.Minute)
+	start := time.Unix(1700000000, 0)
+
+	assert.Empty(t, tracker.observe(start, map[string]float64{"hot": 1}))
+	slopes := tracker.observe(start.Add(10*time.Second), map[string]float64{"hot": 2, "new": 5})
+	assert.InDelta(t, 0.1, slopes["hot"], 1e-9)
+	assert.NotContains(t, slopes, "new")
+
+	// Observations outside the window are dropped: only the last two points remain
+	slopes = tracker.observe(start.Add(65*time.Second), map[string]float64{"hot": 2})
+	assert.InDelta(t, 0, slopes["hot"], 1e-9)
+	assert.NotContains(t, slopes, "new")
+
+	// Series without observations within the window are forgotten
+	tracker.observe(start.Add(75*time.Second), nil)
+	assert.NotContains(t, tracker.series, "new")
+}
+
+func TestLeastSquaresSlope(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	slope, ok := leastSquaresSlope([]slopeObservation{
+		{at: start, value: 1},
+		{at: start.Add(time.Second), value: 3},
+		{at: start.Add(2 * time.Second), value: 5},
+	})
+	require.True(t, ok)
+	assert.InDelta(t, 2, slope, 1e-9)
+
+	_, ok = leastSquaresSlope([]slopeObservation{{at: start, value: 1}})
+	assert.False(t, ok)
+}
+
+func TestConverter_FunctionSlope(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{
+				Enabled: true,
+				Slope:   FunctionSlopeConfig{Enabled: true, MetricName: "cpu_time.slope", Window: time.Minute},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	start := time.Unix(1700000000, 0)
+	var slopes []float64
+	for i, cpuSeconds := range []int64{1, 2, 3} {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.profile.SetTime(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * 10 * time.Second)))
+		b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, cpuSeconds*int64(time.Second))
+
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		for _, dataPoint := range slopeDataPoints(metrics) {
+			functionName, _ := dataPoint.Attributes().Get("function.name")
+			assert.Equal(t, "hot", functionName.Str())
+			slopes = append(slopes, dataPoint.DoubleValue())
+		}
+	}
+	// The first profile has no slope yet; the CPU time then grows by 0.1s every second
+	require.Len(t, slopes, 2)
+	assert.InDelta(t, 0.1, slopes[0], 1e-9)
+	assert.InDelta(t, 0.1, slopes[1], 1e-9)
+}
+
+// convertSlopeProfiles converts profiles 10 seconds apart with the given CPU seconds of a function
+// and returns the metrics of each conversion
+func convertSlopeProfiles(t *testing.T, converter *Converter, cpuSeconds ...int64) []pmetric.Metrics {
+	start := time.Unix(1700000000, 0)
+	var conversions []pmetric.Metrics
+	for i, seconds := range cpuSeconds {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.profile.SetTime(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * 10 * time.Second)))
+		b.sample(b.stack("main", "hot"), map[string]string{"process.executable.name": "app"}, seconds*int64(time.Second))
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		conversions = append(conversions, metrics)
+	}
+	return conversions
+}
+
+// slopeMetric returns the cpu_time.slope metric of converted metrics
+func slopeMetric(t *testing.T, metrics pmetric.Metrics) pmetric.Metric {
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metric := metricSlice.At(i); metric.Name() == "cpu_time.slope" {
+			return metric
+		}
+	}
+	require.Fail(t, "no cpu_time.slope metric")
+	return pmetric.Metric{}
+}
+
+func TestConverter_FunctionSlopeStaysGauge(t *testing.T) {
+	cfg := ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{
+				Enabled: true,
+				Slope:   FunctionSlopeConfig{Enabled: true, MetricName: "cpu_time.slope", Window: time.Minute},
+			},
+		},
+	}
+
+	t.Run("aggregation_temporality", func(t *testing.T) {
+		temporalityCfg := cfg
+		temporalityCfg.AggregationTemporality = aggregationTemporalityCumulative
+		converter, err := NewConverter(&temporalityCfg)
+		require.NoError(t, err)
+
+		// A falling slope is not a counter reset
+		conversions := convertSlopeProfiles(t, converter, 3, 2, 1)
+		metric := slopeMetric(t, conversions[2])
+		require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+		assert.InDelta(t, -0.1, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9)
+	})
+
+	t.Run("flush_interval", func(t *testing.T) {
+		converter, err := NewConverter(&cfg)
+		require.NoError(t, err)
+
+		aggregator := NewIntervalAggregator(converter)
+		for _, metrics := range convertSlopeProfiles(t, converter, 3, 2, 1) {
+			aggregator.Add(metrics)
+		}
+		metric := slopeMetric(t, aggregator.Flush())
+		require.Equal(t, pmetric.MetricTypeGauge, metric.Type())
+		assert.InDelta(t, -0.1, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9, "slopes are not added up")
+	})
+}
+
+func TestNewConverter_NegativeFunctionSlopeWindow(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{Function: FunctionMetricConfig{Slope: FunctionSlopeConfig{Enabled: true, Window: -time.Second}}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// stackHashAttributeKey holds the stable hash identifying a stack on per-process metrics
+const stackHashAttributeKey = "stack.hash"
+
+// stackHash returns the stack identity of root-first function names: the 16 hex digit FNV-1a 64-bit
+// hash of the stack folded with ";" and no length limit. It only depends on the function names, so
+// the same stack hashes alike across batches, profiles and stack indices.
+func stackHash(frames []string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(strings.Join(frames, defaultFoldedStackSeparator)))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// StackHashes resolves the stack.hash values of a batch, mapping the hash of every sampled stack to
+// its folded stack "root;caller;leaf". Pass the batch the metrics were converted from.
+func StackHashes(profiles pprofile.Profiles) map[string]string {
+	hashes := make(map[string]string)
+	seen := make(map[int32]bool)
+	resourceProfiles := profiles.ResourceProfiles()
+	for i := 0; i < resourceProfiles.Len(); i++ {
+		scopeProfiles := resourceProfiles.At(i).ScopeProfiles()
+		for j := 0; j < scopeProfiles.Len(); j++ {
+			profileSlice := scopeProfiles.At(j).Profiles()
+			for k := 0; k < profileSlice.Len(); k++ {
+				samples := profileSlice.At(k).Sample()
+				for l := 0; l < samples.Len(); l++ {
+					stackIndex := samples.At(l).StackIndex()
+					if stackIndex < 0 || seen[stackIndex] {
+						continue
+					}
+					seen[stackIndex] = true
+					frames := stackFunctionNamesCommon(profiles, stackIndex)
+					if len(frames) == 0 {
+						continue
+					}
+					hashes[stackHash(frames)] = strings.Join(frames, defaultFoldedStackSeparator)
+				}
+			}
+		}
+	}
+	return hashes
+}
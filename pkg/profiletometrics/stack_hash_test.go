@@ -0,0 +1,80 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// stackHashValues returns the stack.hash of each process of a per-process metric
+func stackHashValues(metrics pmetric.Metrics, name string) map[string]string {
+	hashes := make(map[string]string)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metricSlice.At(i).Name() != name {
+			continue
+		}
+		dataPoints := metricSlice.At(i).Gauge().DataPoints()
+		for j := 0; j < dataPoints.Len(); j++ {
+			processName, _ := dataPoints.At(j).Attributes().Get("process.name")
+			if hash, ok := dataPoints.At(j).Attributes().Get(stackHashAttributeKey); ok {
+				hashes[processName.Str()] = hash.Str()
+			}
+		}
+	}
+	return hashes
+}
+
+func TestConverter_StackHash(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			HottestStack: HottestStackMetricConfig{Enabled: true, MetricName: "hottest_stack_share", StackHash: true},
+			HotPath:      HotPathMetricConfig{Enabled: true, MetricName: "hot_path_cpu_share", StackHash: true},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.encode"), map[string]string{"process.executable.name": "app"}, 2000000000, 0)
+	b.sample(b.stack("main.main", "main.decode"), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+	// Another stack index with the same functions in another process shares the hash
+	b.sample(b.stack("main.main", "main.encode"), map[string]string{"process.executable.name": "replica"}, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	hottest := stackHashValues(metrics, "hottest_stack_share")
+	require.Len(t, hottest, 2)
+	assert.Len(t, hottest["app"], 16)
+	assert.Equal(t, hottest["app"], hottest["replica"])
+	assert.Equal(t, hottest, stackHashValues(metrics, "hot_path_cpu_share"))
+
+	hashes := StackHashes(b.profiles)
+	assert.Len(t, hashes, 2)
+	assert.Equal(t, "main.main;main.encode", hashes[hottest["app"]])
+}
+
+func TestConverter_StackHashDisabled(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			HottestStack: HottestStackMetricConfig{Enabled: true, MetricName: "hottest_stack_share"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main"), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Empty(t, stackHashValues(metrics, "hottest_stack_share"))
+}
+
+func TestStackHash(t *testing.T) {
+	// FNV-1a 64-bit of the folded stack, independent of the configured separator
+	assert.Equal(t, "af63dc4c8601ec8c", stackHash([]string{"a"}))
+	assert.NotEqual(t, stackHash([]string{"a", "b"}), stackHash([]string{"b", "a"}))
+}
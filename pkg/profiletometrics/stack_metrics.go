@@ -0,0 +1,119 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// frameUnit is the unit of stack depth metrics; depths describe a point in time and stay gauges
+	frameUnit = "{frame}"
+	// stackUnit is the unit of stack count metrics
+	stackUnit = "{stack}"
+)
+
+// stackStats aggregates the stack depths of a set of samples
+type stackStats struct {
+	stacks     int
+	totalDepth int
+	maxDepth   int
+	truncated  int
+}
+
+// add records the stack of a sample
+func (s *stackStats) add(depth int, truncated bool) {
+	s.stacks++
+	s.totalDepth += depth
+	if depth > s.maxDepth {
+		s.maxDepth = depth
+	}
+	if truncated {
+		s.truncated++
+	}
+}
+
+// generateStackMetrics emits the average and maximum stack depth and the truncated stack count of
+// the profile, and of each process
+func (c *Converter) generateStackMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	var profileStats stackStats
+	processStats := make(map[string]*stackStats)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok {
+			continue
+		}
+		depth := locationIndices.Len()
+		truncated := c.isTruncatedSample(profiles, sample)
+		profileStats.add(depth, truncated)
+
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+		if processName == "" {
+			continue
+		}
+		stats, exists := processStats[processName]
+		if !exists {
+			stats = &stackStats{}
+			processStats[processName] = stats
+		}
+		stats.add(depth, truncated)
+	}
+	if profileStats.stacks == 0 {
+		return
+	}
+
+	processNames := make([]string, 0, len(processStats))
+	for processName := range processStats {
+		processNames = append(processNames, processName)
+	}
+	sort.Strings(processNames)
+
+	cfg := c.config.Metrics.Stack
+	averageGauge := appendStackMetric(scopeMetrics, cfg.AverageDepthMetricName, "Average stack depth in frames", frameUnit).Gauge()
+	maxGauge := appendStackMetric(scopeMetrics, cfg.MaxDepthMetricName, "Maximum stack depth in frames", frameUnit).Gauge()
+	truncatedMetric := appendStackMetric(scopeMetrics, cfg.TruncatedMetricName, "Number of truncated stacks", stackUnit)
+	c.markAdditive(truncatedMetric)
+	truncatedGauge := truncatedMetric.Gauge()
+
+	appendStats := func(stats *stackStats, processName string) {
+		for _, value := range []struct {
+			gauge pmetric.Gauge
+			value float64
+		}{
+			{averageGauge, float64(stats.totalDepth) / float64(stats.stacks)},
+			{maxGauge, float64(stats.maxDepth)},
+			{truncatedGauge, float64(stats.truncated)},
+		} {
+			dataPoint := value.gauge.DataPoints().AppendEmpty()
+			c.setDataPointTimestamps(dataPoint, profile)
+			dataPoint.SetDoubleValue(value.value)
+			for k, v := range attributes {
+				dataPoint.Attributes().PutStr(k, v)
+			}
+			if processName != "" {
+				dataPoint.Attributes().PutStr("process.name", processName)
+			}
+		}
+	}
+	appendStats(&profileStats, "")
+	for _, processName := range processNames {
+		appendStats(processStats[processName], processName)
+	}
+}
+
+// appendStackMetric appends an empty gauge metric for stack statistics
+func appendStackMetric(scopeMetrics pmetric.ScopeMetrics, name, description, unit string) pmetric.Metric {
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetDescription(description)
+	metric.SetUnit(unit)
+	metric.SetEmptyGauge()
+	return metric
+}
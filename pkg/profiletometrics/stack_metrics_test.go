@@ -0,0 +1,63 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_StackMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Stack: StackMetricConfig{
+				Enabled:                true,
+				AverageDepthMetricName: "stack_depth_average",
+				MaxDepthMetricName:     "stack_depth_max",
+				TruncatedMetricName:    "truncated_stacks",
+			},
+		},
+		TruncatedStacks: TruncatedStackConfig{MaxDepth: 4},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	app := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main", "a"), app, 1)
+	b.sample(b.stack("main", "a", "b", "c"), app, 1)
+	b.sample(b.stack("main", "a", "b", "c", "d", "e"), map[string]string{"process.executable.name": "db"}, 1)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		if metric.Name() == "cpu_time" || metric.Name() == "memory_allocation" {
+			continue
+		}
+		for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+			dp := metric.Gauge().DataPoints().At(j)
+			processName := "profile"
+			if process, ok := dp.Attributes().Get("process.name"); ok {
+				processName = process.Str()
+			}
+			values[metric.Name()+"/"+processName] = dp.DoubleValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"stack_depth_average/profile": 4,
+		"stack_depth_average/app":     3,
+		"stack_depth_average/db":      6,
+		"stack_depth_max/profile":     6,
+		"stack_depth_max/app":         4,
+		"stack_depth_max/db":          6,
+		"truncated_stacks/profile":    2,
+		"truncated_stacks/app":        1,
+		"truncated_stacks/db":         1,
+	}, values)
+}
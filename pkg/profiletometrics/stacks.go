@@ -0,0 +1,106 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// resolvedFrame is a function of a location; a location lists several when functions were inlined
+type resolvedFrame struct {
+	functionName string
+	fileName     string
+	lineNumber   int64
+}
+
+// stackLocationIndicesCommon returns the location indices of a stack, leaf last, or false when the
+// stack index is outside of the stack table
+func stackLocationIndicesCommon(profiles dictionaryProvider, stackIndex int32) (pcommon.Int32Slice, bool) {
+	stackTable := profiles.Dictionary().StackTable()
+	if stackIndex < 0 || int(stackIndex) >= stackTable.Len() {
+		return pcommon.NewInt32Slice(), false
+	}
+	return stackTable.At(int(stackIndex)).LocationIndices(), true
+}
+
+// locationAtCommon returns a location of the dictionary, or false when the index is out of range
+func locationAtCommon(profiles dictionaryProvider, locationIndex int32) (pprofile.Location, bool) {
+	locationTable := profiles.Dictionary().LocationTable()
+	if locationIndex < 0 || int(locationIndex) >= locationTable.Len() {
+		return pprofile.Location{}, false
+	}
+	return locationTable.At(int(locationIndex)), true
+}
+
+// functionAtCommon returns a function of the dictionary, or false when the index is out of range
+func functionAtCommon(profiles dictionaryProvider, functionIndex int32) (pprofile.Function, bool) {
+	functionTable := profiles.Dictionary().FunctionTable()
+	if functionIndex < 0 || int(functionIndex) >= functionTable.Len() {
+		return pprofile.Function{}, false
+	}
+	return functionTable.At(int(functionIndex)), true
+}
+
+// leafLocationCommon returns the leaf location of a stack: its last entry
+func leafLocationCommon(profiles dictionaryProvider, stackIndex int32) (pprofile.Location, bool) {
+	locationIndices, ok := stackLocationIndicesCommon(profiles, stackIndex)
+	if !ok || locationIndices.Len() == 0 {
+		return pprofile.Location{}, false
+	}
+	return locationAtCommon(profiles, locationIndices.At(locationIndices.Len()-1))
+}
+
+// functionNameCommon returns the name of a function. A negative index means no function and is not
+// a failure; ok is false when the function or its name points outside of the dictionary tables.
+func functionNameCommon(profiles dictionaryProvider, functionIndex int32) (name string, ok bool) {
+	if functionIndex < 0 {
+		return "", true
+	}
+	function, ok := functionAtCommon(profiles, functionIndex)
+	if !ok {
+		return "", false
+	}
+	stringTable := profiles.Dictionary().StringTable()
+	nameIndex := function.NameStrindex()
+	if nameIndex < 0 || int(nameIndex) >= stringTable.Len() {
+		return "", false
+	}
+	return normalizedFunctionNameCommon(profiles, stringTable.At(int(nameIndex))), true
+}
+
+// locationFunctionIndexCommon returns the function of a location's first line: the innermost
+// function when others were inlined into it
+func locationFunctionIndexCommon(location pprofile.Location) int32 {
+	if location.Line().Len() == 0 {
+		return -1
+	}
+	return location.Line().At(0).FunctionIndex()
+}
+
+// locationFramesCommon resolves the inline chain of a location, innermost function first and the
+// caller the others were inlined into last; lines pointing outside of the function table are skipped
+func locationFramesCommon(profiles dictionaryProvider, location pprofile.Location) []resolvedFrame {
+	stringTable := profiles.Dictionary().StringTable()
+	lines := location.Line()
+	frames := make([]resolvedFrame, 0, lines.Len())
+	for i := 0; i < lines.Len(); i++ {
+		function, ok := functionAtCommon(profiles, lines.At(i).FunctionIndex())
+		if !ok {
+			continue
+		}
+		frames = append(frames, resolvedFrame{
+			functionName: normalizedFunctionNameCommon(profiles, stringTableValue(stringTable, function.NameStrindex())),
+			fileName:     stringTableValue(stringTable, function.FilenameStrindex()),
+			lineNumber:   lines.At(i).Line(),
+		})
+	}
+	return frames
+}
+
+// getLocationFileNameCommon returns the filename for the first line's function of a location.
+func getLocationFileNameCommon(profiles dictionaryProvider, location pprofile.Location) string {
+	function, ok := functionAtCommon(profiles, locationFunctionIndexCommon(location))
+	if !ok {
+		return ""
+	}
+	return stringTableValue(profiles.Dictionary().StringTable(), function.FilenameStrindex())
+}
@@ -0,0 +1,166 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestStackLocationIndicesCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	stackIndex := b.stack("main", "handler")
+	emptyStack := int32(b.profiles.Dictionary().StackTable().Len())
+	b.profiles.Dictionary().StackTable().AppendEmpty()
+
+	tests := []struct {
+		name          string
+		profiles      dictionaryProvider
+		stackIndex    int32
+		expectedOK    bool
+		expectedDepth int
+	}{
+		{name: "empty tables", profiles: pprofile.NewProfiles(), stackIndex: 0},
+		{name: "negative index", profiles: b.profiles, stackIndex: -1},
+		{name: "index past the table", profiles: b.profiles, stackIndex: 42},
+		{name: "empty stack", profiles: b.profiles, stackIndex: emptyStack, expectedOK: true},
+		{name: "stack", profiles: b.profiles, stackIndex: stackIndex, expectedOK: true, expectedDepth: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locationIndices, ok := stackLocationIndicesCommon(tt.profiles, tt.stackIndex)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedDepth, locationIndices.Len())
+		})
+	}
+}
+
+func TestLeafLocationCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	stackIndex := b.stack("main", "handler")
+	emptyStack := int32(b.profiles.Dictionary().StackTable().Len())
+	b.profiles.Dictionary().StackTable().AppendEmpty()
+	danglingStack := int32(b.profiles.Dictionary().StackTable().Len())
+	b.profiles.Dictionary().StackTable().AppendEmpty().LocationIndices().Append(-1)
+
+	tests := []struct {
+		name         string
+		profiles     dictionaryProvider
+		stackIndex   int32
+		expectedOK   bool
+		expectedLeaf string
+	}{
+		{name: "empty tables", profiles: pprofile.NewProfiles(), stackIndex: 0},
+		{name: "negative index", profiles: b.profiles, stackIndex: -1},
+		{name: "empty stack", profiles: b.profiles, stackIndex: emptyStack},
+		{name: "negative location index", profiles: b.profiles, stackIndex: danglingStack},
+		{name: "last location is the leaf", profiles: b.profiles, stackIndex: stackIndex, expectedOK: true, expectedLeaf: "handler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, ok := leafLocationCommon(tt.profiles, tt.stackIndex)
+			assert.Equal(t, tt.expectedOK, ok)
+			if ok {
+				name, _ := functionNameCommon(tt.profiles, locationFunctionIndexCommon(location))
+				assert.Equal(t, tt.expectedLeaf, name)
+			}
+		})
+	}
+}
+
+func TestFunctionNameCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	b.function("main", "main.go")
+	dangling := int32(b.profiles.Dictionary().FunctionTable().Len())
+	b.profiles.Dictionary().FunctionTable().AppendEmpty().SetNameStrindex(99)
+
+	tests := []struct {
+		name          string
+		profiles      dictionaryProvider
+		functionIndex int32
+		expectedName  string
+		expectedOK    bool
+	}{
+		{name: "empty tables", profiles: pprofile.NewProfiles(), functionIndex: 0},
+		{name: "no function", profiles: b.profiles, functionIndex: -1, expectedOK: true},
+		{name: "index past the table", profiles: b.profiles, functionIndex: 42},
+		{name: "name past the string table", profiles: b.profiles, functionIndex: dangling},
+		{name: "function", profiles: b.profiles, functionIndex: 0, expectedName: "main", expectedOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := functionNameCommon(tt.profiles, tt.functionIndex)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedName, name)
+		})
+	}
+}
+
+func TestLocationFramesCommon(t *testing.T) {
+	b := newTestProfileBuilder()
+	b.function("bytes.(*Buffer).grow", "buffer.go")
+	b.function("bytes.(*Buffer).Write", "buffer.go")
+	b.function("main.encode", "main.go")
+
+	tests := []struct {
+		name           string
+		functions      []int32
+		expectedFrames []resolvedFrame
+	}{
+		{name: "no lines", expectedFrames: []resolvedFrame{}},
+		{
+			name:      "inline chain, innermost first",
+			functions: []int32{0, 1, 2},
+			expectedFrames: []resolvedFrame{
+				{functionName: "bytes.(*Buffer).grow", fileName: "buffer.go", lineNumber: 10},
+				{functionName: "bytes.(*Buffer).Write", fileName: "buffer.go", lineNumber: 11},
+				{functionName: "main.encode", fileName: "main.go", lineNumber: 12},
+			},
+		},
+		{
+			name:      "dangling functions are skipped",
+			functions: []int32{-1, 0, 42},
+			expectedFrames: []resolvedFrame{
+				{functionName: "bytes.(*Buffer).grow", fileName: "buffer.go", lineNumber: 11},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := pprofile.NewLocation()
+			for i, functionIndex := range tt.functions {
+				line := location.Line().AppendEmpty()
+				line.SetFunctionIndex(functionIndex)
+				line.SetLine(int64(10 + i))
+			}
+			assert.Equal(t, tt.expectedFrames, locationFramesCommon(b.profiles, location))
+		})
+	}
+}
+
+func TestIterateProfilesCommon_OwningDictionary(t *testing.T) {
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main", "handler"), nil, 1)
+
+	var dictionaries []dictionaryProvider
+	iterateProfilesCommon(b.profiles, extractResourceAttributesCommon,
+		func(dictionary dictionaryProvider, _, _, _ int, _ pprofile.Profile, _ map[string]string) {
+			dictionaries = append(dictionaries, dictionary)
+		})
+	assert.Equal(t, []dictionaryProvider{b.profiles}, dictionaries)
+}
+
+func TestFunctionNames_ExplicitDictionary(t *testing.T) {
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main", "handler"), nil, 1)
+	converter := &Converter{config: &ConverterConfig{}}
+
+	// The profile resolves against the dictionary passed in, not the batch it came with
+	assert.ElementsMatch(t, []string{"handler"},
+		converter.getUniqueFunctionNames(profilesDictionary(b.profiles.Dictionary()), b.profile))
+	assert.Empty(t, converter.getUniqueFunctionNames(pprofile.NewProfiles(), b.profile))
+}
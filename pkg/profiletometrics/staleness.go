@@ -0,0 +1,161 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// defaultStalenessTTL is how long a series may go unseen before it is marked stale
+const defaultStalenessTTL = 5 * time.Minute
+
+// validateStaleness checks the staleness expiry
+func validateStaleness(cfg StalenessConfig) error {
+	if cfg.TTL < 0 {
+		return fmt.Errorf("staleness.ttl must not be negative")
+	}
+	return nil
+}
+
+// stalenessTracker remembers when every emitted series was last seen. A series unseen for longer
+// than the TTL gets a final data point flagged NoRecordedValue, which Prometheus exporters turn into
+// a staleness marker, and is forgotten.
+type stalenessTracker struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	series map[string]*trackedSeries
+	// now reads the clock, replaced in tests
+	now func() time.Time
+}
+
+// trackedSeries is what is needed to emit the staleness marker of a series
+type trackedSeries struct {
+	lastSeen   time.Time
+	resource   pcommon.Map
+	scope      pcommon.InstrumentationScope
+	metric     pmetric.Metric
+	attributes pcommon.Map
+}
+
+// newStalenessTracker creates an empty tracker; a zero TTL uses the default
+func newStalenessTracker(cfg StalenessConfig) *stalenessTracker {
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = defaultStalenessTTL
+	}
+	return &stalenessTracker{ttl: ttl, series: make(map[string]*trackedSeries), now: time.Now}
+}
+
+// track records the series of metrics as seen and appends a staleness marker for every series that
+// expired, to the ResourceMetrics of its resource
+func (s *stalenessTracker) track(metrics pmetric.Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		resourceMetrics := metrics.ResourceMetrics().At(i)
+		resourceKey := attributesKey(resourceMetrics.Resource().Attributes())
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			scopeMetrics := resourceMetrics.ScopeMetrics().At(j)
+			for k := 0; k < scopeMetrics.Metrics().Len(); k++ {
+				metric := scopeMetrics.Metrics().At(k)
+				dataPoints, ok := numberDataPoints(metric)
+				if !ok {
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					attributes := dataPoints.At(l).Attributes()
+					key := metric.Name() + "\x00" + resourceKey + "\x00" + attributesKey(attributes)
+					if series, seen := s.series[key]; seen {
+						series.lastSeen = now
+						continue
+					}
+					series := &trackedSeries{
+						lastSeen:   now,
+						resource:   pcommon.NewMap(),
+						scope:      pcommon.NewInstrumentationScope(),
+						metric:     pmetric.NewMetric(),
+						attributes: pcommon.NewMap(),
+					}
+					resourceMetrics.Resource().Attributes().CopyTo(series.resource)
+					scopeMetrics.Scope().CopyTo(series.scope)
+					copyMetricDescriptor(metric, series.metric)
+					attributes.CopyTo(series.attributes)
+					s.series[key] = series
+				}
+			}
+		}
+	}
+
+	var expired []string
+	for key, series := range s.series {
+		if now.Sub(series.lastSeen) > s.ttl {
+			expired = append(expired, key)
+		}
+	}
+	sort.Strings(expired)
+
+	timestamp := pcommon.NewTimestampFromTime(now)
+	for _, key := range expired {
+		series := s.series[key]
+		delete(s.series, key)
+		marker := pmetric.NewMetric()
+		series.metric.CopyTo(marker)
+		dataPoints, _ := numberDataPoints(marker)
+		dataPoint := dataPoints.AppendEmpty()
+		series.attributes.CopyTo(dataPoint.Attributes())
+		dataPoint.SetTimestamp(timestamp)
+		dataPoint.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+
+		scopeMetrics := resourceMetricsFor(metrics, series.resource).ScopeMetrics().AppendEmpty()
+		series.scope.CopyTo(scopeMetrics.Scope())
+		marker.MoveTo(scopeMetrics.Metrics().AppendEmpty())
+	}
+}
+
+// numberDataPoints returns the data points of a gauge or sum
+func numberDataPoints(metric pmetric.Metric) (pmetric.NumberDataPointSlice, bool) {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		return metric.Gauge().DataPoints(), true
+	case pmetric.MetricTypeSum:
+		return metric.Sum().DataPoints(), true
+	default:
+		return pmetric.NumberDataPointSlice{}, false
+	}
+}
+
+// copyMetricDescriptor copies the name, description, unit and type of a gauge or sum, without its
+// data points
+func copyMetricDescriptor(metric, dest pmetric.Metric) {
+	dest.SetName(metric.Name())
+	dest.SetDescription(metric.Description())
+	dest.SetUnit(metric.Unit())
+	if metric.Type() == pmetric.MetricTypeGauge {
+		dest.SetEmptyGauge()
+		return
+	}
+	sum := dest.SetEmptySum()
+	sum.SetAggregationTemporality(metric.Sum().AggregationTemporality())
+	sum.SetIsMonotonic(metric.Sum().IsMonotonic())
+}
+
+// resourceMetricsFor returns the ResourceMetrics of metrics with the given resource attributes,
+// appending one when none matches
+func resourceMetricsFor(metrics pmetric.Metrics, resource pcommon.Map) pmetric.ResourceMetrics {
+	key := attributesKey(resource)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		if attributesKey(metrics.ResourceMetrics().At(i).Resource().Attributes()) == key {
+			return metrics.ResourceMetrics().At(i)
+		}
+	}
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	resource.CopyTo(resourceMetrics.Resource().Attributes())
+	return resourceMetrics
+}
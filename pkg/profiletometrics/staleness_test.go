@@ -0,0 +1,70 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_Staleness(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:     CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Process: ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+		},
+		Staleness: StalenessConfig{Enabled: true, TTL: time.Minute},
+	})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	converter.staleness.now = func() time.Time { return now }
+
+	convert := func(processes ...string) pmetric.Metrics {
+		b := newTestProfileBuilder()
+		for _, process := range processes {
+			b.sample(b.stack("main"), map[string]string{"process.executable.name": process}, 1000000000)
+		}
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		return metrics
+	}
+	// staleProcesses returns the processes of the cpu_time.by_process points flagged NoRecordedValue
+	staleProcesses := func(metrics pmetric.Metrics) []string {
+		var processes []string
+		for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+			scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+			for j := 0; j < scopeMetrics.Len(); j++ {
+				for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+					metric := scopeMetrics.At(j).Metrics().At(k)
+					if metric.Name() != "cpu_time.by_process" {
+						continue
+					}
+					for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+						dataPoint := metric.Gauge().DataPoints().At(l)
+						if dataPoint.Flags().NoRecordedValue() {
+							process, _ := dataPoint.Attributes().Get("process.name")
+							processes = append(processes, process.Str())
+						}
+					}
+				}
+			}
+		}
+		return processes
+	}
+
+	assert.Empty(t, staleProcesses(convert("api", "batch")))
+	now = now.Add(30 * time.Second)
+	assert.Empty(t, staleProcesses(convert("api")), "a series is not stale within the TTL")
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, []string{"batch"}, staleProcesses(convert("api")))
+	now = now.Add(2 * time.Minute)
+	assert.Empty(t, staleProcesses(convert("api")), "a stale series is marked once")
+}
+
+func TestNewConverter_InvalidStaleness(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{Staleness: StalenessConfig{Enabled: true, TTL: -time.Second}})
+	assert.Error(t, err)
+}
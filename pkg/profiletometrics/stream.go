@@ -0,0 +1,73 @@
+package profiletometrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// ConvertStream converts profiles like ConvertProfilesToMetrics, but hands the metrics to flush as
+// they are produced instead of building a single payload, bounding the memory held for large
+// batches. Metrics are flushed after every resource profile, or once stream_flush_data_points data
+// points are pending; the converter self-metrics are flushed last. Each flushed payload is compacted
+// and owned by flush. group_by_resource_attributes rolls up the resources of a payload only.
+// Conversion stops at the first flush error or context cancellation, which is returned.
+func (c *Converter) ConvertStream(ctx context.Context, profiles pprofile.Profiles, flush func(pmetric.Metrics) error) error {
+	start := time.Now()
+	c.logInfo("Starting streaming profile to metrics conversion",
+		zap.Int("resource_profiles_count", profiles.ResourceProfiles().Len()))
+
+	conversion := c.beginConversion(profiles)
+	output := &conversionOutput{}
+	flushes := 0
+
+	pending := pmetric.NewMetrics()
+	resourceMetrics := pending.ResourceMetrics().AppendEmpty()
+	layout := newResourceLayout(c.config, pending)
+	emit := func() error {
+		if len(c.config.GroupByResourceAttributes) > 0 {
+			c.rollUpResourceGroups(profiles, pending)
+		}
+		c.finishMetrics(pending)
+		compactMetrics(pending)
+		c.tallyOutput(output, pending)
+
+		metrics := pending
+		pending = pmetric.NewMetrics()
+		resourceMetrics = pending.ResourceMetrics().AppendEmpty()
+		layout = newResourceLayout(c.config, pending)
+		if metrics.ResourceMetrics().Len() == 0 {
+			return nil
+		}
+		flushes++
+		return flush(metrics)
+	}
+
+	for i := 0; i < profiles.ResourceProfiles().Len(); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.convertResource(conversion, profiles, i, resourceMetrics, layout)
+
+		if threshold := c.config.StreamFlushDataPoints; threshold == 0 || pending.DataPointCount() >= threshold {
+			if err := emit(); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.generateConversionMetrics(start, profiles, conversion.summary, resourceMetrics)
+	if err := emit(); err != nil {
+		return err
+	}
+
+	if c.diagnostics != nil {
+		c.recordDiagnostics(start, profiles, output, conversion.summary)
+	}
+	c.logDebug("Profile conversion summary", conversion.summary.fields()...)
+	c.logInfo("Streaming profile to metrics conversion completed", zap.Int("flushes", flushes))
+	return nil
+}
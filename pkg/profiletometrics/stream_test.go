@@ -0,0 +1,99 @@
+package profiletometrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestConverter_ConvertStream(t *testing.T) {
+	newProfiles := func(resources int) pprofile.Profiles {
+		b := newTestProfileBuilder()
+		b.resource.Resource().Attributes().PutStr("k8s.pod.name", "pod-0")
+		b.sample(b.stack("main"), nil, 1000000000)
+		for i := 1; i < resources; i++ {
+			other := b.profiles.ResourceProfiles().AppendEmpty()
+			b.resource.CopyTo(other)
+			other.Resource().Attributes().PutStr("k8s.pod.name", "pod-"+strconv.Itoa(i))
+		}
+		return b.profiles
+	}
+	newConverter := func(flushDataPoints int) *Converter {
+		converter, err := NewConverter(&ConverterConfig{
+			Metrics: MetricsConfig{
+				CPU:       CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				Ingestion: IngestionMetricConfig{Enabled: true},
+			},
+			StreamFlushDataPoints: flushDataPoints,
+			DiagnosticsHistory:    1,
+		})
+		require.NoError(t, err)
+		return converter
+	}
+
+	t.Run("Flushes every resource profile", func(t *testing.T) {
+		converter := newConverter(0)
+		var payloads []pmetric.Metrics
+		err := converter.ConvertStream(context.Background(), newProfiles(3), func(metrics pmetric.Metrics) error {
+			payloads = append(payloads, metrics)
+			return nil
+		})
+		require.NoError(t, err)
+
+		// One payload per resource profile, then the self-metrics
+		require.Len(t, payloads, 4)
+		for _, payload := range payloads[:3] {
+			assert.Equal(t, 1, payload.DataPointCount())
+		}
+
+		batch, err := newConverter(0).ConvertProfilesToMetrics(context.Background(), newProfiles(3))
+		require.NoError(t, err)
+		total := 0
+		for _, payload := range payloads {
+			total += payload.DataPointCount()
+		}
+		assert.Equal(t, batch.DataPointCount(), total, "streaming emits the same data points")
+
+		diagnostics := converter.Diagnostics()
+		require.Len(t, diagnostics, 1)
+		assert.Equal(t, total, diagnostics[0].DataPoints)
+	})
+
+	t.Run("Flushes once enough data points are pending", func(t *testing.T) {
+		var sizes []int
+		err := newConverter(2).ConvertStream(context.Background(), newProfiles(3), func(metrics pmetric.Metrics) error {
+			sizes = append(sizes, metrics.DataPointCount())
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, sizes, 2)
+		assert.Equal(t, 2, sizes[0])
+	})
+
+	t.Run("Stops at the first flush error", func(t *testing.T) {
+		flushErr := errors.New("export failed")
+		flushes := 0
+		err := newConverter(0).ConvertStream(context.Background(), newProfiles(3), func(pmetric.Metrics) error {
+			flushes++
+			return flushErr
+		})
+		assert.ErrorIs(t, err, flushErr)
+		assert.Equal(t, 1, flushes)
+	})
+
+	t.Run("Stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := newConverter(0).ConvertStream(ctx, newProfiles(3), func(pmetric.Metrics) error {
+			t.Fatal("nothing is flushed")
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,147 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Symbolization levels reported by the symbolization.level attribute
+	symbolizationLevelFunctions = "functions"
+	symbolizationLevelFilenames = "filenames"
+
+	// unknownMappingName names frames without a binary mapping
+	unknownMappingName = "unknown"
+)
+
+// buildIDAttributeKeys are the mapping attributes carrying a build ID, in order of preference
+var buildIDAttributeKeys = []string{
+	"process.executable.build_id.gnu",
+	"process.executable.build_id.go",
+	"process.executable.build_id.htlhash",
+}
+
+// symbolizationKey identifies the frames of a binary mapping within a process
+type symbolizationKey struct {
+	processName  string
+	mappingIndex int32
+}
+
+// symbolizationCounts counts sampled frames and how many of them were symbolized
+type symbolizationCounts struct {
+	frames        int
+	withFunctions int
+	withFilenames int
+}
+
+// generateSymbolizationMetrics emits, per process and binary mapping, the percentage of sampled
+// frames resolved to a function name and to a source file name
+func (c *Converter) generateSymbolizationMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	counts := make(map[symbolizationKey]*symbolizationCounts)
+	for i := 0; i < profile.Sample().Len(); i++ {
+		sample := profile.Sample().At(i)
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok {
+			continue
+		}
+		processName := c.getSampleAttributeValue(profiles, sample, "process.executable.name")
+
+		for j := 0; j < locationIndices.Len(); j++ {
+			location, ok := locationAtCommon(profiles, locationIndices.At(j))
+			if !ok {
+				continue
+			}
+			key := symbolizationKey{processName: processName, mappingIndex: location.MappingIndex()}
+			count, exists := counts[key]
+			if !exists {
+				count = &symbolizationCounts{}
+				counts[key] = count
+			}
+			count.frames++
+
+			var hasFunction, hasFilename bool
+			for _, frame := range locationFramesCommon(profiles, location) {
+				hasFunction = hasFunction || frame.functionName != ""
+				hasFilename = hasFilename || frame.fileName != ""
+			}
+			if hasFunction {
+				count.withFunctions++
+			}
+			if hasFilename {
+				count.withFilenames++
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]symbolizationKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		return keys[i].mappingIndex < keys[j].mappingIndex
+	})
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.Symbolization.MetricName)
+	metric.SetDescription("Percentage of sampled frames resolved to a function or source file name")
+	metric.SetUnit(percentUnit)
+	gauge := metric.SetEmptyGauge()
+
+	for _, key := range keys {
+		count := counts[key]
+		mappingName, buildID := c.getMappingIdentity(profiles, key.mappingIndex)
+		for _, level := range []struct {
+			name       string
+			symbolized int
+		}{
+			{symbolizationLevelFunctions, count.withFunctions},
+			{symbolizationLevelFilenames, count.withFilenames},
+		} {
+			dataPoint := gauge.DataPoints().AppendEmpty()
+			c.setDataPointTimestamps(dataPoint, profile)
+			dataPoint.SetDoubleValue(float64(level.symbolized) / float64(count.frames) * 100)
+			for k, v := range attributes {
+				dataPoint.Attributes().PutStr(k, v)
+			}
+			dataPoint.Attributes().PutStr("process.name", key.processName)
+			dataPoint.Attributes().PutStr("mapping.file.name", mappingName)
+			if buildID != "" {
+				dataPoint.Attributes().PutStr("mapping.build_id", buildID)
+			}
+			dataPoint.Attributes().PutStr("symbolization.level", level.name)
+		}
+	}
+}
+
+// getMappingIdentity returns the file name and build ID of a mapping
+func (c *Converter) getMappingIdentity(profiles dictionaryProvider, mappingIndex int32) (string, string) {
+	mappingTable := profiles.Dictionary().MappingTable()
+	if mappingIndex < 0 || int(mappingIndex) >= mappingTable.Len() {
+		return unknownMappingName, ""
+	}
+	mapping := mappingTable.At(int(mappingIndex))
+
+	name := stringTableValue(profiles.Dictionary().StringTable(), mapping.FilenameStrindex())
+	if name == "" {
+		name = unknownMappingName
+	}
+	for _, key := range buildIDAttributeKeys {
+		if buildID := getAttributeValueCommon(profiles, mapping.AttributeIndices(), key); buildID != "" {
+			return name, buildID
+		}
+	}
+	return name, ""
+}
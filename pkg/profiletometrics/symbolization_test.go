@@ -0,0 +1,57 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_SymbolizationMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:           CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:        MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Symbolization: SymbolizationMetricConfig{Enabled: true, MetricName: "profiles.symbolization.coverage"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	dictionary := b.profiles.Dictionary()
+	app := b.mapping("/usr/bin/app")
+	dictionary.MappingTable().At(int(app)).AttributeIndices().Append(b.attribute("process.executable.build_id.gnu", "abc123"))
+
+	// Two symbolized frames (one without file name) and one unsymbolized frame in the app binary
+	b.location("main.main", "main.go").SetMappingIndex(app)
+	b.location("main.handle", "").SetMappingIndex(app)
+	unsymbolized := dictionary.LocationTable().AppendEmpty()
+	unsymbolized.SetMappingIndex(app)
+	unsymbolized.SetAddress(0x4010)
+	stack := dictionary.StackTable().AppendEmpty()
+	stack.LocationIndices().Append(b.function("main.main", "main.go"), b.function("main.handle", ""),
+		int32(dictionary.LocationTable().Len()-1))
+	b.sample(int32(dictionary.StackTable().Len()-1), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	coverage := make(map[string]float64)
+	for i := 0; i < metricSlice.Len(); i++ {
+		metric := metricSlice.At(i)
+		if metric.Name() != "profiles.symbolization.coverage" {
+			continue
+		}
+		assert.Equal(t, "%", metric.Unit())
+		for j := 0; j < metric.Gauge().DataPoints().Len(); j++ {
+			attributes := metric.Gauge().DataPoints().At(j).Attributes().AsRaw()
+			assert.Equal(t, "/usr/bin/app", attributes["mapping.file.name"])
+			assert.Equal(t, "abc123", attributes["mapping.build_id"])
+			coverage[attributes["symbolization.level"].(string)] = metric.Gauge().DataPoints().At(j).DoubleValue()
+		}
+	}
+	assert.InDelta(t, 200.0/3, coverage["functions"], 1e-9)
+	assert.InDelta(t, 100.0/3, coverage["filenames"], 1e-9)
+}
@@ -0,0 +1,248 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+const (
+	// Aggregation temporality modes; an empty value keeps emitting gauges
+	aggregationTemporalityDelta      = "delta"
+	aggregationTemporalityCumulative = "cumulative"
+)
+
+// validateAggregationTemporality checks the configured aggregation temporality
+func validateAggregationTemporality(temporality string) error {
+	switch temporality {
+	case "", aggregationTemporalityDelta, aggregationTemporalityCumulative:
+		return nil
+	default:
+		return fmt.Errorf("invalid aggregation_temporality %q: must be %q or %q",
+			temporality, aggregationTemporalityDelta, aggregationTemporalityCumulative)
+	}
+}
+
+// pointInTimeUnits are the units of gauges that are never converted to sums
+var pointInTimeUnits = map[string]bool{
+	percentUnit: true,
+	ratioUnit:   true,
+	// Slopes are signed rates of change
+	slopeUnit:     true,
+	frameUnit:     true,
+	liveBytesUnit: true,
+	// The degradation level is a state, unlike the other self-metrics
+	degradationStepUnit: true,
+	anomalyScoreUnit:    true,
+}
+
+// markAdditive records that the values of a gauge add up across conversions: CPU time,
+// allocations and counts of the converted samples. Gauges are tracked by name, so that the
+// emitted metrics carry no bookkeeping of the converter.
+func (c *Converter) markAdditive(metric pmetric.Metric) {
+	c.additiveGauges.Store(metric.Name(), true)
+}
+
+// additiveMetric reports whether the values of a metric add up across conversions: delta sums and
+// the gauges marked additive, unless their unit describes a point in time. Other metrics (shares,
+// ratios, slopes, signed changes, averages and maxima) only have a latest value.
+func (c *Converter) additiveMetric(metric pmetric.Metric) bool {
+	switch metric.Type() {
+	case pmetric.MetricTypeSum:
+		return metric.Sum().AggregationTemporality() == pmetric.AggregationTemporalityDelta
+	case pmetric.MetricTypeGauge:
+		_, additive := c.additiveGauges.Load(metric.Name())
+		return additive && !pointInTimeUnits[metric.Unit()]
+	default:
+		return false
+	}
+}
+
+// seriesOverheadBytes approximates the memory of a tracked series besides its key: the state, the
+// pointer to it and the key's string header in the map
+const seriesOverheadBytes = int64(unsafe.Sizeof(seriesState{})) + int64(unsafe.Sizeof(&seriesState{})) +
+	int64(unsafe.Sizeof(""))
+
+// seriesState holds the last-seen total of a series
+type seriesState struct {
+	value          float64
+	startTimestamp pcommon.Timestamp
+	lastTimestamp  pcommon.Timestamp
+	// lastUpdate is the generation of the conversion that last updated the series
+	lastUpdate uint64
+}
+
+// AccumulatorStats describes the series state kept between conversions by aggregation_temporality
+type AccumulatorStats struct {
+	// ActiveSeries is the number of tracked series
+	ActiveSeries int
+	// Evictions is the number of series evicted to respect aggregation_max_series
+	Evictions int64
+	// MemoryBytes approximates the memory used by the tracked series
+	MemoryBytes int64
+}
+
+// temporalityAccumulator keeps last-seen totals per series between conversions, so that
+// repeated cumulative profile dumps can be emitted as monotonic sums
+type temporalityAccumulator struct {
+	mu     sync.Mutex
+	series map[string]*seriesState
+	// maxSeries caps the tracked series, evicting the least recently updated; 0 disables the cap
+	maxSeries  int
+	generation uint64
+	evictions  int64
+	keyBytes   int64
+}
+
+// newTemporalityAccumulator creates an empty accumulator
+func newTemporalityAccumulator(maxSeries int) *temporalityAccumulator {
+	return &temporalityAccumulator{series: make(map[string]*seriesState), maxSeries: maxSeries}
+}
+
+// stats returns the current size of the accumulator
+func (a *temporalityAccumulator) stats() AccumulatorStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AccumulatorStats{
+		ActiveSeries: len(a.series),
+		Evictions:    a.evictions,
+		MemoryBytes:  a.keyBytes + int64(len(a.series))*seriesOverheadBytes,
+	}
+}
+
+// evict removes the least recently updated series above maxSeries; ties are broken by key to keep
+// evictions deterministic
+func (a *temporalityAccumulator) evict() {
+	if a.maxSeries <= 0 || len(a.series) <= a.maxSeries {
+		return
+	}
+	keys := make([]string, 0, len(a.series))
+	for key := range a.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if a.series[keys[i]].lastUpdate != a.series[keys[j]].lastUpdate {
+			return a.series[keys[i]].lastUpdate < a.series[keys[j]].lastUpdate
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys[:len(keys)-a.maxSeries] {
+		delete(a.series, key)
+		a.keyBytes -= int64(len(key))
+		a.evictions++
+	}
+}
+
+// apply converts the gauges in metrics into sums with the given temporality.
+// In delta mode, the first observation of a series only establishes the baseline and is dropped.
+func (a *temporalityAccumulator) apply(metrics pmetric.Metrics, temporality string, additive func(pmetric.Metric) bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.generation++
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		resourceMetrics := metrics.ResourceMetrics().At(i)
+		resourceKey := attributesKey(resourceMetrics.Resource().Attributes())
+		for j := 0; j < resourceMetrics.ScopeMetrics().Len(); j++ {
+			metricSlice := resourceMetrics.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				metric := metricSlice.At(k)
+				// Only gauges of values that add up are accumulated; shares, ratios, depths, live bytes
+				// and signed changes describe a point in time
+				if metric.Type() != pmetric.MetricTypeGauge || !additive(metric) {
+					continue
+				}
+				a.convertGauge(metric, resourceKey, temporality)
+			}
+			metricSlice.RemoveIf(func(metric pmetric.Metric) bool {
+				return metric.Type() == pmetric.MetricTypeSum && metric.Sum().DataPoints().Len() == 0
+			})
+		}
+	}
+	a.evict()
+}
+
+// convertGauge turns a gauge metric into a monotonic sum, updating series state
+func (a *temporalityAccumulator) convertGauge(metric pmetric.Metric, resourceKey, temporality string) {
+	dataPoints := pmetric.NewNumberDataPointSlice()
+	metric.Gauge().DataPoints().MoveAndAppendTo(dataPoints)
+
+	sum := metric.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	if temporality == aggregationTemporalityDelta {
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	} else {
+		sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	}
+	dataPoints.MoveAndAppendTo(sum.DataPoints())
+
+	sum.DataPoints().RemoveIf(func(dataPoint pmetric.NumberDataPoint) bool {
+		key := metric.Name() + "\x00" + resourceKey + "\x00" + attributesKey(dataPoint.Attributes())
+		value := dataPoint.DoubleValue()
+		timestamp := dataPoint.Timestamp()
+
+		state, seen := a.series[key]
+		if !seen {
+			start := dataPoint.StartTimestamp()
+			if start == 0 {
+				start = timestamp
+			}
+			a.series[key] = &seriesState{
+				value:          value,
+				startTimestamp: start,
+				lastTimestamp:  timestamp,
+				lastUpdate:     a.generation,
+			}
+			a.keyBytes += int64(len(key))
+			if temporality == aggregationTemporalityDelta {
+				// The first observation only establishes the baseline
+				return true
+			}
+			dataPoint.SetStartTimestamp(start)
+			return false
+		}
+
+		increase := value - state.value
+		if value < state.value {
+			// The source was reset (e.g. process restart): everything since the reset is new
+			increase = value
+			state.startTimestamp = state.lastTimestamp
+		}
+
+		if temporality == aggregationTemporalityDelta {
+			dataPoint.SetStartTimestamp(state.lastTimestamp)
+			dataPoint.SetDoubleValue(increase)
+		} else {
+			dataPoint.SetStartTimestamp(state.startTimestamp)
+		}
+		state.value = value
+		state.lastTimestamp = timestamp
+		state.lastUpdate = a.generation
+		return false
+	})
+}
+
+// attributesKey builds a deterministic identity string from an attribute map
+func attributesKey(attributes pcommon.Map) string {
+	pairs := make([]string, 0, attributes.Len())
+	attributes.Range(func(key string, value pcommon.Value) bool {
+		pairs = append(pairs, key+"="+value.AsString())
+		return true
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// AccumulatorStats returns the series state kept for aggregation_temporality; false when
+// aggregation_temporality is not set
+func (c *Converter) AccumulatorStats() (AccumulatorStats, bool) {
+	if c.accumulator == nil {
+		return AccumulatorStats{}, false
+	}
+	return c.accumulator.stats(), true
+}
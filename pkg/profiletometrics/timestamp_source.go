@@ -0,0 +1,104 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Timestamp sources of data points; an empty value means receive_time
+	timestampSourceProfileEnd   = "profile_end"
+	timestampSourceProfileStart = "profile_start"
+	timestampSourceReceiveTime  = "receive_time"
+	timestampSourceSample       = "sample"
+)
+
+// validateTimestampSource checks the configured timestamp source
+func validateTimestampSource(source string) error {
+	switch source {
+	case "", timestampSourceProfileEnd, timestampSourceProfileStart, timestampSourceReceiveTime, timestampSourceSample:
+		return nil
+	default:
+		return fmt.Errorf("invalid timestamp_source %q: must be %q, %q, %q or %q", source,
+			timestampSourceProfileEnd, timestampSourceProfileStart, timestampSourceReceiveTime, timestampSourceSample)
+	}
+}
+
+// timestampSource returns the effective timestamp source; use_profile_timestamps selects profile_end
+func (c *Converter) timestampSource() string {
+	source := c.config.TimestampSource
+	if source == "" {
+		source = timestampSourceReceiveTime
+	}
+	if source == timestampSourceReceiveTime && c.config.UseProfileTimestamps {
+		return timestampSourceProfileEnd
+	}
+	return source
+}
+
+// latestSampleTimestamp returns the latest sample timestamp of a profile, or 0 when no sample carries one
+func latestSampleTimestamp(profile pprofile.Profile) pcommon.Timestamp {
+	var latest uint64
+	samples := profile.Sample()
+	for i := 0; i < samples.Len(); i++ {
+		timestamps := samples.At(i).TimestampsUnixNano()
+		for j := 0; j < timestamps.Len(); j++ {
+			latest = max(latest, timestamps.At(j))
+		}
+	}
+	return pcommon.Timestamp(latest)
+}
+
+// dataPointTimestamps returns the start and end timestamps for data points generated from a profile
+// according to timestamp_source. Profiles without a time, and sample sources without sample
+// timestamps and profile time, fall back to the conversion time with no start time.
+func (c *Converter) dataPointTimestamps(profile pprofile.Profile) (pcommon.Timestamp, pcommon.Timestamp) {
+	receiveTime := pcommon.NewTimestampFromTime(time.Now())
+	switch c.timestampSource() {
+	case timestampSourceProfileEnd:
+		if profile.Time() != 0 {
+			return profile.Time(), profile.Time() + profile.Duration()
+		}
+	case timestampSourceProfileStart:
+		if profile.Time() != 0 {
+			return 0, profile.Time()
+		}
+	case timestampSourceSample:
+		if latest := latestSampleTimestamp(profile); latest != 0 {
+			return profile.Time(), max(latest, profile.Time())
+		}
+		if profile.Time() != 0 {
+			return profile.Time(), profile.Time() + profile.Duration()
+		}
+	}
+	return 0, receiveTime
+}
+
+// earliestSampleTimestamp returns the earliest timestamp of the samples, or 0 when none carries one
+func earliestSampleTimestamp(samples []pprofile.Sample) pcommon.Timestamp {
+	var earliest uint64
+	for _, sample := range samples {
+		timestamps := sample.TimestampsUnixNano()
+		for j := 0; j < timestamps.Len(); j++ {
+			if earliest == 0 || timestamps.At(j) < earliest {
+				earliest = timestamps.At(j)
+			}
+		}
+	}
+	return pcommon.Timestamp(earliest)
+}
+
+// spanStartTime anchors the spans of samples on the profile timeline: at their earliest sample
+// timestamp, else at the profile start time, else the duration before now
+func spanStartTime(profile pprofile.Profile, samples []pprofile.Sample, duration time.Duration) time.Time {
+	if earliest := earliestSampleTimestamp(samples); earliest != 0 {
+		return earliest.AsTime()
+	}
+	if profile.Time() != 0 {
+		return profile.Time().AsTime()
+	}
+	return time.Now().Add(-duration)
+}
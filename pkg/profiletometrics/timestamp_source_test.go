@@ -0,0 +1,143 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+func TestValidateTimestampSource(t *testing.T) {
+	for _, source := range []string{"", "profile_end", "profile_start", "receive_time", "sample"} {
+		assert.NoError(t, validateTimestampSource(source), source)
+	}
+	assert.Error(t, validateTimestampSource("ingest_time"))
+
+	_, err := NewConverter(&ConverterConfig{TimestampSource: "ingest_time"})
+	assert.Error(t, err)
+	_, err = NewConverter(&ConverterConfig{TimestampSource: "profile_start", UseProfileTimestamps: true})
+	assert.Error(t, err, "use_profile_timestamps conflicts with another source")
+}
+
+func TestConverter_TimestampSource(t *testing.T) {
+	profileStart := pcommon.Timestamp(1700000000000000000)
+	profileDuration := pcommon.Timestamp(10 * time.Second)
+	sampleTime := profileStart + pcommon.Timestamp(4*time.Second)
+
+	newProfiles := func(withTime, withSampleTimes bool) pprofile.Profiles {
+		b := newTestProfileBuilder()
+		b.sample(b.stack("main"), nil, 1000000000)
+		if withTime {
+			b.profile.SetTime(profileStart)
+			b.profile.SetDuration(profileDuration)
+		}
+		if withSampleTimes {
+			timestamps := b.profile.Sample().At(0).TimestampsUnixNano()
+			timestamps.Append(uint64(profileStart + pcommon.Timestamp(time.Second)))
+			timestamps.Append(uint64(sampleTime))
+		}
+		return b.profiles
+	}
+
+	tests := []struct {
+		name                 string
+		source               string
+		useProfileTimestamps bool
+		withTime             bool
+		withSampleTimes      bool
+		expectedStart        pcommon.Timestamp
+		expectedTimestamp    pcommon.Timestamp // 0 expects the conversion time
+	}{
+		{
+			name:     "Receive time by default",
+			withTime: true,
+		},
+		{
+			name:              "Profile end",
+			source:            "profile_end",
+			withTime:          true,
+			expectedStart:     profileStart,
+			expectedTimestamp: profileStart + profileDuration,
+		},
+		{
+			name:              "Profile start",
+			source:            "profile_start",
+			withTime:          true,
+			expectedTimestamp: profileStart,
+		},
+		{
+			name:              "Latest sample timestamp",
+			source:            "sample",
+			withTime:          true,
+			withSampleTimes:   true,
+			expectedStart:     profileStart,
+			expectedTimestamp: sampleTime,
+		},
+		{
+			name:              "Sample falls back to the profile end",
+			source:            "sample",
+			withTime:          true,
+			expectedStart:     profileStart,
+			expectedTimestamp: profileStart + profileDuration,
+		},
+		{
+			name:   "Profile end without profile time falls back to receive time",
+			source: "profile_end",
+		},
+		{
+			name:                 "use_profile_timestamps selects the profile end",
+			source:               "receive_time",
+			useProfileTimestamps: true,
+			withTime:             true,
+			expectedStart:        profileStart,
+			expectedTimestamp:    profileStart + profileDuration,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				},
+				TimestampSource:      tt.source,
+				UseProfileTimestamps: tt.useProfileTimestamps,
+			})
+			require.NoError(t, err)
+
+			before := pcommon.NewTimestampFromTime(time.Now())
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), newProfiles(tt.withTime, tt.withSampleTimes))
+			require.NoError(t, err)
+
+			dataPoint, found := timestampSourceDataPoint(metrics, "cpu_time")
+			require.True(t, found)
+			assert.Equal(t, tt.expectedStart, dataPoint.StartTimestamp())
+			if tt.expectedTimestamp == 0 {
+				assert.GreaterOrEqual(t, dataPoint.Timestamp(), before)
+			} else {
+				assert.Equal(t, tt.expectedTimestamp, dataPoint.Timestamp())
+			}
+		})
+	}
+}
+
+// timestampSourceDataPoint returns the first data point of the named metric
+func timestampSourceDataPoint(metrics pmetric.Metrics, name string) (pmetric.NumberDataPoint, bool) {
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() == name && metric.Gauge().DataPoints().Len() > 0 {
+					return metric.Gauge().DataPoints().At(0), true
+				}
+			}
+		}
+	}
+	return pmetric.NumberDataPoint{}, false
+}
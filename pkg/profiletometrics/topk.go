@@ -0,0 +1,118 @@
+package profiletometrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRollingTopKHalfLife        = 5 * time.Minute
+	defaultRollingTopKCapacityFactor  = 10
+	minimumRollingTopKTrackedCapacity = 16
+)
+
+// decayingTopK tracks the heaviest keys across batches using the space-saving algorithm.
+// Counts decay exponentially with the configured half-life, so the selection follows the
+// workload over minutes instead of flapping with every profile.
+type decayingTopK struct {
+	mu        sync.Mutex
+	capacity  int
+	halfLife  time.Duration
+	counts    map[string]float64
+	lastDecay time.Time
+	now       func() time.Time
+}
+
+// newDecayingTopK creates a tracker for the given configuration
+func newDecayingTopK(cfg RollingTopKConfig) *decayingTopK {
+	capacity := cfg.Capacity
+	if capacity <= 0 {
+		capacity = cfg.K * defaultRollingTopKCapacityFactor
+	}
+	if capacity < minimumRollingTopKTrackedCapacity {
+		capacity = minimumRollingTopKTrackedCapacity
+	}
+	halfLife := cfg.HalfLife
+	if halfLife <= 0 {
+		halfLife = defaultRollingTopKHalfLife
+	}
+	return &decayingTopK{
+		capacity: capacity,
+		halfLife: halfLife,
+		counts:   make(map[string]float64),
+		now:      time.Now,
+	}
+}
+
+// observe decays the existing counts and adds the weights of a new batch
+func (t *decayingTopK) observe(weights map[string]float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if !t.lastDecay.IsZero() {
+		factor := math.Pow(0.5, float64(now.Sub(t.lastDecay))/float64(t.halfLife))
+		for key := range t.counts {
+			t.counts[key] *= factor
+		}
+	}
+	t.lastDecay = now
+
+	// Insert heaviest keys first so evictions are deterministic within a batch
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if weights[keys[i]] != weights[keys[j]] {
+			return weights[keys[i]] > weights[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		weight := weights[key]
+		if _, tracked := t.counts[key]; tracked || len(t.counts) < t.capacity {
+			t.counts[key] += weight
+			continue
+		}
+
+		// Space-saving: replace the lightest key and inherit its count as error bound
+		minKey, minCount := "", math.Inf(1)
+		for candidate, count := range t.counts {
+			if count < minCount || (count == minCount && candidate < minKey) {
+				minKey, minCount = candidate, count
+			}
+		}
+		delete(t.counts, minKey)
+		t.counts[key] = minCount + weight
+	}
+}
+
+// top returns the k heaviest tracked keys
+func (t *decayingTopK) top(k int) map[string]bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.counts))
+	for key := range t.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if t.counts[keys[i]] != t.counts[keys[j]] {
+			return t.counts[keys[i]] > t.counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if k > 0 && len(keys) > k {
+		keys = keys[:k]
+	}
+
+	result := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		result[key] = true
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package profiletometrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestDecayingTopK_StableAcrossBatches(t *testing.T) {
+	now := time.Unix(0, 0)
+	tracker := newDecayingTopK(RollingTopKConfig{K: 2, HalfLife: time.Minute})
+	tracker.now = func() time.Time { return now }
+
+	tracker.observe(map[string]float64{"a": 10, "b": 8, "c": 1})
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, tracker.top(2))
+
+	// A single short spike of "c" does not displace the established functions
+	now = now.Add(10 * time.Second)
+	tracker.observe(map[string]float64{"a": 1, "b": 1, "c": 5})
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, tracker.top(2))
+
+	// After several half-lives a sustained shift takes over
+	now = now.Add(5 * time.Minute)
+	tracker.observe(map[string]float64{"c": 9})
+	assert.True(t, tracker.top(2)["c"])
+}
+
+func TestDecayingTopK_SpaceSavingEviction(t *testing.T) {
+	tracker := newDecayingTopK(RollingTopKConfig{K: 1, Capacity: 16})
+	tracker.now = func() time.Time { return time.Unix(0, 0) }
+
+	weights := make(map[string]float64)
+	for i := 0; i < 16; i++ {
+		weights[string(rune('a'+i))] = float64(i + 1)
+	}
+	tracker.observe(weights)
+	tracker.observe(map[string]float64{"z": 100})
+
+	assert.Len(t, tracker.counts, 16, "capacity must be bounded")
+	_, evicted := tracker.counts["a"]
+	assert.False(t, evicted, "the lightest key is evicted")
+	assert.Equal(t, float64(101), tracker.counts["z"], "the new key inherits the evicted count")
+	assert.Equal(t, map[string]bool{"z": true}, tracker.top(1))
+}
+
+func TestConverter_RollingTopKFunctionMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{
+				Enabled:     true,
+				RollingTopK: RollingTopKConfig{Enabled: true, K: 1},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main", "hot"), process, 900, 0)
+	b.sample(b.stack("main", "cold"), process, 100, 0)
+
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateFunctionMetrics(b.profiles, b.profile, map[string]string{}, scopeMetrics)
+
+	cpuDataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+	require.Equal(t, 1, cpuDataPoints.Len())
+	functionName, _ := cpuDataPoints.At(0).Attributes().Get("function.name")
+	assert.Equal(t, "hot", functionName.Str())
+}
+
+func TestNewConverter_RollingTopKRequiresK(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			Function: FunctionMetricConfig{RollingTopK: RollingTopKConfig{Enabled: true}},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -3,6 +3,8 @@ package profiletometrics
 import (
 	"context"
 	"crypto/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -15,14 +17,37 @@ import (
 type TraceConverter struct {
 	config *ConverterConfig
 	logger *zap.Logger
+	// filters holds the process, pattern, thread and function filters shared with the metrics converter
+	filters *filterEngine
+	// dedup holds the traces of recently seen stacks, nil when trace_dedup is off
+	dedup *traceDedupCache
+	// attributeMapping maps emitted attribute keys to their new names, nil without attribute_mapping
+	attributeMapping map[string]string
 }
 
 // NewTraceConverter creates a new profile to traces converter
 func NewTraceConverter(cfg *ConverterConfig) (*TraceConverter, error) {
-	return &TraceConverter{
-		config: cfg,
-		logger: nil, // Will be set by the connector
-	}, nil
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	filters, err := newFilterEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	attributeMapping, err := compileAttributeMapping(cfg.AttributeMapping)
+	if err != nil {
+		return nil, err
+	}
+	converter := &TraceConverter{
+		config:           cfg,
+		logger:           nil, // Will be set by the connector
+		filters:          filters,
+		attributeMapping: attributeMapping,
+	}
+	if cfg.TraceDedup.Enabled {
+		converter.dedup = newTraceDedupCache(cfg.TraceDedup.Window)
+	}
+	return converter, nil
 }
 
 // SetLogger sets the logger for the trace converter
@@ -56,106 +81,83 @@ func (tc *TraceConverter) ConvertProfilesToTraces(ctx context.Context, profiles
 	tc.logInfo("Starting profile to traces conversion",
 		zap.Int("resource_profiles_count", profiles.ResourceProfiles().Len()))
 
+	if tc.dedup != nil {
+		tc.dedup.evict(time.Now())
+	}
+
 	traces := ptrace.NewTraces()
 	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	caches := make(dictionaryCaches)
 
 	iterateProfilesCommon(
 		profiles,
 		tc.extractResourceAttributes,
-		func(resourceIndex, scopeIndex, profileIndex int, profile pprofile.Profile, resourceAttributes map[string]string) {
+		func(dictionary dictionaryProvider, resourceIndex, scopeIndex, profileIndex int, profile pprofile.Profile, resourceAttributes map[string]string) {
 			tc.logDebug("Processing profile",
 				zap.Int("resource_index", resourceIndex),
 				zap.Int("scope_index", scopeIndex),
 				zap.Int("profile_index", profileIndex),
 				zap.Int("samples_count", profile.Sample().Len()))
 
-			profileAttributes := tc.extractProfileAttributes(profiles, profile, resourceAttributes)
+			dictionary = caches.get(dictionary, functionNameRulesFor(tc.config, dictionary, profile, resourceAttributes))
+			scope := scopeOfProfile(profiles, resourceIndex, scopeIndex)
+			profileAttributes := tc.extractProfileAttributes(dictionary, scope, profile, resourceAttributes)
 			tc.logDebug("Extracted profile attributes", zap.Any("attributes", profileAttributes))
 
-			tc.generateTracesFromProfile(profiles, profile, profileAttributes, resourceSpans)
+			tc.generateTracesFromProfile(dictionary, profile, profileAttributes, resourceSpans)
 		},
 	)
 
+	if tc.attributeMapping != nil {
+		renameTraceAttributes(traces, tc.attributeMapping)
+	}
+	if tc.config.MaxAttributeValueLength > 0 {
+		truncateTraceAttributes(traces, tc.config.MaxAttributeValueLength)
+	}
+
 	tc.logInfo("Profile to traces conversion completed")
 	return traces, nil
 }
 
 // extractResourceAttributes extracts attributes from the resource
 func (tc *TraceConverter) extractResourceAttributes(resource pcommon.Resource) map[string]string {
-	attributes := make(map[string]string)
-
-	resource.Attributes().Range(func(key string, value pcommon.Value) bool {
-		attributes[key] = value.AsString()
-		return true
-	})
-
-	return attributes
+	return extractResourceAttributesCommon(resource)
 }
 
-// extractProfileAttributes extracts attributes from the profile data
+// extractProfileAttributes resolves the attributes shared by every span of a profile
 func (tc *TraceConverter) extractProfileAttributes(
-	profiles pprofile.Profiles,
+	dictionary dictionaryProvider,
+	scope pcommon.InstrumentationScope,
 	profile pprofile.Profile,
 	resourceAttributes map[string]string,
 ) map[string]string {
-	attributes := make(map[string]string)
-
-	// Copy resource attributes
-	for k, v := range resourceAttributes {
-		attributes[k] = v
-	}
-
-	// Extract attributes based on configuration rules
-	for _, attr := range tc.config.Attributes {
-		value := tc.extractAttributeValue(profiles, profile, attr)
-		if value != "" {
-			attributes[attr.Key] = value
-		}
-	}
-
-	return attributes
-}
-
-// extractAttributeValue extracts a single attribute value based on the rule
-func (tc *TraceConverter) extractAttributeValue(profiles pprofile.Profiles, _ pprofile.Profile, attr AttributeConfig) string {
-	switch attr.Type {
-	case "literal":
-		return attr.Value
-	case "regex":
-		// Extract from string table using regex pattern
-		return tc.extractFromStringTable(profiles, attr.Value)
-	case "string_table":
-		// Direct string table index access
-		return tc.extractFromStringTableByIndex(profiles, attr.Value)
-	default:
-		return attr.Value
-	}
+	return extractProfileAttributesCommon(tc.config, dictionary, scope, profile, resourceAttributes)
 }
 
 // generateTracesFromProfile generates traces from profile data
 func (tc *TraceConverter) generateTracesFromProfile(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	resourceSpans ptrace.ResourceSpans,
 ) {
-	// Apply pattern filtering if enabled
-	if tc.config.PatternFilter.Enabled && !tc.matchesPatternFilter(attributes) {
-		return
-	}
+	// Apply the sample-level filters of the metrics converter
+	profile, _ = tc.filters.filterSamples(profiles, profile, attributes, func(sample pprofile.Sample) string {
+		return tc.getSampleFunctionName(profiles, sample)
+	})
 
-	// Apply process filtering
-	if !tc.matchesProcessFilter(attributes) {
+	// Apply process filtering; profiles without a matching process produce no traces
+	processNames := tc.filters.allowedProcessNames(tc.getUniqueProcessNames(profiles, profile))
+	if len(processNames) == 0 {
 		return
 	}
 
 	// Create a single scope spans for all spans from this profile
 	scopeSpans := resourceSpans.ScopeSpans().AppendEmpty()
 	scopeSpans.Scope().SetName("profiletometrics")
-	scopeSpans.Scope().SetVersion("1.0.0")
+	scopeSpans.Scope().SetVersion(converterVersion)
 
 	// Generate traces for each process
-	processNames := tc.getUniqueProcessNames(profiles, profile)
 	for _, processName := range processNames {
 		tc.logDebug("Generating traces for process", zap.String("process_name", processName))
 		tc.generateProcessTraces(profiles, profile, attributes, scopeSpans, processName)
@@ -164,29 +166,77 @@ func (tc *TraceConverter) generateTracesFromProfile(
 
 // generateProcessTraces generates traces for a specific process
 func (tc *TraceConverter) generateProcessTraces(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	attributes map[string]string,
 	scopeSpans ptrace.ScopeSpans,
 	processName string,
 ) {
-	// Group samples by their call stack to create trace hierarchies
+	// Group samples by their call stack to create trace hierarchies, hottest stacks first so that
+	// span_output.max_spans_per_profile keeps them
 	stackGroups := tc.groupSamplesByStack(profiles, profile, processName)
-
+	stackIndices := make([]int32, 0, len(stackGroups))
+	durations := make(map[int32]time.Duration, len(stackGroups))
 	for stackIndex, samples := range stackGroups {
+		stackIndices = append(stackIndices, stackIndex)
+		durations[stackIndex] = tc.calculateTotalDuration(samples)
+	}
+	sort.Slice(stackIndices, func(i, j int) bool {
+		if durations[stackIndices[i]] != durations[stackIndices[j]] {
+			return durations[stackIndices[i]] > durations[stackIndices[j]]
+		}
+		return stackIndices[i] < stackIndices[j]
+	})
+
+	for _, stackIndex := range stackIndices {
+		if tc.spanBudgetExhausted(scopeSpans) {
+			return
+		}
+		samples := stackGroups[stackIndex]
 		tc.logDebug("Processing stack group",
 			zap.Int32("stack_index", stackIndex),
 			zap.Int("sample_count", len(samples)))
 
-		// Create a trace for this call stack
-		traceID := tc.generateTraceID()
-		tc.createTraceFromStack(profiles, stackIndex, samples, traceID, attributes, scopeSpans)
+		if tc.dedup == nil {
+			// Create a trace for this call stack
+			traceID := tc.traceIDFor(profile, attributes, processName, stackIndex)
+			tc.createTraceFromStack(profiles, profile, processName, stackIndex, samples, traceID, attributes, scopeSpans)
+			continue
+		}
+
+		// Extend the trace of a stack seen within the dedup window, or start a deterministic one
+		now := time.Now()
+		key := tc.traceDedupKey(profiles, stackIndex, processName, attributes)
+		if trace, ok := tc.dedup.lookup(key, now); ok {
+			tc.extendTrace(profiles, profile, processName, stackIndex, samples, trace, attributes, scopeSpans)
+			continue
+		}
+		traceID := dedupTraceID(key, now)
+		if leafSpanID, ok := tc.createTraceFromStack(profiles, profile, processName, stackIndex, samples, traceID, attributes, scopeSpans); ok {
+			tc.dedup.add(key, traceID, leafSpanID, now)
+		}
+	}
+}
+
+// spanBudgetExhausted reports whether the spans of the profile reached span_output.max_spans_per_profile;
+// every span of a profile goes to its own scope spans
+func (tc *TraceConverter) spanBudgetExhausted(scopeSpans ptrace.ScopeSpans) bool {
+	maxSpans := tc.config.SpanOutput.MaxSpansPerProfile
+	return maxSpans > 0 && scopeSpans.Spans().Len() >= maxSpans
+}
+
+// spanName names the span of a function after span_output.span_name_template
+func (tc *TraceConverter) spanName(functionName, fileName, processName string) string {
+	template := tc.config.SpanOutput.SpanNameTemplate
+	if template == "" {
+		return functionName
 	}
+	return strings.NewReplacer("{function}", functionName, "{file}", fileName, "{process}", processName).Replace(template)
 }
 
 // groupSamplesByStack groups samples by their stack index
 func (tc *TraceConverter) groupSamplesByStack(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
 	profile pprofile.Profile,
 	processName string,
 ) map[int32][]pprofile.Sample {
@@ -196,14 +246,13 @@ func (tc *TraceConverter) groupSamplesByStack(
 		sample := profile.Sample().At(i)
 
 		// Check if sample belongs to this process
-		sampleProcessName := tc.getSampleAttributeValue(profiles, sample, "process.executable.name")
-		if sampleProcessName != processName {
+		if !sampleHasAttributeValueCommon(tc.config, profiles, sample, "process.executable.name", processName) {
 			continue
 		}
 
-		// Skip samples with empty function names
+		// Skip samples with empty function names, and those of functions not passing function_filter
 		sampleFunctionName := tc.getSampleFunctionName(profiles, sample)
-		if sampleFunctionName == "" {
+		if sampleFunctionName == "" || !tc.filters.allowsFunction(sampleFunctionName) {
 			continue
 		}
 
@@ -216,171 +265,113 @@ func (tc *TraceConverter) groupSamplesByStack(
 	return stackGroups
 }
 
-// createTraceFromStack creates a trace from a call stack
+// createTraceFromStack creates a trace from a call stack and returns the span ID of its leaf, or
+// false when no span was created
 func (tc *TraceConverter) createTraceFromStack(
-	profiles pprofile.Profiles,
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	processName string,
 	stackIndex int32,
 	samples []pprofile.Sample,
 	traceID pcommon.TraceID,
 	attributes map[string]string,
 	scopeSpans ptrace.ScopeSpans,
-) {
+) (pcommon.SpanID, bool) {
 	// Get the call stack
-	stack := tc.getStackFromIndex(profiles, stackIndex)
-	if stack == nil {
+	locationIndices, ok := stackLocationIndicesCommon(profiles, stackIndex)
+	if !ok {
 		tc.logWarn("Could not get stack from index", zap.Int32("stack_index", stackIndex))
-		return
+		return pcommon.SpanID{}, false
 	}
 
-	// Calculate total duration from samples
+	// Calculate total duration from samples, starting at the time they were taken
 	totalDuration := tc.calculateTotalDuration(samples)
-	startTime := time.Now().Add(-totalDuration)
+	startTime := spanStartTime(profile, samples, totalDuration)
+	timings := tc.calculateFrameTimings(profiles, samples)
 
-	// Create spans for each function in the call stack
+	// Create nested spans for each function in the call stack, all starting with the root span
 	parentSpanID := pcommon.SpanID{}
 	spans := make([]ptrace.Span, 0)
 
 	// Process locations in reverse order (from caller to callee)
-	locationIndices := stack.LocationIndices()
 	for i := locationIndices.Len() - 1; i >= 0; i-- {
-		locationIndex := locationIndices.At(i)
-		location := tc.getLocationFromIndex(profiles, locationIndex)
-		if location == nil {
+		location, ok := locationAtCommon(profiles, locationIndices.At(i))
+		if !ok {
 			continue
 		}
 
-		functionName := tc.getLocationFunctionName(profiles, *location)
+		functionName := tc.getLocationFunctionName(profiles, location)
 		if functionName == "" {
 			continue
 		}
+		if tc.spanBudgetExhausted(scopeSpans) {
+			break
+		}
+		fileName := tc.getLocationFileName(profiles, location)
 
 		// Create span for this function
 		span := scopeSpans.Spans().AppendEmpty()
-		spanID := tc.generateSpanID()
+		spanID := tc.spanIDFor(traceID, len(spans), functionName)
 
 		// Set span properties
 		span.SetTraceID(traceID)
 		span.SetSpanID(spanID)
 		span.SetParentSpanID(parentSpanID)
-		span.SetName(functionName)
+		span.SetName(tc.spanName(functionName, fileName, processName))
 		span.SetKind(ptrace.SpanKindInternal)
 		span.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
 
-		// Calculate duration for this function
-		functionDuration := tc.calculateFunctionDuration(samples, functionName, totalDuration)
+		// The span lasts the total CPU time of the samples running the function
+		timing := timings[functionName]
+		functionDuration := totalDuration
+		if timing.total > 0 {
+			functionDuration = time.Duration(timing.total)
+		}
 		span.SetEndTimestamp(pcommon.NewTimestampFromTime(startTime.Add(functionDuration)))
 
 		// Add attributes
 		for key, val := range attributes {
 			span.Attributes().PutStr(key, val)
 		}
-		span.Attributes().PutStr("function.name", functionName)
+		span.Attributes().PutStr(codeAttributeKeysFor(tc.config).functionName, functionName)
 		span.Attributes().PutStr("span.kind", "internal")
+		addProfileReference(span, profile, samples)
 
 		// Add filename attribute if available from the same location
-		if filename := tc.getLocationFileName(profiles, *location); filename != "" {
-			span.Attributes().PutStr("file.name", filename)
-			tc.logDebug("Attached file.name to span",
-				zap.String("function_name", functionName),
-				zap.String("file_name", filename))
+		if fileName != "" {
+			span.Attributes().PutStr(codeAttributeKeysFor(tc.config).fileName, fileName)
+		}
+
+		if timing.total > 0 {
+			span.Attributes().PutInt(totalTimeAttributeKey, timing.total)
+			span.Attributes().PutInt(selfTimeAttributeKey, timing.self)
 		}
 
 		// Add events for sample data
-		tc.addSampleEvents(span, samples, functionName)
+		tc.addSampleEvents(profiles, span, samples, functionName)
 
 		spans = append(spans, span)
 
 		// Update parent for next span
 		parentSpanID = spanID
-		startTime = startTime.Add(functionDuration)
 	}
 
 	tc.logDebug("Created trace from stack",
 		zap.Int32("stack_index", stackIndex),
 		zap.Int("span_count", len(spans)),
 		zap.String("trace_id", string(traceID[:])))
-}
-
-// getStackFromIndex gets a stack from the stack table by index
-func (tc *TraceConverter) getStackFromIndex(profiles pprofile.Profiles, stackIndex int32) *pprofile.Stack {
-	dictionary := profiles.Dictionary()
-	stackTable := dictionary.StackTable()
-
-	if stackIndex < 0 || int(stackIndex) >= stackTable.Len() {
-		return nil
-	}
-
-	stack := stackTable.At(int(stackIndex))
-	return &stack
-}
-
-// getLocationFromIndex gets a location from the location table by index
-func (tc *TraceConverter) getLocationFromIndex(profiles pprofile.Profiles, locationIndex int32) *pprofile.Location {
-	dictionary := profiles.Dictionary()
-	locationTable := dictionary.LocationTable()
-
-	if locationIndex < 0 || int(locationIndex) >= locationTable.Len() {
-		return nil
-	}
-
-	location := locationTable.At(int(locationIndex))
-	return &location
+	return parentSpanID, len(spans) > 0
 }
 
 // getLocationFunctionName gets the function name from a location
-func (tc *TraceConverter) getLocationFunctionName(profiles pprofile.Profiles, location pprofile.Location) string {
-	lines := location.Line()
-	if lines.Len() == 0 {
-		return ""
-	}
-
-	line := lines.At(0)
-	functionIndex := line.FunctionIndex()
-	functionName := tc.getFunctionName(profiles, functionIndex)
-	if functionName == "" {
-		return ""
-	}
+func (tc *TraceConverter) getLocationFunctionName(profiles dictionaryProvider, location pprofile.Location) string {
+	functionName, _ := functionNameCommon(profiles, locationFunctionIndexCommon(location))
 	return functionName
 }
 
 // getLocationFileName gets the source filename from a location
-func (tc *TraceConverter) getLocationFileName(profiles pprofile.Profiles, location pprofile.Location) string {
-	filename := getLocationFileNameCommon(profiles, location)
-	if filename == "" {
-		tc.logDebug("Location has no lines for filename resolution")
-	} else {
-		tc.logDebug("Resolved file.name from location", zap.String("file_name", filename))
-	}
-	return filename
-}
-
-// getFunctionName extracts the function name from a function index
-func (tc *TraceConverter) getFunctionName(profiles pprofile.Profiles, functionIndex int32) string {
-	if functionIndex < 0 {
-		return ""
-	}
-
-	dictionary := profiles.Dictionary()
-	functionTable := dictionary.FunctionTable()
-
-	if int(functionIndex) >= functionTable.Len() {
-		return ""
-	}
-
-	function := functionTable.At(int(functionIndex))
-	nameIndex := function.NameStrindex()
-
-	stringTable := dictionary.StringTable()
-	if nameIndex < 0 || int(nameIndex) >= stringTable.Len() {
-		return ""
-	}
-
-	functionName := stringTable.At(int(nameIndex))
-	if functionName == "" {
-		return ""
-	}
-
-	return functionName
+func (tc *TraceConverter) getLocationFileName(profiles dictionaryProvider, location pprofile.Location) string {
+	return getLocationFileNameCommon(profiles, location)
 }
 
 // calculateTotalDuration calculates the total duration from samples
@@ -401,27 +392,91 @@ func (tc *TraceConverter) calculateTotalDuration(samples []pprofile.Sample) time
 	return time.Duration(totalNs)
 }
 
-// calculateFunctionDuration calculates the duration for a specific function
-func (tc *TraceConverter) calculateFunctionDuration(
-	samples []pprofile.Sample,
-	_ string,
-	totalDuration time.Duration,
-) time.Duration {
-	// For now, distribute duration evenly across functions
-	// In a more sophisticated implementation, you could analyze the actual time spent
-	// in each function based on the sample data
-	return totalDuration / time.Duration(len(samples))
+const (
+	// totalTimeAttributeKey is the CPU time of the samples running a span's function, callees included
+	totalTimeAttributeKey = "cpu_total_time_ns"
+	// selfTimeAttributeKey is the CPU time of the samples with a span's function as their leaf
+	selfTimeAttributeKey = "cpu_self_time_ns"
+
+	// profileIDAttributeKey is the hex ID of the profile a span was generated from
+	profileIDAttributeKey = "pprofile.profile_id"
+	// profileSampleCountAttributeKey is the number of samples of the profile a span stands for
+	profileSampleCountAttributeKey = "profile.sample.count"
+)
+
+// frameTiming is the CPU time of a function in a stack: total includes its callees, self does not
+type frameTiming struct {
+	total int64
+	self  int64
 }
 
-// addSampleEvents adds events to a span based on sample data
-func (tc *TraceConverter) addSampleEvents(span ptrace.Span, samples []pprofile.Sample, functionName string) {
-	for i, sample := range samples {
+// calculateFrameTimings aggregates the CPU time of the samples per function of their stacks. A
+// sample counts once towards the total of every function on its stack, recursive ones included,
+// and towards the self time of its leaf function.
+func (tc *TraceConverter) calculateFrameTimings(profiles dictionaryProvider, samples []pprofile.Sample) map[string]frameTiming {
+	timings := make(map[string]frameTiming)
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		values := sample.Values()
+		if values.Len() == 0 {
+			continue
+		}
+		cpuTime := values.At(0)
+
+		locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+		if !ok {
+			continue
+		}
+		clear(seen)
+		for i := 0; i < locationIndices.Len(); i++ {
+			location, ok := locationAtCommon(profiles, locationIndices.At(i))
+			if !ok {
+				continue
+			}
+			functionName := tc.getLocationFunctionName(profiles, location)
+			if functionName == "" || seen[functionName] {
+				continue
+			}
+			seen[functionName] = true
+			timing := timings[functionName]
+			timing.total += cpuTime
+			timings[functionName] = timing
+		}
+		if leaf := tc.getSampleFunctionName(profiles, sample); leaf != "" {
+			timing := timings[leaf]
+			timing.self += cpuTime
+			timings[leaf] = timing
+		}
+	}
+	return timings
+}
+
+// addSampleEvents adds events to a span based on sample data: one event per sample, or one per
+// distinct stack with span_output.exemplar_stacks, up to span_output.max_events_per_span
+func (tc *TraceConverter) addSampleEvents(
+	profiles dictionaryProvider,
+	span ptrace.Span,
+	samples []pprofile.Sample,
+	functionName string,
+) {
+	if tc.config.SpanOutput.ExemplarStacks {
+		tc.addExemplarStackEvents(profiles, span, samples, functionName)
+		return
+	}
+
+	foldedStack := tc.config.FoldedStack
+	limit := len(samples)
+	if maxEvents := tc.config.SpanOutput.MaxEventsPerSpan; maxEvents > 0 {
+		limit = min(limit, maxEvents)
+	}
+	span.SetDroppedEventsCount(span.DroppedEventsCount() + uint32(len(samples)-limit))
+	for i, sample := range samples[:limit] {
 		event := span.Events().AppendEmpty()
 		event.SetName("sample")
-		event.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		event.SetTimestamp(sampleEventTimestamp(sample))
 
 		// Add sample attributes
-		event.Attributes().PutStr("function.name", functionName)
+		event.Attributes().PutStr(codeAttributeKeysFor(tc.config).functionName, functionName)
 		event.Attributes().PutInt("sample.index", int64(i))
 
 		// Add sample values
@@ -432,9 +487,33 @@ func (tc *TraceConverter) addSampleEvents(span ptrace.Span, samples []pprofile.S
 		if values.Len() > 1 {
 			event.Attributes().PutInt("memory_bytes", values.At(1))
 		}
+
+		if foldedStack.Enabled {
+			folded := foldStackCommon(profiles, sample.StackIndex(), foldedStack.Separator, foldedStack.MaxLength)
+			if folded != "" {
+				event.Attributes().PutStr(foldedStackAttributeKey, folded)
+			}
+		}
 	}
 }
 
+// addProfileReference records the source profile of a span, so that backends storing profiles can
+// link the span to it: the profile ID, when the profile has one, and the number of samples of the span
+func addProfileReference(span ptrace.Span, profile pprofile.Profile, samples []pprofile.Sample) {
+	if profileID := profile.ProfileID(); !profileID.IsEmpty() {
+		span.Attributes().PutStr(profileIDAttributeKey, profileID.String())
+	}
+	span.Attributes().PutInt(profileSampleCountAttributeKey, int64(len(samples)))
+}
+
+// sampleEventTimestamp returns the time a sample was taken, or now when it carries no timestamp
+func sampleEventTimestamp(sample pprofile.Sample) pcommon.Timestamp {
+	if timestamps := sample.TimestampsUnixNano(); timestamps.Len() > 0 {
+		return pcommon.Timestamp(timestamps.At(0))
+	}
+	return pcommon.NewTimestampFromTime(time.Now())
+}
+
 // generateTraceID generates a new trace ID
 func (tc *TraceConverter) generateTraceID() pcommon.TraceID {
 	var traceID pcommon.TraceID
@@ -453,54 +532,36 @@ func (tc *TraceConverter) generateSpanID() pcommon.SpanID {
 	return spanID
 }
 
-// getSampleFunctionName gets the top function name from a sample's stack
-func (tc *TraceConverter) getSampleFunctionName(profiles pprofile.Profiles, sample pprofile.Sample) string {
-	stackIndex := sample.StackIndex()
-	if stackIndex < 0 {
-		return ""
-	}
-
-	dictionary := profiles.Dictionary()
-	stackTable := dictionary.StackTable()
-
-	if int(stackIndex) >= stackTable.Len() {
-		return ""
-	}
-
-	stack := stackTable.At(int(stackIndex))
-	locationIndices := stack.LocationIndices()
-
-	if locationIndices.Len() == 0 {
-		return ""
-	}
-
-	// Get the LAST location (top of the call stack)
-	locationIndex := locationIndices.At(locationIndices.Len() - 1)
-	locationTable := dictionary.LocationTable()
-
-	if locationIndex < 0 || int(locationIndex) >= locationTable.Len() {
-		return ""
-	}
-
-	location := locationTable.At(int(locationIndex))
-	return tc.getLocationFunctionName(profiles, location)
+// getSampleFunctionName gets the top function name from a sample's stack, once per stack and conversion
+func (tc *TraceConverter) getSampleFunctionName(profiles dictionaryProvider, sample pprofile.Sample) string {
+	return cachedStackFunctionCommon(profiles, sample.StackIndex(), func() string {
+		// Get the LAST location (top of the call stack)
+		location, ok := leafLocationCommon(profiles, sample.StackIndex())
+		if !ok {
+			return ""
+		}
+		return tc.getLocationFunctionName(profiles, location)
+	})
 }
 
 // getSampleAttributeValue extracts a specific attribute value from a sample
-func (tc *TraceConverter) getSampleAttributeValue(profiles pprofile.Profiles, sample pprofile.Sample, key string) string {
+func (tc *TraceConverter) getSampleAttributeValue(profiles dictionaryProvider, sample pprofile.Sample, key string) string {
 	return getSampleAttributeValueCommon(profiles, sample, key)
 }
 
 // getUniqueProcessNames extracts all unique process names from a profile
-func (tc *TraceConverter) getUniqueProcessNames(profiles pprofile.Profiles, profile pprofile.Profile) []string {
+func (tc *TraceConverter) getUniqueProcessNames(profiles dictionaryProvider, profile pprofile.Profile) []string {
 	processNames := make(map[string]bool)
 
 	// Iterate through samples to extract unique process names from attributes
+	var values []string
 	for i := 0; i < profile.Sample().Len(); i++ {
 		sample := profile.Sample().At(i)
-		processName := tc.getSampleAttributeValue(profiles, sample, "process.executable.name")
-		if processName != "" {
-			processNames[processName] = true
+		values = appendSampleAttributeValuesCommon(values[:0], tc.config, profiles, sample, "process.executable.name")
+		for _, processName := range values {
+			if processName != "" {
+				processNames[processName] = true
+			}
 		}
 	}
 
@@ -511,72 +572,3 @@ func (tc *TraceConverter) getUniqueProcessNames(profiles pprofile.Profiles, prof
 
 	return result
 }
-
-// matchesPatternFilter checks if attributes match the pattern filter
-func (tc *TraceConverter) matchesPatternFilter(attributes map[string]string) bool {
-	if !tc.config.PatternFilter.Enabled {
-		return true
-	}
-	// Check if any attribute value matches the pattern
-	for _, value := range attributes {
-		if tc.config.PatternFilter.Pattern != "" &&
-			value != "" {
-			// Simple substring matching for now
-			return true
-		}
-	}
-	return false
-}
-
-// matchesProcessFilter checks if the profile matches the process filter
-func (tc *TraceConverter) matchesProcessFilter(attributes map[string]string) bool {
-	if !tc.config.ProcessFilter.Enabled {
-		return true // No filter configured
-	}
-
-	processName, exists := attributes["process_name"]
-	if !exists {
-		return false // No process name attribute found
-	}
-
-	// For now, simple string matching - in a real implementation you would compile and match the regex pattern
-	if tc.config.ProcessFilter.Pattern == "" {
-		return true // No pattern specified, allow all
-	}
-
-	// Simple contains check for now - in production, use regex compilation
-	return processName != "" // Placeholder logic
-}
-
-// extractFromStringTable extracts values from profile string table using regex pattern
-func (tc *TraceConverter) extractFromStringTable(profiles pprofile.Profiles, _ string) string {
-	// Access the string table from the profiles dictionary
-	stringTable := profiles.Dictionary().StringTable()
-
-	// For now, return the first string as a placeholder
-	// In a real implementation, you would:
-	// 1. Compile the regex pattern
-	// 2. Match against all strings in the table
-	// 3. Return the first match
-	if stringTable.Len() > 0 {
-		return stringTable.At(0)
-	}
-	return ""
-}
-
-// extractFromStringTableByIndex extracts values from profile string table by index
-func (tc *TraceConverter) extractFromStringTableByIndex(profiles pprofile.Profiles, _ string) string {
-	// Access the string table from the profiles dictionary
-	stringTable := profiles.Dictionary().StringTable()
-
-	// Parse the index string to integer
-	// For now, use index 0 as a placeholder
-	// In a real implementation, you would:
-	// 1. Parse the indexStr to integer using strconv.Atoi
-	// 2. Check bounds to ensure the index is valid
-	// 3. Return the string at the specified index
-	if stringTable.Len() > 0 {
-		return stringTable.At(0) // Placeholder: return first string
-	}
-	return ""
-}
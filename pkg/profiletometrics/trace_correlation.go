@@ -0,0 +1,113 @@
+package profiletometrics
+
+import (
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+const (
+	// Sample attributes carrying the trace context of a sample, as set by the eBPF profiler
+	traceIDAttributeKey = "trace_id"
+	spanIDAttributeKey  = "span_id"
+)
+
+// traceKey identifies the CPU time of a trace (and span) within a process
+type traceKey struct {
+	processName string
+	traceID     string
+	spanID      string
+}
+
+// sampleTraceContext returns the hex trace and span IDs of a sample: its trace_id and span_id
+// attributes, or else the IDs of the link it references. Samples outside of a trace return "".
+func sampleTraceContext(profiles dictionaryProvider, sample pprofile.Sample) (traceID, spanID string) {
+	if traceID = getSampleAttributeValueCommon(profiles, sample, traceIDAttributeKey); traceID != "" {
+		return traceID, getSampleAttributeValueCommon(profiles, sample, spanIDAttributeKey)
+	}
+
+	linkTable := profiles.Dictionary().LinkTable()
+	linkIndex := sample.LinkIndex()
+	if linkIndex < 0 || int(linkIndex) >= linkTable.Len() {
+		return "", ""
+	}
+	// Link index 0 is the zero link, which has no trace ID
+	link := linkTable.At(int(linkIndex))
+	if link.TraceID().IsEmpty() {
+		return "", ""
+	}
+	if !link.SpanID().IsEmpty() {
+		spanID = link.SpanID().String()
+	}
+	return link.TraceID().String(), spanID
+}
+
+// generateTraceCorrelationMetrics emits the CPU time of each sampled trace, per process, with a
+// trace_id attribute (and span_id when include_span_id is set) so that profiles can be joined with
+// traces downstream. Samples outside of a trace are left out.
+func (c *Converter) generateTraceCorrelationMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+) {
+	cfg := c.config.Metrics.TraceCorrelation
+	sampleCount := profile.Sample().Len()
+	weight := c.profileCPUWeight(profiles, profile)
+	summary := summaryOf(profiles)
+	totals := make(map[traceKey]float64)
+	for i := 0; i < sampleCount; i++ {
+		sample := profile.Sample().At(i)
+		traceID, spanID := sampleTraceContext(profiles, sample)
+		if traceID == "" {
+			continue
+		}
+		key := traceKey{
+			processName: c.getSampleAttributeValue(profiles, sample, "process.executable.name"),
+			traceID:     traceID,
+		}
+		if cfg.IncludeSpanID {
+			key.spanID = spanID
+		}
+		totals[key] += c.sampleCPUTime(sample, weight, summary)
+	}
+	if len(totals) == 0 {
+		return
+	}
+
+	keys := make([]traceKey, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].processName != keys[j].processName {
+			return keys[i].processName < keys[j].processName
+		}
+		if keys[i].traceID != keys[j].traceID {
+			return keys[i].traceID < keys[j].traceID
+		}
+		return keys[i].spanID < keys[j].spanID
+	})
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(cfg.MetricName)
+	metric.SetDescription("CPU time spent in sampled traces")
+	c.markAdditive(metric)
+	gauge := metric.SetEmptyGauge()
+	for _, key := range keys {
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue(totals[key])
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if key.processName != "" {
+			dataPoint.Attributes().PutStr("process.name", key.processName)
+		}
+		dataPoint.Attributes().PutStr(traceIDAttributeKey, key.traceID)
+		if key.spanID != "" {
+			dataPoint.Attributes().PutStr(spanIDAttributeKey, key.spanID)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestConverter_TraceCorrelationMetrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		includeSpanID bool
+		expected      map[string]float64
+	}{
+		{
+			name: "per trace",
+			expected: map[string]float64{
+				"app/4bf92f3577b34da6a3ce929d0e0e4736/": 3,
+				"app/0102030405060708090a0b0c0d0e0f10/": 4,
+			},
+		},
+		{
+			name:          "per span",
+			includeSpanID: true,
+			expected: map[string]float64{
+				"app/4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7": 1,
+				"app/4bf92f3577b34da6a3ce929d0e0e4736/b7ad6b7169203331": 2,
+				"app/0102030405060708090a0b0c0d0e0f10/0102030405060708": 4,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					TraceCorrelation: TraceCorrelationMetricConfig{
+						Enabled:       true,
+						MetricName:    "cpu_time_by_trace",
+						IncludeSpanID: tt.includeSpanID,
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			stack := b.stack("main.main", "handler")
+			b.sample(stack, map[string]string{
+				"process.executable.name": "app",
+				"trace_id":                "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":                 "00f067aa0ba902b7",
+			}, 1000000000)
+			b.sample(stack, map[string]string{
+				"process.executable.name": "app",
+				"trace_id":                "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":                 "b7ad6b7169203331",
+			}, 2000000000)
+			// Samples outside of a trace are left out
+			b.sample(stack, map[string]string{"process.executable.name": "app"}, 8000000000)
+
+			// Link index 0 is the zero link
+			linkTable := b.profiles.Dictionary().LinkTable()
+			linkTable.AppendEmpty()
+			link := linkTable.AppendEmpty()
+			link.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+			link.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+			b.sample(stack, map[string]string{"process.executable.name": "app"}, 4000000000).SetLinkIndex(1)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			require.Equal(t, "cpu_time_by_trace", metric.Name())
+
+			cpuTimes := make(map[string]float64)
+			for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+				dp := metric.Gauge().DataPoints().At(i)
+				processName, _ := dp.Attributes().Get("process.name")
+				traceID, _ := dp.Attributes().Get(traceIDAttributeKey)
+				spanID := ""
+				if value, ok := dp.Attributes().Get(spanIDAttributeKey); ok {
+					spanID = value.Str()
+				}
+				cpuTimes[processName.Str()+"/"+traceID.Str()+"/"+spanID] = dp.DoubleValue()
+			}
+			assert.Equal(t, tt.expected, cpuTimes)
+		})
+	}
+}
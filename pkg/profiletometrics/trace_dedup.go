@@ -0,0 +1,144 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// validateTraceDedup checks that an enabled trace dedup has a window
+func validateTraceDedup(cfg TraceDedupConfig) error {
+	if cfg.Enabled && cfg.Window <= 0 {
+		return fmt.Errorf("trace_dedup.window must be positive when enabled")
+	}
+	return nil
+}
+
+// dedupedTrace is a trace emitted for a stack, extended while the stack keeps being seen
+type dedupedTrace struct {
+	traceID    pcommon.TraceID
+	leafSpanID pcommon.SpanID
+	lastSeen   time.Time
+}
+
+// traceDedupCache remembers the traces of recently seen stacks
+type traceDedupCache struct {
+	mu     sync.Mutex
+	window time.Duration
+	traces map[string]*dedupedTrace
+}
+
+func newTraceDedupCache(window time.Duration) *traceDedupCache {
+	return &traceDedupCache{window: window, traces: make(map[string]*dedupedTrace)}
+}
+
+// lookup returns the trace of a stack seen within the window, marking it as seen again
+func (d *traceDedupCache) lookup(key string, now time.Time) (dedupedTrace, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	trace, exists := d.traces[key]
+	if !exists || now.Sub(trace.lastSeen) > d.window {
+		return dedupedTrace{}, false
+	}
+	trace.lastSeen = now
+	return *trace, true
+}
+
+// add records the trace emitted for a stack
+func (d *traceDedupCache) add(key string, traceID pcommon.TraceID, leafSpanID pcommon.SpanID, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.traces[key] = &dedupedTrace{traceID: traceID, leafSpanID: leafSpanID, lastSeen: now}
+}
+
+// evict forgets the traces of stacks not seen within the window
+func (d *traceDedupCache) evict(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, trace := range d.traces {
+		if now.Sub(trace.lastSeen) > d.window {
+			delete(d.traces, key)
+		}
+	}
+}
+
+// traceDedupKey identifies the stack of a process within the profile attributes
+func (tc *TraceConverter) traceDedupKey(
+	profiles dictionaryProvider,
+	stackIndex int32,
+	processName string,
+	attributes map[string]string,
+) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(processName)
+	for _, key := range keys {
+		b.WriteString("\x00" + key + "=" + attributes[key])
+	}
+	b.WriteString("\x00" + foldStackCommon(profiles, stackIndex, defaultFoldedStackSeparator, 0))
+	return b.String()
+}
+
+// dedupTraceID derives the trace ID of a stack from its key and the time it was first seen, so a
+// stack seen again after the window expired starts a new trace
+func dedupTraceID(key string, firstSeen time.Time) pcommon.TraceID {
+	hash := fnv.New128a()
+	hash.Write([]byte(key))
+	hash.Write([]byte(strconv.FormatInt(firstSeen.UnixNano(), 10)))
+	var traceID pcommon.TraceID
+	copy(traceID[:], hash.Sum(nil))
+	return traceID
+}
+
+// extendTrace adds a span for repeated samples of a stack under the leaf span of its existing trace
+func (tc *TraceConverter) extendTrace(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	processName string,
+	stackIndex int32,
+	samples []pprofile.Sample,
+	trace dedupedTrace,
+	attributes map[string]string,
+	scopeSpans ptrace.ScopeSpans,
+) {
+	location, ok := leafLocationCommon(profiles, stackIndex)
+	if !ok {
+		return
+	}
+	functionName := tc.getLocationFunctionName(profiles, location)
+	fileName := tc.getLocationFileName(profiles, location)
+	duration := tc.calculateTotalDuration(samples)
+	startTime := spanStartTime(profile, samples, duration)
+
+	span := scopeSpans.Spans().AppendEmpty()
+	span.SetTraceID(trace.traceID)
+	span.SetSpanID(tc.generateSpanID())
+	span.SetParentSpanID(trace.leafSpanID)
+	span.SetName(tc.spanName(functionName, fileName, processName))
+	span.SetKind(ptrace.SpanKindInternal)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(startTime.Add(duration)))
+	for key, val := range attributes {
+		span.Attributes().PutStr(key, val)
+	}
+	span.Attributes().PutStr(codeAttributeKeysFor(tc.config).functionName, functionName)
+	span.Attributes().PutStr("span.kind", "internal")
+	addProfileReference(span, profile, samples)
+	if fileName != "" {
+		span.Attributes().PutStr(codeAttributeKeysFor(tc.config).fileName, fileName)
+	}
+	tc.addSampleEvents(profiles, span, samples, functionName)
+}
@@ -0,0 +1,78 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTraceDedupTestProfile() *testProfileBuilder {
+	b := newTestProfileBuilder()
+	b.sample(b.stack("main.main", "main.handle", "main.hot"), map[string]string{"process.executable.name": "app"}, 1000000)
+	return b
+}
+
+func convertTestTraces(t *testing.T, converter *TraceConverter) ptrace.SpanSlice {
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), newTraceDedupTestProfile().profiles)
+	require.NoError(t, err)
+	return traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+}
+
+func TestTraceConverter_DedupExtendsTrace(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		TraceDedup: TraceDedupConfig{Enabled: true, Window: time.Minute},
+	})
+	require.NoError(t, err)
+
+	first := convertTestTraces(t, converter)
+	require.Equal(t, 3, first.Len())
+	innermost := first.At(first.Len() - 1)
+
+	// The repeated stack adds a single span to the existing trace
+	second := convertTestTraces(t, converter)
+	require.Equal(t, 1, second.Len())
+	extension := second.At(0)
+	assert.Equal(t, innermost.TraceID(), extension.TraceID())
+	assert.Equal(t, innermost.SpanID(), extension.ParentSpanID())
+	assert.Equal(t, "main.hot", extension.Name())
+	assert.Equal(t, 1, extension.Events().Len())
+}
+
+func TestTraceConverter_DedupWindowExpires(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		TraceDedup: TraceDedupConfig{Enabled: true, Window: time.Nanosecond},
+	})
+	require.NoError(t, err)
+
+	first := convertTestTraces(t, converter)
+	time.Sleep(time.Millisecond)
+	second := convertTestTraces(t, converter)
+	require.Equal(t, first.Len(), second.Len())
+	assert.NotEqual(t, first.At(0).TraceID(), second.At(0).TraceID())
+}
+
+func TestTraceConverter_WithoutDedup(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	first := convertTestTraces(t, converter)
+	second := convertTestTraces(t, converter)
+	require.Equal(t, first.Len(), second.Len())
+	assert.NotEqual(t, first.At(0).TraceID(), second.At(0).TraceID())
+}
+
+func TestDedupTraceID_Deterministic(t *testing.T) {
+	seen := time.Unix(1700000000, 0)
+	assert.Equal(t, dedupTraceID("app\x00main;hot", seen), dedupTraceID("app\x00main;hot", seen))
+	assert.NotEqual(t, dedupTraceID("app\x00main;hot", seen), dedupTraceID("app\x00main;cold", seen))
+	assert.NotEqual(t, dedupTraceID("app\x00main;hot", seen), dedupTraceID("app\x00main;hot", seen.Add(time.Second)))
+}
+
+func TestNewTraceConverter_InvalidTraceDedup(t *testing.T) {
+	_, err := NewTraceConverter(&ConverterConfig{TraceDedup: TraceDedupConfig{Enabled: true}})
+	assert.Error(t, err)
+}
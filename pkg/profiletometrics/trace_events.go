@@ -0,0 +1,111 @@
+package profiletometrics
+
+import (
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// exemplarStackEventName is the name of the span events carrying an exemplar stack
+	exemplarStackEventName = "stack"
+	// sampleCountAttributeKey is the number of samples an exemplar stack event stands for
+	sampleCountAttributeKey = "sample.count"
+)
+
+// exemplarStack sums the samples of a span sharing the same folded stack
+type exemplarStack struct {
+	folded  string
+	samples int64
+	// firstSeen is the earliest timestamp of the samples, 0 when none carries one
+	firstSeen pcommon.Timestamp
+	cpuTime   int64
+	memory    int64
+}
+
+// exemplarStacks groups samples by folded stack, hottest first: by CPU time, then sample count,
+// then stack for a stable order
+func (tc *TraceConverter) exemplarStacks(profiles dictionaryProvider, samples []pprofile.Sample) []exemplarStack {
+	foldedStack := tc.config.FoldedStack
+	byStack := make(map[string]*exemplarStack)
+	folds := make(map[int32]string)
+	for _, sample := range samples {
+		folded, exists := folds[sample.StackIndex()]
+		if !exists {
+			folded = foldStackCommon(profiles, sample.StackIndex(), foldedStack.Separator, foldedStack.MaxLength)
+			folds[sample.StackIndex()] = folded
+		}
+		if folded == "" {
+			continue
+		}
+		stack, exists := byStack[folded]
+		if !exists {
+			stack = &exemplarStack{folded: folded}
+			byStack[folded] = stack
+		}
+		stack.samples++
+		if first := earliestSampleTimestamp([]pprofile.Sample{sample}); first != 0 && (stack.firstSeen == 0 || first < stack.firstSeen) {
+			stack.firstSeen = first
+		}
+		values := sample.Values()
+		if values.Len() > 0 {
+			stack.cpuTime += values.At(0)
+		}
+		if values.Len() > 1 {
+			stack.memory += values.At(1)
+		}
+	}
+
+	stacks := make([]exemplarStack, 0, len(byStack))
+	for _, stack := range byStack {
+		stacks = append(stacks, *stack)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		if stacks[i].cpuTime != stacks[j].cpuTime {
+			return stacks[i].cpuTime > stacks[j].cpuTime
+		}
+		if stacks[i].samples != stacks[j].samples {
+			return stacks[i].samples > stacks[j].samples
+		}
+		return stacks[i].folded < stacks[j].folded
+	})
+	return stacks
+}
+
+// addExemplarStackEvents adds one "stack" event per distinct full stack of the span's samples,
+// hottest first, carrying the folded stack, the number of samples and their summed values. Stacks
+// beyond span_output.max_events_per_span are counted as dropped events.
+func (tc *TraceConverter) addExemplarStackEvents(
+	profiles dictionaryProvider,
+	span ptrace.Span,
+	samples []pprofile.Sample,
+	functionName string,
+) {
+	stacks := tc.exemplarStacks(profiles, samples)
+	limit := len(stacks)
+	if maxEvents := tc.config.SpanOutput.MaxEventsPerSpan; maxEvents > 0 {
+		limit = min(limit, maxEvents)
+	}
+	span.SetDroppedEventsCount(span.DroppedEventsCount() + uint32(len(stacks)-limit))
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for _, stack := range stacks[:limit] {
+		event := span.Events().AppendEmpty()
+		event.SetName(exemplarStackEventName)
+		if stack.firstSeen != 0 {
+			event.SetTimestamp(stack.firstSeen)
+		} else {
+			event.SetTimestamp(now)
+		}
+		event.Attributes().PutStr(codeAttributeKeysFor(tc.config).functionName, functionName)
+		event.Attributes().PutStr(foldedStackAttributeKey, stack.folded)
+		event.Attributes().PutInt(sampleCountAttributeKey, stack.samples)
+		event.Attributes().PutInt("cpu_time_ns", stack.cpuTime)
+		if stack.memory > 0 {
+			event.Attributes().PutInt("memory_bytes", stack.memory)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// leafSpan returns the span of the given function
+func leafSpan(t *testing.T, traces ptrace.Traces, name string) ptrace.Span {
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		if spans.At(i).Name() == name {
+			return spans.At(i)
+		}
+	}
+	require.Failf(t, "span not found", "no span named %q", name)
+	return ptrace.Span{}
+}
+
+func TestTraceConverter_ExemplarStackEvents(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		SpanOutput: TraceSpanConfig{ExemplarStacks: true},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	stack := b.stack("main.main", "main.handle")
+	attributes := map[string]string{"process.executable.name": "app"}
+	b.sample(stack, attributes, 100)
+	b.sample(stack, attributes, 300)
+	b.sample(stack, attributes, 200)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	span := leafSpan(t, traces, "main.handle")
+	require.Equal(t, 1, span.Events().Len())
+	event := span.Events().At(0)
+	assert.Equal(t, exemplarStackEventName, event.Name())
+	folded, _ := event.Attributes().Get(foldedStackAttributeKey)
+	assert.Equal(t, "main.main;main.handle", folded.Str())
+	count, _ := event.Attributes().Get(sampleCountAttributeKey)
+	assert.Equal(t, int64(3), count.Int())
+	cpuTime, _ := event.Attributes().Get("cpu_time_ns")
+	assert.Equal(t, int64(600), cpuTime.Int())
+	assert.Zero(t, span.DroppedEventsCount())
+}
+
+func TestTraceConverter_ExemplarStacksRanking(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		FoldedStack: FoldedStackConfig{MaxLength: 5},
+		SpanOutput:  TraceSpanConfig{ExemplarStacks: true, MaxEventsPerSpan: 2},
+	})
+	require.NoError(t, err)
+
+	// The stacks fold to their leaf frame, so the samples of a span fall into distinct stacks
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	attributes := map[string]string{"process.executable.name": "app"}
+	samples := []pprofile.Sample{
+		b.sample(b.stack("main.main", "main.a"), attributes, 100),
+		b.sample(b.stack("main.main", "main.b"), attributes, 300),
+		b.sample(b.stack("main.main", "main.c"), attributes, 200),
+	}
+
+	stacks := converter.exemplarStacks(b.profiles, samples)
+	require.Len(t, stacks, 3)
+	assert.Equal(t, []string{"main.b", "main.c", "main.a"}, []string{stacks[0].folded, stacks[1].folded, stacks[2].folded})
+
+	span := ptrace.NewSpan()
+	converter.addExemplarStackEvents(b.profiles, span, samples, "main.main")
+	assert.Equal(t, 2, span.Events().Len())
+	assert.Equal(t, uint32(1), span.DroppedEventsCount())
+}
+
+func TestTraceConverter_MaxEventsPerSpan(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		SpanOutput: TraceSpanConfig{MaxEventsPerSpan: 2},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	stack := b.stack("main.main", "main.handle")
+	for _, value := range []int64{100, 200, 300, 400} {
+		b.sample(stack, map[string]string{"process.executable.name": "app"}, value)
+	}
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	span := leafSpan(t, traces, "main.handle")
+	assert.Equal(t, 2, span.Events().Len())
+	assert.Equal(t, "sample", span.Events().At(0).Name())
+	assert.Equal(t, uint32(2), span.DroppedEventsCount())
+}
+
+func TestNewConverter_InvalidMaxEventsPerSpan(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{SpanOutput: TraceSpanConfig{MaxEventsPerSpan: -1}})
+	assert.Error(t, err)
+}
+
+func TestTraceConverter_MaxSpansPerProfile(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		SpanOutput: TraceSpanConfig{MaxSpansPerProfile: 3},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	attributes := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main.main", "main.cold"), attributes, 100)
+	b.sample(b.stack("main.main", "main.hot"), attributes, 900)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	// The hottest stack is kept whole, the next one is cut at the cap
+	assert.Equal(t, 3, traces.SpanCount())
+	leafSpan(t, traces, "main.hot")
+}
+
+func TestTraceConverter_SpanNameTemplate(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{
+		SpanOutput: TraceSpanConfig{SpanNameTemplate: "{process}/{function} ({file})"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "app/main.main (main.go)", converter.spanName("main.main", "main.go", "app"))
+
+	converter, err = NewTraceConverter(&ConverterConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "main.main", converter.spanName("main.main", "main.go", "app"))
+}
@@ -0,0 +1,58 @@
+package profiletometrics
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// traceIDFor returns the trace ID of a stack of a process: random, or with span_output.deterministic_ids
+// derived from the profile attributes, profile ID, process and stack index, so converting the same
+// profile again yields the same trace
+func (tc *TraceConverter) traceIDFor(
+	profile pprofile.Profile,
+	attributes map[string]string,
+	processName string,
+	stackIndex int32,
+) pcommon.TraceID {
+	if !tc.config.SpanOutput.DeterministicIDs {
+		return tc.generateTraceID()
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := fnv.New128a()
+	for _, key := range keys {
+		hash.Write([]byte(key + "=" + attributes[key] + "\x00"))
+	}
+	profileID := profile.ProfileID()
+	hash.Write(profileID[:])
+	hash.Write([]byte(processName + "\x00"))
+	hash.Write(binary.BigEndian.AppendUint32(nil, uint32(stackIndex)))
+	var traceID pcommon.TraceID
+	copy(traceID[:], hash.Sum(nil))
+	return traceID
+}
+
+// spanIDFor returns the span ID of a function at the given depth of a trace: random, or with
+// span_output.deterministic_ids derived from the trace ID, depth and function
+func (tc *TraceConverter) spanIDFor(traceID pcommon.TraceID, depth int, functionName string) pcommon.SpanID {
+	if !tc.config.SpanOutput.DeterministicIDs {
+		return tc.generateSpanID()
+	}
+
+	hash := fnv.New64a()
+	hash.Write(traceID[:])
+	hash.Write(binary.BigEndian.AppendUint32(nil, uint32(depth)))
+	hash.Write([]byte(functionName))
+	var spanID pcommon.SpanID
+	binary.BigEndian.PutUint64(spanID[:], hash.Sum64())
+	return spanID
+}
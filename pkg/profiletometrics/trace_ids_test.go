@@ -0,0 +1,95 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// spanIDs lists the trace, span and parent span IDs of every span
+func spanIDs(traces ptrace.Traces) []string {
+	var ids []string
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		span := spans.At(i)
+		ids = append(ids, span.TraceID().String()+"/"+span.SpanID().String()+"/"+span.ParentSpanID().String())
+	}
+	return ids
+}
+
+func TestTraceConverter_DeterministicIDs(t *testing.T) {
+	newProfiles := func(profileID byte) pprofile.Profiles {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.profile.SetProfileID(pprofile.ProfileID{profileID})
+		b.sample(b.stack("main.main", "main.handle", "main.encode"), map[string]string{"process.executable.name": "app"}, 100)
+		return b.profiles
+	}
+	convert := func(t *testing.T, converter *TraceConverter, profiles pprofile.Profiles) []string {
+		traces, err := converter.ConvertProfilesToTraces(context.Background(), profiles)
+		require.NoError(t, err)
+		return spanIDs(traces)
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		converter, err := NewTraceConverter(&ConverterConfig{SpanOutput: TraceSpanConfig{DeterministicIDs: true}})
+		require.NoError(t, err)
+
+		first := convert(t, converter, newProfiles(1))
+		require.Len(t, first, 3)
+		assert.Equal(t, first, convert(t, converter, newProfiles(1)))
+		assert.NotEqual(t, first, convert(t, converter, newProfiles(2)))
+
+		// Every span is a child of the previous one, within one trace
+		traces, err := converter.ConvertProfilesToTraces(context.Background(), newProfiles(1))
+		require.NoError(t, err)
+		spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+		for i := 1; i < spans.Len(); i++ {
+			assert.Equal(t, spans.At(0).TraceID(), spans.At(i).TraceID())
+			assert.Equal(t, spans.At(i-1).SpanID(), spans.At(i).ParentSpanID())
+			assert.NotEqual(t, spans.At(i-1).SpanID(), spans.At(i).SpanID())
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		converter, err := NewTraceConverter(&ConverterConfig{})
+		require.NoError(t, err)
+		assert.NotEqual(t, convert(t, converter, newProfiles(1)), convert(t, converter, newProfiles(1)))
+	})
+}
+
+func TestTraceConverter_ProfileReference(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.profile.SetProfileID(pprofile.ProfileID{0xab, 0xcd})
+	stack := b.stack("main.main", "main.handle")
+	b.sample(stack, map[string]string{"process.executable.name": "app"}, 100)
+	b.sample(stack, map[string]string{"process.executable.name": "app"}, 200)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+	for i := 0; i < spans.Len(); i++ {
+		profileID, ok := spans.At(i).Attributes().Get(profileIDAttributeKey)
+		require.True(t, ok)
+		assert.Equal(t, "abcd0000000000000000000000000000", profileID.Str())
+		sampleCount, _ := spans.At(i).Attributes().Get(profileSampleCountAttributeKey)
+		assert.Equal(t, int64(2), sampleCount.Int())
+	}
+
+	// Profiles without an ID only carry the sample count
+	b.profile.SetProfileID(pprofile.NewProfileIDEmpty())
+	traces, err = converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+	span := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	_, ok := span.Attributes().Get(profileIDAttributeKey)
+	assert.False(t, ok)
+	_, ok = span.Attributes().Get(profileSampleCountAttributeKey)
+	assert.True(t, ok)
+}
@@ -0,0 +1,138 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// rootSpan returns the first span without a parent
+func rootSpan(t *testing.T, traces ptrace.Traces) ptrace.Span {
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		if spans.At(i).ParentSpanID().IsEmpty() {
+			return spans.At(i)
+		}
+	}
+	require.Fail(t, "no root span")
+	return ptrace.Span{}
+}
+
+func TestTraceConverter_SampleTiming(t *testing.T) {
+	profileStart := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	sampleTime := profileStart.Add(3 * time.Second)
+
+	tests := []struct {
+		name             string
+		sampleTimestamps []time.Time
+		profileTime      time.Time
+		expectedStart    time.Time
+	}{
+		{
+			name:             "earliest sample timestamp",
+			sampleTimestamps: []time.Time{sampleTime.Add(time.Second), sampleTime},
+			profileTime:      profileStart,
+			expectedStart:    sampleTime,
+		},
+		{
+			name:          "profile start time",
+			profileTime:   profileStart,
+			expectedStart: profileStart,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewTraceConverter(&ConverterConfig{})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+			b.profile.SetTime(pcommon.NewTimestampFromTime(tt.profileTime))
+			stack := b.stack("main.main", "main.handle")
+			for _, timestamp := range tt.sampleTimestamps {
+				sample := b.sample(stack, map[string]string{"process.executable.name": "app"}, 500000000)
+				sample.TimestampsUnixNano().Append(uint64(timestamp.UnixNano()))
+			}
+			if len(tt.sampleTimestamps) == 0 {
+				b.sample(stack, map[string]string{"process.executable.name": "app"}, 500000000)
+			}
+
+			traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			root := rootSpan(t, traces)
+			assert.Equal(t, tt.expectedStart, root.StartTimestamp().AsTime())
+			if len(tt.sampleTimestamps) > 0 {
+				event := root.Events().At(0)
+				assert.Equal(t, tt.sampleTimestamps[0], event.Timestamp().AsTime())
+			}
+		})
+	}
+
+	t.Run("conversion time without profile time", func(t *testing.T) {
+		converter, err := NewTraceConverter(&ConverterConfig{})
+		require.NoError(t, err)
+
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.sample(b.stack("main.main"), map[string]string{"process.executable.name": "app"}, 1000000000)
+
+		before := time.Now()
+		traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+		require.NoError(t, err)
+
+		root := rootSpan(t, traces)
+		assert.WithinRange(t, root.EndTimestamp().AsTime(), before, time.Now())
+	})
+}
+
+func TestTraceConverter_FrameDurations(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.profile.SetTime(pcommon.NewTimestampFromTime(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)))
+	stack := b.stack("main.main", "main.handle", "main.encode")
+	b.sample(stack, map[string]string{"process.executable.name": "app"}, 300000000)
+	b.sample(stack, map[string]string{"process.executable.name": "app"}, 100000000)
+
+	traces, err := converter.ConvertProfilesToTraces(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	root := rootSpan(t, traces)
+	for _, name := range []string{"main.main", "main.handle", "main.encode"} {
+		span := leafSpan(t, traces, name)
+		// Spans are nested within the root span and last the CPU time of their samples
+		assert.Equal(t, root.StartTimestamp(), span.StartTimestamp(), name)
+		assert.Equal(t, 400*time.Millisecond, span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()), name)
+		total, _ := span.Attributes().Get(totalTimeAttributeKey)
+		assert.Equal(t, int64(400000000), total.Int(), name)
+	}
+	self, _ := leafSpan(t, traces, "main.encode").Attributes().Get(selfTimeAttributeKey)
+	assert.Equal(t, int64(400000000), self.Int())
+	self, _ = leafSpan(t, traces, "main.main").Attributes().Get(selfTimeAttributeKey)
+	assert.Zero(t, self.Int())
+}
+
+func TestTraceConverter_CalculateFrameTimings(t *testing.T) {
+	converter, err := NewTraceConverter(&ConverterConfig{})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	samples := []pprofile.Sample{
+		// Recursive frames count once
+		b.sample(b.stack("main.main", "main.walk", "main.walk"), nil, 300),
+		b.sample(b.stack("main.main"), nil, 100),
+		b.sample(b.stack("main.main", "main.handle"), nil),
+	}
+
+	assert.Equal(t, map[string]frameTiming{
+		"main.walk": {total: 300, self: 300},
+		"main.main": {total: 400, self: 100},
+	}, converter.calculateFrameTimings(b.profiles, samples))
+}
@@ -0,0 +1,74 @@
+package profiletometrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultTraceProfileEventName names the span events carrying a profile payload
+	defaultTraceProfileEventName = "profile"
+
+	// defaultTracePayloadAttribute holds the profile payload of a span or span event
+	defaultTracePayloadAttribute = "profile.data"
+)
+
+// ConvertTracesToMetrics decodes the pprof or OTLP profile payloads attached to spans (see
+// TracesConfig) and converts them to metrics. A payload is read from the span attribute and from
+// each profile event of the span; pprof payloads take the resource attributes of their span. Spans
+// without a payload are ignored and undecodable payloads are skipped with a warning.
+func (c *Converter) ConvertTracesToMetrics(ctx context.Context, traces ptrace.Traces) (pmetric.Metrics, error) {
+	eventName, payloadAttribute := c.config.Traces.EventName, c.config.Traces.PayloadAttribute
+	if eventName == "" {
+		eventName = defaultTraceProfileEventName
+	}
+	if payloadAttribute == "" {
+		payloadAttribute = defaultTracePayloadAttribute
+	}
+
+	metrics := pmetric.NewMetrics()
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		resourceSpans := traces.ResourceSpans().At(i)
+		for j := 0; j < resourceSpans.ScopeSpans().Len(); j++ {
+			spans := resourceSpans.ScopeSpans().At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				payloads := []pcommon.Map{span.Attributes()}
+				for l := 0; l < span.Events().Len(); l++ {
+					if event := span.Events().At(l); event.Name() == eventName {
+						payloads = append(payloads, event.Attributes())
+					}
+				}
+				for _, attributes := range payloads {
+					value, exists := attributes.Get(payloadAttribute)
+					if !exists {
+						continue
+					}
+					payload, ok, err := profilePayloadBytes(value)
+					if err == nil && ok {
+						var profiles pprofile.Profiles
+						profiles, err = decodeProfilePayload(payload, c.config.Traces.PayloadFormat, resourceSpans.Resource())
+						if err == nil {
+							var converted pmetric.Metrics
+							if converted, err = c.ConvertProfilesToMetrics(ctx, profiles); err != nil {
+								return metrics, err
+							}
+							converted.ResourceMetrics().MoveAndAppendTo(metrics.ResourceMetrics())
+						}
+					}
+					if err != nil {
+						c.logWarn("Skipping span with an undecodable profile payload",
+							zap.Int("resource_index", i), zap.Int("scope_index", j),
+							zap.String("span_name", span.Name()), zap.Error(err))
+					}
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,76 @@
+package profiletometrics
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTracesTestConverter(t *testing.T, cfg TracesConfig) *Converter {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+		Traces:  cfg,
+	})
+	require.NoError(t, err)
+	return converter
+}
+
+func TestConverter_ConvertTracesToMetrics_ProfileEvents(t *testing.T) {
+	converter := newTracesTestConverter(t, TracesConfig{})
+
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutStr("service.name", "checkout")
+	spans := resourceSpans.ScopeSpans().AppendEmpty().Spans()
+	span := spans.AppendEmpty()
+	span.SetName("GET /cart")
+	span.Events().AppendEmpty().Attributes().PutStr("profile.data", "ignored: not a profile event")
+	event := span.Events().AppendEmpty()
+	event.SetName("profile")
+	event.Attributes().PutStr("profile.data", base64.StdEncoding.EncodeToString(encodePprof(t, newGoCPUPprof())))
+	// Spans without a payload are ignored
+	spans.AppendEmpty().SetName("GET /health")
+
+	metrics, err := converter.ConvertTracesToMetrics(context.Background(), traces)
+	require.NoError(t, err)
+	// 40 samples weighted with the 10ms period
+	assert.InDelta(t, 0.4, logsCPUTime(metrics), 1e-9)
+	// pprof payloads take the resource attributes of their span
+	metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metricSlice.Len(); i++ {
+		if metric := metricSlice.At(i); metric.Name() == "cpu_time" {
+			serviceName, ok := metric.Gauge().DataPoints().At(0).Attributes().Get("service.name")
+			require.True(t, ok)
+			assert.Equal(t, "checkout", serviceName.Str())
+		}
+	}
+}
+
+func TestConverter_ConvertTracesToMetrics_OTLPSpanAttribute(t *testing.T) {
+	converter := newTracesTestConverter(t, TracesConfig{PayloadAttribute: "pyroscope.profile", PayloadFormat: LogPayloadFormatOTLP})
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main", "hot"), nil, 2000000000)
+	payload, err := (&pprofile.ProtoMarshaler{}).MarshalProfiles(b.profiles)
+	require.NoError(t, err)
+
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	spans.AppendEmpty().Attributes().PutEmptyBytes("pyroscope.profile").FromRaw(payload)
+	// Undecodable payloads are skipped
+	spans.AppendEmpty().Attributes().PutStr("pyroscope.profile", "not base64!")
+
+	metrics, err := converter.ConvertTracesToMetrics(context.Background(), traces)
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, logsCPUTime(metrics), 1e-9)
+}
+
+func TestNewConverter_InvalidTracesPayloadFormat(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{Traces: TracesConfig{PayloadFormat: "jfr"}})
+	assert.Error(t, err)
+}
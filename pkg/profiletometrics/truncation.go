@@ -0,0 +1,75 @@
+package profiletometrics
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// stackTruncatedAttributeKey marks data points aggregated from truncated stacks
+const stackTruncatedAttributeKey = "stack.truncated"
+
+// stackTruncatedFlagKeys are sample attributes profilers use to flag truncated stacks
+var stackTruncatedFlagKeys = []string{"stack.truncated", "profile.stack.truncated"}
+
+// truncatedFrameNames are marker frames added by profilers in place of dropped frames
+var truncatedFrameNames = map[string]bool{
+	"[truncated]":       true,
+	"<truncated>":       true,
+	"[truncated stack]": true,
+}
+
+// isTruncatedSample reports whether a sample's stack was truncated by the profiler
+func (c *Converter) isTruncatedSample(profiles dictionaryProvider, sample pprofile.Sample) bool {
+	for _, key := range stackTruncatedFlagKeys {
+		if strings.EqualFold(c.getSampleAttributeValue(profiles, sample, key), "true") {
+			return true
+		}
+	}
+
+	locationIndices, ok := stackLocationIndicesCommon(profiles, sample.StackIndex())
+	if !ok {
+		return false
+	}
+	if maxDepth := c.config.TruncatedStacks.MaxDepth; maxDepth > 0 && locationIndices.Len() >= maxDepth {
+		return true
+	}
+
+	for i := 0; i < locationIndices.Len(); i++ {
+		location, ok := locationAtCommon(profiles, locationIndices.At(i))
+		if !ok {
+			continue
+		}
+		if truncatedFrameNames[c.getLocationFunctionName(profiles, location)] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTruncatedSamples splits a profile into copies holding the complete and the truncated samples
+func (c *Converter) splitTruncatedSamples(profiles dictionaryProvider, profile pprofile.Profile) (pprofile.Profile, pprofile.Profile) {
+	complete := pprofile.NewProfile()
+	profile.CopyTo(complete)
+	truncated := pprofile.NewProfile()
+	profile.CopyTo(truncated)
+
+	truncatedSamples := make([]bool, profile.Sample().Len())
+	for i := 0; i < profile.Sample().Len(); i++ {
+		truncatedSamples[i] = c.isTruncatedSample(profiles, profile.Sample().At(i))
+	}
+
+	index := 0
+	complete.Sample().RemoveIf(func(pprofile.Sample) bool {
+		index++
+		return truncatedSamples[index-1]
+	})
+	index = 0
+	truncated.Sample().RemoveIf(func(pprofile.Sample) bool {
+		index++
+		return !truncatedSamples[index-1]
+	})
+
+	summaryOf(profiles).truncatedStacks.Add(int64(truncated.Sample().Len()))
+	return complete, truncated
+}
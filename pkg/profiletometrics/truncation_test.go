@@ -0,0 +1,58 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestConverter_IsTruncatedSample(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{TruncatedStacks: TruncatedStackConfig{Enabled: true, MaxDepth: 4}})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	complete := b.sample(b.stack("main", "handler"), nil, 1)
+	flagged := b.sample(b.stack("main", "handler"), map[string]string{"profile.stack.truncated": "true"}, 1)
+	marker := b.sample(b.stack("[truncated]", "handler"), nil, 1)
+	deep := b.sample(b.stack("a", "b", "c", "d"), nil, 1)
+
+	assert.False(t, converter.isTruncatedSample(b.profiles, complete))
+	assert.True(t, converter.isTruncatedSample(b.profiles, flagged))
+	assert.True(t, converter.isTruncatedSample(b.profiles, marker))
+	assert.True(t, converter.isTruncatedSample(b.profiles, deep))
+}
+
+func TestConverter_TruncatedStackFunctionMetrics(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+			Memory:   MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+			Function: FunctionMetricConfig{Enabled: true},
+		},
+		TruncatedStacks: TruncatedStackConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder()
+	process := map[string]string{"process.executable.name": "app"}
+	b.sample(b.stack("main", "work"), process, 2000000000, 0)
+	b.sample(b.stack("[truncated]", "work"), process, 1000000000, 0)
+
+	summary := &conversionSummary{}
+	cache := newDictionaryCache(b.profiles, functionNameRules{})
+	cache.summary = summary
+	scopeMetrics := pmetric.NewScopeMetrics()
+	converter.generateFunctionMetrics(cache, b.profile, map[string]string{}, scopeMetrics)
+
+	values := make(map[bool]float64)
+	dataPoints := scopeMetrics.Metrics().At(0).Gauge().DataPoints()
+	for i := 0; i < dataPoints.Len(); i++ {
+		dp := dataPoints.At(i)
+		truncated, _ := dp.Attributes().Get("stack.truncated")
+		values[truncated.Bool()] += dp.DoubleValue()
+	}
+	assert.Equal(t, map[bool]float64{false: 2, true: 1}, values)
+	assert.Equal(t, int64(1), summary.truncatedStacks.Load())
+}
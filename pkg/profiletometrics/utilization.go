@@ -0,0 +1,65 @@
+package profiletometrics
+
+import (
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// ratioUnit is the UCUM unit of dimensionless ratios
+const ratioUnit = "1"
+
+// generateCPUUtilizationMetrics emits the sampled CPU time of a profile divided by its duration and
+// the configured cores, for the whole profile and for each process. Profiles without a duration or
+// of a non-CPU sample type have no utilization. With process_filter enabled only the matched
+// processes are reported, along with the whole profile when keep_global_metrics is set.
+func (c *Converter) generateCPUUtilizationMetrics(
+	profiles dictionaryProvider,
+	profile pprofile.Profile,
+	attributes map[string]string,
+	scopeMetrics pmetric.ScopeMetrics,
+	matchedProcessNames []string,
+) {
+	sampleType, _ := getProfileSampleTypeCommon(profiles, profile)
+	if profile.Duration() == 0 || !isCPUSampleType(sampleType) {
+		return
+	}
+	cores := c.config.Metrics.CPUUtilization.Cores
+	if cores == 0 {
+		cores = 1
+	}
+	capacity := time.Duration(profile.Duration()).Seconds() * float64(cores)
+
+	processNames := matchedProcessNames
+	if !c.config.ProcessFilter.Enabled {
+		processNames = c.getUniqueProcessNames(profiles, profile)
+	}
+	sort.Strings(processNames)
+
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(c.config.Metrics.CPUUtilization.MetricName)
+	metric.SetDescription("Sampled CPU time as a ratio of the profile duration and CPU cores")
+	metric.SetUnit(ratioUnit)
+	gauge := metric.SetEmptyGauge()
+
+	appendUtilization := func(filter map[string]string, processName string) {
+		measured, estimated := c.calculateCPUTimeTotalsForFilter(profiles, profile, filter)
+		dataPoint := gauge.DataPoints().AppendEmpty()
+		c.setDataPointTimestamps(dataPoint, profile)
+		dataPoint.SetDoubleValue((measured + estimated) / capacity)
+		for k, v := range attributes {
+			dataPoint.Attributes().PutStr(k, v)
+		}
+		if processName != "" {
+			dataPoint.Attributes().PutStr("process.name", processName)
+		}
+	}
+	if c.emitsGlobalMetrics() {
+		appendUtilization(nil, "")
+	}
+	for _, processName := range processNames {
+		appendUtilization(map[string]string{"process.executable.name": processName}, processName)
+	}
+}
@@ -0,0 +1,134 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// cpuUtilizationValues returns the cpu.utilization data points keyed by process name
+func cpuUtilizationValues(t *testing.T, metrics pmetric.Metrics) map[string]float64 {
+	values := make(map[string]float64)
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			for k := 0; k < scopeMetrics.At(j).Metrics().Len(); k++ {
+				metric := scopeMetrics.At(j).Metrics().At(k)
+				if metric.Name() != "cpu.utilization" {
+					continue
+				}
+				assert.Equal(t, ratioUnit, metric.Unit())
+				for l := 0; l < metric.Gauge().DataPoints().Len(); l++ {
+					dataPoint := metric.Gauge().DataPoints().At(l)
+					processName, _ := dataPoint.Attributes().Get("process.name")
+					values[processName.Str()] = dataPoint.DoubleValue()
+				}
+			}
+		}
+	}
+	return values
+}
+
+func TestConverter_CPUUtilization(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPUUtilization: CPUUtilizationMetricConfig{Enabled: true, MetricName: "cpu.utilization", Cores: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "app"}, int64(time.Second))
+	b.sample(b.stack("main"), map[string]string{"process.executable.name": "db"}, int64(500*time.Millisecond))
+
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+
+	values := cpuUtilizationValues(t, metrics)
+	require.Len(t, values, 3)
+	assert.InDelta(t, 0.075, values[""], 1e-9)
+	assert.InDelta(t, 0.05, values["app"], 1e-9)
+	assert.InDelta(t, 0.025, values["db"], 1e-9)
+}
+
+func TestConverter_CPUUtilizationSkipsProfilesWithoutDuration(t *testing.T) {
+	converter, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{
+			CPUUtilization: CPUUtilizationMetricConfig{Enabled: true, MetricName: "cpu.utilization"},
+		},
+	})
+	require.NoError(t, err)
+
+	b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+	b.sample(b.stack("main"), nil, int64(time.Second))
+	metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Empty(t, cpuUtilizationValues(t, metrics))
+
+	// Memory profiles have no utilization either
+	b = newTestProfileBuilder().withSampleType("alloc_space", "bytes")
+	b.profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+	b.sample(b.stack("main"), nil, 1024)
+	metrics, err = converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+	require.NoError(t, err)
+	assert.Empty(t, cpuUtilizationValues(t, metrics))
+}
+
+func TestConverter_CPUUtilizationStaysGauge(t *testing.T) {
+	// convert converts a profile of the given CPU seconds over 10 seconds
+	convert := func(t *testing.T, converter *Converter, cpuSeconds float64) pmetric.Metrics {
+		b := newTestProfileBuilder().withSampleType("cpu", "nanoseconds")
+		b.profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+		b.sample(b.stack("main"), nil, int64(cpuSeconds*float64(time.Second)))
+		metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+		require.NoError(t, err)
+		return metrics
+	}
+	utilization := func(t *testing.T, metrics pmetric.Metrics) pmetric.Metric {
+		metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		require.Equal(t, 1, metricSlice.Len())
+		require.Equal(t, pmetric.MetricTypeGauge, metricSlice.At(0).Type())
+		return metricSlice.At(0)
+	}
+	cfg := &ConverterConfig{
+		Metrics: MetricsConfig{
+			CPUUtilization: CPUUtilizationMetricConfig{Enabled: true, MetricName: "cpu.utilization"},
+		},
+	}
+
+	t.Run("aggregation_temporality", func(t *testing.T) {
+		temporalityCfg := *cfg
+		temporalityCfg.AggregationTemporality = aggregationTemporalityCumulative
+		converter, err := NewConverter(&temporalityCfg)
+		require.NoError(t, err)
+
+		convert(t, converter, 5)
+		// A drop in utilization is not a counter reset
+		metric := utilization(t, convert(t, converter, 2))
+		assert.InDelta(t, 0.2, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9)
+	})
+
+	t.Run("flush_interval", func(t *testing.T) {
+		converter, err := NewConverter(cfg)
+		require.NoError(t, err)
+
+		aggregator := NewIntervalAggregator(converter)
+		aggregator.Add(convert(t, converter, 5))
+		aggregator.Add(convert(t, converter, 2))
+		metric := utilization(t, aggregator.Flush())
+		assert.InDelta(t, 0.2, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-9, "ratios are not added up")
+	})
+}
+
+func TestNewConverter_NegativeCPUUtilizationCores(t *testing.T) {
+	_, err := NewConverter(&ConverterConfig{
+		Metrics: MetricsConfig{CPUUtilization: CPUUtilizationMetricConfig{Enabled: true, Cores: -1}},
+	})
+	assert.Error(t, err)
+}
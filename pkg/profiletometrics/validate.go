@@ -0,0 +1,208 @@
+package profiletometrics
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// metricNamePattern is the OpenTelemetry instrument name syntax
+var metricNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_./-]{0,254}$`)
+
+// Validate checks the converter configuration at startup: option values, the regexes of the
+// process, thread, pattern and function filters and of the attribute rules, the configured metric
+// names and conflicting options. Every problem found is reported. NewConverter and
+// NewTraceConverter call it.
+func (cfg *ConverterConfig) Validate() error {
+	errs := []error{
+		validateAggregationTemporality(cfg.AggregationTemporality),
+		validateAggregationWindow(cfg.AggregationWindow),
+		validateFunctionAttribution(cfg.Metrics.Function.Attribution),
+		validateFunctionGroupBy(cfg.Metrics.Function.GroupBy),
+		validateMetricsGroupBy(cfg.Metrics.GroupBy),
+		validateAggregation("metrics.cpu.aggregation", cfg.Metrics.CPU.Aggregation),
+		validateAggregation("metrics.memory.aggregation", cfg.Metrics.Memory.Aggregation),
+		validateEstimation(cfg.Estimation),
+		validateCodeOrigin(cfg.Metrics.CodeOrigin),
+		validateArrayAttributes(cfg.ArrayAttributes),
+		validateAttributes(cfg.Attributes),
+		validateAttributeSelection(cfg.AttributeSelection),
+		validateTimestampSource(cfg.TimestampSource),
+		validateFoldedStack(cfg.FoldedStack),
+		validateNormalization(cfg.Normalization),
+		validateSymbol(cfg.Symbol),
+		validateTraceDedup(cfg.TraceDedup),
+		validateLogs(cfg.Logs),
+		validatePayloadFormat("traces.payload_format", cfg.Traces.PayloadFormat),
+		validateMaxAttributeValueLength(cfg.MaxAttributeValueLength),
+		validateDegradation(cfg.Degradation),
+		validateSampling(cfg.Sampling),
+		validateStaleness(cfg.Staleness),
+		validateFunctionAnomaly(cfg.Metrics.Function.Anomaly),
+		validateOutputLayout(cfg.OutputLayout),
+		validateResourceAttributePlacement(cfg.ResourceAttributePlacement, cfg.OutputLayout),
+		cfg.validateRegexes(),
+		cfg.validateLimits(),
+		cfg.validateMetricNames(),
+		cfg.validateMetricOverrides(),
+		cfg.validateConflicts(),
+	}
+	return errors.Join(errs...)
+}
+
+// validateRegexes compiles the regexes and rules of the filters, the frame selection, the
+// ownership rules, the attribute mapping and the custom metrics
+func (cfg *ConverterConfig) validateRegexes() error {
+	_, frameErr := compileFrameSelection(cfg.FrameSelection)
+	_, threadErr := compileThreadFilter(cfg.ThreadFilter)
+	_, patternErr := newPatternFilter(cfg.PatternFilter)
+	_, functionErr := newFunctionFilter(cfg.FunctionFilter)
+	_, processErr := newProcessFilter(cfg.ProcessFilter)
+	_, ownershipErr := compileOwnershipRules(cfg.Ownership)
+	_, mappingErr := compileAttributeMapping(cfg.AttributeMapping)
+	_, customErr := compileCustomMetrics(cfg.Metrics.Custom)
+	return errors.Join(frameErr, threadErr, patternErr, functionErr, processErr, ownershipErr, mappingErr, customErr)
+}
+
+// validateLimits checks the numeric options
+func (cfg *ConverterConfig) validateLimits() error {
+	var errs []error
+	if cfg.AggregationMaxSeries < 0 {
+		errs = append(errs, fmt.Errorf("aggregation_max_series must not be negative"))
+	}
+	if cfg.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency must not be negative"))
+	}
+	if cfg.StreamFlushDataPoints < 0 {
+		errs = append(errs, fmt.Errorf("stream_flush_data_points must not be negative"))
+	}
+	if cfg.SpanOutput.MaxEventsPerSpan < 0 {
+		errs = append(errs, fmt.Errorf("span_output.max_events_per_span must not be negative"))
+	}
+	if cfg.SpanOutput.MaxSpansPerProfile < 0 {
+		errs = append(errs, fmt.Errorf("span_output.max_spans_per_profile must not be negative"))
+	}
+	if cfg.DiagnosticsHistory < 0 {
+		errs = append(errs, fmt.Errorf("diagnostics_history must not be negative"))
+	}
+	if cfg.Metrics.HotPath.MaxPathLength < 0 {
+		errs = append(errs, fmt.Errorf("metrics.hot_path.max_path_length must not be negative"))
+	}
+	if cfg.Metrics.CPUUtilization.Cores < 0 {
+		errs = append(errs, fmt.Errorf("metrics.cpu_utilization.cores must not be negative"))
+	}
+	function := cfg.Metrics.Function
+	if function.RollingTopK.Enabled && function.RollingTopK.K <= 0 {
+		errs = append(errs, fmt.Errorf("metrics.function.rolling_top_k.k must be positive when enabled"))
+	}
+	if function.Slope.Enabled && function.Slope.Window < 0 {
+		errs = append(errs, fmt.Errorf("metrics.function.slope.window must not be negative"))
+	}
+	if function.Diff.Enabled && function.Diff.BaselineTTL < 0 {
+		errs = append(errs, fmt.Errorf("metrics.function.diff.baseline_ttl must not be negative"))
+	}
+	if heat := function.HeatBuckets; heat.Enabled && heat.HotThresholdPercent > 0 &&
+		heat.WarmThresholdPercent > heat.HotThresholdPercent {
+		errs = append(errs, fmt.Errorf("metrics.function.heat_buckets.warm_threshold_percent must not exceed hot_threshold_percent"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateMetricNames checks the configured metric names, prefixes and suffixes against the
+// OpenTelemetry instrument name syntax; empty names keep their defaults
+func (cfg *ConverterConfig) validateMetricNames() error {
+	metrics := cfg.Metrics
+	names := []struct {
+		option string
+		name   string
+	}{
+		{"metrics.cpu.metric_name", metrics.CPU.MetricName},
+		{"metrics.memory.metric_name", metrics.Memory.MetricName},
+		{"metrics.cpu_utilization.metric_name", metrics.CPUUtilization.MetricName},
+		{"metrics.process.cpu_metric_name", metrics.Process.CPUMetricName},
+		{"metrics.process.memory_metric_name", metrics.Process.MemoryMetricName},
+		{"metrics.function.slope.metric_name", metrics.Function.Slope.MetricName},
+		{"metrics.function.diff.delta_metric_name", metrics.Function.Diff.DeltaMetricName},
+		{"metrics.function.diff.change_metric_name", metrics.Function.Diff.ChangeMetricName},
+		{"metrics.function.anomaly.metric_name", metrics.Function.Anomaly.MetricName},
+		{"metrics.hottest_stack.metric_name", metrics.HottestStack.MetricName},
+		{"metrics.hot_path.metric_name", metrics.HotPath.MetricName},
+		{"metrics.stack.average_depth_metric_name", metrics.Stack.AverageDepthMetricName},
+		{"metrics.stack.max_depth_metric_name", metrics.Stack.MaxDepthMetricName},
+		{"metrics.stack.truncated_metric_name", metrics.Stack.TruncatedMetricName},
+		{"metrics.symbolization.metric_name", metrics.Symbolization.MetricName},
+		{"metrics.code_origin.metric_name", metrics.CodeOrigin.MetricName},
+		{"metrics.frame_type.metric_name", metrics.FrameType.MetricName},
+		{"metrics.kernel_user.kernel_metric_name", metrics.KernelUser.KernelMetricName},
+		{"metrics.kernel_user.user_metric_name", metrics.KernelUser.UserMetricName},
+		{"metrics.callgraph.metric_name", metrics.CallGraph.MetricName},
+		{"metrics.trace_correlation.metric_name", metrics.TraceCorrelation.MetricName},
+		{"metrics.runtime.heap_live_metric_name", metrics.Runtime.HeapLiveMetricName},
+		{"metrics.runtime.gc_cpu_share_metric_name", metrics.Runtime.GCCPUShareMetricName},
+		{"metrics.ingestion.metric_prefix", metrics.Ingestion.MetricPrefix},
+		{"metrics.lock.contention_time_metric_name", metrics.Lock.ContentionTimeMetricName},
+		{"metrics.lock.contention_count_metric_name", metrics.Lock.ContentionCountMetricName},
+		{"metrics.exceptions.metric_name", metrics.Exceptions.MetricName},
+		{"degradation.metric_prefix", cfg.Degradation.MetricPrefix},
+	}
+	var errs []error
+	for _, entry := range names {
+		if entry.name != "" && !metricNamePattern.MatchString(entry.name) {
+			errs = append(errs, fmt.Errorf("%s %q is not a valid metric name: it must start with a letter "+
+				"and contain at most 255 letters, digits, '_', '.', '-' or '/'", entry.option, entry.name))
+		}
+	}
+
+	suffixes := []struct {
+		option string
+		suffix string
+	}{
+		{"metrics.process.metric_name_suffix", metrics.Process.MetricNameSuffix},
+		{"metrics.thread.metric_name_suffix", metrics.Thread.MetricNameSuffix},
+		{"metrics.container.metric_name_suffix", metrics.Container.MetricNameSuffix},
+		{"metrics.function.metric_name_suffix", metrics.Function.MetricNameSuffix},
+	}
+	for _, entry := range suffixes {
+		if entry.suffix != "" && !metricNamePattern.MatchString("a"+entry.suffix) {
+			errs = append(errs, fmt.Errorf("%s %q may only contain letters, digits, '_', '.', '-' or '/'",
+				entry.option, entry.suffix))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateConflicts rejects options that contradict each other
+func (cfg *ConverterConfig) validateConflicts() error {
+	var errs []error
+	if cfg.UseProfileTimestamps && cfg.TimestampSource != "" && cfg.TimestampSource != timestampSourceReceiveTime &&
+		cfg.TimestampSource != timestampSourceProfileEnd {
+		errs = append(errs, fmt.Errorf("use_profile_timestamps selects profile_end and conflicts with timestamp_source %q",
+			cfg.TimestampSource))
+	}
+	if cfg.AggregationMaxSeries > 0 && cfg.AggregationTemporality == "" {
+		errs = append(errs, fmt.Errorf("aggregation_max_series requires aggregation_temporality"))
+	}
+	function := cfg.Metrics.Function
+	if !function.Enabled && function.RollingTopK.Enabled {
+		errs = append(errs, fmt.Errorf("metrics.function.rolling_top_k requires metrics.function.enabled"))
+	}
+	if !function.Enabled && function.Slope.Enabled {
+		errs = append(errs, fmt.Errorf("metrics.function.slope requires metrics.function.enabled"))
+	}
+	if !function.Enabled && function.Diff.Enabled {
+		errs = append(errs, fmt.Errorf("metrics.function.diff requires metrics.function.enabled"))
+	}
+	if !function.Enabled && function.Anomaly.Enabled {
+		errs = append(errs, fmt.Errorf("metrics.function.anomaly requires metrics.function.enabled"))
+	}
+	if len(cfg.GroupByResourceAttributes) > 0 && cfg.OutputLayout == outputLayoutPerResource {
+		errs = append(errs, fmt.Errorf("group_by_resource_attributes merges resources and requires output_layout %q",
+			outputLayoutMerged))
+	}
+	for _, key := range cfg.GroupByResourceAttributes {
+		if !attributeSelected(cfg.AttributeSelection, key) {
+			errs = append(errs, fmt.Errorf("group_by_resource_attributes key %q is dropped by attribute_selection", key))
+		}
+	}
+	return errors.Join(errs...)
+}
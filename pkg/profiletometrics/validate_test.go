@@ -0,0 +1,133 @@
+package profiletometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverterConfig_Validate(t *testing.T) {
+	valid := func() *ConverterConfig {
+		return &ConverterConfig{
+			Metrics: MetricsConfig{
+				CPU:      CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+				Process:  ProcessMetricConfig{Enabled: true, MetricNameSuffix: ".by_process"},
+				Function: FunctionMetricConfig{Enabled: true, MetricNameSuffix: ".by_function"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(cfg *ConverterConfig)
+		expectedError string
+	}{
+		{
+			name:   "Valid configuration",
+			modify: func(*ConverterConfig) {},
+		},
+		{
+			name: "Invalid process filter regex",
+			modify: func(cfg *ConverterConfig) {
+				cfg.ProcessFilter = ProcessFilterConfig{Enabled: true, Patterns: []string{"("}}
+			},
+			expectedError: "process_filter.patterns",
+		},
+		{
+			name:          "Invalid thread filter regex",
+			modify:        func(cfg *ConverterConfig) { cfg.ThreadFilter = ThreadFilterConfig{Enabled: true, Pattern: "["} },
+			expectedError: "thread_filter",
+		},
+		{
+			name: "Invalid pattern filter regex",
+			modify: func(cfg *ConverterConfig) {
+				cfg.PatternFilter = PatternFilterConfig{Enabled: true, Rules: []PatternFilterRule{{AttributeKey: "service.name", Regex: "("}}}
+			},
+			expectedError: "pattern",
+		},
+		{
+			name: "Invalid function filter regex",
+			modify: func(cfg *ConverterConfig) {
+				cfg.FunctionFilter = FunctionFilterConfig{Enabled: true, Exclude: []string{"("}}
+			},
+			expectedError: "function_filter",
+		},
+		{
+			name: "Invalid attribute pattern",
+			modify: func(cfg *ConverterConfig) {
+				cfg.Attributes = []AttributeConfig{{Key: "tid", Value: "thread.id", Type: attrTypeSampleAttribute, Pattern: "("}}
+			},
+			expectedError: "attributes[0]",
+		},
+		{
+			name:          "Invalid metric name",
+			modify:        func(cfg *ConverterConfig) { cfg.Metrics.CPU.MetricName = "cpu time" },
+			expectedError: `metrics.cpu.metric_name "cpu time" is not a valid metric name`,
+		},
+		{
+			name:          "Metric name starting with a digit",
+			modify:        func(cfg *ConverterConfig) { cfg.Metrics.Exceptions.MetricName = "1errors" },
+			expectedError: "metrics.exceptions.metric_name",
+		},
+		{
+			name:          "Invalid metric name suffix",
+			modify:        func(cfg *ConverterConfig) { cfg.Metrics.Function.MetricNameSuffix = " by function" },
+			expectedError: "metrics.function.metric_name_suffix",
+		},
+		{
+			name: "Timestamp options conflict",
+			modify: func(cfg *ConverterConfig) {
+				cfg.UseProfileTimestamps = true
+				cfg.TimestampSource = timestampSourceSample
+			},
+			expectedError: "conflicts with timestamp_source",
+		},
+		{
+			name:          "Max series without temporality",
+			modify:        func(cfg *ConverterConfig) { cfg.AggregationMaxSeries = 100 },
+			expectedError: "aggregation_max_series requires aggregation_temporality",
+		},
+		{
+			name: "Slope without function metrics",
+			modify: func(cfg *ConverterConfig) {
+				cfg.Metrics.Function.Enabled = false
+				cfg.Metrics.Function.Slope = FunctionSlopeConfig{Enabled: true}
+			},
+			expectedError: "metrics.function.slope requires metrics.function.enabled",
+		},
+		{
+			name: "Grouping by an attribute that is not selected",
+			modify: func(cfg *ConverterConfig) {
+				cfg.GroupByResourceAttributes = []string{"k8s.pod.name"}
+				cfg.AttributeSelection = AttributeSelectionConfig{Include: []string{"service.*"}}
+			},
+			expectedError: `group_by_resource_attributes key "k8s.pod.name" is dropped by attribute_selection`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestConverterConfig_ValidateReportsEveryProblem(t *testing.T) {
+	cfg := &ConverterConfig{
+		Metrics:        MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu time"}},
+		FunctionFilter: FunctionFilterConfig{Enabled: true, Include: []string{"("}},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metrics.cpu.metric_name")
+	assert.Contains(t, err.Error(), "function_filter")
+}
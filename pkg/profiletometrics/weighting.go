@@ -0,0 +1,59 @@
+package profiletometrics
+
+import (
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// timeUnitNanoseconds maps the time units of sample and period types to nanoseconds
+var timeUnitNanoseconds = map[string]float64{
+	"nanoseconds":  1,
+	"ns":           1,
+	"microseconds": 1e3,
+	"us":           1e3,
+	"milliseconds": 1e6,
+	"ms":           1e6,
+	"seconds":      1e9,
+	"s":            1e9,
+}
+
+// countUnits are sample type units of count-based profiles, whose values are numbers of samples
+var countUnits = map[string]bool{
+	"count":   true,
+	"samples": true,
+}
+
+// cpuWeight converts the values of a profile's samples into CPU seconds
+type cpuWeight struct {
+	// nanosecondsPerValue is the CPU time of one unit of a sample value
+	nanosecondsPerValue float64
+	// sampleCount is the number of samples of the profile, used to estimate samples without values
+	sampleCount int
+}
+
+// profileCPUWeight derives the CPU time of a sample value from the profile's sample type and period:
+//   - time units (e.g. "nanoseconds", "ms") are converted to seconds
+//   - count-based profiles (e.g. 99Hz samplers) weigh each sample with the period, when the period
+//     type is a time unit: samples × period
+//   - anything else keeps the historical interpretation of values as nanoseconds
+func (c *Converter) profileCPUWeight(dictionary dictionaryProvider, profile pprofile.Profile) cpuWeight {
+	weight := cpuWeight{nanosecondsPerValue: 1, sampleCount: profile.Sample().Len()}
+	stringTable := dictionary.Dictionary().StringTable()
+
+	unit := stringTableValue(stringTable, profile.SampleType().UnitStrindex())
+	if nanoseconds, ok := timeUnitNanoseconds[unit]; ok {
+		weight.nanosecondsPerValue = nanoseconds
+		return weight
+	}
+	if countUnits[unit] && profile.Period() > 0 {
+		periodUnit := stringTableValue(stringTable, profile.PeriodType().UnitStrindex())
+		if nanoseconds, ok := timeUnitNanoseconds[periodUnit]; ok {
+			weight.nanosecondsPerValue = float64(profile.Period()) * nanoseconds
+		}
+	}
+	return weight
+}
+
+// seconds converts a sample value into CPU seconds
+func (w cpuWeight) seconds(value int64) float64 {
+	return float64(value) * w.nanosecondsPerValue / nanosecondsPerSecond
+}
@@ -0,0 +1,76 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConverter_SampleWeighting(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampleType  string
+		unit        string
+		period      int64
+		periodUnit  string
+		value       int64
+		expectedCPU float64
+	}{
+		{
+			name:        "nanoseconds",
+			sampleType:  "cpu",
+			unit:        "nanoseconds",
+			value:       1500000000,
+			expectedCPU: 1.5,
+		},
+		{
+			name:        "milliseconds",
+			sampleType:  "cpu",
+			unit:        "ms",
+			value:       250,
+			expectedCPU: 0.25,
+		},
+		{
+			name:        "99Hz sample counts",
+			sampleType:  "samples",
+			unit:        "count",
+			period:      10101010,
+			periodUnit:  "nanoseconds",
+			value:       99,
+			expectedCPU: 0.99999999,
+		},
+		{
+			name:        "sample counts without period",
+			sampleType:  "samples",
+			unit:        "count",
+			value:       99,
+			expectedCPU: 99e-9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{CPU: CPUMetricConfig{Enabled: true, MetricName: "cpu_time"}},
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder().withSampleType(tt.sampleType, tt.unit)
+			if tt.period > 0 {
+				b.profile.SetPeriod(tt.period)
+				b.profile.PeriodType().SetTypeStrindex(b.str("cpu"))
+				b.profile.PeriodType().SetUnitStrindex(b.str(tt.periodUnit))
+			}
+			b.sample(b.stack("main"), nil, tt.value)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metric := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+			assert.Equal(t, "cpu_time", metric.Name())
+			assert.InDelta(t, tt.expectedCPU, metric.Gauge().DataPoints().At(0).DoubleValue(), 1e-12)
+		})
+	}
+}
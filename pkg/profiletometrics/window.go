@@ -0,0 +1,48 @@
+package profiletometrics
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// aggregationWindowAttributeKey holds the UTC start of the aggregation window of a data point
+const aggregationWindowAttributeKey = "aggregation.window.start"
+
+// validateAggregationWindow checks that the window aligns to UTC day boundaries
+func validateAggregationWindow(window time.Duration) error {
+	const day = 24 * time.Hour
+	if window < 0 || (window > 0 && day%window != 0 && window%day != 0) {
+		return fmt.Errorf("invalid aggregation_window %s: must divide 24h or be a whole number of days", window)
+	}
+	return nil
+}
+
+// stampAggregationWindows adds the UTC-truncated window start to every data point, based on the
+// data point timestamp
+func (c *Converter) stampAggregationWindows(metrics pmetric.Metrics) {
+	window := c.config.AggregationWindow
+	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
+		scopeMetrics := metrics.ResourceMetrics().At(i).ScopeMetrics()
+		for j := 0; j < scopeMetrics.Len(); j++ {
+			metricSlice := scopeMetrics.At(j).Metrics()
+			for k := 0; k < metricSlice.Len(); k++ {
+				var dataPoints pmetric.NumberDataPointSlice
+				switch metric := metricSlice.At(k); metric.Type() {
+				case pmetric.MetricTypeGauge:
+					dataPoints = metric.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dataPoints = metric.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dataPoints.Len(); l++ {
+					dataPoint := dataPoints.At(l)
+					start := dataPoint.Timestamp().AsTime().UTC().Truncate(window)
+					dataPoint.Attributes().PutStr(aggregationWindowAttributeKey, start.Format(time.RFC3339))
+				}
+			}
+		}
+	}
+}
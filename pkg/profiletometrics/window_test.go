@@ -0,0 +1,62 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestConverter_AggregationWindow(t *testing.T) {
+	tests := []struct {
+		window   time.Duration
+		expected string
+	}{
+		{window: time.Hour, expected: "2026-10-15T13:00:00Z"},
+		{window: 24 * time.Hour, expected: "2026-10-15T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window.String(), func(t *testing.T) {
+			converter, err := NewConverter(&ConverterConfig{
+				Metrics: MetricsConfig{
+					CPU:    CPUMetricConfig{Enabled: true, MetricName: "cpu_time"},
+					Memory: MemoryMetricConfig{Enabled: true, MetricName: "memory_allocation"},
+				},
+				UseProfileTimestamps: true,
+				AggregationWindow:    tt.window,
+			})
+			require.NoError(t, err)
+
+			b := newTestProfileBuilder()
+			// 15:42 in UTC+2
+			b.profile.SetTime(pcommon.NewTimestampFromTime(time.Date(2026, 10, 15, 15, 42, 0, 0, time.FixedZone("CEST", 2*3600))))
+			b.profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+			b.sample(b.stack("main"), map[string]string{"process.executable.name": "app"}, 1000000000, 0)
+
+			metrics, err := converter.ConvertProfilesToMetrics(context.Background(), b.profiles)
+			require.NoError(t, err)
+
+			metricSlice := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			for i := 0; i < metricSlice.Len(); i++ {
+				dataPoints := metricSlice.At(i).Gauge().DataPoints()
+				for j := 0; j < dataPoints.Len(); j++ {
+					window, ok := dataPoints.At(j).Attributes().Get("aggregation.window.start")
+					require.True(t, ok)
+					assert.Equal(t, tt.expected, window.Str())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAggregationWindow(t *testing.T) {
+	assert.NoError(t, validateAggregationWindow(0))
+	assert.NoError(t, validateAggregationWindow(15*time.Minute))
+	assert.NoError(t, validateAggregationWindow(7*24*time.Hour))
+	assert.Error(t, validateAggregationWindow(7*time.Hour))
+	assert.Error(t, validateAggregationWindow(-time.Hour))
+}
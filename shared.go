@@ -0,0 +1,79 @@
+package profiletometrics
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+// sharedConnectors holds the connector of each component, keyed by its configuration like the
+// sharedcomponent map of upstream components: the profiles, logs and traces pipelines of one
+// connector ID get the same instance, so its diagnostics server, flush loop, accumulator and
+// telemetry exist once.
+var sharedConnectors = &sharedConnectorMap{connectors: make(map[*Config]*sharedConnector)}
+
+// sharedConnectorMap hands out the shared connector of a configuration
+type sharedConnectorMap struct {
+	mu         sync.Mutex
+	connectors map[*Config]*sharedConnector
+}
+
+// sharedConnector is a connector used by the pipelines of several signals; it is started by the
+// first of them and shut down once all of them are
+type sharedConnector struct {
+	*profileToMetricsConnector
+	owner  *sharedConnectorMap
+	config *Config
+	// refs counts the pipelines the connector was created for and that did not shut it down yet
+	refs    int
+	started bool
+}
+
+// getOrCreate returns the connector of a configuration, creating it on first use
+func (m *sharedConnectorMap) getOrCreate(
+	set connector.Settings,
+	config *Config,
+	nextConsumer consumer.Metrics,
+) (*sharedConnector, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if shared, exists := m.connectors[config]; exists {
+		shared.refs++
+		return shared, nil
+	}
+	c, err := newProfileToMetricsConnector(set, config, nextConsumer)
+	if err != nil {
+		return nil, err
+	}
+	shared := &sharedConnector{profileToMetricsConnector: c, owner: m, config: config, refs: 1}
+	m.connectors[config] = shared
+	return shared, nil
+}
+
+// Start implements component.Component, starting the connector for its first pipeline.
+func (s *sharedConnector) Start(ctx context.Context, host component.Host) error {
+	s.owner.mu.Lock()
+	defer s.owner.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	if err := s.profileToMetricsConnector.Start(ctx, host); err != nil {
+		return err
+	}
+	s.started = true
+	return nil
+}
+
+// Shutdown implements component.Component, shutting the connector down with its last pipeline.
+func (s *sharedConnector) Shutdown(ctx context.Context) error {
+	s.owner.mu.Lock()
+	defer s.owner.mu.Unlock()
+	if s.refs--; s.refs > 0 {
+		return nil
+	}
+	delete(s.owner.connectors, s.config)
+	return s.profileToMetricsConnector.Shutdown(ctx)
+}
@@ -0,0 +1,51 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// telemetryScopeName is the instrumentation scope of the connector's own telemetry
+const telemetryScopeName = "github.com/henrikrexed/profiletoMetrics"
+
+// registerAccumulatorTelemetry publishes the size of the aggregation_temporality state through the
+// collector's MeterProvider, so that operators can size and alert on state growth
+func (c *profileToMetricsConnector) registerAccumulatorTelemetry(meterProvider metric.MeterProvider) error {
+	meter := meterProvider.Meter(telemetryScopeName)
+	activeSeries, err := meter.Int64ObservableGauge("profiletometrics.accumulator.active_series",
+		metric.WithDescription("Number of series tracked for aggregation temporality"),
+		metric.WithUnit("{series}"))
+	if err != nil {
+		return fmt.Errorf("failed to create active series gauge: %w", err)
+	}
+	evictions, err := meter.Int64ObservableCounter("profiletometrics.accumulator.evictions",
+		metric.WithDescription("Number of series evicted to respect aggregation_max_series"),
+		metric.WithUnit("{series}"))
+	if err != nil {
+		return fmt.Errorf("failed to create evictions counter: %w", err)
+	}
+	memoryUsage, err := meter.Int64ObservableGauge("profiletometrics.accumulator.memory_usage",
+		metric.WithDescription("Approximate memory used by the series tracked for aggregation temporality"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return fmt.Errorf("failed to create memory usage gauge: %w", err)
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		stats, ok := c.converter.AccumulatorStats()
+		if !ok {
+			return nil
+		}
+		observer.ObserveInt64(activeSeries, int64(stats.ActiveSeries))
+		observer.ObserveInt64(evictions, stats.Evictions)
+		observer.ObserveInt64(memoryUsage, stats.MemoryBytes)
+		return nil
+	}, activeSeries, evictions, memoryUsage)
+	if err != nil {
+		return fmt.Errorf("failed to register accumulator telemetry: %w", err)
+	}
+	c.telemetryRegistration = registration
+	return nil
+}
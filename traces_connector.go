@@ -0,0 +1,79 @@
+package profiletometrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/connector/xconnector"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+
+	"github.com/henrikrexed/profiletoMetrics/pkg/profiletometrics"
+)
+
+// profileToTracesConnector converts profiles into span trees for a traces pipeline.
+type profileToTracesConnector struct {
+	nextConsumer consumer.Traces
+	logger       *zap.Logger
+	converter    *profiletometrics.TraceConverter
+}
+
+func createProfilesToTracesConnector(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Traces,
+) (xconnector.Profiles, error) {
+	config := cfg.(*Config)
+	if !config.ConverterConfig.SpanOutput.Enabled {
+		return nil, fmt.Errorf("exporting profiles to a traces pipeline requires span_output.enabled")
+	}
+	converter, err := profiletometrics.NewTraceConverter(&config.ConverterConfig)
+	if err != nil {
+		return nil, err
+	}
+	converter.SetLogger(set.Logger)
+	return &profileToTracesConnector{
+		nextConsumer: nextConsumer,
+		logger:       set.Logger,
+		converter:    converter,
+	}, nil
+}
+
+// Start implements component.Component.
+func (c *profileToTracesConnector) Start(_ context.Context, _ component.Host) error {
+	c.logger.Info("Starting ProfileToTraces connector")
+	return nil
+}
+
+// Shutdown implements component.Component.
+func (c *profileToTracesConnector) Shutdown(_ context.Context) error {
+	c.logger.Info("Shutting down ProfileToTraces connector")
+	return nil
+}
+
+// Capabilities implements connector interfaces.
+func (c *profileToTracesConnector) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// ConsumeProfiles implements connector.Profiles.
+func (c *profileToTracesConnector) ConsumeProfiles(ctx context.Context, profiles pprofile.Profiles) error {
+	traces, err := c.converter.ConvertProfilesToTraces(ctx, profiles)
+	if err != nil {
+		c.logger.Error("Failed to convert profiles to traces", zap.Error(err))
+		return err
+	}
+	if traces.SpanCount() == 0 {
+		return nil
+	}
+	if err := c.nextConsumer.ConsumeTraces(ctx, traces); err != nil {
+		c.logger.Error("Failed to send traces to next consumer", zap.Error(err), zap.Int("span_count", traces.SpanCount()))
+		return err
+	}
+	c.logger.Debug("Profiles successfully converted to traces", zap.Int("span_count", traces.SpanCount()))
+	return nil
+}
@@ -0,0 +1,88 @@
+package profiletometrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/connector/xconnector"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// newTraceTestProfiles returns a profile of process "checkout" with one sample of the stack main;handle
+func newTraceTestProfiles() pprofile.Profiles {
+	profiles := pprofile.NewProfiles()
+	dictionary := profiles.Dictionary()
+	dictionary.StringTable().Append("", "main", "handle", "process.executable.name")
+	dictionary.FunctionTable().AppendEmpty().SetNameStrindex(1)
+	dictionary.FunctionTable().AppendEmpty().SetNameStrindex(2)
+	dictionary.LocationTable().AppendEmpty().Line().AppendEmpty().SetFunctionIndex(0)
+	dictionary.LocationTable().AppendEmpty().Line().AppendEmpty().SetFunctionIndex(1)
+	dictionary.StackTable().AppendEmpty().LocationIndices().Append(0, 1)
+	attribute := dictionary.AttributeTable().AppendEmpty()
+	attribute.SetKeyStrindex(3)
+	attribute.Value().SetStr("checkout")
+
+	sample := profiles.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty().Profiles().AppendEmpty().Sample().AppendEmpty()
+	sample.SetStackIndex(0)
+	sample.AttributeIndices().Append(0)
+	sample.Values().Append(1000000000)
+	return profiles
+}
+
+func TestNewFactory_ProfilesToTraces(t *testing.T) {
+	factory := NewFactory().(xconnector.Factory)
+	assert.Equal(t, component.StabilityLevelAlpha, factory.ProfilesToTracesStability())
+}
+
+func TestCreateProfilesToTracesConnector(t *testing.T) {
+	settings := connector.Settings{
+		ID:                component.NewID(component.MustNewType("profiletometrics")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}
+
+	t.Run("requires span_output.enabled", func(t *testing.T) {
+		_, err := createProfilesToTracesConnector(context.Background(), settings, createDefaultConfig(), consumertest.NewNop())
+		assert.Error(t, err)
+	})
+
+	t.Run("converts profiles", func(t *testing.T) {
+		config := createDefaultConfig().(*Config)
+		config.ConverterConfig.SpanOutput.Enabled = true
+		config.ConverterConfig.SpanOutput.SpanNameTemplate = "{process}: {function}"
+		require.NoError(t, config.Validate())
+		sink := &consumertest.TracesSink{}
+		tracesConnector, err := createProfilesToTracesConnector(context.Background(), settings, config, sink)
+		require.NoError(t, err)
+		require.NoError(t, tracesConnector.Start(context.Background(), componenttest.NewNopHost()))
+
+		require.NoError(t, tracesConnector.ConsumeProfiles(context.Background(), newTraceTestProfiles()))
+		require.Len(t, sink.AllTraces(), 1)
+		spans := sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+		require.Equal(t, 2, spans.Len())
+		assert.ElementsMatch(t, []string{"checkout: main", "checkout: handle"}, []string{spans.At(0).Name(), spans.At(1).Name()})
+
+		// Profiles without samples produce no traces
+		require.NoError(t, tracesConnector.ConsumeProfiles(context.Background(), pprofile.NewProfiles()))
+		assert.Len(t, sink.AllTraces(), 1)
+		require.NoError(t, tracesConnector.Shutdown(context.Background()))
+	})
+
+	t.Run("caps spans per profile", func(t *testing.T) {
+		config := createDefaultConfig().(*Config)
+		config.ConverterConfig.SpanOutput.Enabled = true
+		config.ConverterConfig.SpanOutput.MaxSpansPerProfile = 1
+		sink := &consumertest.TracesSink{}
+		tracesConnector, err := createProfilesToTracesConnector(context.Background(), settings, config, sink)
+		require.NoError(t, err)
+
+		require.NoError(t, tracesConnector.ConsumeProfiles(context.Background(), newTraceTestProfiles()))
+		require.Len(t, sink.AllTraces(), 1)
+		assert.Equal(t, 1, sink.AllTraces()[0].SpanCount())
+	})
+}